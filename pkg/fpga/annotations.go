@@ -0,0 +1,67 @@
+// Copyright 2023 Intel Corporation. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fpga
+
+import "sync"
+
+// deviceAnnotations holds user metadata of the devices keyed by device node.
+// It's kept in memory only, so the annotations survive closing and reopening
+// of the device, but not restart of the process.
+var deviceAnnotations = struct {
+	values map[string]map[string]string
+	sync.RWMutex
+}{
+	values: map[string]map[string]string{},
+}
+
+// setAnnotation sets annotation key of the device. Empty value removes the key.
+func setAnnotation(devPath, key, value string) {
+	deviceAnnotations.Lock()
+	defer deviceAnnotations.Unlock()
+
+	if value == "" {
+		delete(deviceAnnotations.values[devPath], key)
+
+		if len(deviceAnnotations.values[devPath]) == 0 {
+			delete(deviceAnnotations.values, devPath)
+		}
+
+		return
+	}
+
+	if deviceAnnotations.values[devPath] == nil {
+		deviceAnnotations.values[devPath] = map[string]string{}
+	}
+
+	deviceAnnotations.values[devPath][key] = value
+}
+
+// getAnnotations returns copy of the device annotations or nil if there're none.
+func getAnnotations(devPath string) map[string]string {
+	deviceAnnotations.RLock()
+	defer deviceAnnotations.RUnlock()
+
+	values := deviceAnnotations.values[devPath]
+	if len(values) == 0 {
+		return nil
+	}
+
+	ret := make(map[string]string, len(values))
+	for k, v := range values {
+		ret[k] = v
+	}
+
+	return ret
+}
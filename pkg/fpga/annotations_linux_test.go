@@ -0,0 +1,73 @@
+// Copyright 2023 Intel Corporation. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fpga
+
+import (
+	"reflect"
+	"testing"
+)
+
+// clearTestAnnotations removes annotations of the devices when the test ends.
+func clearTestAnnotations(t *testing.T, devPaths ...string) {
+	t.Helper()
+
+	t.Cleanup(func() {
+		deviceAnnotations.Lock()
+		defer deviceAnnotations.Unlock()
+
+		for _, devPath := range devPaths {
+			delete(deviceAnnotations.values, devPath)
+		}
+	})
+}
+
+func TestAnnotations(t *testing.T) {
+	clearTestAnnotations(t, "/dev/intel-fpga-port.3", "/dev/dfl-port.3")
+
+	port := &IntelFpgaPort{DevPath: "/dev/intel-fpga-port.3"}
+	if got := port.Annotations(); got != nil {
+		t.Errorf("expected no annotations, but got %v", got)
+	}
+
+	port.SetAnnotation("rack", "r12")
+	port.SetAnnotation("purpose", "inference")
+	port.SetAnnotation("purpose", "training")
+
+	expected := map[string]string{"rack": "r12", "purpose": "training"}
+
+	// Annotations are shared by all handles of the device node.
+	reopened := &IntelFpgaPort{DevPath: "/dev/intel-fpga-port.3"}
+
+	got := reopened.Annotations()
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected %v, but got %v", expected, got)
+	}
+
+	got["rack"] = "modified"
+	if port.Annotations()["rack"] != "r12" {
+		t.Error("modifying the returned map must not change annotations")
+	}
+
+	if other := (&DflPort{DevPath: "/dev/dfl-port.3"}).Annotations(); other != nil {
+		t.Errorf("unexpected annotations of another device %v", other)
+	}
+
+	port.SetAnnotation("rack", "")
+	port.SetAnnotation("purpose", "")
+
+	if got := port.Annotations(); got != nil {
+		t.Errorf("expected annotations to be removed, but got %v", got)
+	}
+}
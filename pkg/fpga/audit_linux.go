@@ -0,0 +1,130 @@
+// Copyright 2023 Intel Corporation. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fpga
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"sync"
+	"time"
+)
+
+// Operations reported in AuditEvent.
+const (
+	AuditPR          = "pr"
+	AuditPortReset   = "port-reset"
+	AuditPortRelease = "port-release"
+	AuditPortAssign  = "port-assign"
+)
+
+// AuditEvent describes an operation changing the device state.
+type AuditEvent struct {
+	// Timestamp is the time the operation completed.
+	Timestamp time.Time
+	// Err is the error the operation failed with, nil on success.
+	Err error
+	// PortID is the ID of the port the FME operation is done on. It's nil
+	// for the operations of ports, Device is the port then.
+	PortID *uint32
+	// Operation is one of the Audit* operations.
+	Operation string
+	// Device is the device node of the FME or the port.
+	Device string
+	// AFU is the AFU UUID of the PR bitstream, if known.
+	AFU string
+	// Checksum is hex SHA-256 of the raw PR bitstream.
+	Checksum string
+}
+
+// Auditor records the device state changes, e.g. to the audit log of
+// the host.
+type Auditor interface {
+	// Record is called once the operation is done. It must not block
+	// for long, the device operation waits for it
+	Record(event AuditEvent)
+}
+
+// auditor holds the Auditor set by SetAuditor. Nil is no-op.
+var auditor = struct {
+	a Auditor
+	sync.RWMutex
+}{}
+
+// auditAFUKey is the context key of the AFU UUID of the bitstream
+// programmed by Port.PR, for the PR audit event of the FME.
+type auditAFUKey struct{}
+
+// SetAuditor makes the operations changing the device state, i.e. partial
+// reconfiguration, port reset, release and assign, report the outcome to a.
+// The events are recorded for the operations submitted to the driver, the
+// ones refused before, e.g. in read-only mode, aren't. The auditor applies
+// to the whole process. Nil disables auditing, that is the default.
+func SetAuditor(a Auditor) {
+	auditor.Lock()
+	defer auditor.Unlock()
+
+	auditor.a = a
+}
+
+// getAuditor returns the auditor set by SetAuditor, nil if there's none.
+func getAuditor() Auditor {
+	auditor.RLock()
+	defer auditor.RUnlock()
+
+	return auditor.a
+}
+
+// recordAudit passes the event to the auditor, if set.
+func recordAudit(event AuditEvent) {
+	a := getAuditor()
+	if a == nil {
+		return
+	}
+
+	event.Timestamp = time.Now()
+	a.Record(event)
+}
+
+// recordPortAudit records FME operation done on the port.
+func recordPortAudit(op, dev string, port uint32, err error) {
+	recordAudit(AuditEvent{Operation: op, Device: dev, PortID: &port, Err: err})
+}
+
+// recordPRAudit records partial reconfiguration of the port with data. The
+// AFU UUID is taken from ctx, see withAuditAFU. The checksum is only
+// computed with the auditor set, bitstreams are large.
+func recordPRAudit(ctx context.Context, dev string, port uint32, data []byte, err error) {
+	if getAuditor() == nil {
+		return
+	}
+
+	sum := sha256.Sum256(data)
+	afu, _ := ctx.Value(auditAFUKey{}).(string)
+
+	recordAudit(AuditEvent{
+		Operation: AuditPR,
+		Device:    dev,
+		PortID:    &port,
+		AFU:       afu,
+		Checksum:  hex.EncodeToString(sum[:]),
+		Err:       err,
+	})
+}
+
+// withAuditAFU returns ctx making the PR audit events report the AFU UUID.
+func withAuditAFU(ctx context.Context, afu string) context.Context {
+	return context.WithValue(ctx, auditAFUKey{}, afu)
+}
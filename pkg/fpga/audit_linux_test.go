@@ -0,0 +1,161 @@
+// Copyright 2023 Intel Corporation. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fpga
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"syscall"
+	"testing"
+	"unsafe"
+
+	"github.com/pkg/errors"
+)
+
+type testAuditor struct {
+	events []AuditEvent
+}
+
+func (a *testAuditor) Record(event AuditEvent) {
+	a.events = append(a.events, event)
+}
+
+func TestAudit(t *testing.T) {
+	var ioctlErr error
+
+	setTestIoctl(t, func(dev string, req uint, arg unsafe.Pointer) (uintptr, error) {
+		return 0, ioctlErr
+	})
+
+	intelFME := newTestIntelFpgaFME(t, nil)
+	intelFME.CompatID = testInterface
+	port := newTestIntelFpgaPort(t, intelFME, testAFUOld)
+	dflFME := &DflFME{DevPath: "/dev/dfl-fme.0"}
+	dflPort := &DflPort{DevPath: "/dev/dfl-port.0"}
+	bs := newTestGBS(t, testInterface, testAFUNew)
+
+	raw, err := bs.RawBitstreamData()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rawSum := sha256.Sum256(raw)
+	checksum := hex.EncodeToString(rawSum[:])
+	zeroSum := sha256.Sum256([]byte{0})
+	zeroChecksum := hex.EncodeToString(zeroSum[:])
+
+	tcases := []struct {
+		mutate   func() error
+		ioctlErr error
+		name     string
+		expected AuditEvent
+		port     bool
+	}{
+		{
+			name:     "intel-fpga PR",
+			mutate:   func() error { _, prErr := port.PRWithOptions(bs, PROptions{SkipReadback: true}); return prErr },
+			expected: AuditEvent{Operation: AuditPR, Device: intelFME.DevPath, AFU: testAFUNew, Checksum: checksum},
+			port:     true,
+		},
+		{
+			name:     "intel-fpga PortPR failure",
+			mutate:   func() error { return intelFME.PortPR(0, []byte{0}) },
+			ioctlErr: syscall.EINVAL,
+			expected: AuditEvent{Operation: AuditPR, Device: intelFME.DevPath, Checksum: zeroChecksum},
+			port:     true,
+		},
+		{
+			name:     "intel-fpga PortRelease",
+			mutate:   func() error { return intelFME.PortRelease(0) },
+			expected: AuditEvent{Operation: AuditPortRelease, Device: intelFME.DevPath},
+			port:     true,
+		},
+		{
+			name:     "intel-fpga PortAssign",
+			mutate:   func() error { return intelFME.PortAssign(0) },
+			expected: AuditEvent{Operation: AuditPortAssign, Device: intelFME.DevPath},
+			port:     true,
+		},
+		{
+			name:     "intel-fpga PortReset",
+			mutate:   port.PortReset,
+			expected: AuditEvent{Operation: AuditPortReset, Device: port.DevPath},
+		},
+		{
+			name:     "DFL PortPR",
+			mutate:   func() error { return dflFME.PortPR(0, []byte{0}) },
+			expected: AuditEvent{Operation: AuditPR, Device: dflFME.DevPath, Checksum: zeroChecksum},
+			port:     true,
+		},
+		{
+			name:     "DFL PortRelease failure",
+			mutate:   func() error { return dflFME.PortRelease(0) },
+			ioctlErr: syscall.EBUSY,
+			expected: AuditEvent{Operation: AuditPortRelease, Device: dflFME.DevPath},
+			port:     true,
+		},
+		{
+			name:     "DFL PortAssign",
+			mutate:   func() error { return dflFME.PortAssign(0) },
+			expected: AuditEvent{Operation: AuditPortAssign, Device: dflFME.DevPath},
+			port:     true,
+		},
+		{
+			name:     "DFL PortReset",
+			mutate:   dflPort.PortReset,
+			expected: AuditEvent{Operation: AuditPortReset, Device: dflPort.DevPath},
+		},
+	}
+	for _, tc := range tcases {
+		t.Run(tc.name, func(t *testing.T) {
+			auditor := &testAuditor{}
+			SetAuditor(auditor)
+			t.Cleanup(func() { SetAuditor(nil) })
+
+			ioctlErr = tc.ioctlErr
+			err := tc.mutate()
+
+			if len(auditor.events) != 1 {
+				t.Fatalf("expected one audit event, but got %+v", auditor.events)
+			}
+
+			event := auditor.events[0]
+
+			if !errors.Is(event.Err, tc.ioctlErr) || !errors.Is(err, tc.ioctlErr) {
+				t.Errorf("expected error %v, but got %v recorded for %v", tc.ioctlErr, event.Err, err)
+			}
+
+			if event.Timestamp.IsZero() {
+				t.Error("no timestamp recorded")
+			}
+
+			if tc.port != (event.PortID != nil) || (event.PortID != nil && *event.PortID != 0) {
+				t.Errorf("unexpected port ID %v", event.PortID)
+			}
+
+			event.Timestamp, event.Err, event.PortID = tc.expected.Timestamp, nil, nil
+
+			if event != tc.expected {
+				t.Errorf("expected %+v, but got %+v", tc.expected, event)
+			}
+		})
+	}
+
+	ioctlErr = nil
+
+	if err := intelFME.PortRelease(0); err != nil {
+		t.Errorf("unexpected error without auditor: %+v", err)
+	}
+}
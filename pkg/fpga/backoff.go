@@ -0,0 +1,130 @@
+// Copyright 2023 Intel Corporation. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fpga
+
+import (
+	"context"
+	"math/rand"
+	"time"
+)
+
+// Backoff defines delays between repeated driver operations, such as
+// polling sysfs for a value to change. The delay starts from Initial and is
+// multiplied by Factor after every attempt until it reaches Max. Every
+// delay is randomly spread by up to Jitter fraction of its value. Zero
+// fields are replaced with the package defaults.
+type Backoff struct {
+	// Initial is the delay before the second attempt.
+	Initial time.Duration
+	// Max caps the delay between attempts.
+	Max time.Duration
+	// Factor multiplies the delay after every attempt.
+	Factor float64
+	// Jitter is the fraction of the delay it can be randomly spread by.
+	// Negative value disables jitter.
+	Jitter float64
+	// Steps limits the number of retries of operations that are retried
+	// on failure, such as reading AFU UUID after PR. Zero means no retries.
+	// Wait* methods ignore it and poll until their context is done.
+	Steps int
+}
+
+var (
+	defaultBackoff = Backoff{
+		Initial: 100 * time.Millisecond,
+		Max:     time.Second,
+		Factor:  2,
+		Jitter:  0.1,
+	}
+
+	// jitterRand returns pseudo-random number in [0.0,1.0). Tests replace it
+	// to get deterministic delays.
+	jitterRand = rand.Float64
+)
+
+// backoff generates the sequence of delays defined by Backoff.
+type backoff struct {
+	Backoff
+	next time.Duration
+}
+
+func newBackoff(b Backoff) *backoff {
+	if b.Initial <= 0 {
+		b.Initial = defaultBackoff.Initial
+	}
+
+	if b.Max <= 0 {
+		b.Max = defaultBackoff.Max
+	}
+
+	if b.Max < b.Initial {
+		b.Max = b.Initial
+	}
+
+	if b.Factor < 1 {
+		b.Factor = defaultBackoff.Factor
+	}
+
+	switch {
+	case b.Jitter == 0:
+		b.Jitter = defaultBackoff.Jitter
+	case b.Jitter < 0:
+		b.Jitter = 0
+	}
+
+	return &backoff{Backoff: b, next: b.Initial}
+}
+
+// delay returns the next delay of the sequence.
+func (b *backoff) delay() time.Duration {
+	d := b.next
+
+	if next := time.Duration(float64(b.next) * b.Factor); next < b.Max {
+		b.next = next
+	} else {
+		b.next = b.Max
+	}
+
+	return d + time.Duration((2*jitterRand()-1)*b.Jitter*float64(d))
+}
+
+// wait sleeps for the next delay of the sequence or until ctx is done.
+func (b *backoff) wait(ctx context.Context) error {
+	timer := time.NewTimer(b.delay())
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// retry calls op until it succeeds, ctx is done or the number of retries
+// reaches Steps. The last error of op is returned.
+func (b *backoff) retry(ctx context.Context, op func() error) error {
+	err := op()
+
+	for i := 0; err != nil && i < b.Steps; i++ {
+		if werr := b.wait(ctx); werr != nil {
+			return err
+		}
+
+		err = op()
+	}
+
+	return err
+}
@@ -0,0 +1,150 @@
+// Copyright 2023 Intel Corporation. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fpga
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+func TestBackoffDelay(t *testing.T) {
+	tcases := []struct {
+		name     string
+		backoff  Backoff
+		rand     float64
+		expected []time.Duration
+	}{
+		{
+			name:     "defaults",
+			backoff:  Backoff{Jitter: -1},
+			expected: []time.Duration{100, 200, 400, 800, 1000, 1000},
+		},
+		{
+			name:     "custom",
+			backoff:  Backoff{Initial: 10, Max: 100, Factor: 3, Jitter: -1},
+			expected: []time.Duration{10, 30, 90, 100, 100},
+		},
+		{
+			name:     "max below initial",
+			backoff:  Backoff{Initial: 50, Max: 20, Jitter: -1},
+			expected: []time.Duration{50, 50, 50},
+		},
+		{
+			name:     "upper jitter",
+			backoff:  Backoff{Initial: 100, Max: 400, Factor: 2, Jitter: 0.5},
+			rand:     1,
+			expected: []time.Duration{150, 300, 600, 600},
+		},
+		{
+			name:     "lower jitter",
+			backoff:  Backoff{Initial: 100, Max: 400, Factor: 2, Jitter: 0.5},
+			rand:     0,
+			expected: []time.Duration{50, 100, 200, 200},
+		},
+	}
+	for _, tc := range tcases {
+		t.Run(tc.name, func(t *testing.T) {
+			origRand := jitterRand
+			jitterRand = func() float64 { return tc.rand }
+
+			t.Cleanup(func() { jitterRand = origRand })
+
+			unit := time.Millisecond
+			if tc.backoff.Initial != 0 {
+				// Custom values are given in nanoseconds.
+				unit = 1
+			}
+
+			b := newBackoff(tc.backoff)
+			for i, expected := range tc.expected {
+				if d := b.delay(); d != expected*unit {
+					t.Errorf("delay %d: expected %v, but got %v", i, expected*unit, d)
+				}
+			}
+		})
+	}
+}
+
+func TestBackoffRetry(t *testing.T) {
+	errFailed := errors.New("failed")
+
+	tcases := []struct {
+		name          string
+		steps         int
+		failures      int
+		expectedCalls int
+		expectedErr   bool
+	}{
+		{
+			name:          "no retries",
+			failures:      1,
+			expectedCalls: 1,
+			expectedErr:   true,
+		},
+		{
+			name:          "succeeds after retries",
+			steps:         3,
+			failures:      2,
+			expectedCalls: 3,
+		},
+		{
+			name:          "retries exhausted",
+			steps:         2,
+			failures:      5,
+			expectedCalls: 3,
+			expectedErr:   true,
+		},
+	}
+	for _, tc := range tcases {
+		t.Run(tc.name, func(t *testing.T) {
+			calls := 0
+			b := newBackoff(Backoff{Initial: time.Millisecond, Steps: tc.steps})
+
+			err := b.retry(context.Background(), func() error {
+				calls++
+				if calls <= tc.failures {
+					return errFailed
+				}
+
+				return nil
+			})
+			if tc.expectedErr != (err != nil) {
+				t.Errorf("unexpected error: %v", err)
+			}
+
+			if calls != tc.expectedCalls {
+				t.Errorf("expected %d calls, but got %d", tc.expectedCalls, calls)
+			}
+		})
+	}
+
+	t.Run("cancelled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		calls := 0
+		err := newBackoff(Backoff{Steps: 10}).retry(ctx, func() error {
+			calls++
+
+			return errFailed
+		})
+		if !errors.Is(err, errFailed) || calls != 1 {
+			t.Errorf("expected the op error after a single call, but got %v after %d calls", err, calls)
+		}
+	})
+}
@@ -23,6 +23,7 @@ import (
 	"path/filepath"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/pkg/errors"
 )
@@ -244,3 +245,58 @@ func (f *FileAOCX) ExtraMetadata() map[string]string {
 		"Size":    strconv.FormatUint(f.GBS.Bitstream.Size, 10),
 	}
 }
+
+// BuildTime returns build time of the underlying GBS.
+func (f *FileAOCX) BuildTime() (time.Time, error) {
+	if f.GBS == nil {
+		return time.Time{}, errors.Wrap(ErrNotSupported, "no GBS in AOCX file")
+	}
+
+	return f.GBS.BuildTime()
+}
+
+// RequiredInterfaceVersion returns the interface version required by the underlying GBS.
+func (f *FileAOCX) RequiredInterfaceVersion() (string, error) {
+	if f.GBS == nil {
+		return "", errors.Wrap(ErrNotSupported, "no GBS in AOCX file")
+	}
+
+	return f.GBS.RequiredInterfaceVersion()
+}
+
+// PlatformName returns the platform name of the underlying GBS.
+func (f *FileAOCX) PlatformName() (string, error) {
+	if f.GBS == nil {
+		return "", errors.Wrap(ErrNotSupported, "no GBS in AOCX file")
+	}
+
+	return f.GBS.PlatformName()
+}
+
+// Tags returns the capability tags of the underlying GBS.
+func (f *FileAOCX) Tags() (ret []string) {
+	if f.GBS != nil {
+		ret = f.GBS.Tags()
+	}
+
+	return
+}
+
+// ImageType returns the image type of the underlying GBS.
+func (f *FileAOCX) ImageType() (ImageType, error) {
+	if f.GBS == nil {
+		return 0, errors.Wrap(ErrNotSupported, "no GBS in AOCX file")
+	}
+
+	return f.GBS.ImageType()
+}
+
+// IsSigned isn't applicable to AOCX files.
+func (f *FileAOCX) IsSigned() (bool, error) {
+	return false, ErrNotSupported
+}
+
+// SignatureInfo isn't applicable to AOCX files.
+func (f *FileAOCX) SignatureInfo() (*SignatureInfo, error) {
+	return nil, ErrNotSupported
+}
@@ -15,19 +15,138 @@
 package bitstream
 
 import (
+	"bytes"
+	"debug/elf"
 	"os"
 	"path/filepath"
+	"strings"
 
 	"github.com/pkg/errors"
 )
 
+// ImageType tells how the bitstream is loaded to the FPGA.
+type ImageType int
+
+const (
+	// PartialReconfig images are programmed to a port with partial
+	// reconfiguration, leaving the rest of the FPGA running.
+	PartialReconfig ImageType = iota
+	// FullChip images replace the whole FPGA design, including the FIM.
+	// They are written to the flash of the card and can't be programmed
+	// with partial reconfiguration.
+	FullChip
+)
+
+func (t ImageType) String() string {
+	switch t {
+	case PartialReconfig:
+		return "partial reconfiguration"
+	case FullChip:
+		return "full chip"
+	default:
+		return "unknown"
+	}
+}
+
+// Format describes a bitstream file format the package can parse.
+type Format struct {
+	open  func(string) (File, error)
+	parse func(*bytes.Reader) (File, error)
+	// Name is a human readable name of the format.
+	Name string
+	// Extension is the file name extension of the format, including the dot.
+	Extension string
+	// Magic is the sequence of bytes every file of the format starts with.
+	Magic []byte
+}
+
+// Matches returns true if the file header starts with the format's magic bytes.
+func (f Format) Matches(header []byte) bool {
+	return bytes.HasPrefix(header, f.Magic)
+}
+
+var formats = []Format{
+	{
+		Name:      "Green Bitstream (GBS)",
+		Extension: fileExtensionGBS,
+		// bitstreamGUID1 and bitstreamGUID2 in little-endian byte order.
+		Magic: []byte("XeonFPGA\xb7GBSv001"),
+		open: func(fname string) (File, error) {
+			f, err := OpenGBS(fname)
+			if err != nil {
+				return nil, err
+			}
+
+			return f, nil
+		},
+		parse: func(r *bytes.Reader) (File, error) {
+			f, err := NewFileGBS(r)
+			if err != nil {
+				return nil, err
+			}
+
+			return f, nil
+		},
+	},
+	{
+		Name:      "Intel FPGA SDK for OpenCL (AOCX)",
+		Extension: fileExtensionAOCX,
+		Magic:     []byte(elf.ELFMAG),
+		open: func(fname string) (File, error) {
+			f, err := OpenAOCX(fname)
+			if err != nil {
+				return nil, err
+			}
+
+			return f, nil
+		},
+		parse: func(r *bytes.Reader) (File, error) {
+			f, err := NewFileAOCX(r)
+			if err != nil {
+				return nil, err
+			}
+
+			return f, nil
+		},
+	},
+}
+
+// SupportedFormats returns bitstream file formats the package can parse.
+func SupportedFormats() []Format {
+	ret := make([]Format, len(formats))
+	for i, f := range formats {
+		f.Magic = append([]byte(nil), f.Magic...)
+		ret[i] = f
+	}
+
+	return ret
+}
+
+// supportedExtensions returns comma-separated list of known file name extensions.
+func supportedExtensions() string {
+	exts := make([]string, 0, len(formats))
+	for _, f := range formats {
+		exts = append(exts, f.Extension)
+	}
+
+	return strings.Join(exts, ", ")
+}
+
+// formatByExtension returns the format with the given file name extension.
+func formatByExtension(ext string) (Format, bool) {
+	for _, format := range formats {
+		if ext == format.Extension {
+			return format, true
+		}
+	}
+
+	return Format{}, false
+}
+
 // GetFPGABitstream scans bitstream storage and returns first found bitstream by region and afu id.
 func GetFPGABitstream(bitstreamDir, region, afu string) (File, error) {
-	bitstreamPath := ""
-	// Temporarily only support gbs bitstreams
-	// for _, ext := range []string{".gbs", ".aocx"} {
-	for _, ext := range []string{".gbs", ".aocx"} {
-		bitstreamPath = filepath.Join(bitstreamDir, region, afu+ext)
+	for _, format := range formats {
+		bitstreamPath := filepath.Join(bitstreamDir, region, afu+format.Extension)
 
 		_, err := os.Stat(bitstreamPath)
 		if os.IsNotExist(err) {
@@ -38,7 +157,7 @@ func GetFPGABitstream(bitstreamDir, region, afu string) (File, error) {
 			return nil, errors.Errorf("%s: stat error: %v", bitstreamPath, err)
 		}
 
-		return Open(bitstreamPath)
+		return format.open(bitstreamPath)
 	}
 
 	return nil, errors.Errorf("%s/%s: bitstream not found", region, afu)
@@ -46,12 +165,9 @@ func GetFPGABitstream(bitstreamDir, region, afu string) (File, error) {
 
 // Open bitstream file, detecting type based on the filename extension.
 func Open(fname string) (File, error) {
-	switch filepath.Ext(fname) {
-	case ".gbs":
-		return OpenGBS(fname)
-	case ".aocx":
-		return OpenAOCX(fname)
+	if format, ok := formatByExtension(filepath.Ext(fname)); ok {
+		return format.open(fname)
 	}
 
-	return nil, errors.Errorf("unsupported file format %s", fname)
+	return nil, errors.Errorf("unsupported file format %s, supported extensions: %s", fname, supportedExtensions())
 }
@@ -15,6 +15,9 @@
 package bitstream
 
 import (
+	"encoding/binary"
+	"io"
+	"os"
 	"testing"
 )
 
@@ -100,3 +103,65 @@ func TestOpen(t *testing.T) {
 		})
 	}
 }
+
+func readHeader(fname string) ([]byte, error) {
+	f, err := os.Open(fname)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	header := make([]byte, 64)
+	n, err := io.ReadFull(f, header)
+
+	return header[:n], err
+}
+
+func TestSupportedFormats(t *testing.T) {
+	// The only AOCX sample in testdata is empty, but any ELF binary has the right header.
+	elfBinary, err := os.Executable()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	samples := map[string]string{
+		".gbs":  "testdata/intel.com/fpga/69528db6eb31577a8c3668f9faa081f6/d8424dc4a4a3c413f89e433683f9040b.gbs",
+		".aocx": elfBinary,
+	}
+
+	formats := SupportedFormats()
+	if len(formats) != len(samples) {
+		t.Fatalf("expected %d formats, but got %d", len(samples), len(formats))
+	}
+
+	for _, format := range formats {
+		sample, ok := samples[format.Extension]
+		if !ok {
+			t.Errorf("unexpected format %q (%s)", format.Name, format.Extension)
+			continue
+		}
+
+		data, err := readHeader(sample)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for _, other := range formats {
+			if matches := other.Matches(data); matches != (other.Extension == format.Extension) {
+				t.Errorf("%s sample: unexpected match result %t for format %q", format.Extension, matches, other.Name)
+			}
+		}
+	}
+
+	gbs := formats[0]
+	if len(gbs.Magic) != 16 ||
+		binary.LittleEndian.Uint64(gbs.Magic[:8]) != bitstreamGUID1 ||
+		binary.LittleEndian.Uint64(gbs.Magic[8:]) != bitstreamGUID2 {
+		t.Errorf("GBS magic %q doesn't match file header GUIDs", gbs.Magic)
+	}
+
+	gbs.Magic[0] = 0
+	if SupportedFormats()[0].Magic[0] == 0 {
+		t.Error("modification of returned formats must not affect the package")
+	}
+}
@@ -0,0 +1,149 @@
+// Copyright 2023 Intel Corporation. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bitstream
+
+import (
+	"io/fs"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// CatalogEntry describes a bitstream file found by Catalog.
+type CatalogEntry struct {
+	// Err is set if the file couldn't be parsed. The metadata fields
+	// are empty in that case.
+	Err error
+	// Path is the path of the file relative to the catalog directory.
+	Path string
+	// Format is the name of the file format, see Format.Name.
+	Format string
+	// InterfaceUUID is the interface UUID of the bitstream.
+	InterfaceUUID string
+	// AcceleratorTypeUUID is the AFU UUID of the bitstream.
+	AcceleratorTypeUUID string
+	// Size is the file size in bytes.
+	Size int64
+}
+
+// ErrBitstreamNotFound is returned by FindByTag when no bitstream matches.
+var ErrBitstreamNotFound = errors.New("bitstream not found")
+
+// errStopWalk stops walkBitstreams without error.
+var errStopWalk = errors.New("stop walk")
+
+// Catalog walks dir recursively and returns entries for all files with
+// bitstream file name extensions, in lexical order. Files with other
+// extensions are skipped. Failures to parse individual files are recorded
+// in their entries, only failures to walk dir are returned as error.
+func Catalog(dir string) ([]CatalogEntry, error) {
+	entries := []CatalogEntry{}
+
+	err := walkBitstreams(dir, func(path string, format Format, d fs.DirEntry) error {
+		entry := CatalogEntry{Format: format.Name}
+		entry.Path, _ = filepath.Rel(dir, path)
+
+		if info, infoErr := d.Info(); infoErr == nil {
+			entry.Size = info.Size()
+		}
+
+		f, openErr := format.open(path)
+		if openErr != nil {
+			entry.Err = openErr
+			entries = append(entries, entry)
+
+			return nil
+		}
+		defer f.Close()
+
+		entry.InterfaceUUID = f.InterfaceUUID()
+		entry.AcceleratorTypeUUID = f.AcceleratorTypeUUID()
+		entries = append(entries, entry)
+
+		return nil
+	})
+	if err != nil {
+		return entries, errors.Wrapf(err, "unable to catalog %s", dir)
+	}
+
+	return entries, nil
+}
+
+// FindByTag walks dir recursively, in lexical order, and returns the first
+// bitstream with the capability tag, see File.Tags. Tags are compared case
+// insensitively. Files failing to parse are skipped. ErrBitstreamNotFound
+// is returned if no bitstream has the tag. The caller is responsible for
+// closing the bitstream.
+func FindByTag(dir, tag string) (File, error) {
+	var found File
+
+	err := walkBitstreams(dir, func(path string, format Format, _ fs.DirEntry) error {
+		f, openErr := format.open(path)
+		if openErr != nil {
+			return nil
+		}
+
+		if !hasTag(f, tag) {
+			f.Close()
+
+			return nil
+		}
+
+		found = f
+
+		return errStopWalk
+	})
+	if err != nil && !errors.Is(err, errStopWalk) {
+		return nil, errors.Wrapf(err, "unable to search %s", dir)
+	}
+
+	if found == nil {
+		return nil, errors.Wrapf(ErrBitstreamNotFound, "%s: no bitstream with tag %q", dir, tag)
+	}
+
+	return found, nil
+}
+
+func hasTag(f File, tag string) bool {
+	for _, t := range f.Tags() {
+		if strings.EqualFold(strings.TrimSpace(t), tag) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// walkBitstreams calls fn for all regular files under dir with bitstream
+// file name extensions, in lexical order. Errors of fn stop the walk.
+func walkBitstreams(dir string, fn func(path string, format Format, d fs.DirEntry) error) error {
+	return filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if !d.Type().IsRegular() {
+			return nil
+		}
+
+		format, ok := formatByExtension(filepath.Ext(path))
+		if !ok {
+			return nil
+		}
+
+		return fn(path, format, d)
+	})
+}
@@ -0,0 +1,137 @@
+// Copyright 2023 Intel Corporation. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bitstream
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/pkg/errors"
+)
+
+func TestCatalog(t *testing.T) {
+	dir := t.TempDir()
+
+	gbs, err := os.ReadFile("testdata/intel.com/fpga/69528db6eb31577a8c3668f9faa081f6/d8424dc4a4a3c413f89e433683f9040b.gbs")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	files := map[string][]byte{
+		"69528db6eb31577a8c3668f9faa081f6/d8424dc4a4a3c413f89e433683f9040b.gbs": gbs,
+		"69528db6eb31577a8c3668f9faa081f6/README.md":                            []byte("not a bitstream"),
+		"broken.gbs": []byte("garbage"),
+		"empty.aocx": {},
+	}
+	for name, data := range files {
+		path := filepath.Join(dir, name)
+		if err = os.MkdirAll(filepath.Dir(path), 0750); err != nil {
+			t.Fatal(err)
+		}
+
+		if err = os.WriteFile(path, data, 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	entries, err := Catalog(dir)
+	if err != nil {
+		t.Fatalf("unexpected error: %+v", err)
+	}
+
+	expected := []CatalogEntry{
+		{
+			Path:                "69528db6eb31577a8c3668f9faa081f6/d8424dc4a4a3c413f89e433683f9040b.gbs",
+			Format:              formats[0].Name,
+			InterfaceUUID:       "69528db6eb31577a8c3668f9faa081f6",
+			AcceleratorTypeUUID: "d8424dc4a4a3c413f89e433683f9040b",
+			Size:                int64(len(gbs)),
+		},
+		{Path: "broken.gbs", Format: formats[0].Name, Size: 7},
+		{Path: "empty.aocx", Format: formats[1].Name},
+	}
+
+	if len(entries) != len(expected) {
+		t.Fatalf("expected %d entries, but got %+v", len(expected), entries)
+	}
+
+	for i, entry := range entries {
+		if (entry.Err != nil) != (expected[i].InterfaceUUID == "") {
+			t.Errorf("%s: unexpected error %v", entry.Path, entry.Err)
+		}
+
+		entry.Err = nil
+		if entry != expected[i] {
+			t.Errorf("expected %+v, but got %+v", expected[i], entry)
+		}
+	}
+
+	if _, err = Catalog(filepath.Join(dir, "nonexistent")); err == nil {
+		t.Error("no error returned for missing directory")
+	}
+}
+
+func TestFindByTag(t *testing.T) {
+	tcases := []struct {
+		name         string
+		tag          string
+		expectedTags []string
+		notFound     bool
+	}{
+		{
+			name:         "first match in lexical order",
+			tag:          "gzip",
+			expectedTags: []string{"gzip"},
+		},
+		{
+			name:         "second tag",
+			tag:          "deflate",
+			expectedTags: []string{"gzip", "deflate"},
+		},
+		{
+			name:         "case insensitive",
+			tag:          "aes256",
+			expectedTags: []string{"AES256"},
+		},
+		{
+			name:     "no match",
+			tag:      "sha3",
+			notFound: true,
+		},
+	}
+	for _, tc := range tcases {
+		t.Run(tc.name, func(t *testing.T) {
+			f, err := FindByTag("testdata/tags", tc.tag)
+			if tc.notFound {
+				if !errors.Is(err, ErrBitstreamNotFound) {
+					t.Errorf("expected ErrBitstreamNotFound, but got %v", err)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("unexpected error: %+v", err)
+			}
+			defer f.Close()
+
+			if !reflect.DeepEqual(f.Tags(), tc.expectedTags) {
+				t.Errorf("expected bitstream with tags %v, but got %v", tc.expectedTags, f.Tags())
+			}
+		})
+	}
+}
@@ -22,10 +22,14 @@ import (
 	"path/filepath"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/pkg/errors"
 )
 
+// GBSMagicNo is the magic number the packager puts into afu-image metadata.
+const GBSMagicNo = 488605312
+
 const (
 	bitstreamGUID1   uint64 = 0x414750466e6f6558
 	bitstreamGUID2   uint64 = 0x31303076534247b7
@@ -33,6 +37,12 @@ const (
 	fileExtensionGBS        = ".gbs"
 )
 
+// Values of "image-type" of the metadata.
+const (
+	gbsImageTypePartial = "partial"
+	gbsImageTypeFull    = "full"
+)
+
 // Header represents header struct of the GBS file.
 type Header struct {
 	GUID1          uint64
@@ -52,8 +62,10 @@ type FileGBS struct {
 type Metadata struct {
 	PlatformName string `json:"platform-name,omitempty"`
 	AfuImage     struct {
-		InterfaceUUID   string `json:"interface-uuid,omitempty"`
-		AfuTopInterface struct {
+		InterfaceUUID    string `json:"interface-uuid,omitempty"`
+		InterfaceVersion string `json:"min-interface-version,omitempty"`
+		ImageType        string `json:"image-type,omitempty"`
+		AfuTopInterface  struct {
 			Class       string `json:"class"`
 			ModulePorts []struct {
 				Params struct {
@@ -70,8 +82,10 @@ type Metadata struct {
 			Name                string `json:"name"`
 			TotalContexts       int    `json:"total-contexts"`
 		} `json:"accelerator-clusters"`
-		MagicNo int `json:"magic-no,omitempty"`
-		Power   int `json:"power"`
+		Tags      []string    `json:"tags,omitempty"`
+		BuildTime interface{} `json:"build-time,omitempty"`
+		MagicNo   int         `json:"magic-no,omitempty"`
+		Power     int         `json:"power"`
 	} `json:"afu-image"`
 	Version int `json:"version"`
 }
@@ -148,6 +162,82 @@ func (f *FileGBS) AcceleratorTypeUUID() (ret string) {
 	return
 }
 
+// MagicNo returns magic number of the afu-image metadata or zero if it's not set.
+func (f *FileGBS) MagicNo() int {
+	return f.Metadata.AfuImage.MagicNo
+}
+
+// PowerClass returns power in watts the AFU requires from the board.
+// Zero means the AFU has no specific power requirements.
+func (f *FileGBS) PowerClass() int {
+	return f.Metadata.AfuImage.Power
+}
+
+// BuildTime returns the time the AFU image was built at. The packagers
+// store it either as seconds since the Unix epoch or as RFC 3339 string.
+// ErrNotSupported is returned if the metadata has no build time.
+func (f *FileGBS) BuildTime() (time.Time, error) {
+	switch v := f.Metadata.AfuImage.BuildTime.(type) {
+	case nil:
+		return time.Time{}, errors.Wrap(ErrNotSupported, "no build time in GBS metadata")
+	case float64:
+		return time.Unix(int64(v), 0).UTC(), nil
+	case string:
+		if sec, err := strconv.ParseInt(v, 10, 64); err == nil {
+			return time.Unix(sec, 0).UTC(), nil
+		}
+
+		t, err := time.Parse(time.RFC3339, v)
+
+		return t, errors.Wrapf(err, "unable to parse build time %q", v)
+	default:
+		return time.Time{}, errors.Errorf("unexpected type %T of build time in GBS metadata", v)
+	}
+}
+
+// RequiredInterfaceVersion returns the oldest FIM version, in the
+// major.minor.patch form, the AFU was built to work with.
+// ErrNotSupported is returned if the metadata doesn't declare it.
+func (f *FileGBS) RequiredInterfaceVersion() (string, error) {
+	if v := strings.TrimSpace(f.Metadata.AfuImage.InterfaceVersion); v != "" {
+		return v, nil
+	}
+
+	return "", errors.Wrap(ErrNotSupported, "no interface version in GBS metadata")
+}
+
+// PlatformName returns name of the platform, e.g. "Intel PAC with Arria 10",
+// the AFU was built for. ErrNotSupported is returned if the metadata doesn't
+// declare it.
+func (f *FileGBS) PlatformName() (string, error) {
+	if name := strings.TrimSpace(f.Metadata.PlatformName); name != "" {
+		return name, nil
+	}
+
+	return "", errors.Wrap(ErrNotSupported, "no platform name in GBS metadata")
+}
+
+// Tags returns the capability tags of the AFU, e.g. "gzip" or "aes256",
+// declared by the metadata.
+func (f *FileGBS) Tags() []string {
+	return f.Metadata.AfuImage.Tags
+}
+
+// ImageType returns the type of the image declared by "image-type" of the
+// metadata, "partial" or "full". The AFU packager doesn't set it, so images
+// not declaring the type are partial reconfiguration images. Unknown types
+// are an error.
+func (f *FileGBS) ImageType() (ImageType, error) {
+	switch imageType := strings.ToLower(strings.TrimSpace(f.Metadata.AfuImage.ImageType)); imageType {
+	case "", gbsImageTypePartial:
+		return PartialReconfig, nil
+	case gbsImageTypeFull:
+		return FullChip, nil
+	default:
+		return 0, errors.Errorf("unknown image type %q in GBS metadata", imageType)
+	}
+}
+
 // We need both Seek and ReadAt.
 type bitstreamReader interface {
 	io.ReadSeeker
@@ -164,26 +254,9 @@ func NewFileGBS(r bitstreamReader) (*FileGBS, error) {
 	if _, err := sr.Seek(0, io.SeekStart); err != nil {
 		return nil, errors.Wrap(err, "unable to seek")
 	}
-
-	if err := binary.Read(sr, binary.LittleEndian, &f.Header); err != nil {
-		return nil, errors.Wrap(err, "unable to read header")
-	}
-	// 2. Validate Magic/GUIDs
-	if f.GUID1 != bitstreamGUID1 || f.GUID2 != bitstreamGUID2 {
-		return nil, errors.Errorf("wrong magic in GBS file: %#x %#x Expected %#x %#x", f.GUID1, f.GUID2, bitstreamGUID1, bitstreamGUID2)
-	}
-	// 3. Read/unmarshal metadata JSON
-	if f.MetadataLength == 0 || f.MetadataLength >= 4096 {
-		return nil, errors.Errorf("incorrect length of GBS metadata %d", f.MetadataLength)
-	}
-
-	dec := json.NewDecoder(io.NewSectionReader(r, fileHeaderLength, int64(f.MetadataLength)))
-	if err := dec.Decode(&f.Metadata); err != nil {
-		return nil, errors.Wrap(err, "unable to parse GBS metadata")
-	}
-
-	if afus := len(f.Metadata.AfuImage.AcceleratorClusters); afus != 1 {
-		return nil, errors.Errorf("incorrect length of AcceleratorClusters in GBS metadata: %d", afus)
+	// 2-3. Validate header and read metadata
+	if err := readGBSHeader(sr, f); err != nil {
+		return nil, err
 	}
 	// 4. Create bitsream struct
 	b := new(Bitstream)
@@ -203,6 +276,44 @@ func NewFileGBS(r bitstreamReader) (*FileGBS, error) {
 	return f, nil
 }
 
+// maxMetadataLength is the limit of GBS metadata length readGBSHeader accepts.
+const maxMetadataLength = 4096
+
+// readGBSHeader reads and validates the GBS header and metadata from r
+// positioned at the start of the file. r is left at the start of the raw
+// bitstream.
+func readGBSHeader(r io.Reader, f *FileGBS) error {
+	if err := binary.Read(r, binary.LittleEndian, &f.Header); err != nil {
+		return errors.Wrap(err, "unable to read header")
+	}
+	// Validate Magic/GUIDs
+	if f.GUID1 != bitstreamGUID1 || f.GUID2 != bitstreamGUID2 {
+		return errors.Errorf("wrong magic in GBS file: %#x %#x Expected %#x %#x", f.GUID1, f.GUID2, bitstreamGUID1, bitstreamGUID2)
+	}
+	// Read/unmarshal metadata JSON
+	if f.MetadataLength == 0 || f.MetadataLength >= maxMetadataLength {
+		return errors.Errorf("incorrect length of GBS metadata %d", f.MetadataLength)
+	}
+
+	metadata := io.LimitReader(r, int64(f.MetadataLength))
+
+	dec := json.NewDecoder(metadata)
+	if err := dec.Decode(&f.Metadata); err != nil {
+		return errors.Wrap(err, "unable to parse GBS metadata")
+	}
+
+	// Skip whatever follows the JSON document within the metadata.
+	if _, err := io.Copy(io.Discard, io.MultiReader(dec.Buffered(), metadata)); err != nil {
+		return errors.Wrap(err, "unable to read GBS metadata")
+	}
+
+	if afus := len(f.Metadata.AfuImage.AcceleratorClusters); afus != 1 {
+		return errors.Errorf("incorrect length of AcceleratorClusters in GBS metadata: %d", afus)
+	}
+
+	return nil
+}
+
 // File interfaces implementations
 
 // RawBitstreamReader returns Reader for raw bitstream data.
@@ -237,3 +348,24 @@ func (f *FileGBS) InstallPath(root string) (ret string) {
 func (f *FileGBS) ExtraMetadata() map[string]string {
 	return map[string]string{"Size": strconv.FormatUint(f.Bitstream.Size, 10)}
 }
+
+// IsSigned reports whether raw bitstream data is prepended with signature blocks.
+func (f *FileGBS) IsSigned() (bool, error) {
+	info, err := readSignature(f.Bitstream, f.Bitstream.Size)
+
+	return info != nil, err
+}
+
+// SignatureInfo returns parsed signature blocks of signed bitstream.
+func (f *FileGBS) SignatureInfo() (*SignatureInfo, error) {
+	info, err := readSignature(f.Bitstream, f.Bitstream.Size)
+	if err != nil {
+		return nil, err
+	}
+
+	if info == nil {
+		return nil, ErrNotSigned
+	}
+
+	return info, nil
+}
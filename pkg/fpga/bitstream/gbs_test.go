@@ -15,8 +15,14 @@
 package bitstream
 
 import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
 	"path/filepath"
 	"testing"
+	"time"
+
+	"github.com/pkg/errors"
 )
 
 func TestOpenGBS(t *testing.T) {
@@ -85,6 +91,14 @@ func TestFileGBSMethods(t *testing.T) {
 		t.Errorf("unexpected Accelerator type UUID value: %s", typUUID)
 	}
 
+	if magicNo := gbs.MagicNo(); magicNo != GBSMagicNo {
+		t.Errorf("unexpected magic-no value: %d", magicNo)
+	}
+
+	if power := gbs.PowerClass(); power != 0 {
+		t.Errorf("unexpected power class value: %d", power)
+	}
+
 	extraMD := gbs.ExtraMetadata()
 	if extraMD == nil || extraMD["Size"] != "1" {
 		t.Errorf("unexpected extra metadata: %+v", extraMD)
@@ -95,3 +109,317 @@ func TestFileGBSMethods(t *testing.T) {
 		t.Errorf("unexpected close error: %+v", err)
 	}
 }
+
+func TestGBSSignature(t *testing.T) {
+	tcases := []struct {
+		name           string
+		fname          string
+		expectedSigned bool
+		expectedInfo   *SignatureInfo
+	}{
+		{
+			name:           "signed GBS",
+			fname:          "testdata/signing/signed.gbs",
+			expectedSigned: true,
+			expectedInfo:   &SignatureInfo{ContentLength: 4, ContentType: ContentTypePR},
+		},
+		{
+			name:  "unsigned GBS",
+			fname: "testdata/signing/unsigned.gbs",
+		},
+		{
+			name:  "too short bitstream",
+			fname: "testdata/intel.com/fpga/69528db6eb31577a8c3668f9faa081f6/d8424dc4a4a3c413f89e433683f9040b.gbs",
+		},
+	}
+	for _, tc := range tcases {
+		t.Run(tc.name, func(t *testing.T) {
+			gbs, err := OpenGBS(tc.fname)
+			if err != nil {
+				t.Fatalf("unexpected open error: %+v", err)
+			}
+			defer gbs.Close()
+
+			signed, err := gbs.IsSigned()
+			if err != nil {
+				t.Errorf("unexpected error: %+v", err)
+			}
+
+			if signed != tc.expectedSigned {
+				t.Errorf("expected signed %t, but got %t", tc.expectedSigned, signed)
+			}
+
+			info, err := gbs.SignatureInfo()
+			if tc.expectedInfo == nil {
+				if !errors.Is(err, ErrNotSigned) {
+					t.Errorf("expected ErrNotSigned, but got %v", err)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Errorf("unexpected error: %+v", err)
+			}
+
+			if *info != *tc.expectedInfo {
+				t.Errorf("expected %+v, but got %+v", tc.expectedInfo, info)
+			}
+		})
+	}
+}
+
+func TestAOCXSignature(t *testing.T) {
+	aocx := &FileAOCX{}
+
+	if _, err := aocx.IsSigned(); !errors.Is(err, ErrNotSupported) {
+		t.Errorf("expected ErrNotSupported, but got %v", err)
+	}
+
+	if _, err := aocx.SignatureInfo(); !errors.Is(err, ErrNotSupported) {
+		t.Errorf("expected ErrNotSupported, but got %v", err)
+	}
+}
+
+func TestGBSBuildTime(t *testing.T) {
+	buildTime := time.Date(2023, time.March, 14, 9, 26, 53, 0, time.UTC)
+
+	tcases := []struct {
+		expected     time.Time
+		name         string
+		buildTime    string
+		expectedErr  bool
+		notSupported bool
+	}{
+		{
+			name:      "epoch seconds",
+			buildTime: `, "build-time": 1678786013`,
+			expected:  buildTime,
+		},
+		{
+			name:      "epoch seconds string",
+			buildTime: `, "build-time": "1678786013"`,
+			expected:  buildTime,
+		},
+		{
+			name:      "RFC 3339 string",
+			buildTime: `, "build-time": "2023-03-14T10:26:53+01:00"`,
+			expected:  buildTime,
+		},
+		{
+			name:        "malformed string",
+			buildTime:   `, "build-time": "yesterday"`,
+			expectedErr: true,
+		},
+		{
+			name:         "no build time",
+			expectedErr:  true,
+			notSupported: true,
+		},
+	}
+	for _, tc := range tcases {
+		t.Run(tc.name, func(t *testing.T) {
+			metadata := fmt.Sprintf(`{"version": 1, "afu-image": {"accelerator-clusters": [{"accelerator-type-uuid": "d8424dc4a4a3c413f89e433683f9040b"}]%s}}`, tc.buildTime)
+
+			var buf bytes.Buffer
+
+			_ = binary.Write(&buf, binary.LittleEndian, Header{
+				GUID1:          bitstreamGUID1,
+				GUID2:          bitstreamGUID2,
+				MetadataLength: uint32(len(metadata)),
+			})
+			buf.WriteString(metadata)
+
+			gbs, err := NewFileGBS(bytes.NewReader(buf.Bytes()))
+			if err != nil {
+				t.Fatalf("unable to create GBS: %+v", err)
+			}
+
+			aocx := &FileAOCX{GBS: gbs}
+			for _, f := range []File{gbs, aocx} {
+				got, err := f.BuildTime()
+				if tc.expectedErr {
+					if err == nil || tc.notSupported != errors.Is(err, ErrNotSupported) {
+						t.Errorf("unexpected error: %v", err)
+					}
+
+					continue
+				}
+
+				if err != nil {
+					t.Fatalf("unexpected error: %+v", err)
+				}
+
+				if !got.Equal(tc.expected) {
+					t.Errorf("expected %v, but got %v", tc.expected, got)
+				}
+			}
+		})
+	}
+
+	if _, err := (&FileAOCX{}).BuildTime(); !errors.Is(err, ErrNotSupported) {
+		t.Errorf("expected ErrNotSupported for AOCX without GBS, but got %v", err)
+	}
+}
+
+func TestGBSRequiredInterfaceVersion(t *testing.T) {
+	for name, tc := range map[string]struct {
+		metadata string
+		expected string
+	}{
+		"declared":     {metadata: `, "min-interface-version": "1.2.0"`, expected: "1.2.0"},
+		"not declared": {},
+	} {
+		t.Run(name, func(t *testing.T) {
+			metadata := fmt.Sprintf(`{"version": 1, "afu-image": {"accelerator-clusters": [{"accelerator-type-uuid": "d8424dc4a4a3c413f89e433683f9040b"}]%s}}`, tc.metadata)
+
+			var buf bytes.Buffer
+
+			_ = binary.Write(&buf, binary.LittleEndian, Header{
+				GUID1:          bitstreamGUID1,
+				GUID2:          bitstreamGUID2,
+				MetadataLength: uint32(len(metadata)),
+			})
+			buf.WriteString(metadata)
+
+			gbs, err := NewFileGBS(bytes.NewReader(buf.Bytes()))
+			if err != nil {
+				t.Fatalf("unable to create GBS: %+v", err)
+			}
+
+			for _, f := range []File{gbs, &FileAOCX{GBS: gbs}} {
+				version, err := f.RequiredInterfaceVersion()
+				if tc.expected == "" {
+					if !errors.Is(err, ErrNotSupported) {
+						t.Errorf("expected ErrNotSupported, but got %v", err)
+					}
+
+					continue
+				}
+
+				if err != nil || version != tc.expected {
+					t.Errorf("expected %q, but got %q (%v)", tc.expected, version, err)
+				}
+			}
+		})
+	}
+
+	if _, err := (&FileAOCX{}).RequiredInterfaceVersion(); !errors.Is(err, ErrNotSupported) {
+		t.Errorf("expected ErrNotSupported for AOCX without GBS, but got %v", err)
+	}
+}
+
+func TestGBSPlatformName(t *testing.T) {
+	for name, tc := range map[string]struct {
+		metadata string
+		expected string
+	}{
+		"declared":     {metadata: `"platform-name": " Intel PAC with Arria 10 ", `, expected: "Intel PAC with Arria 10"},
+		"not declared": {},
+	} {
+		t.Run(name, func(t *testing.T) {
+			metadata := fmt.Sprintf(`{"version": 1, %s"afu-image": {"accelerator-clusters": [{"accelerator-type-uuid": "d8424dc4a4a3c413f89e433683f9040b"}]}}`, tc.metadata)
+
+			var buf bytes.Buffer
+
+			_ = binary.Write(&buf, binary.LittleEndian, Header{
+				GUID1:          bitstreamGUID1,
+				GUID2:          bitstreamGUID2,
+				MetadataLength: uint32(len(metadata)),
+			})
+			buf.WriteString(metadata)
+
+			gbs, err := NewFileGBS(bytes.NewReader(buf.Bytes()))
+			if err != nil {
+				t.Fatalf("unable to create GBS: %+v", err)
+			}
+
+			for _, f := range []File{gbs, &FileAOCX{GBS: gbs}} {
+				platform, err := f.PlatformName()
+				if tc.expected == "" {
+					if !errors.Is(err, ErrNotSupported) {
+						t.Errorf("expected ErrNotSupported, but got %v", err)
+					}
+
+					continue
+				}
+
+				if err != nil || platform != tc.expected {
+					t.Errorf("expected %q, but got %q (%v)", tc.expected, platform, err)
+				}
+			}
+		})
+	}
+
+	if _, err := (&FileAOCX{}).PlatformName(); !errors.Is(err, ErrNotSupported) {
+		t.Errorf("expected ErrNotSupported for AOCX without GBS, but got %v", err)
+	}
+}
+
+func TestGBSImageType(t *testing.T) {
+	for fname, expected := range map[string]ImageType{
+		"testdata/intel.com/fpga/69528db6eb31577a8c3668f9faa081f6/d8424dc4a4a3c413f89e433683f9040b.gbs": PartialReconfig,
+		"testdata/images/fullchip.gbs": FullChip,
+	} {
+		gbs, err := OpenGBS(fname)
+		if err != nil {
+			t.Fatalf("%s: unable to open: %+v", fname, err)
+		}
+
+		for _, f := range []File{gbs, &FileAOCX{GBS: gbs}} {
+			imageType, typeErr := f.ImageType()
+			if typeErr != nil || imageType != expected {
+				t.Errorf("%s: expected %s image, but got %s (%v)", fname, expected, imageType, typeErr)
+			}
+		}
+
+		gbs.Close()
+	}
+
+	for name, tc := range map[string]struct {
+		metadata    string
+		expected    ImageType
+		expectedErr bool
+	}{
+		"not declared":      {expected: PartialReconfig},
+		"partial":           {metadata: `, "image-type": "partial"`, expected: PartialReconfig},
+		"full in uppercase": {metadata: `, "image-type": "FULL"`, expected: FullChip},
+		"unknown":           {metadata: `, "image-type": "flash"`, expectedErr: true},
+	} {
+		t.Run(name, func(t *testing.T) {
+			// No interface UUID, the metadata is incomplete.
+			metadata := fmt.Sprintf(`{"version": 1, "afu-image": {"accelerator-clusters": [{"accelerator-type-uuid": "d8424dc4a4a3c413f89e433683f9040b"}]%s}}`, tc.metadata)
+
+			var buf bytes.Buffer
+
+			_ = binary.Write(&buf, binary.LittleEndian, Header{
+				GUID1:          bitstreamGUID1,
+				GUID2:          bitstreamGUID2,
+				MetadataLength: uint32(len(metadata)),
+			})
+			buf.WriteString(metadata)
+
+			gbs, err := NewFileGBS(bytes.NewReader(buf.Bytes()))
+			if err != nil {
+				t.Fatalf("unable to create GBS: %+v", err)
+			}
+
+			imageType, err := gbs.ImageType()
+			if tc.expectedErr {
+				if err == nil {
+					t.Errorf("expected error, but got %s image", imageType)
+				}
+
+				return
+			}
+
+			if err != nil || imageType != tc.expected {
+				t.Errorf("expected %s image, but got %s (%v)", tc.expected, imageType, err)
+			}
+		})
+	}
+
+	if _, err := (&FileAOCX{}).ImageType(); !errors.Is(err, ErrNotSupported) {
+		t.Errorf("expected ErrNotSupported for AOCX without GBS, but got %v", err)
+	}
+}
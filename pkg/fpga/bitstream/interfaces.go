@@ -19,7 +19,10 @@
 
 package bitstream
 
-import "io"
+import (
+	"io"
+	"time"
+)
 
 // File defines interfaces that are common for all supported bitstream file formats
 // It should provide mechanisms to get raw bitstream data as a reader or as a byte array
@@ -40,4 +43,18 @@ type File interface {
 	InstallPath(string) string
 	// ExtraMetadata returns map of key/value with additional metadata that can be detected from bitstream
 	ExtraMetadata() map[string]string
+	// IsSigned reports whether bitstream has signature blocks required by secure boards
+	IsSigned() (bool, error)
+	// SignatureInfo returns parsed signature blocks of signed bitstream
+	SignatureInfo() (*SignatureInfo, error)
+	// BuildTime returns the time the bitstream was built at
+	BuildTime() (time.Time, error)
+	// RequiredInterfaceVersion returns the oldest FIM version the bitstream works with
+	RequiredInterfaceVersion() (string, error)
+	// PlatformName returns name of the platform the bitstream was built for
+	PlatformName() (string, error)
+	// Tags returns the capability tags of the bitstream, e.g. "gzip"
+	Tags() []string
+	// ImageType returns whether the bitstream is a partial reconfiguration or a full chip image
+	ImageType() (ImageType, error)
 }
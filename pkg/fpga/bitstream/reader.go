@@ -0,0 +1,112 @@
+// Copyright 2023 Intel Corporation. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bitstream
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"io"
+
+	"github.com/pkg/errors"
+)
+
+// DefaultMaxDecompressedSize limits size of decompressed bitstreams unless
+// ReaderOptions say otherwise.
+const DefaultMaxDecompressedSize = 512 << 20
+
+var (
+	// ErrTooLarge is returned when decompressed bitstream exceeds the size limit.
+	ErrTooLarge = errors.New("decompressed bitstream is too large")
+
+	gzipMagic = []byte{0x1f, 0x8b}
+	zstdMagic = []byte{0x28, 0xb5, 0x2f, 0xfd}
+)
+
+// ReaderOptions control how OpenReader reads bitstreams.
+type ReaderOptions struct {
+	// MaxDecompressedSize limits size of decompressed data to guard
+	// against decompression bombs. Zero means DefaultMaxDecompressedSize.
+	MaxDecompressedSize int64
+}
+
+// OpenReader reads bitstream from r, detecting its format by the magic bytes.
+// Gzip compressed streams are decompressed transparently before that.
+// The whole bitstream is read into memory, so the returned File doesn't
+// refer to r and Close has no effect.
+func OpenReader(r io.Reader, opts ReaderOptions) (File, error) {
+	br := bufio.NewReader(r)
+
+	header, err := br.Peek(len(zstdMagic))
+	if err != nil && err != io.EOF {
+		return nil, errors.Wrap(err, "unable to read bitstream header")
+	}
+
+	var data []byte
+
+	switch {
+	case bytes.HasPrefix(header, gzipMagic):
+		data, err = gunzip(br, opts.maxDecompressedSize())
+	case bytes.HasPrefix(header, zstdMagic):
+		return nil, errors.Wrap(ErrNotSupported, "zstd compression")
+	default:
+		data, err = io.ReadAll(br)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return newFile(data)
+}
+
+func (o ReaderOptions) maxDecompressedSize() int64 {
+	if o.MaxDecompressedSize > 0 {
+		return o.MaxDecompressedSize
+	}
+
+	return DefaultMaxDecompressedSize
+}
+
+// gunzip decompresses r, reading at most limit bytes of decompressed data.
+func gunzip(r io.Reader, limit int64) ([]byte, error) {
+	zr, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid gzip stream")
+	}
+	defer zr.Close()
+
+	data, err := io.ReadAll(io.LimitReader(zr, limit+1))
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to decompress bitstream")
+	}
+
+	if int64(len(data)) > limit {
+		return nil, errors.Wrapf(ErrTooLarge, "limit is %d bytes", limit)
+	}
+
+	return data, nil
+}
+
+// newFile parses in-memory bitstream of any supported format.
+func newFile(data []byte) (File, error) {
+	for _, format := range formats {
+		if format.Matches(data) {
+			return format.parse(bytes.NewReader(data))
+		}
+	}
+
+	return nil, errors.New("unsupported bitstream format")
+}
@@ -0,0 +1,117 @@
+// Copyright 2023 Intel Corporation. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bitstream
+
+import (
+	"bytes"
+	"compress/gzip"
+	"os"
+	"testing"
+
+	"github.com/pkg/errors"
+)
+
+func TestOpenReader(t *testing.T) {
+	const (
+		plainGBS      = "testdata/intel.com/fpga/69528db6eb31577a8c3668f9faa081f6/d8424dc4a4a3c413f89e433683f9040b.gbs"
+		compressedGBS = "testdata/compressed/d8424dc4a4a3c413f89e433683f9040b.gbs.gz"
+	)
+
+	var bomb bytes.Buffer
+
+	zw := gzip.NewWriter(&bomb)
+	if _, err := zw.Write(make([]byte, 1<<20)); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	tcases := []struct {
+		expectedErr error
+		name        string
+		fname       string
+		data        []byte
+		opts        ReaderOptions
+		invalid     bool
+	}{
+		{
+			name:  "plain GBS",
+			fname: plainGBS,
+		},
+		{
+			name:  "gzipped GBS",
+			fname: compressedGBS,
+		},
+		{
+			name:        "gzipped GBS over the limit",
+			fname:       compressedGBS,
+			opts:        ReaderOptions{MaxDecompressedSize: 100},
+			expectedErr: ErrTooLarge,
+		},
+		{
+			name:        "decompression bomb",
+			data:        bomb.Bytes(),
+			opts:        ReaderOptions{MaxDecompressedSize: 64 << 10},
+			expectedErr: ErrTooLarge,
+		},
+		{
+			name:        "zstd",
+			data:        []byte{0x28, 0xb5, 0x2f, 0xfd, 0, 0, 0, 0},
+			expectedErr: ErrNotSupported,
+		},
+		{
+			name:    "unknown format",
+			data:    []byte("not a bitstream"),
+			invalid: true,
+		},
+	}
+	for _, tc := range tcases {
+		t.Run(tc.name, func(t *testing.T) {
+			data := tc.data
+			if tc.fname != "" {
+				var err error
+				if data, err = os.ReadFile(tc.fname); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			f, err := OpenReader(bytes.NewReader(data), tc.opts)
+
+			switch {
+			case tc.expectedErr != nil:
+				if !errors.Is(err, tc.expectedErr) {
+					t.Errorf("expected error %v, but got %v", tc.expectedErr, err)
+				}
+
+				return
+			case tc.invalid:
+				if err == nil {
+					t.Error("unexpected success")
+				}
+
+				return
+			case err != nil:
+				t.Fatalf("unexpected error: %+v", err)
+			}
+			defer f.Close()
+
+			if f.InterfaceUUID() != "69528db6eb31577a8c3668f9faa081f6" || f.AcceleratorTypeUUID() != "d8424dc4a4a3c413f89e433683f9040b" {
+				t.Errorf("unexpected bitstream %s/%s", f.InterfaceUUID(), f.AcceleratorTypeUUID())
+			}
+		})
+	}
+}
@@ -0,0 +1,85 @@
+// Copyright 2023 Intel Corporation. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bitstream
+
+import (
+	"encoding/binary"
+	"io"
+
+	"github.com/pkg/errors"
+)
+
+// Signature block layout as produced by PACSign for secure PACs: the raw
+// bitstream is prepended with Block0 (128 bytes) and Block1 (896 bytes).
+// Block0 starts with its magic followed by content length, content type and
+// certificate type, Block1 starts with its own magic.
+const (
+	block0Magic     uint32 = 0xb6eafd19
+	block1Magic     uint32 = 0xc7b88c74
+	block0Length           = 128
+	block1Length           = 896
+	signatureLength        = block0Length + block1Length
+)
+
+// Content types of signed images.
+const (
+	// ContentTypeSR is a static region (FIM) image.
+	ContentTypeSR uint32 = 0
+	// ContentTypeBMC is a BMC firmware image.
+	ContentTypeBMC uint32 = 1
+	// ContentTypePR is a partial reconfiguration (AFU) image.
+	ContentTypePR uint32 = 2
+)
+
+var (
+	// ErrNotSupported is returned when the bitstream format doesn't support the operation.
+	ErrNotSupported = errors.New("not supported by bitstream format")
+	// ErrNotSigned is returned when signature information is requested for unsigned bitstream.
+	ErrNotSigned = errors.New("bitstream is not signed")
+)
+
+// SignatureInfo represents the fields of the signature Block0.
+type SignatureInfo struct {
+	// ContentLength is the length of the signed payload.
+	ContentLength uint32
+	// ContentType is one of ContentType* constants.
+	ContentType uint32
+	// CertType is 0 for update and 1 for key cancellation certificates.
+	CertType uint32
+}
+
+// readSignature parses signature blocks at the start of raw bitstream data.
+// It returns nil if the data doesn't start with signature blocks.
+func readSignature(r io.ReaderAt, size uint64) (*SignatureInfo, error) {
+	if size < signatureLength {
+		return nil, nil
+	}
+
+	blocks := make([]byte, signatureLength)
+	if _, err := r.ReadAt(blocks, 0); err != nil {
+		return nil, errors.Wrap(err, "unable to read signature blocks")
+	}
+
+	if binary.LittleEndian.Uint32(blocks) != block0Magic ||
+		binary.LittleEndian.Uint32(blocks[block0Length:]) != block1Magic {
+		return nil, nil
+	}
+
+	return &SignatureInfo{
+		ContentLength: binary.LittleEndian.Uint32(blocks[4:]),
+		ContentType:   binary.LittleEndian.Uint32(blocks[8:]),
+		CertType:      binary.LittleEndian.Uint32(blocks[12:]),
+	}, nil
+}
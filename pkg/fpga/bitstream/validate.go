@@ -0,0 +1,158 @@
+// Copyright 2023 Intel Corporation. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bitstream
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"os"
+	"runtime"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// ErrBitstreamTooLarge is returned by ValidateReader when the bitstream
+// exceeds the size limit.
+var ErrBitstreamTooLarge = errors.New("bitstream is too large")
+
+// validateFile validates bitstream file, replaced by tests.
+var validateFile = func(ctx context.Context, path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	defer f.Close()
+
+	return ValidateReader(ctx, f, DefaultMaxDecompressedSize)
+}
+
+// boundedReader fails reads once more than limit bytes are read from r or
+// ctx is done.
+type boundedReader struct {
+	ctx   context.Context
+	r     io.Reader
+	limit int64
+	read  int64
+}
+
+func (b *boundedReader) Read(p []byte) (int, error) {
+	if err := b.ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	// Read one byte past the limit to tell exact fit from overflow.
+	if left := b.limit - b.read + 1; int64(len(p)) > left {
+		p = p[:left]
+	}
+
+	n, err := b.r.Read(p)
+	b.read += int64(n)
+
+	if b.read > b.limit {
+		return n, errors.Wrapf(ErrBitstreamTooLarge, "limit is %d bytes", b.limit)
+	}
+
+	return n, err
+}
+
+// ValidateReader checks that r contains a valid bitstream of a supported
+// format no larger than maxBytes, without keeping the whole bitstream in
+// memory when possible. GBS files are parsed as a stream: the header and
+// the metadata are validated and the raw bitstream is only counted. Other
+// formats, including compressed ones, need random access and are read into
+// memory, up to maxBytes. ErrBitstreamTooLarge is returned once r, or the
+// decompressed data, exceeds maxBytes and the context error once ctx is done,
+// whichever comes first. maxBytes must be positive.
+func ValidateReader(ctx context.Context, r io.Reader, maxBytes int64) error {
+	if maxBytes <= 0 {
+		return errors.Errorf("invalid bitstream size limit %d", maxBytes)
+	}
+
+	gbs, _ := formatByExtension(fileExtensionGBS)
+	br := bufio.NewReader(&boundedReader{ctx: ctx, r: r, limit: maxBytes})
+
+	header, err := br.Peek(len(gbs.Magic))
+	if err != nil && err != io.EOF {
+		return errors.Wrap(err, "unable to read bitstream header")
+	}
+
+	if !gbs.Matches(header) {
+		// The limit applies to decompressed data as well.
+		_, err = OpenReader(br, ReaderOptions{MaxDecompressedSize: maxBytes})
+		if errors.Is(err, ErrTooLarge) {
+			return errors.Wrap(ErrBitstreamTooLarge, err.Error())
+		}
+
+		return err
+	}
+
+	if err = readGBSHeader(br, new(FileGBS)); err != nil {
+		return err
+	}
+
+	if _, err = io.Copy(io.Discard, br); err != nil {
+		return errors.Wrap(err, "unable to read bitstream")
+	}
+
+	return nil
+}
+
+// ValidateAll validates the bitstream files with ValidateReader, at most
+// concurrency of them at once, and returns the errors keyed by path, nil for
+// the valid files. Zero or negative concurrency means GOMAXPROCS. The files
+// are limited to DefaultMaxDecompressedSize. Once ctx is done, validation in
+// progress is abandoned and the files not validated yet get the context
+// error without being opened.
+func ValidateAll(ctx context.Context, paths []string, concurrency int) map[string]error {
+	if concurrency <= 0 {
+		concurrency = runtime.GOMAXPROCS(0)
+	}
+
+	// Every index is written by a single worker, so no locking is needed.
+	errs := make([]error, len(paths))
+	jobs := make(chan int)
+
+	var wg sync.WaitGroup
+
+	for w := 0; w < concurrency && w < len(paths); w++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			for i := range jobs {
+				if errs[i] = ctx.Err(); errs[i] == nil {
+					errs[i] = validateFile(ctx, paths[i])
+				}
+			}
+		}()
+	}
+
+	for i := range paths {
+		jobs <- i
+	}
+
+	close(jobs)
+	wg.Wait()
+
+	ret := make(map[string]error, len(paths))
+	for i, path := range paths {
+		ret[path] = errs[i]
+	}
+
+	return ret
+}
@@ -0,0 +1,258 @@
+// Copyright 2023 Intel Corporation. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bitstream
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// cancelingReader cancels the context after the first read, like a client
+// going away in the middle of an upload.
+type cancelingReader struct {
+	r      io.Reader
+	cancel context.CancelFunc
+}
+
+func (c *cancelingReader) Read(p []byte) (int, error) {
+	defer c.cancel()
+
+	if len(p) > 64 {
+		p = p[:64]
+	}
+
+	return c.r.Read(p)
+}
+
+func TestValidateReader(t *testing.T) {
+	gbs, err := os.ReadFile("testdata/intel.com/fpga/69528db6eb31577a8c3668f9faa081f6/d8424dc4a4a3c413f89e433683f9040b.gbs")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	compressed, err := os.ReadFile("testdata/compressed/d8424dc4a4a3c413f89e433683f9040b.gbs.gz")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	size := int64(len(gbs))
+
+	tcases := []struct {
+		expectedErr error
+		name        string
+		data        []byte
+		maxBytes    int64
+		cancel      bool
+		invalid     bool
+	}{
+		{
+			name:     "GBS",
+			data:     gbs,
+			maxBytes: 1 << 20,
+		},
+		{
+			name:     "GBS of exactly the limit",
+			data:     gbs,
+			maxBytes: size,
+		},
+		{
+			name:        "GBS over the limit",
+			data:        gbs,
+			maxBytes:    size - 1,
+			expectedErr: ErrBitstreamTooLarge,
+		},
+		{
+			name:        "GBS metadata over the limit",
+			data:        gbs,
+			maxBytes:    64,
+			expectedErr: ErrBitstreamTooLarge,
+		},
+		{
+			name:     "compressed GBS",
+			data:     compressed,
+			maxBytes: 1 << 20,
+		},
+		{
+			name:        "compressed GBS over the limit",
+			data:        compressed,
+			maxBytes:    int64(len(compressed)) - 1,
+			expectedErr: ErrBitstreamTooLarge,
+		},
+		{
+			name:    "zero limit",
+			data:    gbs,
+			invalid: true,
+		},
+		{
+			name:     "negative limit",
+			data:     bytes.Repeat([]byte{0x42}, 100),
+			maxBytes: -5,
+			invalid:  true,
+		},
+		{
+			name:     "garbage",
+			data:     bytes.Repeat([]byte{0x42}, 128),
+			maxBytes: 1 << 20,
+			invalid:  true,
+		},
+		{
+			name:     "truncated GBS",
+			data:     gbs[:32],
+			maxBytes: 1 << 20,
+			invalid:  true,
+		},
+		{
+			name:        "canceled upload",
+			data:        gbs,
+			maxBytes:    1 << 20,
+			cancel:      true,
+			expectedErr: context.Canceled,
+		},
+	}
+	for _, tc := range tcases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			var r io.Reader = bytes.NewReader(tc.data)
+			if tc.cancel {
+				r = &cancelingReader{r: r, cancel: cancel}
+			}
+
+			err := ValidateReader(ctx, r, tc.maxBytes)
+
+			switch {
+			case tc.expectedErr != nil:
+				if !errors.Is(err, tc.expectedErr) {
+					t.Errorf("expected error %v, but got %v", tc.expectedErr, err)
+				}
+			case tc.invalid:
+				if err == nil {
+					t.Error("no error returned")
+				}
+			case err != nil:
+				t.Errorf("unexpected error: %+v", err)
+			}
+		})
+	}
+}
+
+func TestValidateAll(t *testing.T) {
+	dir := t.TempDir()
+	garbage := filepath.Join(dir, "garbage.gbs")
+
+	if err := os.WriteFile(garbage, []byte("not a bitstream"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	valid := []string{
+		"testdata/intel.com/fpga/69528db6eb31577a8c3668f9faa081f6/d8424dc4a4a3c413f89e433683f9040b.gbs",
+		"testdata/compressed/d8424dc4a4a3c413f89e433683f9040b.gbs.gz",
+		"testdata/tags/crypto/aes.gbs",
+	}
+	invalid := []string{garbage, filepath.Join(dir, "missing.gbs")}
+
+	res := ValidateAll(context.Background(), append(valid, invalid...), 2)
+	if len(res) != len(valid)+len(invalid) {
+		t.Errorf("expected %d results, but got %v", len(valid)+len(invalid), res)
+	}
+
+	for _, path := range valid {
+		if err, ok := res[path]; !ok || err != nil {
+			t.Errorf("%s: unexpected result %v (%t)", path, err, ok)
+		}
+	}
+
+	for _, path := range invalid {
+		if res[path] == nil {
+			t.Errorf("%s: no error returned", path)
+		}
+	}
+}
+
+func TestValidateAllConcurrency(t *testing.T) {
+	const concurrency = 3
+
+	paths := make([]string, 20)
+	for i := range paths {
+		paths[i] = fmt.Sprintf("bitstream%d.gbs", i)
+	}
+
+	var active, maxActive int32
+
+	origValidate := validateFile
+	t.Cleanup(func() { validateFile = origValidate })
+
+	validateFile = func(ctx context.Context, path string) error {
+		n := atomic.AddInt32(&active, 1)
+		defer atomic.AddInt32(&active, -1)
+
+		for {
+			m := atomic.LoadInt32(&maxActive)
+			if n <= m || atomic.CompareAndSwapInt32(&maxActive, m, n) {
+				break
+			}
+		}
+
+		time.Sleep(5 * time.Millisecond)
+
+		return nil
+	}
+
+	for path, err := range ValidateAll(context.Background(), paths, concurrency) {
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", path, err)
+		}
+	}
+
+	if maxActive > concurrency {
+		t.Errorf("%d files validated at once, the limit is %d", maxActive, concurrency)
+	}
+
+	t.Run("cancel", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		var calls int32
+
+		validateFile = func(ctx context.Context, path string) error {
+			atomic.AddInt32(&calls, 1)
+			cancel()
+
+			return ctx.Err()
+		}
+
+		res := ValidateAll(ctx, paths, 1)
+
+		if calls != 1 {
+			t.Errorf("expected validation to stop after the first file, but got %d calls", calls)
+		}
+
+		for _, path := range paths {
+			if !errors.Is(res[path], context.Canceled) {
+				t.Errorf("%s: expected context.Canceled, but got %v", path, res[path])
+			}
+		}
+	})
+}
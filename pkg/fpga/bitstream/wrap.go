@@ -0,0 +1,174 @@
+// Copyright 2023 Intel Corporation. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bitstream
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"io"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// uuidRE matches UUIDs with the dashes removed.
+var uuidRE = regexp.MustCompile(`^[[:xdigit:]]{32}$`)
+
+// Wrap packages the raw bitstream (RBF) as GBS with the given metadata,
+// the same way the AFU packager does, so that the bitstream built with other
+// toolchains can be programmed. The metadata must have the interface UUID and
+// a single accelerator cluster with AFU UUID. The clock frequencies, if set,
+// must be positive numbers of MHz or "auto" optionally followed by "-" and
+// the frequency. The version and the magic number default to 1 and GBSMagicNo.
+func Wrap(raw []byte, meta Metadata) (File, error) {
+	data, err := encodeGBS(raw, meta)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewFileGBS(bytes.NewReader(data))
+}
+
+// WriteWrapped writes the GBS file made by Wrap to w, e.g. to install it
+// to the bitstream directory.
+func WriteWrapped(w io.Writer, raw []byte, meta Metadata) error {
+	data, err := encodeGBS(raw, meta)
+	if err != nil {
+		return err
+	}
+
+	_, err = w.Write(data)
+
+	return errors.Wrap(err, "unable to write GBS")
+}
+
+// encodeGBS returns the GBS file with the raw bitstream and the metadata.
+func encodeGBS(raw []byte, meta Metadata) ([]byte, error) {
+	if len(raw) == 0 {
+		return nil, errors.New("empty raw bitstream")
+	}
+
+	gbs, _ := formatByExtension(fileExtensionGBS)
+	if gbs.Matches(raw) {
+		return nil, errors.New("raw bitstream is GBS already")
+	}
+
+	if meta.Version == 0 {
+		meta.Version = 1
+	}
+
+	if meta.AfuImage.MagicNo == 0 {
+		meta.AfuImage.MagicNo = GBSMagicNo
+	}
+
+	if err := validateMetadata(&meta); err != nil {
+		return nil, errors.Wrap(err, "invalid GBS metadata")
+	}
+
+	metadata, err := json.Marshal(meta)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to serialize GBS metadata")
+	}
+
+	if len(metadata) >= maxMetadataLength {
+		return nil, errors.Errorf("GBS metadata is too long: %d bytes", len(metadata))
+	}
+
+	var buf bytes.Buffer
+
+	buf.Grow(fileHeaderLength + len(metadata) + len(raw))
+
+	header := Header{GUID1: bitstreamGUID1, GUID2: bitstreamGUID2, MetadataLength: uint32(len(metadata))}
+	if err = binary.Write(&buf, binary.LittleEndian, header); err != nil {
+		return nil, errors.Wrap(err, "unable to write GBS header")
+	}
+
+	buf.Write(metadata)
+	buf.Write(raw)
+
+	return buf.Bytes(), nil
+}
+
+func validateMetadata(meta *Metadata) error {
+	if meta.AfuImage.MagicNo != GBSMagicNo {
+		return errors.Errorf("magic number is %d, %d expected", meta.AfuImage.MagicNo, GBSMagicNo)
+	}
+
+	if !uuidRE.MatchString(normalizeUUID(meta.AfuImage.InterfaceUUID)) {
+		return errors.Errorf("malformed interface UUID %q", meta.AfuImage.InterfaceUUID)
+	}
+
+	if afus := len(meta.AfuImage.AcceleratorClusters); afus != 1 {
+		return errors.Errorf("%d accelerator clusters, single one expected", afus)
+	}
+
+	if afu := meta.AfuImage.AcceleratorClusters[0].AcceleratorTypeUUID; !uuidRE.MatchString(normalizeUUID(afu)) {
+		return errors.Errorf("malformed AFU UUID %q", afu)
+	}
+
+	if meta.AfuImage.Power < 0 {
+		return errors.Errorf("negative power %d", meta.AfuImage.Power)
+	}
+
+	if err := validateClock(meta.AfuImage.ClockFrequencyHigh); err != nil {
+		return errors.Wrap(err, "clock-frequency-high")
+	}
+
+	return errors.Wrap(validateClock(meta.AfuImage.ClockFrequencyLow), "clock-frequency-low")
+}
+
+// validateClock checks the user clock frequency of the metadata.
+func validateClock(clock interface{}) error {
+	var freq float64
+
+	switch v := clock.(type) {
+	case nil:
+		return nil
+	case int:
+		freq = float64(v)
+	case float64:
+		freq = v
+	case string:
+		if v == "auto" {
+			return nil
+		}
+
+		if !strings.HasPrefix(v, "auto-") {
+			return errors.Errorf("malformed frequency %q", v)
+		}
+
+		var err error
+		if freq, err = strconv.ParseFloat(strings.TrimPrefix(v, "auto-"), 64); err != nil {
+			return errors.Errorf("malformed frequency %q", v)
+		}
+	default:
+		return errors.Errorf("unexpected type %T of frequency", v)
+	}
+
+	if math.IsNaN(freq) || freq <= 0 {
+		return errors.Errorf("frequency %v isn't positive", clock)
+	}
+
+	return nil
+}
+
+// normalizeUUID lowercases the UUID and removes the dashes.
+func normalizeUUID(uuid string) string {
+	return strings.ToLower(strings.ReplaceAll(uuid, "-", ""))
+}
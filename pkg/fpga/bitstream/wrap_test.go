@@ -0,0 +1,166 @@
+// Copyright 2023 Intel Corporation. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bitstream
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const testWrapMetadata = `{
+	"platform-name": "Intel PAC with Arria 10",
+	"afu-image": {
+		"interface-uuid": "69528db6-eb31-577a-8c36-68f9faa081f6",
+		"clock-frequency-high": "auto-312",
+		"clock-frequency-low": 156.25,
+		"power": 40,
+		"accelerator-clusters": [{"name": "nlb3", "total-contexts": 1, "accelerator-type-uuid": "d8424dc4-a4a3-c413-f89e-433683f9040b"}]
+	}
+}`
+
+func testWrapMeta(t *testing.T, modify func(*Metadata)) Metadata {
+	t.Helper()
+
+	var meta Metadata
+	if err := json.Unmarshal([]byte(testWrapMetadata), &meta); err != nil {
+		t.Fatal(err)
+	}
+
+	if modify != nil {
+		modify(&meta)
+	}
+
+	return meta
+}
+
+func TestWrap(t *testing.T) {
+	raw := []byte("rbf payload")
+
+	wrapped, err := Wrap(raw, testWrapMeta(t, nil))
+	if err != nil {
+		t.Fatalf("unexpected error: %+v", err)
+	}
+
+	data, err := wrapped.RawBitstreamData()
+	if err != nil || !bytes.Equal(data, raw) {
+		t.Errorf("expected raw bitstream %q, but got %q, %v", raw, data, err)
+	}
+
+	// Re-parse the written file.
+	dir := t.TempDir()
+	path := wrapped.InstallPath(dir)
+
+	if err = os.MkdirAll(filepath.Dir(path), 0750); err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+
+	if err = WriteWrapped(&buf, raw, testWrapMeta(t, nil)); err != nil {
+		t.Fatalf("unexpected error: %+v", err)
+	}
+
+	if err = os.WriteFile(path, buf.Bytes(), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	opened, err := Open(path)
+	if err != nil {
+		t.Fatalf("unable to open wrapped bitstream: %+v", err)
+	}
+	defer opened.Close()
+
+	gbs := opened.(*FileGBS)
+
+	if path != filepath.Join(dir, "69528db6eb31577a8c3668f9faa081f6", "d8424dc4a4a3c413f89e433683f9040b.gbs") {
+		t.Errorf("unexpected install path %s", path)
+	}
+
+	platform, _ := gbs.PlatformName()
+	if platform != "Intel PAC with Arria 10" || gbs.PowerClass() != 40 || gbs.MagicNo() != GBSMagicNo || gbs.Metadata.Version != 1 {
+		t.Errorf("unexpected metadata %+v", gbs.Metadata)
+	}
+
+	if gbs.Metadata.AfuImage.ClockFrequencyHigh != "auto-312" || gbs.Metadata.AfuImage.ClockFrequencyLow != 156.25 {
+		t.Errorf("unexpected clocks %v and %v", gbs.Metadata.AfuImage.ClockFrequencyHigh, gbs.Metadata.AfuImage.ClockFrequencyLow)
+	}
+
+	if data, err = gbs.RawBitstreamData(); err != nil || !bytes.Equal(data, raw) {
+		t.Errorf("expected raw bitstream %q, but got %q, %v", raw, data, err)
+	}
+}
+
+func TestWrapInvalid(t *testing.T) {
+	tcases := []struct {
+		modify func(*Metadata)
+		name   string
+		raw    string
+	}{
+		{
+			name: "empty bitstream",
+		},
+		{
+			name: "GBS already",
+			raw:  "XeonFPGA\xb7GBSv001",
+		},
+		{
+			name:   "malformed interface UUID",
+			modify: func(m *Metadata) { m.AfuImage.InterfaceUUID = "69528db6" },
+		},
+		{
+			name:   "malformed AFU UUID",
+			modify: func(m *Metadata) { m.AfuImage.AcceleratorClusters[0].AcceleratorTypeUUID = "nlb3" },
+		},
+		{
+			name:   "no accelerator clusters",
+			modify: func(m *Metadata) { m.AfuImage.AcceleratorClusters = nil },
+		},
+		{
+			name:   "wrong magic number",
+			modify: func(m *Metadata) { m.AfuImage.MagicNo = 42 },
+		},
+		{
+			name:   "negative power",
+			modify: func(m *Metadata) { m.AfuImage.Power = -1 },
+		},
+		{
+			name:   "malformed clock",
+			modify: func(m *Metadata) { m.AfuImage.ClockFrequencyHigh = "fast" },
+		},
+		{
+			name:   "zero clock",
+			modify: func(m *Metadata) { m.AfuImage.ClockFrequencyLow = 0 },
+		},
+		{
+			name:   "too long metadata",
+			modify: func(m *Metadata) { m.PlatformName = string(bytes.Repeat([]byte("x"), maxMetadataLength)) },
+		},
+	}
+	for _, tc := range tcases {
+		t.Run(tc.name, func(t *testing.T) {
+			raw := tc.raw
+			if raw == "" && tc.modify != nil {
+				raw = "rbf payload"
+			}
+
+			if _, err := Wrap([]byte(raw), testWrapMeta(t, tc.modify)); err == nil {
+				t.Error("no error returned")
+			}
+		})
+	}
+}
@@ -0,0 +1,266 @@
+// Copyright 2023 Intel Corporation. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fpga
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"unicode"
+
+	"github.com/pkg/errors"
+	"k8s.io/klog/v2"
+
+	"github.com/intel/intel-device-plugins-for-kubernetes/pkg/fpga/bitstream"
+)
+
+// FIMVersion is the version of the FPGA Interface Manager encoded in the
+// FME bitstream id.
+type FIMVersion struct {
+	Major uint8
+	Minor uint8
+	Patch uint8
+}
+
+// String returns the version in the major.minor.patch form.
+func (v FIMVersion) String() string {
+	return fmt.Sprintf("%d.%d.%d", v.Major, v.Minor, v.Patch)
+}
+
+// Less returns true if v is older than other.
+func (v FIMVersion) Less(other FIMVersion) bool {
+	if v.Major != other.Major {
+		return v.Major < other.Major
+	}
+
+	if v.Minor != other.Minor {
+		return v.Minor < other.Minor
+	}
+
+	return v.Patch < other.Patch
+}
+
+// ParseBitstreamID decodes the FIM version from FME bitstream id, e.g.
+// "0x23000410010309" is version 0.2.3. The version is in bits 59:48.
+func ParseBitstreamID(id string) (FIMVersion, error) {
+	value, err := strconv.ParseUint(strings.TrimSpace(id), 0, 64)
+	if err != nil {
+		return FIMVersion{}, errors.Wrapf(err, "unable to parse bitstream id %q", id)
+	}
+
+	return FIMVersion{
+		Major: uint8((value >> 56) & 0xf),
+		Minor: uint8((value >> 52) & 0xf),
+		Patch: uint8((value >> 48) & 0xf),
+	}, nil
+}
+
+// ParseFIMVersion parses version in the major.minor.patch form. Minor and
+// patch numbers are optional and default to zero.
+func ParseFIMVersion(version string) (FIMVersion, error) {
+	var (
+		v     FIMVersion
+		parts = strings.Split(strings.TrimSpace(version), ".")
+	)
+
+	if len(parts) > 3 {
+		return v, errors.Errorf("invalid FIM version %q", version)
+	}
+
+	for i, field := range []*uint8{&v.Major, &v.Minor, &v.Patch}[:len(parts)] {
+		n, err := strconv.ParseUint(parts[i], 10, 8)
+		if err != nil {
+			return FIMVersion{}, errors.Wrapf(err, "invalid FIM version %q", version)
+		}
+
+		*field = uint8(n)
+	}
+
+	return v, nil
+}
+
+// checkInterfaceVersion rejects the bitstream if it requires newer FIM
+// than the FME runs. Bitstreams not declaring the version and FMEs with
+// unknown FIM version pass.
+func checkInterfaceVersion(fme FME, bs bitstream.File) error {
+	required, err := bs.RequiredInterfaceVersion()
+	if errors.Is(err, bitstream.ErrNotSupported) {
+		return nil
+	}
+
+	if err != nil {
+		return err
+	}
+
+	want, err := ParseFIMVersion(required)
+	if err != nil {
+		return errors.Wrapf(err, "bitstream %s", bs.UniqueUUID())
+	}
+
+	fim, err := ParseBitstreamID(fme.GetBitstreamID())
+	if err != nil {
+		return nil
+	}
+
+	if fim.Less(want) {
+		return errors.Errorf("bitstream %s requires FIM %s or newer, but %s runs FIM %s", bs.UniqueUUID(), want, fme.GetName(), fim)
+	}
+
+	return nil
+}
+
+// PlatformCheck defines how CompatibleWith handles bitstreams whose
+// platform name doesn't match the model of the board.
+type PlatformCheck int
+
+const (
+	// PlatformCheckWarn logs the mismatch and accepts the bitstream.
+	PlatformCheckWarn PlatformCheck = iota
+	// PlatformCheckStrict rejects the bitstream on mismatch.
+	PlatformCheckStrict
+	// PlatformCheckOff disables the check.
+	PlatformCheckOff
+)
+
+// platformNameCheck is the PlatformCheck set by SetPlatformNameCheck.
+var platformNameCheck int32
+
+// SetPlatformNameCheck sets the strictness of the platform name check done
+// by CompatibleWith for the whole process. The names of the platforms in GBS
+// metadata vary between the AFU build environments, so the mismatches are
+// only logged by default, i.e. PlatformCheckWarn.
+func SetPlatformNameCheck(check PlatformCheck) {
+	atomic.StoreInt32(&platformNameCheck, int32(check))
+}
+
+// getPlatformNameCheck returns the PlatformCheck set by SetPlatformNameCheck.
+func getPlatformNameCheck() PlatformCheck {
+	return PlatformCheck(atomic.LoadInt32(&platformNameCheck))
+}
+
+// normalizePlatformName lowercases name and replaces punctuation with
+// single spaces, e.g. "Intel PAC (Arria-10)" becomes "intel pac arria 10".
+func normalizePlatformName(name string) string {
+	return strings.Join(strings.FieldsFunc(strings.ToLower(name), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}), " ")
+}
+
+// platformMatches returns true if the platform name and the model name
+// match. As the names are often abbreviated, e.g. "Intel PAC with Arria 10"
+// for "Intel PAC with Arria 10 GX FPGA", it's enough for one of them to
+// contain the other.
+func platformMatches(platform, model string) bool {
+	platform, model = normalizePlatformName(platform), normalizePlatformName(model)
+
+	return strings.Contains(model, platform) || strings.Contains(platform, model)
+}
+
+// checkPlatformName compares the platform name of the bitstream with the model
+// of the board as configured by SetPlatformNameCheck. Bitstreams not declaring
+// the platform and boards of unknown models pass.
+func checkPlatformName(fme FME, bs bitstream.File) error {
+	check := getPlatformNameCheck()
+	if check == PlatformCheckOff {
+		return nil
+	}
+
+	platform, err := bs.PlatformName()
+	if errors.Is(err, bitstream.ErrNotSupported) {
+		return nil
+	}
+
+	if err != nil {
+		return err
+	}
+
+	model := fme.GetModelName()
+	if model == "" || platformMatches(platform, model) {
+		return nil
+	}
+
+	if check == PlatformCheckStrict {
+		return errors.Errorf("bitstream %s is built for %q, but %s is %q", bs.UniqueUUID(), platform, fme.GetName(), model)
+	}
+
+	klog.Warningf("bitstream %s is built for %q, but %s is %q", bs.UniqueUUID(), platform, fme.GetName(), model)
+
+	return nil
+}
+
+// FIMRange is a range of FIM versions known to work with a driver API version.
+type FIMRange struct {
+	// Min is the oldest supported FIM version.
+	Min FIMVersion
+	// Max is the newest supported FIM version. Zero value means
+	// there is no upper bound.
+	Max FIMVersion
+	// APIVersion is the driver API version as reported by GetAPIVersion.
+	APIVersion int
+}
+
+// KnownGoodFIMs lists FIM versions known to work with the driver API
+// versions. Entries are added as new driver and FIM releases are validated.
+// A FIM is considered compatible if it's within any of the ranges listed
+// for the driver API version.
+var KnownGoodFIMs = []FIMRange{
+	// All released FIMs work with API version 0 of the intel-fpga driver.
+	{APIVersion: 0},
+}
+
+// contains returns true if v is within the range.
+func (r FIMRange) contains(v FIMVersion) bool {
+	return !v.Less(r.Min) && (r.Max == FIMVersion{} || !r.Max.Less(v))
+}
+
+// checkCompatibility compares the FIM version encoded in bitstreamID with
+// the ranges known to work with the driver API version. The returned
+// warnings describe the mismatches.
+func checkCompatibility(apiVersion int, bitstreamID string, known []FIMRange) (bool, []string, error) {
+	fim, err := ParseBitstreamID(bitstreamID)
+	if err != nil {
+		return false, nil, err
+	}
+
+	warnings := []string{}
+	found := false
+
+	for _, r := range known {
+		if r.APIVersion != apiVersion {
+			continue
+		}
+
+		found = true
+
+		if r.contains(fim) {
+			return true, nil, nil
+		}
+
+		upper := "any"
+		if r.Max != (FIMVersion{}) {
+			upper = r.Max.String()
+		}
+
+		warnings = append(warnings, fmt.Sprintf("FIM version %s is outside of range %s - %s known to work with driver API version %d",
+			fim, r.Min, upper, apiVersion))
+	}
+
+	if !found {
+		warnings = append(warnings, fmt.Sprintf("driver API version %d is unknown, FIM version %s compatibility can't be checked", apiVersion, fim))
+	}
+
+	return false, warnings, nil
+}
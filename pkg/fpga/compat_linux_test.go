@@ -0,0 +1,174 @@
+// Copyright 2023 Intel Corporation. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fpga
+
+import (
+	"testing"
+	"unsafe"
+)
+
+func TestParseBitstreamID(t *testing.T) {
+	tcases := []struct {
+		id          string
+		expected    FIMVersion
+		expectedErr bool
+	}{
+		{id: "0x23000410010309", expected: FIMVersion{0, 2, 3}},
+		{id: "0x5010302A97C08E5\n", expected: FIMVersion{5, 0, 1}},
+		{id: "0x0", expected: FIMVersion{}},
+		{id: "bogus", expectedErr: true},
+	}
+	for _, tc := range tcases {
+		t.Run(tc.id, func(t *testing.T) {
+			v, err := ParseBitstreamID(tc.id)
+			if tc.expectedErr != (err != nil) {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if v != tc.expected {
+				t.Errorf("expected %s, but got %s", tc.expected, v)
+			}
+		})
+	}
+}
+
+func TestParseFIMVersion(t *testing.T) {
+	tcases := []struct {
+		version     string
+		expected    FIMVersion
+		expectedErr bool
+	}{
+		{version: "1.2.3", expected: FIMVersion{1, 2, 3}},
+		{version: "2.1\n", expected: FIMVersion{2, 1, 0}},
+		{version: "5", expected: FIMVersion{5, 0, 0}},
+		{version: "1.2.3.4", expectedErr: true},
+		{version: "1.x", expectedErr: true},
+		{version: "", expectedErr: true},
+	}
+	for _, tc := range tcases {
+		t.Run(tc.version, func(t *testing.T) {
+			v, err := ParseFIMVersion(tc.version)
+			if tc.expectedErr != (err != nil) {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if v != tc.expected {
+				t.Errorf("expected %s, but got %s", tc.expected, v)
+			}
+		})
+	}
+}
+
+func TestCheckCompatibility(t *testing.T) {
+	known := []FIMRange{
+		{APIVersion: 0},
+		{APIVersion: 1, Min: FIMVersion{1, 0, 0}, Max: FIMVersion{2, 1, 0}},
+		{APIVersion: 2, Min: FIMVersion{3, 0, 0}},
+		{APIVersion: 2, Min: FIMVersion{1, 2, 0}, Max: FIMVersion{1, 2, 9}},
+	}
+
+	tcases := []struct {
+		name        string
+		bitstreamID string
+		apiVersion  int
+		expected    bool
+		warnings    int
+		expectedErr bool
+	}{
+		{
+			name:        "unbounded range",
+			apiVersion:  0,
+			bitstreamID: "0x23000410010309",
+			expected:    true,
+		},
+		{
+			name:        "within bounded range",
+			apiVersion:  1,
+			bitstreamID: "0x0210000000000000",
+			expected:    true,
+		},
+		{
+			name:        "FIM too old",
+			apiVersion:  1,
+			bitstreamID: "0x23000410010309",
+			warnings:    1,
+		},
+		{
+			name:        "FIM too new",
+			apiVersion:  1,
+			bitstreamID: "0x0211000000000000",
+			warnings:    1,
+		},
+		{
+			name:        "second range matches",
+			apiVersion:  2,
+			bitstreamID: "0x0125000000000000",
+			expected:    true,
+		},
+		{
+			name:        "no range matches",
+			apiVersion:  2,
+			bitstreamID: "0x0200000000000000",
+			warnings:    2,
+		},
+		{
+			name:        "unknown driver",
+			apiVersion:  7,
+			bitstreamID: "0x23000410010309",
+			warnings:    1,
+		},
+		{
+			name:        "broken bitstream id",
+			apiVersion:  0,
+			bitstreamID: "",
+			expectedErr: true,
+		},
+	}
+	for _, tc := range tcases {
+		t.Run(tc.name, func(t *testing.T) {
+			ok, warnings, err := checkCompatibility(tc.apiVersion, tc.bitstreamID, known)
+			if tc.expectedErr != (err != nil) {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if ok != tc.expected {
+				t.Errorf("expected %t, but got %t", tc.expected, ok)
+			}
+
+			if len(warnings) != tc.warnings {
+				t.Errorf("expected %d warnings, but got %q", tc.warnings, warnings)
+			}
+		})
+	}
+}
+
+func TestIntelFpgaFMECheckCompatibility(t *testing.T) {
+	setTestIoctl(t, func(string, uint, unsafe.Pointer) (uintptr, error) { return 0, nil })
+
+	fme := newTestIntelFpgaFME(t, map[string]string{"bitstream_id": "0x23000410010309\n"})
+	gen := fme.PropertiesGeneration()
+
+	if _, _, err := fme.CheckCompatibility(); err != nil {
+		t.Fatalf("unexpected error: %+v", err)
+	}
+
+	if fme.GetBitstreamID() != "0x23000410010309" {
+		t.Errorf("expected bitstream id re-read, but got %q", fme.GetBitstreamID())
+	}
+
+	if newGen := fme.PropertiesGeneration(); newGen != gen+1 {
+		t.Errorf("expected generation %d after the refresh, but got %d", gen+1, newGen)
+	}
+}
@@ -0,0 +1,66 @@
+// Copyright 2023 Intel Corporation. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fpga
+
+import (
+	"hash/crc32"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// portRegionCRCFile is an optional port attribute with CRC-32 of the image
+// loaded to the port's region, reported by the hardware.
+const portRegionCRCFile = "region_crc"
+
+// ErrPostPRCRCMismatch is returned by PR with PROptions.VerifyLoadedCRC if
+// the CRC the hardware reports for the loaded image differs from the CRC of
+// the programmed bitstream.
+var ErrPostPRCRCMismatch = errors.New("loaded image CRC mismatch")
+
+// verifyLoadedCRC compares the CRC-32 (IEEE) of data with the CRC of the
+// image loaded to the port. ErrNotSupported is returned if the port doesn't
+// report the CRC.
+func verifyLoadedCRC(port Port, data []byte) error {
+	sysfs := port.GetSysFsPath()
+	if sysfs == "" {
+		return errors.Wrapf(ErrNotSupported, "%s: no sysfs entry", port.GetName())
+	}
+
+	raw, err := readSysfsFile(filepath.Join(sysfs, portRegionCRCFile))
+	if os.IsNotExist(err) {
+		return errors.Wrapf(ErrNotSupported, "%s: no loaded image CRC", port.GetName())
+	}
+
+	if err != nil {
+		return errors.Wrapf(err, "%s: unable to read loaded image CRC", port.GetName())
+	}
+
+	value := strings.TrimPrefix(strings.ToLower(strings.TrimSpace(string(raw))), "0x")
+
+	loaded, err := strconv.ParseUint(value, 16, 32)
+	if err != nil {
+		return errors.Wrapf(err, "%s: malformed loaded image CRC", port.GetName())
+	}
+
+	if expected := crc32.ChecksumIEEE(data); uint32(loaded) != expected {
+		return errors.Wrapf(ErrPostPRCRCMismatch, "%s: loaded image has CRC %08x, bitstream %08x", port.GetName(), loaded, expected)
+	}
+
+	return nil
+}
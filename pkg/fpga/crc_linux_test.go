@@ -0,0 +1,99 @@
+// Copyright 2023 Intel Corporation. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fpga
+
+import (
+	"context"
+	"fmt"
+	"hash/crc32"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/pkg/errors"
+)
+
+func TestPRVerifyLoadedCRC(t *testing.T) {
+	tcases := []struct {
+		expectedErr error
+		name        string
+		// crc returns the CRC the port reports for the programmed data,
+		// empty string for no readback.
+		crc            func([]byte) string
+		verify         bool
+		expectVerified bool
+	}{
+		{
+			name:           "matching CRC",
+			crc:            func(data []byte) string { return fmt.Sprintf("0x%08x\n", crc32.ChecksumIEEE(data)) },
+			verify:         true,
+			expectVerified: true,
+		},
+		{
+			name:           "matching CRC without prefix",
+			crc:            func(data []byte) string { return fmt.Sprintf("%08X\n", crc32.ChecksumIEEE(data)) },
+			verify:         true,
+			expectVerified: true,
+		},
+		{
+			name:        "mismatching CRC",
+			crc:         func(data []byte) string { return fmt.Sprintf("0x%08x\n", crc32.ChecksumIEEE(data)+1) },
+			verify:      true,
+			expectedErr: ErrPostPRCRCMismatch,
+		},
+		{
+			name:   "no readback",
+			crc:    func([]byte) string { return "" },
+			verify: true,
+		},
+		{
+			name: "mismatching CRC not verified",
+			crc:  func(data []byte) string { return fmt.Sprintf("0x%08x\n", crc32.ChecksumIEEE(data)+1) },
+		},
+	}
+	for _, tc := range tcases {
+		t.Run(tc.name, func(t *testing.T) {
+			fme := &testFME{interfaceUUID: testInterface}
+			port := newTestIntelFpgaPort(t, fme, testAFUOld)
+			fme.portPR = func(_ uint32, data []byte) error {
+				if crc := tc.crc(data); crc != "" {
+					if err := os.WriteFile(filepath.Join(port.SysFsPath, portRegionCRCFile), []byte(crc), 0600); err != nil {
+						return err
+					}
+				}
+
+				return os.WriteFile(filepath.Join(port.SysFsPath, "afu_id"), []byte(testAFUNew), 0600)
+			}
+
+			res, err := port.PRContext(context.Background(), newTestGBS(t, testInterface, testAFUNew), PROptions{VerifyLoadedCRC: tc.verify})
+
+			if tc.expectedErr == nil && err != nil {
+				t.Errorf("unexpected error: %+v", err)
+			}
+
+			if tc.expectedErr != nil && !errors.Is(err, tc.expectedErr) {
+				t.Errorf("expected %v, but got %v", tc.expectedErr, err)
+			}
+
+			if res.CRCVerified != tc.expectVerified {
+				t.Errorf("expected CRC verified: %t, but got %t", tc.expectVerified, res.CRCVerified)
+			}
+
+			if res.AFU != testAFUNew {
+				t.Errorf("expected AFU %s read back, but got %q", testAFUNew, res.AFU)
+			}
+		})
+	}
+}
@@ -0,0 +1,166 @@
+// Copyright 2023 Intel Corporation. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fpga
+
+import (
+	"fmt"
+
+	"github.com/pkg/errors"
+)
+
+// Device Feature Header is the 64-bit register every feature in AFU MMIO
+// space starts with:
+//
+//	bits 11:0  - feature ID
+//	bits 15:12 - feature revision
+//	bits 39:16 - byte offset of the next DFH relative to this one
+//	bit  40    - end of the DFH list
+//	bits 59:41 - reserved
+//	bits 63:60 - feature type, 1 for AFU
+//
+// The AFU DFH, at offset 0 of the AFU MMIO region, is followed by the low
+// and high 64 bits of the AFU ID at offsets 0x8 and 0x10.
+const (
+	dfhAFUIDLowOffset  = 0x8
+	dfhAFUIDHighOffset = 0x10
+	afuDescriptorSize  = 0x18
+	// maxDFHEntries caps the walk of DFH lists with broken next pointers.
+	maxDFHEntries = 1024
+)
+
+// DFH feature types.
+const (
+	DFHTypeAFU     = 1
+	DFHTypeBBB     = 2
+	DFHTypePrivate = 3
+)
+
+// DFH is a decoded Device Feature Header.
+type DFH struct {
+	// NextOffset is the byte offset of the next DFH relative to this one.
+	NextOffset uint32
+	// ID is the feature ID.
+	ID uint16
+	// Type is the feature type, e.g. DFHTypeAFU.
+	Type uint8
+	// Revision is the feature revision.
+	Revision uint8
+	// EOL is set on the last DFH of the list.
+	EOL bool
+}
+
+// parseDFH decodes raw Device Feature Header.
+func parseDFH(raw uint64) DFH {
+	return DFH{
+		ID:         uint16(raw & 0xfff),
+		Revision:   uint8((raw >> 12) & 0xf),
+		NextOffset: uint32((raw >> 16) & 0xffffff),
+		EOL:        (raw>>40)&1 == 1,
+		Type:       uint8(raw >> 60),
+	}
+}
+
+// AFUDescriptor is the AFU identity read from its MMIO space.
+type AFUDescriptor struct {
+	// AFUID is the AFU UUID in the same format as the port's afu_id in sysfs.
+	AFUID string
+	// DFH is the AFU's Device Feature Header.
+	DFH DFH
+}
+
+// readAFUDescriptor maps AFU MMIO region of the port and reads the AFU DFH
+// and AFU ID from it.
+func readAFUDescriptor(f Port) (AFUDescriptor, error) {
+	region, err := mapAFURegion(f)
+	if err != nil {
+		return AFUDescriptor{}, err
+	}
+	defer region.Close()
+
+	var regs [afuDescriptorSize / 8]uint64
+
+	for i := range regs {
+		if regs[i], err = region.readUint64(uint64(i) * 8); err != nil {
+			return AFUDescriptor{}, errors.Wrapf(err, "%s: unable to read AFU descriptor", f.GetName())
+		}
+	}
+
+	desc := AFUDescriptor{
+		DFH:   parseDFH(regs[0]),
+		AFUID: fmt.Sprintf("%016x%016x", regs[dfhAFUIDHighOffset/8], regs[dfhAFUIDLowOffset/8]),
+	}
+
+	if desc.DFH.Type != DFHTypeAFU {
+		return desc, errors.Errorf("%s: unexpected feature type %d of AFU DFH", f.GetName(), desc.DFH.Type)
+	}
+
+	return desc, nil
+}
+
+// DFHEntry is a feature found in the DFH list of the AFU.
+type DFHEntry struct {
+	// Offset is the byte offset of the feature's DFH in the AFU MMIO region.
+	Offset uint64
+	DFH
+}
+
+// walkDFH maps AFU MMIO region of the port and follows the DFH list starting
+// with the AFU DFH at offset 0. The next DFH is at the current one's offset
+// plus its NextOffset, so the list only goes forward. It ends with the DFH
+// having EOL set or, as some AFUs don't set EOL, zero NextOffset. The walk
+// fails if the next DFH is misaligned or outside of the region, or if there
+// are more than maxDFHEntries features. The features found before the
+// failure are returned along with the error.
+func walkDFH(f Port) ([]DFHEntry, error) {
+	region, err := mapAFURegion(f)
+	if err != nil {
+		return nil, err
+	}
+	defer region.Close()
+
+	entries := []DFHEntry{}
+
+	for offset := uint64(0); len(entries) < maxDFHEntries; {
+		raw, readErr := region.readUint64(offset)
+		if readErr != nil {
+			return entries, errors.Wrapf(readErr, "%s: unable to read DFH at %#x", f.GetName(), offset)
+		}
+
+		entry := DFHEntry{Offset: offset, DFH: parseDFH(raw)}
+		entries = append(entries, entry)
+
+		if entry.EOL || entry.NextOffset == 0 {
+			return entries, nil
+		}
+
+		offset += uint64(entry.NextOffset)
+	}
+
+	return entries, errors.Errorf("%s: DFH list has more than %d features", f.GetName(), maxDFHEntries)
+}
+
+// mapAFURegion maps the AFU MMIO region of the port.
+func mapAFURegion(f Port) (*mmioRegion, error) {
+	info, err := f.PortGetRegionInfo(FPGA_PORT_INDEX_UAFU)
+	if err != nil {
+		return nil, errors.Wrapf(err, "%s: unable to get AFU region info", f.GetName())
+	}
+
+	if info.Size < afuDescriptorSize {
+		return nil, errors.Errorf("%s: AFU region is too small (%d bytes)", f.GetName(), info.Size)
+	}
+
+	return mapRegion(f.GetDevPath(), info.Offset, info.Size)
+}
@@ -0,0 +1,244 @@
+// Copyright 2023 Intel Corporation. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fpga
+
+import (
+	"encoding/binary"
+	"reflect"
+	"testing"
+
+	"github.com/pkg/errors"
+)
+
+// setTestMMIO makes mapRegion return in-memory region with the given
+// 64-bit registers. The number of regions still mapped is tracked in mapped.
+func setTestMMIO(t *testing.T, regs []uint64, mapErr error, mapped *int) {
+	t.Helper()
+
+	origMap := mapRegion
+
+	mapRegion = func(dev string, offset, size uint64) (*mmioRegion, error) {
+		if mapErr != nil {
+			return nil, mapErr
+		}
+
+		data := make([]byte, size)
+		for i, reg := range regs {
+			binary.LittleEndian.PutUint64(data[i*8:], reg)
+		}
+
+		*mapped++
+
+		return &mmioRegion{data: data, unmap: func([]byte) error {
+			*mapped--
+
+			return nil
+		}}, nil
+	}
+
+	t.Cleanup(func() { mapRegion = origMap })
+}
+
+func TestReadAFUDescriptor(t *testing.T) {
+	afuRegion := []PortRegionInfo{{Index: FPGA_PORT_INDEX_UAFU, Flags: 3, Size: 0x1000}}
+
+	tcases := []struct {
+		mapErr      error
+		port        *testPort
+		name        string
+		expected    AFUDescriptor
+		regs        []uint64
+		expectedErr bool
+	}{
+		{
+			name: "AFU",
+			port: &testPort{regions: afuRegion, numRegion: 1, failAt: -1},
+			regs: []uint64{
+				// Type 1, EOL, next DFH at 0x100, revision 2, ID 0x123.
+				0x1000_0100_0100_2123,
+				0xa5a5_a5a5_0000_0001,
+				0xd8424dc4_a4a3c413,
+			},
+			expected: AFUDescriptor{
+				AFUID: "d8424dc4a4a3c413a5a5a5a500000001",
+				DFH:   DFH{Type: DFHTypeAFU, EOL: true, NextOffset: 0x100, Revision: 2, ID: 0x123},
+			},
+		},
+		{
+			name:        "not an AFU",
+			port:        &testPort{regions: afuRegion, numRegion: 1, failAt: -1},
+			regs:        []uint64{0x3000_0000_0000_0001},
+			expectedErr: true,
+		},
+		{
+			name:        "region too small",
+			port:        &testPort{regions: []PortRegionInfo{{Size: 0x10}}, numRegion: 1, failAt: -1},
+			expectedErr: true,
+		},
+		{
+			name:        "no region info",
+			port:        &testPort{failAt: 0},
+			expectedErr: true,
+		},
+		{
+			name:        "mapping fails",
+			port:        &testPort{regions: afuRegion, numRegion: 1, failAt: -1},
+			mapErr:      errors.New("permission denied"),
+			expectedErr: true,
+		},
+	}
+	for _, tc := range tcases {
+		t.Run(tc.name, func(t *testing.T) {
+			mapped := 0
+			setTestMMIO(t, tc.regs, tc.mapErr, &mapped)
+
+			desc, err := readAFUDescriptor(tc.port)
+			if mapped != 0 {
+				t.Errorf("%d regions left mapped", mapped)
+			}
+
+			if tc.expectedErr {
+				if err == nil {
+					t.Error("no error returned")
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("unexpected error: %+v", err)
+			}
+
+			if desc != tc.expected {
+				t.Errorf("expected %+v, but got %+v", tc.expected, desc)
+			}
+		})
+	}
+}
+
+// dfhRegs returns registers of AFU region with the given raw DFHs at
+// the given byte offsets.
+func dfhRegs(size uint64, dfhs map[uint64]uint64) []uint64 {
+	regs := make([]uint64, size/8)
+	for offset, raw := range dfhs {
+		regs[offset/8] = raw
+	}
+
+	return regs
+}
+
+func TestWalkDFH(t *testing.T) {
+	afuRegion := []PortRegionInfo{{Index: FPGA_PORT_INDEX_UAFU, Flags: 3, Size: 0x1000}}
+
+	tcases := []struct {
+		dfhs        map[uint64]uint64
+		name        string
+		expected    []DFHEntry
+		expectedErr bool
+	}{
+		{
+			name: "multi-feature chain",
+			dfhs: map[uint64]uint64{
+				// AFU, next at 0x100.
+				0x000: 0x1000_0000_0100_0123,
+				// BBB ID 0x12 rev 1, next at +0x200.
+				0x100: 0x2000_0000_0200_1012,
+				// private feature ID 0x7, EOL.
+				0x300: 0x3000_0100_0000_0007,
+			},
+			expected: []DFHEntry{
+				{Offset: 0x000, DFH: DFH{Type: DFHTypeAFU, NextOffset: 0x100, ID: 0x123}},
+				{Offset: 0x100, DFH: DFH{Type: DFHTypeBBB, NextOffset: 0x200, Revision: 1, ID: 0x12}},
+				{Offset: 0x300, DFH: DFH{Type: DFHTypePrivate, EOL: true, ID: 0x7}},
+			},
+		},
+		{
+			name: "list ending with zero next offset",
+			dfhs: map[uint64]uint64{
+				0x000: 0x1000_0000_0040_0123,
+				0x040: 0x3000_0000_0000_0001,
+			},
+			expected: []DFHEntry{
+				{Offset: 0x000, DFH: DFH{Type: DFHTypeAFU, NextOffset: 0x40, ID: 0x123}},
+				{Offset: 0x040, DFH: DFH{Type: DFHTypePrivate, ID: 0x1}},
+			},
+		},
+		{
+			name: "next pointer outside of region",
+			dfhs: map[uint64]uint64{
+				0x000: 0x1000_0000_2000_0123,
+			},
+			expectedErr: true,
+		},
+		{
+			name: "misaligned next pointer",
+			dfhs: map[uint64]uint64{
+				0x000: 0x1000_0000_0100_0123,
+				0x100: 0x3000_0000_0004_0001,
+			},
+			expectedErr: true,
+		},
+	}
+	for _, tc := range tcases {
+		t.Run(tc.name, func(t *testing.T) {
+			mapped := 0
+			setTestMMIO(t, dfhRegs(0x1000, tc.dfhs), nil, &mapped)
+
+			entries, err := walkDFH(&testPort{regions: afuRegion, numRegion: 1, failAt: -1})
+			if mapped != 0 {
+				t.Errorf("%d regions left mapped", mapped)
+			}
+
+			if tc.expectedErr {
+				if err == nil {
+					t.Errorf("no error returned, got %+v", entries)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("unexpected error: %+v", err)
+			}
+
+			if !reflect.DeepEqual(entries, tc.expected) {
+				t.Errorf("expected %+v, but got %+v", tc.expected, entries)
+			}
+		})
+	}
+}
+
+func TestWalkDFHCap(t *testing.T) {
+	// Every DFH points to the next one, but the region is too large to
+	// have EOL within the cap.
+	size := uint64(maxDFHEntries+1) * 8
+	regs := make([]uint64, size/8)
+
+	for i := range regs {
+		regs[i] = 0x3000_0000_0008_0001
+	}
+
+	mapped := 0
+	setTestMMIO(t, regs, nil, &mapped)
+
+	entries, err := walkDFH(&testPort{regions: []PortRegionInfo{{Index: FPGA_PORT_INDEX_UAFU, Size: size}}, numRegion: 1, failAt: -1})
+	if err == nil {
+		t.Error("no error returned")
+	}
+
+	if len(entries) != maxDFHEntries {
+		t.Errorf("expected walk to stop after %d features, but got %d", maxDFHEntries, len(entries))
+	}
+}
@@ -15,9 +15,12 @@
 package fpga
 
 import (
+	"context"
 	"math"
 	"path/filepath"
 	"strconv"
+	"sync/atomic"
+	"time"
 	"unsafe"
 
 	"github.com/intel/intel-device-plugins-for-kubernetes/pkg/fpga/bitstream"
@@ -25,14 +28,17 @@ import (
 )
 
 const (
-	dflFpgaFmePrefix  = "dfl-fme."
-	dflFpgaPortPrefix = "dfl-port."
-	dflFpgaFmeGlobPCI = "fpga_region/region*/dfl-fme.*"
+	dflFpgaFmePrefix   = "dfl-fme."
+	dflFpgaPortPrefix  = "dfl-port."
+	dflFpgaFmeGlobPCI  = "fpga_region/region*/dfl-fme.*"
+	dflFpgaPortGlobPCI = "fpga_region/region*/dfl-port.*"
 )
 
 // DflFME represent DFL FPGA FME device.
 type DflFME struct {
 	FME
+	// generation is accessed atomically, keep it 64-bit aligned.
+	generation        uint64
 	DevPath           string
 	SysFsPath         string
 	Name              string
@@ -55,7 +61,12 @@ func (f *DflFME) Close() error {
 
 // NewDflFME Opens device.
 func NewDflFME(dev string) (FME, error) {
-	fme := &DflFME{DevPath: dev}
+	return newDflFME(dev, "")
+}
+
+// newDflFME opens device with already known sysfs entry.
+func newDflFME(dev, sysfs string) (FME, error) {
+	fme := &DflFME{DevPath: dev, SysFsPath: sysfs}
 	if err := checkPCIDeviceType(fme); err != nil {
 		return nil, err
 	}
@@ -70,14 +81,17 @@ func NewDflFME(dev string) (FME, error) {
 // DflPort represent DFL FPGA Port device.
 type DflPort struct {
 	Port
-	PCIDevice *PCIDevice
-	FME       FME
-	DevPath   string
-	SysFsPath string
-	Name      string
-	Dev       string
-	AFUID     string
-	ID        string
+	// resetCount and generation are accessed atomically, keep them 64-bit aligned.
+	resetCount uint64
+	generation uint64
+	PCIDevice  *PCIDevice
+	FME        FME
+	DevPath    string
+	SysFsPath  string
+	Name       string
+	Dev        string
+	AFUID      string
+	ID         string
 }
 
 // Close closes open device.
@@ -93,7 +107,12 @@ func (f *DflPort) Close() error {
 
 // NewDflPort Opens device.
 func NewDflPort(dev string) (Port, error) {
-	port := &DflPort{DevPath: dev}
+	return newDflPort(dev, "")
+}
+
+// newDflPort opens device with already known sysfs entry.
+func newDflPort(dev, sysfs string) (Port, error) {
+	port := &DflPort{DevPath: dev, SysFsPath: sysfs}
 	if err := checkPCIDeviceType(port); err != nil {
 		return nil, err
 	}
@@ -107,11 +126,11 @@ func NewDflPort(dev string) (Port, error) {
 
 // common ioctls for FME and Port.
 func commonDflGetAPIVersion(dev string) (int, error) {
-	v, err := ioctlDev(dev, DFL_FPGA_GET_API_VERSION, 0)
+	v, err := ioctlDev(dev, DFL_FPGA_GET_API_VERSION, nil)
 	return int(v), err
 }
 func commonDflCheckExtension(dev string) (int, error) {
-	v, err := ioctlDev(dev, DFL_FPGA_CHECK_EXTENSION, 0)
+	v, err := ioctlDev(dev, DFL_FPGA_CHECK_EXTENSION, nil)
 	return int(v), err
 }
 
@@ -128,6 +147,30 @@ func (f *DflFME) CheckExtension() (int, error) {
 	return commonDflCheckExtension(f.DevPath)
 }
 
+// PingDevice measures round-trip latency of GetAPIVersion ioctl. It has no
+// side effects and can be used as a lightweight liveness probe.
+func (f *DflFME) PingDevice() (time.Duration, error) {
+	return pingDevice(f)
+}
+
+// IsVirtualFunction reports whether the device belongs to a PCI virtual function.
+func (f *DflFME) IsVirtualFunction() (bool, error) {
+	return isVirtualFunction(f)
+}
+
+// GetDeviceType returns device type reported by sysfs. See getDeviceType for details.
+func (f *DflFME) GetDeviceType() (string, error) {
+	return getDeviceType(f)
+}
+
+// GetStableID returns ID of the device derived from its PCI address and interface UUID.
+// The ID survives reboots, so it's suitable as the device ID reported to the
+// kubelet, but it changes if the card is moved to another PCI slot or the
+// FIM is updated.
+func (f *DflFME) GetStableID() (string, error) {
+	return getFMEStableID(f)
+}
+
 // GetAPIVersion  Report the version of the driver API.
 // * Return: Driver API Version.
 func (f *DflPort) GetAPIVersion() (int, error) {
@@ -140,6 +183,30 @@ func (f *DflPort) CheckExtension() (int, error) {
 	return commonDflCheckExtension(f.DevPath)
 }
 
+// PingDevice measures round-trip latency of GetAPIVersion ioctl. It has no
+// side effects and can be used as a lightweight liveness probe.
+func (f *DflPort) PingDevice() (time.Duration, error) {
+	return pingDevice(f)
+}
+
+// IsVirtualFunction reports whether the device belongs to a PCI virtual function.
+func (f *DflPort) IsVirtualFunction() (bool, error) {
+	return isVirtualFunction(f)
+}
+
+// GetDeviceType returns device type reported by sysfs. See getDeviceType for details.
+func (f *DflPort) GetDeviceType() (string, error) {
+	return getDeviceType(f)
+}
+
+// GetStableID returns ID of the device derived from its PCI address, interface UUID and port id.
+// The ID survives reboots, so it's suitable as the device ID reported to the
+// kubelet, but it changes if the card is moved to another PCI slot or the
+// FIM is updated.
+func (f *DflPort) GetStableID() (string, error) {
+	return getPortStableID(f)
+}
+
 // FME interfaces
 
 // PortPR does Partial Reconfiguration based on Port ID and Buffer (Image)
@@ -149,13 +216,29 @@ func (f *DflPort) CheckExtension() (int, error) {
 //     some errors during PR, under this case, the user can fetch HW error info
 //     from the status of FME's fpga manager.
 func (f *DflFME) PortPR(port uint32, bitstream []byte) error {
+	return f.PortPRContext(context.Background(), port, bitstream)
+}
+
+// PortPRContext is the same as PortPR, but the operation is abandoned
+// if ctx is done before the request is submitted to the driver.
+func (f *DflFME) PortPRContext(ctx context.Context, port uint32, bitstream []byte) error {
+	if err := requireWritable(f.GetName(), "PR"); err != nil {
+		return err
+	}
+
+	if err := requirePhysicalFunction(f, "PR"); err != nil {
+		return err
+	}
+
 	var value DflFpgaFmePortPR
 
 	value.Argsz = uint32(unsafe.Sizeof(value))
 	value.Port_id = port
 	value.Buffer_size = uint32(len(bitstream))
 	value.Buffer_address = uint64(uintptr(unsafe.Pointer(&bitstream[0])))
-	_, err := ioctlDev(f.DevPath, DFL_FPGA_FME_PORT_PR, uintptr(unsafe.Pointer(&value)))
+	_, err := ioctlDevContext(ctx, f.DevPath, DFL_FPGA_FME_PORT_PR, unsafe.Pointer(&value))
+
+	recordPRAudit(ctx, f.DevPath, port, bitstream, err)
 
 	return err
 }
@@ -163,8 +246,18 @@ func (f *DflFME) PortPR(port uint32, bitstream []byte) error {
 // PortRelease releases the port per Port ID provided by caller.
 // * Return: 0 on success, -errno on failure.
 func (f *DflFME) PortRelease(port uint32) error {
+	if err := requireWritable(f.GetName(), "port release"); err != nil {
+		return err
+	}
+
+	if err := requirePhysicalFunction(f, "port release"); err != nil {
+		return err
+	}
+
 	value := port
-	_, err := ioctlDev(f.DevPath, DFL_FPGA_FME_PORT_RELEASE, uintptr(unsafe.Pointer(&value)))
+	_, err := ioctlDev(f.DevPath, DFL_FPGA_FME_PORT_RELEASE, unsafe.Pointer(&value))
+
+	recordPortAudit(AuditPortRelease, f.DevPath, port, err)
 
 	return err
 }
@@ -172,12 +265,35 @@ func (f *DflFME) PortRelease(port uint32) error {
 // PortAssign assigns the port back per Port ID provided by caller.
 // * Return: 0 on success, -errno on failure.
 func (f *DflFME) PortAssign(port uint32) error {
+	if err := requireWritable(f.GetName(), "port assign"); err != nil {
+		return err
+	}
+
+	if err := requirePhysicalFunction(f, "port assign"); err != nil {
+		return err
+	}
+
 	value := port
-	_, err := ioctlDev(f.DevPath, DFL_FPGA_FME_PORT_ASSIGN, uintptr(unsafe.Pointer(&value)))
+	_, err := ioctlDev(f.DevPath, DFL_FPGA_FME_PORT_ASSIGN, unsafe.Pointer(&value))
+
+	recordPortAudit(AuditPortAssign, f.DevPath, port, err)
 
 	return err
 }
 
+// SetAnnotation attaches user metadata to the device, e.g. its rack or purpose.
+// Annotations are kept in memory per device node and are shared by all
+// handles of the device. They aren't persisted across process restarts.
+// Setting an empty value removes the key.
+func (f *DflFME) SetAnnotation(key, value string) {
+	setAnnotation(f.GetDevPath(), key, value)
+}
+
+// Annotations returns copy of the user metadata attached to the device.
+func (f *DflFME) Annotations() map[string]string {
+	return getAnnotations(f.GetDevPath())
+}
+
 // GetDevPath returns path to device node.
 func (f *DflFME) GetDevPath() string {
 	return f.DevPath
@@ -205,7 +321,12 @@ func (f *DflFME) GetName() string {
 		return f.Name
 	}
 
-	f.Name = filepath.Base(f.GetSysFsPath())
+	sysfs := f.GetSysFsPath()
+	if sysfs == "" {
+		return devNodeName(f.DevPath)
+	}
+
+	f.Name = filepath.Base(sysfs)
 
 	return f.Name
 }
@@ -226,6 +347,18 @@ func (f *DflFME) GetPCIDevice() (*PCIDevice, error) {
 	return f.PCIDevice, nil
 }
 
+// GetPCIIDs returns numeric PCI vendor and device IDs of this device.
+func (f *DflFME) GetPCIIDs() (vendor, device uint16, err error) {
+	ids, err := getPCIIDs(f)
+
+	return ids.Vendor, ids.Device, err
+}
+
+// GetPCIIDsExtended returns numeric PCI IDs of this device including subsystem IDs.
+func (f *DflFME) GetPCIIDsExtended() (PCIIDs, error) {
+	return getPCIIDs(f)
+}
+
 // GetPortsNum returns amount of FPGA Ports associated to this FME.
 func (f *DflFME) GetPortsNum() int {
 	if f.PortsNum == "" {
@@ -266,6 +399,12 @@ func (f *DflFME) GetSocketID() (uint32, error) {
 	return uint32(id), err
 }
 
+// GetNUMANode returns NUMA node the FPGA is attached to or -1 if it's unknown.
+// See getNUMANode for the sources it's determined from.
+func (f *DflFME) GetNUMANode() int {
+	return getNUMANode(f)
+}
+
 // GetBitstreamID returns FME bitstream id.
 func (f *DflFME) GetBitstreamID() string {
 	return f.BitstreamID
@@ -276,6 +415,63 @@ func (f *DflFME) GetBitstreamMetadata() string {
 	return f.BitstreamMetadata
 }
 
+// GetFIMSeed returns the synthesis seed of the FIM from the bitstream
+// metadata, e.g. to check the nodes run identical FIM builds.
+// ErrNotSupported is returned if the metadata has no seed.
+func (f *DflFME) GetFIMSeed() (string, error) {
+	return getFIMSeed(f)
+}
+
+// GetMode returns the operating mode of the board, ResourceModeRegion if its
+// ports can be reprogrammed, ResourceModeAF if they run fixed AFUs or
+// ModeUnknown, e.g. to choose the resources the device plugin advertises.
+func (f *DflFME) GetMode() (string, error) {
+	return boardMode(f)
+}
+
+// GetModelName returns model name of the FPGA card or empty string if it's unknown.
+func (f *DflFME) GetModelName() string {
+	return modelName(f)
+}
+
+// GetPowerInfo returns power consumption and thresholds of the FPGA.
+// ErrNotSupported is returned if the driver doesn't report them.
+func (f *DflFME) GetPowerInfo() (PowerInfo, error) {
+	return getDflPowerInfo(f)
+}
+
+// GetFmeErrors returns the error registers of the FME.
+func (f *DflFME) GetFmeErrors() (DeviceErrors, error) {
+	return readDeviceErrors(f)
+}
+
+// ClearFmeErrors clears the errors latched by the FME.
+func (f *DflFME) ClearFmeErrors() error {
+	return clearDeviceErrors(f)
+}
+
+// Healthy reports whether the board is healthy. See checkHealth for details.
+func (f *DflFME) Healthy() (bool, []string) {
+	return checkHealth(f)
+}
+
+// RefreshProperties re-reads FME properties from sysfs.
+func (f *DflFME) RefreshProperties() error {
+	return refreshProperties(f.updateProperties, &f.generation)
+}
+
+// PropertiesGeneration returns the generation of the FME properties. See
+// refreshProperties for details.
+func (f *DflFME) PropertiesGeneration() uint64 {
+	return atomic.LoadUint64(&f.generation)
+}
+
+// WaitForBitstreamID polls FME bitstream id until it becomes equal to want or ctx expires.
+// Delays between polls are defined by bo.
+func (f *DflFME) WaitForBitstreamID(ctx context.Context, want string, bo Backoff) error {
+	return waitForBitstreamID(ctx, f, want, bo)
+}
+
 // Update properties from sysfs.
 func (f *DflFME) updateProperties() error {
 	pci, err := f.GetPCIDevice()
@@ -303,8 +499,36 @@ func (f *DflFME) updateProperties() error {
 // (e.g. DMA or PR operation failure) and be recoverable from the failure.
 // * Return: 0 on success, -errno of failure.
 func (f *DflPort) PortReset() error {
-	_, err := ioctlDev(f.DevPath, DFL_FPGA_PORT_RESET, 0)
-	return err
+	if err := requireWritable(f.GetName(), "port reset"); err != nil {
+		return err
+	}
+
+	_, err := ioctlDev(f.DevPath, DFL_FPGA_PORT_RESET, nil)
+
+	recordAudit(AuditEvent{Operation: AuditPortReset, Device: f.DevPath, Err: err})
+
+	if err != nil {
+		return err
+	}
+
+	atomic.AddUint64(&f.resetCount, 1)
+
+	return nil
+}
+
+// GetErrors returns the error registers of the port.
+func (f *DflPort) GetErrors() (DeviceErrors, error) {
+	return readDeviceErrors(f)
+}
+
+// ClearErrors clears the errors latched by the port.
+func (f *DflPort) ClearErrors() error {
+	return clearDeviceErrors(f)
+}
+
+// GetResetCount returns number of port resets. See getResetCount for details.
+func (f *DflPort) GetResetCount() (uint64, error) {
+	return getResetCount(f.GetSysFsPath(), &f.resetCount)
 }
 
 // PortGetInfo Retrieve information about the fpga port.
@@ -315,7 +539,7 @@ func (f *DflPort) PortGetInfo() (ret PortInfo, err error) {
 
 	value.Argsz = uint32(unsafe.Sizeof(value))
 
-	_, err = ioctlDev(f.DevPath, DFL_FPGA_PORT_GET_INFO, uintptr(unsafe.Pointer(&value)))
+	_, err = ioctlDev(f.DevPath, DFL_FPGA_PORT_GET_INFO, unsafe.Pointer(&value))
 	if err == nil {
 		ret.Flags = value.Flags
 		ret.Regions = value.Regions
@@ -336,7 +560,7 @@ func (f *DflPort) PortGetRegionInfo(index uint32) (ret PortRegionInfo, err error
 	value.Argsz = uint32(unsafe.Sizeof(value))
 	value.Index = index
 
-	_, err = ioctlDev(f.DevPath, DFL_FPGA_PORT_GET_REGION_INFO, uintptr(unsafe.Pointer(&value)))
+	_, err = ioctlDev(f.DevPath, DFL_FPGA_PORT_GET_REGION_INFO, unsafe.Pointer(&value))
 	if err == nil {
 		ret.Flags = value.Flags
 		ret.Index = value.Index
@@ -347,6 +571,19 @@ func (f *DflPort) PortGetRegionInfo(index uint32) (ret PortRegionInfo, err error
 	return
 }
 
+// SetAnnotation attaches user metadata to the device, e.g. its rack or purpose.
+// Annotations are kept in memory per device node and are shared by all
+// handles of the device. They aren't persisted across process restarts.
+// Setting an empty value removes the key.
+func (f *DflPort) SetAnnotation(key, value string) {
+	setAnnotation(f.GetDevPath(), key, value)
+}
+
+// Annotations returns copy of the user metadata attached to the device.
+func (f *DflPort) Annotations() map[string]string {
+	return getAnnotations(f.GetDevPath())
+}
+
 // GetDevPath returns path to device node.
 func (f *DflPort) GetDevPath() string {
 	return f.DevPath
@@ -374,7 +611,12 @@ func (f *DflPort) GetName() string {
 		return f.Name
 	}
 
-	f.Name = filepath.Base(f.GetSysFsPath())
+	sysfs := f.GetSysFsPath()
+	if sysfs == "" {
+		return devNodeName(f.DevPath)
+	}
+
+	f.Name = filepath.Base(sysfs)
 
 	return f.Name
 }
@@ -395,6 +637,18 @@ func (f *DflPort) GetPCIDevice() (*PCIDevice, error) {
 	return f.PCIDevice, nil
 }
 
+// GetPCIIDs returns numeric PCI vendor and device IDs of this device.
+func (f *DflPort) GetPCIIDs() (vendor, device uint16, err error) {
+	ids, err := getPCIIDs(f)
+
+	return ids.Vendor, ids.Device, err
+}
+
+// GetPCIIDsExtended returns numeric PCI IDs of this device including subsystem IDs.
+func (f *DflPort) GetPCIIDsExtended() (PCIIDs, error) {
+	return getPCIIDs(f)
+}
+
 // GetFME returns FPGA FME device for this port.
 func (f *DflPort) GetFME() (fme FME, err error) {
 	if f.FME != nil {
@@ -420,7 +674,7 @@ func (f *DflPort) GetFME() (fme FME, err error) {
 		return
 	}
 
-	realDev, err := filepath.EvalSymlinks(filepath.Join("/dev/char", dev))
+	realDev, err := resolveCharDev(dev)
 	if err != nil {
 		return
 	}
@@ -449,16 +703,38 @@ func (f *DflPort) GetPortID() (uint32, error) {
 	return uint32(id), err
 }
 
-// GetAcceleratorTypeUUID returns AFU UUID for port.
-func (f *DflPort) GetAcceleratorTypeUUID() (afuID string) {
-	err := f.updateProperties()
-	if err != nil || f.AFUID == "" {
-		return ""
+// GetAcceleratorTypeUUID returns AFU UUID for port. The value is cached:
+// it's read from sysfs on the first call and after the cache is invalidated
+// by PR. Use RefreshProperties or ReadAcceleratorTypeUUIDFresh to re-read it.
+func (f *DflPort) GetAcceleratorTypeUUID() string {
+	if f.AFUID == "" {
+		_ = f.updateProperties()
 	}
 
 	return f.AFUID
 }
 
+// ReadAcceleratorTypeUUIDFresh re-reads AFU UUID of the port from sysfs.
+func (f *DflPort) ReadAcceleratorTypeUUIDFresh() (string, error) {
+	f.AFUID = ""
+	if err := f.updateProperties(); err != nil {
+		return "", err
+	}
+
+	return f.AFUID, nil
+}
+
+// RefreshProperties re-reads port properties from sysfs.
+func (f *DflPort) RefreshProperties() error {
+	return refreshProperties(f.updateProperties, &f.generation)
+}
+
+// PropertiesGeneration returns the generation of the port properties. See
+// refreshProperties for details.
+func (f *DflPort) PropertiesGeneration() uint64 {
+	return atomic.LoadUint64(&f.generation)
+}
+
 // GetInterfaceUUID returns Interface UUID for FME.
 func (f *DflPort) GetInterfaceUUID() (id string) {
 	fme, err := f.GetFME()
@@ -472,16 +748,59 @@ func (f *DflPort) GetInterfaceUUID() (id string) {
 
 // PR programs specified bitstream to port.
 func (f *DflPort) PR(bs bitstream.File, dryRun bool) error {
-	return genericPortPR(f, bs, dryRun)
+	_, err := f.PRContext(context.Background(), bs, PROptions{DryRun: dryRun, SkipReadback: true})
+
+	return err
+}
+
+// PRWithOptions programs specified bitstream to port according to options.
+func (f *DflPort) PRWithOptions(bs bitstream.File, opts PROptions) (PRResult, error) {
+	return f.PRContext(context.Background(), bs, opts)
+}
+
+// PRContext programs specified bitstream to port according to options.
+// The context is checked before each step that talks to the device.
+func (f *DflPort) PRContext(ctx context.Context, bs bitstream.File, opts PROptions) (PRResult, error) {
+	res, err := genericPortPR(ctx, f, bs, opts)
+	if !opts.DryRun {
+		// The cached AFU UUID is stale whatever the outcome. The result has
+		// the fresh one if it was read back, otherwise it's re-read on demand.
+		f.AFUID = res.AFU
+	}
+
+	return res, err
+}
+
+// WaitForAFU polls the port until it reports the AFU want or ctx expires.
+// Delays between polls are defined by bo.
+func (f *DflPort) WaitForAFU(ctx context.Context, want string, bo Backoff) error {
+	return waitForAFU(ctx, f, want, bo)
+}
+
+// PRAndVerify programs bs to the port and confirms the port then reports
+// wantAFU or, if wantAFU is empty, the AFU of the bitstream. ErrAFUMismatch
+// is returned if the port keeps reporting another AFU.
+func (f *DflPort) PRAndVerify(ctx context.Context, bs bitstream.File, wantAFU string) error {
+	return genericPRAndVerify(ctx, f, bs, wantAFU)
 }
 
 // Update properties from sysfs.
 func (f *DflPort) updateProperties() error {
 	fileMap := map[string]*string{
-		"afu_id": &f.AFUID,
-		"dev":    &f.Dev,
-		"id":     &f.ID,
+		"dev": &f.Dev,
+		"id":  &f.ID,
+	}
+
+	if err := readFilesInDirectory(fileMap, f.GetSysFsPath()); err != nil {
+		return err
+	}
+
+	afuID, err := readAFUID(f.GetSysFsPath(), DriverDFL)
+	if err != nil {
+		return err
 	}
 
-	return readFilesInDirectory(fileMap, f.GetSysFsPath())
+	f.AFUID = afuID
+
+	return nil
 }
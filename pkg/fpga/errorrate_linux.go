@@ -0,0 +1,120 @@
+// Copyright 2023 Intel Corporation. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fpga
+
+import (
+	"context"
+	"math/bits"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// ErrorRateSample is the number of errors newly latched by a port between
+// two samples taken by MonitorErrorRate.
+type ErrorRateSample struct {
+	// Time is when the sample was taken.
+	Time time.Time
+	// Err is the failure to read the registers. The other fields are
+	// zero then and the next sample is relative to the last successful one.
+	Err error
+	// Deltas maps the names of the registers with newly set error bits
+	// to the number of those bits.
+	Deltas map[string]uint64
+	// Interval is the time since the previous sample.
+	Interval time.Duration
+	// Total is the sum of Deltas.
+	Total uint64
+	// Rate is Total per second.
+	Rate float64
+}
+
+// MonitorErrorRate samples the error registers of the port, see GetErrors,
+// every interval and sends the number of error bits set since the previous
+// sample to the returned channel, e.g. to spot a marginal board or AFU by
+// a spiking error rate before it fails hard. The registers are latched
+// bitmasks, so only the bits that were clear in the previous sample count:
+// bits cleared in the meantime don't, and an error latching again after its
+// bit was cleared counts again. The channel is closed and the sampling
+// goroutine exits once ctx is done. An error is returned if the first
+// sample fails.
+func MonitorErrorRate(ctx context.Context, port Port, interval time.Duration) (<-chan ErrorRateSample, error) {
+	if interval <= 0 {
+		return nil, errors.Errorf("invalid error rate sampling interval %v", interval)
+	}
+
+	last, err := port.GetErrors()
+	if err != nil {
+		return nil, errors.Wrapf(err, "%s: unable to read errors", port.GetName())
+	}
+
+	lastTime := time.Now()
+	samples := make(chan ErrorRateSample)
+
+	go func() {
+		defer close(samples)
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+			case <-ctx.Done():
+				return
+			}
+
+			sample := ErrorRateSample{Time: time.Now()}
+
+			current, readErr := port.GetErrors()
+			if readErr != nil {
+				sample.Err = errors.Wrapf(readErr, "%s: unable to read errors", port.GetName())
+			} else {
+				sample.Interval = sample.Time.Sub(lastTime)
+				sample.Deltas, sample.Total = errorDeltas(last, current)
+
+				if sample.Interval > 0 {
+					sample.Rate = float64(sample.Total) / sample.Interval.Seconds()
+				}
+
+				last, lastTime = current, sample.Time
+			}
+
+			select {
+			case samples <- sample:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return samples, nil
+}
+
+// errorDeltas returns the number of bits set in the registers of cur, but
+// not in prev, and the sum of them.
+func errorDeltas(prev, cur DeviceErrors) (map[string]uint64, uint64) {
+	deltas := map[string]uint64{}
+	total := uint64(0)
+
+	for name, value := range cur.Registers {
+		if delta := uint64(bits.OnesCount64(value &^ prev.Registers[name])); delta > 0 {
+			deltas[name] = delta
+			total += delta
+		}
+	}
+
+	return deltas, total
+}
@@ -0,0 +1,132 @@
+// Copyright 2023 Intel Corporation. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fpga
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestMonitorErrorRate(t *testing.T) {
+	port := newTestIntelFpgaPort(t, &testFME{}, testAFUOld)
+	portErrors := filepath.Join(port.SysFsPath, "errors/errors")
+	// newErrors is outside of the errors subtree not to be sampled.
+	newErrors := filepath.Join(port.SysFsPath, "errors.new")
+
+	if _, err := MonitorErrorRate(context.Background(), port, time.Millisecond); err == nil {
+		t.Error("no error returned for port without errors")
+	}
+
+	if err := createTestFiles(port.SysFsPath, []string{"errors"}, map[string]string{"errors/errors": "0x2\n"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := MonitorErrorRate(context.Background(), port, 0); err == nil {
+		t.Error("no error returned for zero interval")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	samples, err := MonitorErrorRate(ctx, port, time.Millisecond)
+	if err != nil {
+		t.Fatalf("unexpected error: %+v", err)
+	}
+
+	// setErrors replaces the register atomically, so that no sample sees
+	// the file empty.
+	setErrors := func(value string) {
+		t.Helper()
+
+		if err = os.WriteFile(newErrors, []byte(value), 0600); err != nil {
+			t.Fatal(err)
+		}
+
+		if err = os.Rename(newErrors, portErrors); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// expectNewErrors sets the register and waits for the sample with the
+	// newly set bits.
+	expectNewErrors := func(value string, expected map[string]uint64) {
+		t.Helper()
+
+		setErrors(value)
+
+		timeout := time.After(5 * time.Second)
+
+		for {
+			select {
+			case sample := <-samples:
+				if sample.Err != nil {
+					t.Fatalf("unexpected error: %+v", sample.Err)
+				}
+
+				if sample.Total == 0 {
+					continue
+				}
+
+				if !reflect.DeepEqual(sample.Deltas, expected) || sample.Rate <= 0 || sample.Interval <= 0 {
+					t.Errorf("expected new errors %v, but got %+v", expected, sample)
+				}
+
+				return
+			case <-timeout:
+				t.Fatalf("no new errors %v sampled", expected)
+			}
+		}
+	}
+
+	// Bits 0 and 2 set, bit 1 cleared.
+	expectNewErrors("0x5\n", map[string]uint64{"errors": 2})
+	// Bit 1 set again.
+	expectNewErrors("0x7\n", map[string]uint64{"errors": 1})
+	// Bits 1 and 2 cleared, which is no new error, then bit 2 set again.
+	setErrors("0x1\n")
+
+	// The first sample may be read before the change, the second one isn't.
+	for i := 0; i < 2; i++ {
+		select {
+		case sample := <-samples:
+			if sample.Err != nil || sample.Total != 0 {
+				t.Errorf("expected no new errors, but got %+v", sample)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("no sample received")
+		}
+	}
+
+	expectNewErrors("0x5\n", map[string]uint64{"errors": 1})
+
+	cancel()
+
+	timeout := time.After(5 * time.Second)
+
+	for {
+		select {
+		case _, ok := <-samples:
+			if !ok {
+				return
+			}
+		case <-timeout:
+			t.Fatal("samples not closed after cancellation")
+		}
+	}
+}
@@ -0,0 +1,239 @@
+// Copyright 2023 Intel Corporation. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fpga
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// errorsDir is the error subtree of FME and port sysfs entries.
+	errorsDir = "errors"
+	// errorsClearFile is used by the intel-fpga driver to clear errors:
+	// the value of the errors register is written to it. The dfl driver
+	// clears a register when its value is written back to it instead.
+	errorsClearFile = "clear"
+	// errorsInjectFile is the error injection register, it's not an error.
+	errorsInjectFile = "inject_errors"
+	// errorsRevisionFile has the revision of the error reporting feature.
+	errorsRevisionFile = "revision"
+	// firstErrorTimeFile has the time the first error latched at, if the
+	// driver exposes it. The value is in seconds since the Unix epoch.
+	firstErrorTimeFile = "first_error_timestamp"
+)
+
+// DeviceErrors holds the error registers of an FME or a port.
+type DeviceErrors struct {
+	// FirstErrorTime is the time the first error latched at. It's zero
+	// if the driver doesn't expose it.
+	FirstErrorTime time.Time
+	// Registers maps error register names, relative to the errors
+	// subtree of the device (e.g. "first_error" or "fme-errors/errors"),
+	// to their values.
+	Registers map[string]uint64
+}
+
+// Active returns true if any error register is non-zero.
+func (e DeviceErrors) Active() bool {
+	for _, value := range e.Registers {
+		if value != 0 {
+			return true
+		}
+	}
+
+	return false
+}
+
+// readDeviceErrors reads errors subtree of the device. Files in the subtree
+// and in its direct subdirectories are treated as error registers.
+func readDeviceErrors(dev commonFpgaAPI) (DeviceErrors, error) {
+	dir, err := deviceErrorsDir(dev)
+	if err != nil {
+		return DeviceErrors{}, err
+	}
+
+	devErrors := DeviceErrors{Registers: map[string]uint64{}}
+
+	files, err := filepath.Glob(filepath.Join(dir, "*"))
+	if err != nil {
+		return DeviceErrors{}, errors.WithStack(err)
+	}
+
+	subFiles, err := filepath.Glob(filepath.Join(dir, "*", "*"))
+	if err != nil {
+		return DeviceErrors{}, errors.WithStack(err)
+	}
+
+	for _, file := range append(files, subFiles...) {
+		name, _ := filepath.Rel(dir, file)
+
+		switch filepath.Base(name) {
+		case errorsClearFile, errorsInjectFile, errorsRevisionFile:
+			continue
+		case firstErrorTimeFile:
+			if devErrors.FirstErrorTime, err = readTimestamp(file); err != nil {
+				return DeviceErrors{}, err
+			}
+
+			continue
+		}
+
+		if info, statErr := os.Stat(file); statErr != nil || !info.Mode().IsRegular() {
+			continue
+		}
+
+		data, readErr := readSysfsFile(file)
+		if errors.Is(readErr, ErrDeviceGone) {
+			return DeviceErrors{}, errors.Wrapf(readErr, "%s: unable to read %s", dev.GetName(), name)
+		}
+
+		if readErr != nil {
+			// Some registers are write-only.
+			continue
+		}
+
+		if value, parseErr := strconv.ParseUint(strings.TrimSpace(string(data)), 0, 64); parseErr == nil {
+			devErrors.Registers[name] = value
+		}
+	}
+
+	return devErrors, nil
+}
+
+// clearDeviceErrors clears the errors reported by the device. Registers
+// which can't be cleared, such as first_error, are reset by the driver.
+func clearDeviceErrors(dev commonFpgaAPI) error {
+	if err := requireWritable(dev.GetName(), "clearing errors"); err != nil {
+		return err
+	}
+
+	devErrors, err := readDeviceErrors(dev)
+	if err != nil {
+		return err
+	}
+
+	dir, _ := deviceErrorsDir(dev)
+
+	for name, value := range devErrors.Registers {
+		if value == 0 {
+			continue
+		}
+
+		file := filepath.Join(dir, name)
+		clearFile := filepath.Join(filepath.Dir(file), errorsClearFile)
+
+		if _, statErr := os.Stat(clearFile); statErr == nil && filepath.Base(name) == "errors" {
+			file = clearFile
+		} else if info, statErr := os.Stat(file); statErr != nil || info.Mode().Perm()&0200 == 0 {
+			continue
+		}
+
+		if err = os.WriteFile(file, []byte(fmt.Sprintf("0x%x", value)), 0600); err != nil {
+			return errors.Wrapf(err, "%s: unable to clear %s", dev.GetName(), name)
+		}
+	}
+
+	return nil
+}
+
+// errorInjectionFile returns the error injection register of the device.
+func errorInjectionFile(dev commonFpgaAPI) (string, error) {
+	dir, err := deviceErrorsDir(dev)
+	if err != nil {
+		return "", err
+	}
+
+	file := filepath.Join(dir, errorsInjectFile)
+	if _, err = os.Stat(file); err != nil {
+		return "", errors.Wrapf(ErrNotSupported, "%s: no error injection", dev.GetName())
+	}
+
+	return file, nil
+}
+
+// getErrorInjection reads the mask of the errors injected to the device.
+func getErrorInjection(dev commonFpgaAPI) (uint64, error) {
+	file, err := errorInjectionFile(dev)
+	if err != nil {
+		return 0, err
+	}
+
+	data, err := readSysfsFile(file)
+	if err != nil {
+		return 0, errors.Wrapf(err, "%s: unable to read error injection", dev.GetName())
+	}
+
+	mask, err := strconv.ParseUint(strings.TrimPrefix(strings.TrimSpace(string(data)), "0x"), 16, 64)
+
+	return mask, errors.Wrapf(err, "%s: malformed error injection %q", dev.GetName(), data)
+}
+
+// setErrorInjection makes the device report the errors of mask.
+func setErrorInjection(dev commonFpgaAPI, mask uint64) error {
+	if err := requireWritable(dev.GetName(), "error injection"); err != nil {
+		return err
+	}
+
+	file, err := errorInjectionFile(dev)
+	if err != nil {
+		return err
+	}
+
+	err = os.WriteFile(file, []byte(fmt.Sprintf("0x%x", mask)), 0600)
+
+	return errors.Wrapf(err, "%s: unable to inject errors", dev.GetName())
+}
+
+// deviceErrorsDir returns errors subtree of the device.
+func deviceErrorsDir(dev commonFpgaAPI) (string, error) {
+	sysfs := dev.GetSysFsPath()
+	if sysfs == "" {
+		return "", errors.Errorf("%s: unknown sysfs entry", dev.GetName())
+	}
+
+	dir := filepath.Join(sysfs, errorsDir)
+	if _, err := os.Stat(dir); err != nil {
+		return "", errors.Wrapf(ErrNotSupported, "%s: no error reporting", dev.GetName())
+	}
+
+	return dir, nil
+}
+
+// readTimestamp parses file with seconds since the Unix epoch.
+func readTimestamp(file string) (time.Time, error) {
+	data, err := readSysfsFile(file)
+	if err != nil {
+		return time.Time{}, errors.WithStack(err)
+	}
+
+	sec, err := strconv.ParseInt(strings.TrimSpace(string(data)), 10, 64)
+	if err != nil {
+		return time.Time{}, errors.Wrapf(err, "unable to parse %s", file)
+	}
+
+	if sec == 0 {
+		// No error latched.
+		return time.Time{}, nil
+	}
+
+	return time.Unix(sec, 0).UTC(), nil
+}
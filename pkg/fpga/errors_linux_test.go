@@ -0,0 +1,207 @@
+// Copyright 2023 Intel Corporation. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fpga
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+func TestGetFmeErrors(t *testing.T) {
+	tcases := []struct {
+		files        map[string]string
+		expected     DeviceErrors
+		name         string
+		expectedErr  error
+		expectActive bool
+	}{
+		{
+			name: "with timestamp",
+			files: map[string]string{
+				"errors/fme-errors/errors":      "0x4\n",
+				"errors/fme-errors/first_error": "0x4\n",
+				"errors/fme-errors/clear":       "",
+				"errors/pcie0_errors":           "0x0\n",
+				"errors/inject_errors":          "0x0\n",
+				"errors/first_error_timestamp":  "1672574405\n",
+			},
+			expected: DeviceErrors{
+				FirstErrorTime: time.Date(2023, time.January, 1, 12, 0, 5, 0, time.UTC),
+				Registers: map[string]uint64{
+					"fme-errors/errors":      4,
+					"fme-errors/first_error": 4,
+					"pcie0_errors":           0,
+				},
+			},
+			expectActive: true,
+		},
+		{
+			name: "without timestamp",
+			files: map[string]string{
+				"errors/fme-errors/errors": "0x0\n",
+				"errors/revision":          "1\n",
+			},
+			expected: DeviceErrors{
+				Registers: map[string]uint64{"fme-errors/errors": 0},
+			},
+		},
+		{
+			name: "no error latched",
+			files: map[string]string{
+				"errors/fme-errors/errors":     "0x0\n",
+				"errors/first_error_timestamp": "0\n",
+			},
+			expected: DeviceErrors{
+				Registers: map[string]uint64{"fme-errors/errors": 0},
+			},
+		},
+		{
+			name:        "no error reporting",
+			files:       map[string]string{"bitstream_id": "0x1"},
+			expectedErr: ErrNotSupported,
+		},
+	}
+	for _, tc := range tcases {
+		t.Run(tc.name, func(t *testing.T) {
+			fme := newTestIntelFpgaFME(t, tc.files)
+
+			devErrors, err := fme.GetFmeErrors()
+			if tc.expectedErr != nil {
+				if !errors.Is(err, tc.expectedErr) {
+					t.Errorf("expected error %v, but got %v", tc.expectedErr, err)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("unexpected error: %+v", err)
+			}
+
+			if !devErrors.FirstErrorTime.Equal(tc.expected.FirstErrorTime) {
+				t.Errorf("expected first error time %v, but got %v", tc.expected.FirstErrorTime, devErrors.FirstErrorTime)
+			}
+
+			if !reflect.DeepEqual(devErrors.Registers, tc.expected.Registers) {
+				t.Errorf("expected registers %v, but got %v", tc.expected.Registers, devErrors.Registers)
+			}
+
+			if devErrors.Active() != tc.expectActive {
+				t.Errorf("expected active %t", tc.expectActive)
+			}
+		})
+	}
+}
+
+func TestClearErrors(t *testing.T) {
+	t.Run("intel-fpga clear file", func(t *testing.T) {
+		fme := newTestIntelFpgaFME(t, map[string]string{
+			"errors/fme-errors/errors":      "0x4\n",
+			"errors/fme-errors/first_error": "0x4\n",
+			"errors/fme-errors/clear":       "",
+		})
+
+		if err := fme.ClearFmeErrors(); err != nil {
+			t.Fatalf("unexpected error: %+v", err)
+		}
+
+		assertFileContent(t, filepath.Join(fme.SysFsPath, "errors/fme-errors/clear"), "0x4")
+		assertFileContent(t, filepath.Join(fme.SysFsPath, "errors/fme-errors/errors"), "0x4\n")
+	})
+
+	t.Run("dfl write-back", func(t *testing.T) {
+		sysfs := t.TempDir()
+		if err := createTestFiles(sysfs, nil, map[string]string{
+			"errors/errors":      "0x11\n",
+			"errors/first_error": "0x1\n",
+		}); err != nil {
+			t.Fatal(err)
+		}
+
+		// first_error is read-only and must not be written to.
+		if err := os.Chmod(filepath.Join(sysfs, "errors/first_error"), 0400); err != nil {
+			t.Fatal(err)
+		}
+
+		port := &DflPort{DevPath: "/dev/dfl-port.0", SysFsPath: sysfs}
+		if err := port.ClearErrors(); err != nil {
+			t.Fatalf("unexpected error: %+v", err)
+		}
+
+		assertFileContent(t, filepath.Join(sysfs, "errors/errors"), "0x11")
+		assertFileContent(t, filepath.Join(sysfs, "errors/first_error"), "0x1\n")
+	})
+}
+
+func TestErrorInjection(t *testing.T) {
+	port := newTestIntelFpgaPort(t, nil, testAFUOld)
+
+	if _, err := port.GetErrorInjection(); !errors.Is(err, ErrNotSupported) {
+		t.Errorf("expected ErrNotSupported without errors subtree, but got %v", err)
+	}
+
+	if err := createTestFiles(port.SysFsPath, []string{"errors"}, map[string]string{"errors/errors": "0x0\n"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := port.SetErrorInjection(0x1); !errors.Is(err, ErrNotSupported) {
+		t.Errorf("expected ErrNotSupported without injection register, but got %v", err)
+	}
+
+	if err := createTestFiles(port.SysFsPath, nil, map[string]string{"errors/inject_errors": "0x0\n"}); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, mask := range []uint64{0x4, 0x8000000000000001, 0} {
+		if err := port.SetErrorInjection(mask); err != nil {
+			t.Fatalf("unexpected error: %+v", err)
+		}
+
+		got, err := port.GetErrorInjection()
+		if err != nil {
+			t.Fatalf("unexpected error: %+v", err)
+		}
+
+		if got != mask {
+			t.Errorf("expected mask %#x, but got %#x", mask, got)
+		}
+	}
+
+	// The injection register isn't an error register.
+	devErrors, err := port.GetErrors()
+	if err != nil || !reflect.DeepEqual(devErrors.Registers, map[string]uint64{"errors": 0}) {
+		t.Errorf("unexpected errors %+v, %v", devErrors, err)
+	}
+
+	assertFileContent(t, filepath.Join(port.SysFsPath, "errors/inject_errors"), "0x0")
+}
+
+func assertFileContent(t *testing.T, file, expected string) {
+	t.Helper()
+
+	data, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(data) != expected {
+		t.Errorf("expected %s to contain %q, but got %q", file, expected, data)
+	}
+}
@@ -0,0 +1,82 @@
+// Copyright 2023 Intel Corporation. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fpga
+
+import "strings"
+
+// FMEPredicate reports whether FME matches some criteria.
+type FMEPredicate func(FME) bool
+
+// FilterFMEs returns FMEs for which predicate returns true.
+func FilterFMEs(fmes []FME, predicate FMEPredicate) []FME {
+	var ret []FME
+
+	for _, fme := range fmes {
+		if predicate(fme) {
+			ret = append(ret, fme)
+		}
+	}
+
+	return ret
+}
+
+// ByModel matches FMEs of the given model, e.g. "Intel FPGA PAC N3000".
+func ByModel(name string) FMEPredicate {
+	return func(fme FME) bool {
+		return strings.EqualFold(fme.GetModelName(), name)
+	}
+}
+
+// ByInterfaceUUID matches FMEs with the given interface UUID.
+func ByInterfaceUUID(uuid string) FMEPredicate {
+	uuid = CanonizeID(uuid)
+
+	return func(fme FME) bool {
+		return CanonizeID(fme.GetInterfaceUUID()) == uuid
+	}
+}
+
+// AllOf matches FMEs that satisfy all predicates.
+func AllOf(predicates ...FMEPredicate) FMEPredicate {
+	return func(fme FME) bool {
+		for _, predicate := range predicates {
+			if !predicate(fme) {
+				return false
+			}
+		}
+
+		return true
+	}
+}
+
+// AnyOf matches FMEs that satisfy at least one of predicates.
+func AnyOf(predicates ...FMEPredicate) FMEPredicate {
+	return func(fme FME) bool {
+		for _, predicate := range predicates {
+			if predicate(fme) {
+				return true
+			}
+		}
+
+		return false
+	}
+}
+
+// Not negates the predicate.
+func Not(predicate FMEPredicate) FMEPredicate {
+	return func(fme FME) bool {
+		return !predicate(fme)
+	}
+}
@@ -0,0 +1,90 @@
+// Copyright 2023 Intel Corporation. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fpga
+
+import (
+	"testing"
+)
+
+func TestFilterFMEs(t *testing.T) {
+	fmes := []FME{
+		&testFME{name: "fme0", model: "Intel FPGA PAC N3000", interfaceUUID: "bfac4d85-1ee8-56fe-8c95-865ce1bbaa2d"},
+		&testFME{name: "fme1", model: "Intel FPGA PAC N3000", interfaceUUID: "ce48969398f05f33946d560708be108a"},
+		&testFME{name: "fme2", model: "Intel FPGA PAC D5005", interfaceUUID: "bfac4d851ee856fe8c95865ce1bbaa2d"},
+		&testFME{name: "fme3", model: "Intel PAC with Arria 10 GX FPGA", interfaceUUID: "69528db6eb31577a8c3668f9faa081f6"},
+	}
+
+	tcases := []struct {
+		name      string
+		predicate FMEPredicate
+		expected  []string
+	}{
+		{
+			name:      "by model",
+			predicate: ByModel("intel fpga pac n3000"),
+			expected:  []string{"fme0", "fme1"},
+		},
+		{
+			name:      "by interface UUID",
+			predicate: ByInterfaceUUID("BFAC4D851EE856FE8C95865CE1BBAA2D"),
+			expected:  []string{"fme0", "fme2"},
+		},
+		{
+			name:      "all of",
+			predicate: AllOf(ByModel("Intel FPGA PAC N3000"), ByInterfaceUUID("bfac4d851ee856fe8c95865ce1bbaa2d")),
+			expected:  []string{"fme0"},
+		},
+		{
+			name:      "any of",
+			predicate: AnyOf(ByModel("Intel FPGA PAC D5005"), ByInterfaceUUID("69528db6eb31577a8c3668f9faa081f6")),
+			expected:  []string{"fme2", "fme3"},
+		},
+		{
+			name:      "not",
+			predicate: Not(AnyOf(ByModel("Intel FPGA PAC N3000"), ByModel("Intel FPGA PAC D5005"))),
+			expected:  []string{"fme3"},
+		},
+		{
+			name:      "nothing matches",
+			predicate: ByModel("unknown"),
+		},
+	}
+	for _, tc := range tcases {
+		t.Run(tc.name, func(t *testing.T) {
+			filtered := FilterFMEs(fmes, tc.predicate)
+			if len(filtered) != len(tc.expected) {
+				t.Fatalf("expected %d FMEs, but got %d", len(tc.expected), len(filtered))
+			}
+
+			for i, fme := range filtered {
+				if fme.GetName() != tc.expected[i] {
+					t.Errorf("expected %s, but got %s", tc.expected[i], fme.GetName())
+				}
+			}
+		})
+	}
+}
+
+func TestGetModelName(t *testing.T) {
+	fme := &IntelFpgaFME{PCIDevice: &PCIDevice{Vendor: "0x8086", Device: "0x0B30"}}
+	if model := fme.GetModelName(); model != "Intel FPGA PAC N3000" {
+		t.Errorf("unexpected model name %q", model)
+	}
+
+	fme = &IntelFpgaFME{PCIDevice: &PCIDevice{Vendor: "0x8086", Device: "0xffff"}}
+	if model := fme.GetModelName(); model != "" {
+		t.Errorf("unexpected model name %q for unknown device", model)
+	}
+}
@@ -0,0 +1,215 @@
+// Copyright 2023 Intel Corporation. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fpga
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// secMgrClass is the sysfs class of FPGA security managers which
+	// handle flash (FIM/BMC image) updates of the card.
+	secMgrClass = "class/fpga_sec_mgr"
+	// mtdClass is the sysfs class of memory technology devices, which
+	// include the card's SPI flash.
+	mtdClass = "class/mtd"
+
+	flashStatusIdle        = "idle"
+	flashStatusWriting     = "writing"
+	flashStatusProgramming = "programming"
+)
+
+// FlashInfo identifies the flash part of the card.
+type FlashInfo struct {
+	// Device is the MTD device of the flash, e.g. mtd0.
+	Device string
+	// Manufacturer, PartName and JEDECID are reported by the SPI NOR
+	// driver. They are empty if the driver doesn't expose them.
+	Manufacturer string
+	PartName     string
+	JEDECID      string
+	// Size is the size of the flash in bytes.
+	Size uint64
+}
+
+// firmwareDir is where the kernel looks up the image named in update/filename.
+var firmwareDir = "/lib/firmware"
+
+// findSecMgrUpdateDir returns the "update" directory of the security manager
+// that belongs to the PCI device located at pciPath.
+func findSecMgrUpdateDir(pciPath string) (string, error) {
+	mgrs, err := os.ReadDir(filepath.Join(sysfsRoot, secMgrClass))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return "", ErrNotSupported
+		}
+
+		return "", errors.Wrap(err, "unable to list FPGA security managers")
+	}
+
+	for _, mgr := range mgrs {
+		realPath, evalErr := filepath.EvalSymlinks(filepath.Join(sysfsRoot, secMgrClass, mgr.Name()))
+		if evalErr != nil {
+			continue
+		}
+
+		if strings.HasPrefix(realPath, pciPath+"/") {
+			return filepath.Join(realPath, "update"), nil
+		}
+	}
+
+	return "", ErrNotSupported
+}
+
+// getFlashUpdateProgress reads update state of the card's flash. The percentage
+// shows which part of the image has been written to the card so far: it stays
+// at zero while the image is being read or prepared and becomes 100 once the
+// card starts programming the written image into flash.
+func getFlashUpdateProgress(dev commonFpgaAPI) (percent int, status string, err error) {
+	pci, err := dev.GetPCIDevice()
+	if err != nil {
+		return 0, "", err
+	}
+
+	updateDir, err := findSecMgrUpdateDir(pci.SysFsPath)
+	if err != nil {
+		return 0, "", err
+	}
+
+	var filename, remaining, updateErr string
+
+	fileMap := map[string]*string{
+		"status":         &status,
+		"filename":       &filename,
+		"remaining_size": &remaining,
+		"error":          &updateErr,
+	}
+	if err = readFilesInDirectory(fileMap, updateDir); err != nil {
+		return 0, "", err
+	}
+
+	if status == "" {
+		return 0, "", ErrNotSupported
+	}
+
+	if updateErr != "" {
+		return 0, status, errors.Errorf("%s: flash update failed: %s", pci.BDF, updateErr)
+	}
+
+	switch status {
+	case flashStatusIdle:
+		// The filename is kept after a successful update.
+		if filename != "" {
+			percent = 100
+		}
+	case flashStatusWriting:
+		percent = writeProgress(filename, remaining)
+	case flashStatusProgramming:
+		percent = 100
+	}
+
+	return percent, status, nil
+}
+
+// writeProgress calculates percentage of the image that has already been written.
+// Zero is returned if sizes can't be determined.
+func writeProgress(filename, remaining string) int {
+	left, err := strconv.ParseUint(remaining, 10, 64)
+	if err != nil {
+		return 0
+	}
+
+	fi, err := os.Stat(filepath.Join(firmwareDir, filename))
+	if err != nil || fi.Size() <= 0 {
+		return 0
+	}
+
+	total := uint64(fi.Size())
+	if left >= total {
+		return 0
+	}
+
+	return int((total - left) * 100 / total)
+}
+
+// getFlashInfo reads identity of the card's flash. The flash is the largest
+// MTD device of the card, as the other ones are its partitions.
+func getFlashInfo(dev commonFpgaAPI) (FlashInfo, error) {
+	pci, err := dev.GetPCIDevice()
+	if err != nil {
+		return FlashInfo{}, err
+	}
+
+	mtds, err := os.ReadDir(filepath.Join(sysfsRoot, mtdClass))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return FlashInfo{}, ErrNotSupported
+		}
+
+		return FlashInfo{}, errors.Wrap(err, "unable to list MTD devices")
+	}
+
+	var (
+		info    FlashInfo
+		mtdPath string
+	)
+
+	for _, mtd := range mtds {
+		// mtdNro are read-only aliases of mtdN.
+		if strings.HasSuffix(mtd.Name(), "ro") {
+			continue
+		}
+
+		realPath, evalErr := filepath.EvalSymlinks(filepath.Join(sysfsRoot, mtdClass, mtd.Name()))
+		if evalErr != nil || !strings.HasPrefix(realPath, pci.SysFsPath+"/") {
+			continue
+		}
+
+		data, readErr := readSysfsFile(filepath.Join(realPath, "size"))
+		if readErr != nil {
+			return FlashInfo{}, errors.Wrapf(readErr, "%s: unable to read size of %s", pci.BDF, mtd.Name())
+		}
+
+		size, convErr := strconv.ParseUint(strings.TrimSpace(string(data)), 10, 64)
+		if convErr != nil {
+			return FlashInfo{}, errors.Wrapf(convErr, "%s: malformed size of %s", pci.BDF, mtd.Name())
+		}
+
+		if mtdPath == "" || size > info.Size {
+			info = FlashInfo{Device: mtd.Name(), Size: size}
+			mtdPath = realPath
+		}
+	}
+
+	if mtdPath == "" {
+		return FlashInfo{}, ErrNotSupported
+	}
+
+	fileMap := map[string]*string{
+		"manufacturer": &info.Manufacturer,
+		"partname":     &info.PartName,
+		"jedec_id":     &info.JEDECID,
+	}
+	if err = readFilesInDirectory(fileMap, filepath.Join(mtdPath, "device", "spi-nor")); err != nil {
+		return FlashInfo{}, err
+	}
+
+	return info, nil
+}
@@ -0,0 +1,191 @@
+// Copyright 2023 Intel Corporation. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fpga
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/pkg/errors"
+)
+
+const testSecMgr = "spi-altera.0/spi_master/spi0/spi0.0/fpga_sec_mgr/fpga_sec0"
+
+// setTestSysfsRoot points sysfsRoot to a temporary directory for the duration of the test.
+func setTestSysfsRoot(t *testing.T) string {
+	t.Helper()
+
+	root := t.TempDir()
+	orig := sysfsRoot
+	sysfsRoot = root
+
+	t.Cleanup(func() { sysfsRoot = orig })
+
+	return root
+}
+
+func TestGetFlashUpdateProgress(t *testing.T) {
+	root := setTestSysfsRoot(t)
+
+	origFirmwareDir := firmwareDir
+	firmwareDir = t.TempDir()
+
+	defer func() { firmwareDir = origFirmwareDir }()
+
+	if err := os.WriteFile(filepath.Join(firmwareDir, "n3000.bin"), make([]byte, 1000), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	fme := newTestIntelFpgaFME(t, nil)
+
+	if _, _, err := fme.GetFlashUpdateProgress(); !errors.Is(err, ErrNotSupported) {
+		t.Fatalf("expected ErrNotSupported without security manager, but got %+v", err)
+	}
+
+	updateDir := filepath.Join(fme.PCIDevice.SysFsPath, testSecMgr, "update")
+	if err := os.MkdirAll(updateDir, 0750); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.MkdirAll(filepath.Join(root, secMgrClass), 0750); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.Symlink(filepath.Dir(updateDir), filepath.Join(root, secMgrClass, "fpga_sec0")); err != nil {
+		t.Fatal(err)
+	}
+
+	progress := []struct {
+		status          string
+		filename        string
+		remaining       string
+		updateErr       string
+		expectedPercent int
+		expectedErr     bool
+	}{
+		{status: "idle", expectedPercent: 0},
+		{status: "reading", filename: "n3000.bin", expectedPercent: 0},
+		{status: "preparing", filename: "n3000.bin", remaining: "1000", expectedPercent: 0},
+		{status: "writing", filename: "n3000.bin", remaining: "1000", expectedPercent: 0},
+		{status: "writing", filename: "n3000.bin", remaining: "750", expectedPercent: 25},
+		{status: "writing", filename: "n3000.bin", remaining: "10", expectedPercent: 99},
+		{status: "programming", filename: "n3000.bin", remaining: "0", expectedPercent: 100},
+		{status: "idle", filename: "n3000.bin", remaining: "0", expectedPercent: 100},
+		{status: "idle", filename: "n3000.bin", updateErr: "programming:hw-error", expectedErr: true},
+	}
+	for _, p := range progress {
+		files := map[string]string{
+			"status":         p.status + "\n",
+			"filename":       p.filename + "\n",
+			"remaining_size": p.remaining + "\n",
+			"error":          p.updateErr + "\n",
+		}
+		if err := createTestFiles(updateDir, nil, files); err != nil {
+			t.Fatal(err)
+		}
+
+		percent, status, err := fme.GetFlashUpdateProgress()
+		if p.expectedErr {
+			if err == nil || !strings.Contains(err.Error(), p.updateErr) {
+				t.Errorf("%s: expected error %q, but got %v", p.status, p.updateErr, err)
+			}
+
+			continue
+		}
+
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %+v", p.status, err)
+		}
+
+		if status != p.status {
+			t.Errorf("expected status %q, but got %q", p.status, status)
+		}
+
+		if percent != p.expectedPercent {
+			t.Errorf("%s (remaining %s): expected %d%%, but got %d%%", p.status, p.remaining, p.expectedPercent, percent)
+		}
+	}
+}
+
+func TestGetFlashInfo(t *testing.T) {
+	root := setTestSysfsRoot(t)
+	fme := newTestIntelFpgaFME(t, nil)
+
+	if _, err := fme.GetFlashInfo(); !errors.Is(err, ErrNotSupported) {
+		t.Fatalf("expected ErrNotSupported without MTD devices, but got %+v", err)
+	}
+
+	spiDev := filepath.Join(fme.PCIDevice.SysFsPath, "spi-altera.0/spi_master/spi0/spi0.0")
+	otherDev := filepath.Join(root, "devices/platform/spi0/spi0.0")
+	files := map[string]string{
+		"spi-nor/manufacturer": "macronix\n",
+		"spi-nor/partname":     "mx25u51245g\n",
+		"spi-nor/jedec_id":     "c2253a\n",
+		"mtd/mtd0/size":        "67108864\n",
+		"mtd/mtd0ro/size":      "67108864\n",
+		"mtd/mtd1/size":        "33554432\n",
+	}
+
+	if err := createTestFiles(spiDev, []string{"mtd/mtd0", "mtd/mtd0ro", "mtd/mtd1"}, files); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := createTestFiles(otherDev, []string{"mtd/mtd2"}, map[string]string{"mtd/mtd2/size": "134217728\n"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.MkdirAll(filepath.Join(root, mtdClass), 0750); err != nil {
+		t.Fatal(err)
+	}
+
+	links := map[string]string{
+		filepath.Join(spiDev, "mtd/mtd0/device"): spiDev,
+		filepath.Join(root, mtdClass, "mtd0"):    filepath.Join(spiDev, "mtd/mtd0"),
+		filepath.Join(root, mtdClass, "mtd0ro"):  filepath.Join(spiDev, "mtd/mtd0ro"),
+		filepath.Join(root, mtdClass, "mtd1"):    filepath.Join(spiDev, "mtd/mtd1"),
+		filepath.Join(root, mtdClass, "mtd2"):    filepath.Join(otherDev, "mtd/mtd2"),
+	}
+	for link, target := range links {
+		if err := os.Symlink(target, link); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	info, err := fme.GetFlashInfo()
+	if err != nil {
+		t.Fatalf("unexpected error: %+v", err)
+	}
+
+	expected := FlashInfo{
+		Device:       "mtd0",
+		Manufacturer: "macronix",
+		PartName:     "mx25u51245g",
+		JEDECID:      "c2253a",
+		Size:         64 << 20,
+	}
+	if info != expected {
+		t.Errorf("expected %+v, but got %+v", expected, info)
+	}
+
+	if err = os.WriteFile(filepath.Join(spiDev, "mtd/mtd0/size"), []byte("64M\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = fme.GetFlashInfo(); err == nil {
+		t.Error("no error returned for malformed size")
+	}
+}
@@ -15,13 +15,78 @@
 package fpga
 
 import (
+	"context"
+	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
+	"strconv"
 	"strings"
+	"sync/atomic"
+	"syscall"
+	"time"
 
 	"github.com/intel/intel-device-plugins-for-kubernetes/pkg/fpga/bitstream"
 
 	"github.com/pkg/errors"
+	"k8s.io/klog/v2"
+)
+
+const (
+	// portResetCountFile is an optional port attribute with driver maintained reset counter.
+	portResetCountFile = "reset_count"
+	// fmePRCountFile is an optional FME attribute with driver maintained count of partial reconfigurations.
+	fmePRCountFile = "pr/pr_count"
+	// fmeConcurrentPRFile is an optional FME attribute set to 1 if the board can reconfigure several ports at once.
+	fmeConcurrentPRFile = "pr/concurrent_pr"
+	// prRegionSizeFile is an optional port attribute with the size of its PR region in bytes.
+	prRegionSizeFile = "pr_region_size"
+)
+
+var (
+	// ErrNotSupported is returned when the device or its driver lacks the requested feature.
+	ErrNotSupported = errors.New("not supported")
+
+	// ErrNotFound is returned when the requested device doesn't exist.
+	ErrNotFound = errors.New("not found")
+
+	// ErrDeviceGone is returned when the device disappeared, e.g. the board was
+	// hot-removed, while it was being accessed. The error also matches the
+	// original errno (ENODEV, ENXIO or ENOENT for the device node).
+	ErrDeviceGone = errors.New("device is gone")
+
+	// ErrRequiresPhysicalFunction is returned when an operation isn't available on a PCI virtual function.
+	ErrRequiresPhysicalFunction = errors.New("requires PCI physical function")
+
+	// ErrAFUMismatch is returned when the port reports other AFU than the one programmed.
+	ErrAFUMismatch = errors.New("AFU mismatch")
+
+	// ErrFullChipImage is returned when a full chip image is programmed with
+	// partial reconfiguration. Such images have to be written to the flash.
+	ErrFullChipImage = errors.New("full chip image")
+
+	// ErrReadOnly is returned by the operations changing the device state while
+	// the package is in the read-only mode, see SetReadOnly.
+	ErrReadOnly = errors.New("read-only mode")
+
+	// readOnly is non-zero in the read-only mode.
+	readOnly int32
+
+	// afuSettleTimeout limits how long PRAndVerify waits for the port to report the new AFU.
+	afuSettleTimeout = 5 * time.Second
+
+	// rollbackTimeout limits the rollback after failed PR, which doesn't
+	// use the context of the PR, see rollbackPortPR.
+	rollbackTimeout = 30 * time.Second
+
+	// sysfsRoot is the mount point of sysfs.
+	sysfsRoot = "/sys"
+
+	// procfsRoot is the mount point of procfs.
+	procfsRoot = "/proc"
+
+	// devfsRoot is the directory with device nodes.
+	devfsRoot = "/dev"
 )
 
 // IsFpgaFME returns true if the name looks like any supported FME device.
@@ -36,17 +101,89 @@ func IsFpgaPort(name string) bool {
 	return strings.HasPrefix(devName, dflFpgaPortPrefix) || strings.HasPrefix(devName, intelFpgaPortPrefix)
 }
 
+// DriverType identifies kernel driver of FPGA devices.
+type DriverType int
+
+const (
+	// DriverUnknown is reported for devices of no supported driver.
+	DriverUnknown DriverType = iota
+	// DriverIntelFpga is the out-of-tree intel-fpga driver of OPAE.
+	DriverIntelFpga
+	// DriverDFL is the upstream Device Feature List driver.
+	DriverDFL
+)
+
+// String returns name of the driver.
+func (d DriverType) String() string {
+	switch d {
+	case DriverIntelFpga:
+		return "intel-fpga"
+	case DriverDFL:
+		return "dfl"
+	default:
+		return "unknown"
+	}
+}
+
+// GetDriverType returns driver of the FME or port device with the given name or path.
+func GetDriverType(name string) DriverType {
+	devName := cleanBasename(name)
+
+	switch {
+	case strings.HasPrefix(devName, dflFpgaFmePrefix), strings.HasPrefix(devName, dflFpgaPortPrefix):
+		return DriverDFL
+	case strings.HasPrefix(devName, intelFpgaFmePrefix), strings.HasPrefix(devName, intelFpgaPortPrefix):
+		return DriverIntelFpga
+	default:
+		return DriverUnknown
+	}
+}
+
+// afuIDFiles lists locations of the AFU ID attribute relative to the port
+// sysfs directory, in order of preference. The intel-fpga driver publishes
+// it in the port directory. DFL does the same for the port, but kernels
+// registering the AFU as a separate device below the port publish it there.
+var afuIDFiles = map[DriverType][]string{
+	DriverIntelFpga: {"afu_id"},
+	DriverDFL:       {"afu_id", "*/afu_id"},
+}
+
+// readAFUID reads AFU ID of the port from the location used by the driver
+// and returns it canonized, so the value is the same for both drivers.
+// Empty ID is returned without error if the driver doesn't publish it.
+func readAFUID(sysfsPath string, driver DriverType) (string, error) {
+	for _, name := range afuIDFiles[driver] {
+		afuID := ""
+		if err := readFilesInDirectory(map[string]*string{name: &afuID}, sysfsPath); err != nil {
+			return "", err
+		}
+
+		if afuID != "" {
+			return CanonizeID(afuID), nil
+		}
+	}
+
+	return "", nil
+}
+
 // CanonizeID canonizes Interface and AFU ids.
 func CanonizeID(ID string) string {
 	return strings.ToLower(strings.Replace(strings.TrimSpace(ID), "-", "", -1))
 }
 
-// NewPort returns Port for specified device node.
-func NewPort(fname string) (Port, error) {
+// devNodePath returns path of the device node. Bare names, e.g.
+// "intel-fpga-port.0", are looked up in devfsRoot.
+func devNodePath(fname string) string {
 	if strings.IndexByte(fname, byte('/')) < 0 {
-		fname = filepath.Join("/dev", fname)
+		return filepath.Join(devfsRoot, fname)
 	}
 
+	return fname
+}
+
+// NewPort returns Port for specified device node.
+func NewPort(fname string) (Port, error) {
+	fname = devNodePath(fname)
 	devName := cleanBasename(fname)
 
 	switch {
@@ -61,10 +198,7 @@ func NewPort(fname string) (Port, error) {
 
 // NewFME returns FME for specified device node.
 func NewFME(fname string) (FME, error) {
-	if strings.IndexByte(fname, byte('/')) < 0 {
-		fname = filepath.Join("/dev", fname)
-	}
-
+	fname = devNodePath(fname)
 	devName := cleanBasename(fname)
 
 	switch {
@@ -77,14 +211,99 @@ func NewFME(fname string) (FME, error) {
 	return nil, errors.Errorf("unknown type of FPGA FME %s", fname)
 }
 
+// NewFMEByPCIAddress returns FME of the FPGA device with the given PCI address,
+// e.g. 0000:3b:00.0. ErrNotFound is returned if there's no such PCI device
+// or it has no FME.
+func NewFMEByPCIAddress(addr string) (FME, error) {
+	sysfs, dev, err := findByPCIAddress(addr, dflFpgaFmeGlobPCI, intelFpgaFmeGlobPCI)
+	if err != nil {
+		return nil, err
+	}
+
+	if strings.HasPrefix(filepath.Base(sysfs), dflFpgaFmePrefix) {
+		return newDflFME(dev, sysfs)
+	}
+
+	return newIntelFpgaFME(dev, sysfs)
+}
+
+// NewPortByPCIAddress returns port of the FPGA device with the given PCI address,
+// e.g. 0000:3b:00.0. If the device has several ports, the first one is returned.
+// ErrNotFound is returned if there's no such PCI device or it has no ports.
+func NewPortByPCIAddress(addr string) (Port, error) {
+	sysfs, dev, err := findByPCIAddress(addr, dflFpgaPortGlobPCI, intelFpgaPortGlobPCI)
+	if err != nil {
+		return nil, err
+	}
+
+	if strings.HasPrefix(filepath.Base(sysfs), dflFpgaPortPrefix) {
+		return newDflPort(dev, sysfs)
+	}
+
+	return newIntelFpgaPort(dev, sysfs)
+}
+
+// findByPCIAddress returns sysfs entry and device node of the first FPGA
+// device matching any of globs below the PCI device with the given address.
+func findByPCIAddress(addr string, globs ...string) (sysfs, dev string, err error) {
+	if !pciAddressRE.MatchString(addr) {
+		return "", "", errors.Errorf("malformed PCI address %q", addr)
+	}
+
+	pciPath, err := filepath.EvalSymlinks(filepath.Join(sysfsRoot, "bus/pci/devices", addr))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return "", "", errors.Wrapf(ErrNotFound, "PCI device %s", addr)
+		}
+
+		return "", "", errors.Wrapf(err, "unable to find PCI device %s", addr)
+	}
+
+	for _, glob := range globs {
+		matches, _ := filepath.Glob(filepath.Join(pciPath, glob))
+		if len(matches) == 0 {
+			continue
+		}
+
+		sort.Strings(matches)
+
+		var devNum string
+		if err = readFilesInDirectory(map[string]*string{"dev": &devNum}, matches[0]); err != nil {
+			return "", "", err
+		}
+
+		dev, err = resolveCharDev(devNum)
+		if err != nil {
+			return "", "", errors.Wrapf(err, "%s: unable to find device node", matches[0])
+		}
+
+		return matches[0], dev, nil
+	}
+
+	return "", "", errors.Wrapf(ErrNotFound, "FPGA device for PCI device %s", addr)
+}
+
 // ListFpgaDevices returns two lists of FPGA device nodes: FMEs and Ports.
 func ListFpgaDevices() (FMEs, Ports []string) {
-	files, err := os.ReadDir("/sys/bus/platform/devices")
+	FMEs, Ports, _ = ListFpgaDevicesContext(context.Background())
+
+	return
+}
+
+// ListFpgaDevicesContext returns two lists of FPGA device nodes: FMEs and Ports.
+// Unlike ListFpgaDevices, it reports an error if the platform devices can't
+// be read or the context is done before the listing is complete.
+func ListFpgaDevicesContext(ctx context.Context) (FMEs, Ports []string, err error) {
+	files, err := os.ReadDir(filepath.Join(sysfsRoot, "bus/platform/devices"))
 	if err != nil {
-		return
+		return nil, nil, errors.Wrap(err, "unable to list platform devices")
 	}
 
 	for _, file := range files {
+		if err = ctx.Err(); err != nil {
+			return nil, nil, err
+		}
+
 		fname := file.Name()
 
 		switch {
@@ -98,32 +317,607 @@ func ListFpgaDevices() (FMEs, Ports []string) {
 	return
 }
 
-func genericPortPR(f Port, bs bitstream.File, dryRun bool) error {
+func genericPortPR(ctx context.Context, f Port, bs bitstream.File, opts PROptions) (PRResult, error) {
+	if err := ctx.Err(); err != nil {
+		return PRResult{}, err
+	}
+
+	// Dry run only validates the bitstream, so it's allowed in read-only mode.
+	if !opts.DryRun {
+		if err := requireWritable(f.GetName(), "PR"); err != nil {
+			return PRResult{}, err
+		}
+	}
+
+	if err := checkPRAllowed(bs.InterfaceUUID()); err != nil {
+		return PRResult{}, err
+	}
+
 	fme, err := f.GetFME()
 	if err != nil {
-		return err
+		return PRResult{}, err
 	}
 
-	ifID := fme.GetInterfaceUUID()
-	bsID := bs.InterfaceUUID()
+	imageType, err := bs.ImageType()
+	if err != nil {
+		return PRResult{}, err
+	}
+
+	if imageType == bitstream.FullChip {
+		return PRResult{}, errors.Wrapf(ErrFullChipImage, "bitstream %s can't be programmed to %s, use flash update instead", bs.UniqueUUID(), f.GetName())
+	}
+
+	if err = CompatibleWith(fme, bs); err != nil {
+		return PRResult{}, err
+	}
 
-	if ifID != bsID {
-		return errors.Errorf("FME interface UUID %q is not compatible with bitstream UUID %q ", ifID, bsID)
+	if opts.RequirePowerHeadroom {
+		if err = checkPowerHeadroom(fme, bs); err != nil {
+			return PRResult{}, err
+		}
 	}
 
 	pNum, err := f.GetPortID()
 	if err != nil {
-		return err
+		return PRResult{}, err
 	}
 
 	rawBistream, err := bs.RawBitstreamData()
 	if err != nil {
+		return PRResult{}, err
+	}
+
+	if opts.DryRun {
+		return PRResult{}, nil
+	}
+
+	var prevAFU string
+	if opts.Rollback != nil {
+		// Without the previous AFU there's nothing to roll back to.
+		prevAFU, _ = f.ReadAcceleratorTypeUUIDFresh()
+	}
+
+	if err = portPRWithRetry(withAuditAFU(ctx, bs.AcceleratorTypeUUID()), fme, pNum, rawBistream, opts); err != nil {
+		if prevAFU == "" {
+			return PRResult{}, err
+		}
+
+		return rollbackPortPR(fme, pNum, prevAFU, opts.Rollback, err)
+	}
+
+	res := PRResult{}
+
+	if !opts.SkipReadback {
+		err = newBackoff(opts.Backoff).retry(ctx, func() (rerr error) {
+			res.AFU, rerr = f.ReadAcceleratorTypeUUIDFresh()
+
+			return rerr
+		})
+		if err != nil {
+			return res, errors.Wrap(err, "unable to read AFU UUID after PR")
+		}
+	}
+
+	if opts.VerifyLoadedCRC {
+		err = verifyLoadedCRC(f, rawBistream)
+
+		switch {
+		case errors.Is(err, ErrNotSupported):
+			// No readback on the board, the check is skipped.
+			err = nil
+		case err == nil:
+			res.CRCVerified = true
+		}
+	}
+
+	return res, err
+}
+
+// transientPRErrors are the errors of PR ioctl that may go away on retry.
+// E.g. the FME is busy programming another port or the driver is in the
+// middle of handling an error.
+var transientPRErrors = []error{syscall.EBUSY, syscall.EAGAIN}
+
+func isTransientPRError(err error) bool {
+	for _, transient := range transientPRErrors {
+		if errors.Is(err, transient) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// portPRWithRetry programs the port and retries up to opts.Retry.Steps times
+// with opts.Retry delays if the failure is transient.
+func portPRWithRetry(ctx context.Context, fme FME, port uint32, data []byte, opts PROptions) error {
+	b := newBackoff(opts.Retry)
+	err := fme.PortPRContext(ctx, port, data)
+
+	for attempt := 1; attempt <= b.Steps && isTransientPRError(err); attempt++ {
+		klog.V(2).Infof("%s: PR of port %d failed, retry %d of %d: %v", fme.GetName(), port, attempt, b.Steps, err)
+
+		if werr := b.wait(ctx); werr != nil {
+			return err
+		}
+
+		err = fme.PortPRContext(ctx, port, data)
+	}
+
+	return err
+}
+
+// rollbackPortPR reprograms the port with its previous AFU after failed PR.
+// The original PR error is always returned, annotated with the rollback outcome.
+// The PR often fails because ctx is done, which must not leave the port half
+// programmed, so the rollback runs with its own context limited by
+// rollbackTimeout.
+func rollbackPortPR(fme FME, port uint32, afu string, resolve RollbackResolver, prErr error) (PRResult, error) {
+	bs, err := resolve(fme.GetInterfaceUUID(), afu)
+	if err != nil {
+		return PRResult{}, errors.Wrapf(prErr, "no rollback to AFU %s: %v", afu, err)
+	}
+	defer bs.Close()
+
+	// The resolver must not be a way around the allowlist.
+	if err = checkPRAllowed(bs.InterfaceUUID()); err != nil {
+		return PRResult{}, errors.Wrapf(prErr, "no rollback to AFU %s: %v", afu, err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), rollbackTimeout)
+	defer cancel()
+
+	rawBitstream, err := bs.RawBitstreamData()
+	if err == nil {
+		err = fme.PortPRContext(withAuditAFU(ctx, bs.AcceleratorTypeUUID()), port, rawBitstream)
+	}
+
+	if err != nil {
+		return PRResult{}, errors.Wrapf(prErr, "rollback to AFU %s failed: %v", afu, err)
+	}
+
+	return PRResult{AFU: afu, RolledBack: true}, errors.Wrapf(prErr, "rolled back to AFU %s", afu)
+}
+
+// RollbackFromDir returns RollbackResolver that looks up previous bitstreams
+// in the bitstream storage directory, see bitstream.GetFPGABitstream.
+func RollbackFromDir(dir string) RollbackResolver {
+	return func(interfaceUUID, afuUUID string) (bitstream.File, error) {
+		return bitstream.GetFPGABitstream(dir, interfaceUUID, afuUUID)
+	}
+}
+
+func allRegions(f Port) ([]PortRegionInfo, error) {
+	info, err := f.PortGetInfo()
+	if err != nil {
+		return nil, err
+	}
+
+	regions := make([]PortRegionInfo, 0, info.Regions)
+
+	for i := uint32(0); i < info.Regions; i++ {
+		var region PortRegionInfo
+
+		if region, err = f.PortGetRegionInfo(i); err != nil {
+			return regions, errors.Wrapf(err, "%s: unable to get region %d of %d", f.GetName(), i, info.Regions)
+		}
+
+		regions = append(regions, region)
+	}
+
+	return regions, nil
+}
+
+// regionMap returns the regions of allRegions keyed by region index.
+func regionMap(f Port) (map[uint32]PortRegionInfo, error) {
+	regions, err := allRegions(f)
+	if regions == nil {
+		return nil, err
+	}
+
+	ret := make(map[uint32]PortRegionInfo, len(regions))
+	for _, region := range regions {
+		ret[region.Index] = region
+	}
+
+	return ret, err
+}
+
+// getPRRegionSize returns size of the partial reconfiguration region of
+// the port. The size is read from sysfs if the driver publishes it,
+// otherwise it's the size of the AFU MMIO region.
+func getPRRegionSize(f Port) (uint64, error) {
+	if sysfs := f.GetSysFsPath(); sysfs != "" {
+		data, err := readSysfsFile(filepath.Join(sysfs, prRegionSizeFile))
+		if err == nil {
+			size, perr := strconv.ParseUint(strings.TrimSpace(string(data)), 0, 64)
+
+			return size, errors.Wrapf(perr, "%s: unable to parse PR region size", f.GetName())
+		}
+	}
+
+	regions, err := allRegions(f)
+	if err != nil {
+		return 0, errors.Wrapf(ErrNotSupported, "%s: unable to derive PR region size: %v", f.GetName(), err)
+	}
+
+	for _, region := range regions {
+		if region.Index == FPGA_PORT_INDEX_UAFU && region.Size > 0 {
+			return region.Size, nil
+		}
+	}
+
+	return 0, errors.Wrapf(ErrNotSupported, "%s: no AFU region", f.GetName())
+}
+
+// getDMACapabilities returns host DMA constraints of the port. The port info
+// flags are reserved and zero in all the driver versions, and no driver
+// publishes the constraints in sysfs, so ErrNotSupported is returned for a
+// responsive port. The port info is queried to tell a port which doesn't
+// respond from a missing feature.
+func getDMACapabilities(f Port) (DMACapabilities, error) {
+	if _, err := f.PortGetInfo(); err != nil {
+		return DMACapabilities{}, errors.Wrapf(err, "%s: unable to get port info", f.GetName())
+	}
+
+	return DMACapabilities{}, errors.Wrapf(ErrNotSupported, "%s: driver doesn't report DMA capabilities", f.GetName())
+}
+
+// getNUMANode returns NUMA node of the FME. The node is taken from, in order
+// of precedence:
+//  1. numa_node of the FME's PCI device, unless it's -1, which the kernel
+//     reports when the BIOS doesn't provide PCI NUMA affinity;
+//  2. the socket the FPGA reports it's attached to, assuming one node
+//     per socket;
+//  3. -1 if neither is available.
+func getNUMANode(fme FME) int {
+	if pci, err := fme.GetPCIDevice(); err == nil {
+		if node, convErr := strconv.Atoi(pci.NUMA); convErr == nil && node >= 0 {
+			return node
+		}
+	}
+
+	if socket, err := fme.GetSocketID(); err == nil {
+		return int(socket)
+	}
+
+	return -1
+}
+
+// isVirtualFunction reports whether the device belongs to a PCI virtual function.
+func isVirtualFunction(dev commonFpgaAPI) (bool, error) {
+	pci, err := dev.GetPCIDevice()
+	if err != nil {
+		return false, err
+	}
+
+	return pci.PhysFn != nil, nil
+}
+
+// requirePhysicalFunction returns ErrRequiresPhysicalFunction if the device
+// is known to belong to a virtual function. If the PCI device can't be
+// resolved, the operation is let through and the driver reports the failure.
+func requirePhysicalFunction(dev commonFpgaAPI, op string) error {
+	if vf, _ := isVirtualFunction(dev); vf {
+		return errors.Wrapf(ErrRequiresPhysicalFunction, "%s: %s isn't available on virtual function", dev.GetName(), op)
+	}
+
+	return nil
+}
+
+// SetReadOnly switches the read-only mode of the whole process. In the mode
+// the operations changing the device state, i.e. partial reconfiguration,
+// port reset, release and assign, bringing the port online or offline,
+// clearing and injecting errors, driver rebinding and setting interrupt
+// affinity, fail with ErrReadOnly before touching the device. That guarantees
+// monitoring and inventory tools never disturb the workloads. Enumerating and
+// reading the devices work as usual, except for GetUserClockStatus, which
+// has to write the frequency counter command to measure the user clocks and
+// fails with ErrReadOnly too.
+func SetReadOnly(enable bool) {
+	var val int32
+	if enable {
+		val = 1
+	}
+
+	atomic.StoreInt32(&readOnly, val)
+}
+
+// requireWritable returns ErrReadOnly in the read-only mode.
+func requireWritable(name, op string) error {
+	if atomic.LoadInt32(&readOnly) != 0 {
+		return errors.Wrapf(ErrReadOnly, "%s: %s isn't allowed", name, op)
+	}
+
+	return nil
+}
+
+// Normalized device types returned by GetDeviceType.
+const (
+	DeviceTypeFME  = "fme"
+	DeviceTypePort = "port"
+)
+
+// dflIDTypes maps "type" attribute of DFL bus devices to device types.
+var dflIDTypes = map[uint64]string{
+	0: DeviceTypeFME,
+	1: DeviceTypePort,
+}
+
+// getDeviceType returns lowercase type of the device as reported by sysfs.
+// DFL bus devices publish numeric "type" attribute in hex, e.g. "0x1", for
+// platform devices of both drivers the type is the last component of the
+// modalias, e.g. "platform:dfl-fme" or "platform:intel-fpga-port". Known FME
+// and port types are returned as DeviceTypeFME and DeviceTypePort, others as
+// is.
+func getDeviceType(dev commonFpgaAPI) (string, error) {
+	var devType, modalias string
+
+	sysfs := dev.GetSysFsPath()
+	if sysfs == "" {
+		return "", errors.Errorf("%s: no sysfs entry", dev.GetName())
+	}
+
+	fileMap := map[string]*string{
+		"type":     &devType,
+		"modalias": &modalias,
+	}
+	if err := readFilesInDirectory(fileMap, sysfs); err != nil {
+		return "", err
+	}
+
+	if devType != "" {
+		if id, err := strconv.ParseUint(devType, 0, 8); err == nil {
+			if t, ok := dflIDTypes[id]; ok {
+				return t, nil
+			}
+		}
+
+		return strings.ToLower(devType), nil
+	}
+
+	if modalias == "" {
+		return "", errors.Wrapf(ErrNotSupported, "%s: device type isn't published", dev.GetName())
+	}
+
+	name := strings.ToLower(modalias[strings.LastIndexByte(modalias, ':')+1:])
+
+	switch name {
+	case strings.TrimSuffix(dflFpgaFmePrefix, "."), strings.TrimSuffix(intelFpgaFmePrefix, "."):
+		return DeviceTypeFME, nil
+	case strings.TrimSuffix(dflFpgaPortPrefix, "."), strings.TrimSuffix(intelFpgaPortPrefix, "."):
+		return DeviceTypePort, nil
+	}
+
+	return name, nil
+}
+
+// pingDevice measures how long the driver takes to report its API version.
+func pingDevice(dev commonFpgaAPI) (time.Duration, error) {
+	start := time.Now()
+	_, err := dev.GetAPIVersion()
+	latency := time.Since(start)
+
+	if err != nil {
+		return latency, errors.Wrapf(err, "%s: ping failed", dev.GetName())
+	}
+
+	return latency, nil
+}
+
+func getPCIIDs(dev commonFpgaAPI) (PCIIDs, error) {
+	pci, err := dev.GetPCIDevice()
+	if err != nil {
+		return PCIIDs{}, err
+	}
+
+	return pci.IDs()
+}
+
+func waitForBitstreamID(ctx context.Context, fme FME, want string, bo Backoff) error {
+	b := newBackoff(bo)
+
+	for {
+		if err := fme.RefreshProperties(); err != nil {
+			return err
+		}
+
+		got := fme.GetBitstreamID()
+		if strings.EqualFold(got, want) {
+			return nil
+		}
+
+		if err := b.wait(ctx); err != nil {
+			return errors.Wrapf(err, "%s: bitstream id is %q, expected %q", fme.GetName(), got, want)
+		}
+	}
+}
+
+func waitForAFU(ctx context.Context, f Port, want string, bo Backoff) error {
+	b := newBackoff(bo)
+
+	for {
+		got, err := f.ReadAcceleratorTypeUUIDFresh()
+		if err != nil {
+			return err
+		}
+
+		if strings.EqualFold(got, want) {
+			return nil
+		}
+
+		if err = b.wait(ctx); err != nil {
+			return errors.Wrapf(err, "%s: AFU is %q, expected %q", f.GetName(), got, want)
+		}
+	}
+}
+
+// genericPRAndVerify programs bs to the port and waits until the port
+// reports wantAFU or, if it's empty, the AFU of the bitstream.
+func genericPRAndVerify(ctx context.Context, f Port, bs bitstream.File, wantAFU string) error {
+	if wantAFU == "" {
+		wantAFU = bs.AcceleratorTypeUUID()
+	}
+
+	if _, err := f.PRContext(ctx, bs, PROptions{SkipReadback: true}); err != nil {
 		return err
 	}
 
-	if dryRun {
-		return nil
+	wctx, cancel := context.WithTimeout(ctx, afuSettleTimeout)
+	defer cancel()
+
+	err := f.WaitForAFU(wctx, wantAFU, Backoff{})
+	if err != nil && ctx.Err() == nil && wctx.Err() != nil {
+		return errors.Wrapf(ErrAFUMismatch, "%s: loaded AFU %s, expected %s", f.GetName(), f.GetAcceleratorTypeUUID(), wantAFU)
+	}
+
+	return err
+}
+
+// stableID builds device ID from the PCI address of the device, the
+// interface UUID of the FME and, for ports, the port id, e.g.
+// "0000-5e-00-0.69528db6eb31577a8c3668f9faa081f6.port0". It consists only
+// of characters safe for Kubernetes device IDs and file names.
+func stableID(dev commonFpgaAPI, interfaceUUID, suffix string) (string, error) {
+	pci, err := dev.GetPCIDevice()
+	if err != nil {
+		return "", errors.Wrapf(err, "%s: unable to get PCI address", dev.GetName())
+	}
+
+	if pci.BDF == "" {
+		return "", errors.Errorf("%s: unknown PCI address", dev.GetName())
+	}
+
+	parts := []string{strings.NewReplacer(":", "-", ".", "-").Replace(pci.BDF)}
+
+	if interfaceUUID != "" {
+		parts = append(parts, CanonizeID(interfaceUUID))
+	}
+
+	if suffix != "" {
+		parts = append(parts, suffix)
+	}
+
+	return strings.Join(parts, "."), nil
+}
+
+// getFMEStableID returns stable ID of the FME. See stableID for details.
+func getFMEStableID(fme FME) (string, error) {
+	return stableID(fme, fme.GetInterfaceUUID(), "")
+}
+
+// getPortStableID returns stable ID of the port. See stableID for details.
+func getPortStableID(port Port) (string, error) {
+	id, err := port.GetPortID()
+	if err != nil {
+		return "", errors.Wrapf(err, "%s: unable to get port id", port.GetName())
+	}
+
+	return stableID(port, port.GetInterfaceUUID(), fmt.Sprintf("port%d", id))
+}
+
+// ownsPort reports whether fme and port share the same PCI physical function.
+func ownsPort(fme FME, port Port) (bool, error) {
+	fmePCI, err := fme.GetPCIDevice()
+	if err != nil {
+		return false, errors.Wrapf(err, "unable to get PCI device of %s", fme.GetDevPath())
+	}
+
+	portPCI, err := port.GetPCIDevice()
+	if err != nil {
+		return false, errors.Wrapf(err, "unable to get PCI device of %s", port.GetDevPath())
+	}
+
+	return fmePCI.physicalFunction().BDF == portPCI.physicalFunction().BDF, nil
+}
+
+// portVF returns the VF the port is assigned to, nil if the port is assigned
+// to the physical function. portGlob matches the sysfs entries of the ports
+// of a PCI device. The port entry moves to the VF on release, so the VFs of
+// the board are searched for the port with the same ID.
+func portVF(port Port, portGlob string) (*PCIDevice, error) {
+	pci, err := port.GetPCIDevice()
+	if err != nil {
+		return nil, errors.Wrapf(err, "%s: unable to get PCI device", port.GetName())
+	}
+
+	if pci.PhysFn != nil {
+		return pci, nil
+	}
+
+	if totalVFs, _ := strconv.Atoi(pci.TotalVFs); totalVFs <= 0 {
+		return nil, errors.Wrapf(ErrNotSupported, "%s: board %s has no SR-IOV support", port.GetName(), pci.BDF)
+	}
+
+	id, err := port.GetPortID()
+	if err != nil {
+		return nil, errors.Wrapf(err, "%s: unable to get port id", port.GetName())
+	}
+
+	vfs, err := pci.GetVFs()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, vf := range vfs {
+		entries, _ := filepath.Glob(filepath.Join(vf.SysFsPath, portGlob))
+
+		for _, entry := range entries {
+			data, readErr := readSysfsFile(filepath.Join(entry, "id"))
+			if readErr == nil && strings.TrimSpace(string(data)) == strconv.FormatUint(uint64(id), 10) {
+				return vf, nil
+			}
+		}
+	}
+
+	return nil, nil
+}
+
+// getResetCount returns the port reset counter. If the driver publishes
+// the counter in sysfs, that value is returned and it accounts resets done
+// by any process, including automatic resets done by the driver itself.
+// Otherwise, the value of the in-process counter is returned, which only
+// tracks successful PortReset calls done through this Port object and
+// starts from zero on every process start.
+func getResetCount(sysfsPath string, local *uint64) (uint64, error) {
+	return readCounter(sysfsPath, portResetCountFile, local)
+}
+
+// refreshProperties re-reads the device properties with update and advances
+// their generation. The generation is advanced even if update fails, as some
+// of the properties may have been updated anyway, so that callers caching
+// state derived from the properties can tell it's stale by the generation
+// change alone.
+func refreshProperties(update func() error, generation *uint64) error {
+	err := update()
+
+	atomic.AddUint64(generation, 1)
+
+	return err
+}
+
+// getPRCount returns the number of partial reconfigurations done by the FME.
+// Like getResetCount, it prefers the driver maintained counter and falls back
+// to the in-process counter of successful PRs done through this FME object.
+func getPRCount(sysfsPath string, local *uint64) (uint64, error) {
+	return readCounter(sysfsPath, fmePRCountFile, local)
+}
+
+// readCounter reads the counter from sysfs attribute or, if it doesn't
+// exist, from the local counter.
+func readCounter(sysfsPath, attr string, local *uint64) (uint64, error) {
+	if sysfsPath != "" {
+		data, err := readSysfsFile(filepath.Join(sysfsPath, attr))
+
+		switch {
+		case err == nil:
+			count, perr := strconv.ParseUint(strings.TrimSpace(string(data)), 10, 64)
+
+			return count, errors.Wrapf(perr, "%s: unable to parse %s", sysfsPath, attr)
+		case !os.IsNotExist(err):
+			return 0, errors.Wrapf(err, "%s: unable to read %s", sysfsPath, attr)
+		}
 	}
 
-	return fme.PortPR(pNum, rawBistream)
+	return atomic.LoadUint64(local), nil
 }
@@ -0,0 +1,1545 @@
+// Copyright 2023 Intel Corporation. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fpga
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+	"unsafe"
+
+	"github.com/pkg/errors"
+
+	"github.com/intel/intel-device-plugins-for-kubernetes/pkg/fpga/bitstream"
+)
+
+const (
+	testBDF        = "0000:5e:00.0"
+	testIntelFME   = "fpga/intel-fpga-dev.0/intel-fpga-fme.0"
+	testBitstreamA = "0x123000200000185"
+	testBitstreamB = "0x123000200000186"
+	testInterface  = "69528db6eb31577a8c3668f9faa081f6"
+	testAFUOld     = "f7df405cbd7acf7222f144b0b93acd18"
+	testAFUNew     = "d8424dc4a4a3c413f89e433683f9040b"
+)
+
+// createTestFiles populates root with the given directories and files.
+func createTestFiles(root string, dirs []string, files map[string]string) error {
+	for _, dir := range dirs {
+		if err := os.MkdirAll(filepath.Join(root, dir), 0750); err != nil {
+			return errors.Wrap(err, "failed to create fake directory")
+		}
+	}
+
+	for name, body := range files {
+		fname := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(fname), 0750); err != nil {
+			return errors.Wrap(err, "failed to create fake directory")
+		}
+
+		if err := os.WriteFile(fname, []byte(body), 0600); err != nil {
+			return errors.Wrap(err, "failed to create fake file")
+		}
+	}
+
+	return nil
+}
+
+// newTestIntelFpgaFME creates fake sysfs tree for intel-fpga FME in tmpdir.
+func newTestIntelFpgaFME(t *testing.T, files map[string]string) *IntelFpgaFME {
+	t.Helper()
+
+	pciPath := filepath.Join(t.TempDir(), testBDF)
+	fmeFiles := make(map[string]string, len(files))
+
+	for k, v := range files {
+		fmeFiles[filepath.Join(testIntelFME, k)] = v
+	}
+
+	if err := createTestFiles(pciPath, []string{testIntelFME}, fmeFiles); err != nil {
+		t.Fatal(err)
+	}
+
+	return &IntelFpgaFME{
+		DevPath:   "/dev/intel-fpga-fme.0",
+		SysFsPath: filepath.Join(pciPath, testIntelFME),
+		PCIDevice: &PCIDevice{SysFsPath: pciPath, BDF: testBDF},
+	}
+}
+
+// testFME represents fake FPGA FME device for testing purposes.
+type testFME struct {
+	FME
+	portPR        func(uint32, []byte) error
+	apiErr        error
+	portRelease   func(uint32) error
+	portAssign    func(uint32) error
+	healthy       func() (bool, []string)
+	powerErr      error
+	name          string
+	model         string
+	interfaceUUID string
+	bitstreamID   string
+	power         PowerInfo
+}
+
+func (f *testFME) GetName() string {
+	return f.name
+}
+
+func (f *testFME) GetAPIVersion() (int, error) {
+	time.Sleep(time.Millisecond)
+
+	return 0, f.apiErr
+}
+
+func (f *testFME) GetModelName() string {
+	return f.model
+}
+
+func (f *testFME) GetInterfaceUUID() string {
+	return f.interfaceUUID
+}
+
+func (f *testFME) GetBitstreamID() string {
+	return f.bitstreamID
+}
+
+func (f *testFME) PortPRContext(ctx context.Context, port uint32, data []byte) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	return f.portPR(port, data)
+}
+
+func (f *testFME) PortRelease(port uint32) error {
+	return f.portRelease(port)
+}
+
+func (f *testFME) PortAssign(port uint32) error {
+	return f.portAssign(port)
+}
+
+func (f *testFME) GetPowerInfo() (PowerInfo, error) {
+	if f.powerErr != nil {
+		return PowerInfo{}, f.powerErr
+	}
+
+	return f.power, nil
+}
+
+func (f *testFME) Healthy() (bool, []string) {
+	return f.healthy()
+}
+
+func (f *testFME) Close() error {
+	return nil
+}
+
+// testPort represents fake FPGA port device for testing purposes.
+type testPort struct {
+	Port
+	infoErr   error
+	sysfs     string
+	regions   []PortRegionInfo
+	failAt    int
+	numRegion uint32
+}
+
+func (p *testPort) GetName() string {
+	return "intel-fpga-port.0"
+}
+
+func (p *testPort) GetDevPath() string {
+	return "/dev/intel-fpga-port.0"
+}
+
+func (p *testPort) GetSysFsPath() string {
+	return p.sysfs
+}
+
+func (p *testPort) PortGetInfo() (PortInfo, error) {
+	return PortInfo{Regions: p.numRegion}, p.infoErr
+}
+
+func (p *testPort) PortGetRegionInfo(index uint32) (PortRegionInfo, error) {
+	if int(index) == p.failAt || int(index) >= len(p.regions) {
+		return PortRegionInfo{}, errors.New("ioctl failed")
+	}
+
+	return p.regions[index], nil
+}
+
+// newTestGBS returns in-memory GBS bitstream with the given UUIDs.
+func newTestGBS(t *testing.T, interfaceUUID, afuUUID string) bitstream.File {
+	t.Helper()
+
+	return newTestGBSWithMetadata(t, fmt.Sprintf(`{"version": 1, "afu-image": {"interface-uuid": %q, "accelerator-clusters": [{"accelerator-type-uuid": %q}]}}`,
+		interfaceUUID, afuUUID))
+}
+
+// newTestGBSWithMetadata returns in-memory GBS bitstream with the given JSON metadata.
+func newTestGBSWithMetadata(t *testing.T, metadata string) *bitstream.FileGBS {
+	t.Helper()
+
+	var buf bytes.Buffer
+
+	_ = binary.Write(&buf, binary.LittleEndian, bitstream.Header{
+		GUID1:          0x414750466e6f6558,
+		GUID2:          0x31303076534247b7,
+		MetadataLength: uint32(len(metadata)),
+	})
+	buf.WriteString(metadata)
+	buf.WriteString("bitstream")
+
+	gbs, err := bitstream.NewFileGBS(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatalf("unable to create test bitstream: %+v", err)
+	}
+
+	return gbs
+}
+
+func TestWaitForBitstreamID(t *testing.T) {
+	tcases := []struct {
+		name        string
+		update      string
+		want        string
+		expectedErr bool
+	}{
+		{
+			name:   "already loaded",
+			want:   testBitstreamA,
+			update: testBitstreamA,
+		},
+		{
+			name:   "value changes",
+			want:   testBitstreamB,
+			update: testBitstreamB,
+		},
+		{
+			name:        "timeout",
+			want:        testBitstreamB,
+			update:      testBitstreamA,
+			expectedErr: true,
+		},
+	}
+	for _, tc := range tcases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			fme := newTestIntelFpgaFME(t, map[string]string{"bitstream_id": testBitstreamA})
+			done := make(chan struct{})
+
+			go func() {
+				defer close(done)
+
+				time.Sleep(30 * time.Millisecond)
+
+				_ = os.WriteFile(filepath.Join(fme.SysFsPath, "bitstream_id"), []byte(tc.update), 0600)
+			}()
+
+			// The writer must not outlive the subtest and its temporary directory.
+			defer func() { <-done }()
+
+			ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+			defer cancel()
+
+			err := fme.WaitForBitstreamID(ctx, tc.want, Backoff{Initial: 10 * time.Millisecond, Max: 20 * time.Millisecond})
+			if tc.expectedErr {
+				if err == nil {
+					t.Fatal("no error returned")
+				}
+
+				if !strings.Contains(err.Error(), tc.update) {
+					t.Errorf("last seen value %q isn't reported in error: %v", tc.update, err)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Errorf("unexpected error: %+v", err)
+			}
+		})
+	}
+}
+
+func TestGetResetCount(t *testing.T) {
+	tcases := []struct {
+		name          string
+		sysfsFiles    map[string]string
+		localCount    uint64
+		expectedCount uint64
+		expectedErr   bool
+	}{
+		{
+			name:          "driver provided counter",
+			sysfsFiles:    map[string]string{portResetCountFile: "7\n"},
+			localCount:    2,
+			expectedCount: 7,
+		},
+		{
+			name:        "broken driver counter",
+			sysfsFiles:  map[string]string{portResetCountFile: "garbage"},
+			expectedErr: true,
+		},
+		{
+			name:          "in-process counter",
+			localCount:    3,
+			expectedCount: 3,
+		},
+	}
+	for _, tc := range tcases {
+		t.Run(tc.name, func(t *testing.T) {
+			sysfs := t.TempDir()
+			if err := createTestFiles(sysfs, nil, tc.sysfsFiles); err != nil {
+				t.Fatal(err)
+			}
+
+			port := &IntelFpgaPort{
+				DevPath:    filepath.Join(sysfs, "intel-fpga-port.0"),
+				SysFsPath:  sysfs,
+				resetCount: tc.localCount,
+			}
+
+			// Device node doesn't exist, so the reset fails and must not be counted.
+			if err := port.PortReset(); err == nil {
+				t.Fatal("unexpected success of port reset")
+			}
+
+			count, err := port.GetResetCount()
+			if tc.expectedErr {
+				if err == nil {
+					t.Error("no error returned")
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Errorf("unexpected error: %+v", err)
+			}
+
+			if count != tc.expectedCount {
+				t.Errorf("expected %d resets, but got %d", tc.expectedCount, count)
+			}
+		})
+	}
+}
+
+func TestGetPRCount(t *testing.T) {
+	tcases := []struct {
+		name          string
+		sysfsFiles    map[string]string
+		expectedCount uint64
+		expectedErr   bool
+	}{
+		{
+			name:          "driver provided counter",
+			sysfsFiles:    map[string]string{fmePRCountFile: "1042\n"},
+			expectedCount: 1042,
+		},
+		{
+			name:        "broken driver counter",
+			sysfsFiles:  map[string]string{fmePRCountFile: "garbage"},
+			expectedErr: true,
+		},
+		{
+			name:          "in-process counter",
+			expectedCount: 2,
+		},
+	}
+	for _, tc := range tcases {
+		t.Run(tc.name, func(t *testing.T) {
+			var prErr error
+
+			setTestIoctl(t, func(dev string, req uint, arg unsafe.Pointer) (uintptr, error) {
+				return 0, prErr
+			})
+
+			fme := newTestIntelFpgaFME(t, tc.sysfsFiles)
+
+			// Only the successful PRs are counted.
+			for _, prErr = range []error{nil, syscall.EBUSY, nil} {
+				_ = fme.PortPR(0, []byte("bitstream"))
+			}
+
+			count, err := fme.GetPRCount()
+			if tc.expectedErr {
+				if err == nil {
+					t.Error("no error returned")
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Errorf("unexpected error: %+v", err)
+			}
+
+			if count != tc.expectedCount {
+				t.Errorf("expected %d PRs, but got %d", tc.expectedCount, count)
+			}
+		})
+	}
+}
+
+// newTestIntelFpgaPort creates fake sysfs tree for intel-fpga port with the given AFU
+// and attaches it to the given FME.
+func newTestIntelFpgaPort(t *testing.T, fme FME, afu string) *IntelFpgaPort {
+	t.Helper()
+
+	sysfs := t.TempDir()
+	if err := createTestFiles(sysfs, nil, map[string]string{"id": "0", "afu_id": afu}); err != nil {
+		t.Fatal(err)
+	}
+
+	return &IntelFpgaPort{
+		FME:       fme,
+		DevPath:   "/dev/intel-fpga-port.0",
+		SysFsPath: sysfs,
+	}
+}
+
+func TestPRContext(t *testing.T) {
+	tcases := []struct {
+		name        string
+		opts        PROptions
+		bsInterface string
+		prErr       error
+		expectedAFU string
+		cancelled   bool
+		expectedPR  bool
+		expectedErr bool
+	}{
+		{
+			name:        "successful PR with readback",
+			bsInterface: testInterface,
+			expectedPR:  true,
+			expectedAFU: testAFUNew,
+		},
+		{
+			name:        "successful PR without readback",
+			bsInterface: testInterface,
+			opts:        PROptions{SkipReadback: true},
+			expectedPR:  true,
+		},
+		{
+			name:        "dry run",
+			bsInterface: testInterface,
+			opts:        PROptions{DryRun: true},
+		},
+		{
+			name:        "incompatible bitstream",
+			bsInterface: "ce48969398f05f33946d560708be108a",
+			expectedErr: true,
+		},
+		{
+			name:        "failed PR",
+			bsInterface: testInterface,
+			prErr:       errors.New("PR failed"),
+			expectedPR:  true,
+			expectedErr: true,
+		},
+		{
+			name:        "cancelled context",
+			bsInterface: testInterface,
+			cancelled:   true,
+			expectedErr: true,
+		},
+	}
+	for _, tc := range tcases {
+		t.Run(tc.name, func(t *testing.T) {
+			programmed := false
+			fme := &testFME{interfaceUUID: testInterface}
+			port := newTestIntelFpgaPort(t, fme, testAFUOld)
+			fme.portPR = func(id uint32, data []byte) error {
+				programmed = true
+				if tc.prErr != nil {
+					return tc.prErr
+				}
+
+				return os.WriteFile(filepath.Join(port.SysFsPath, "afu_id"), []byte(testAFUNew), 0600)
+			}
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			if tc.cancelled {
+				cancel()
+			}
+
+			res, err := port.PRContext(ctx, newTestGBS(t, tc.bsInterface, testAFUNew), tc.opts)
+			if tc.expectedErr && err == nil {
+				t.Error("no error returned")
+			}
+
+			if !tc.expectedErr && err != nil {
+				t.Errorf("unexpected error: %+v", err)
+			}
+
+			if programmed != tc.expectedPR {
+				t.Errorf("expected PR to be called: %t, called: %t", tc.expectedPR, programmed)
+			}
+
+			if res.AFU != tc.expectedAFU {
+				t.Errorf("expected AFU %q, but got %q", tc.expectedAFU, res.AFU)
+			}
+		})
+	}
+}
+
+func TestPRRejectsFullChipImage(t *testing.T) {
+	fme := &testFME{interfaceUUID: testInterface}
+	fme.portPR = func(uint32, []byte) error {
+		t.Error("full chip image programmed")
+
+		return nil
+	}
+
+	port := newTestIntelFpgaPort(t, fme, testAFUOld)
+
+	bs := newTestGBSWithMetadata(t, fmt.Sprintf(`{"version": 1, "afu-image": {"image-type": "full", "accelerator-clusters": [{"accelerator-type-uuid": %q}]}}`, testAFUNew))
+
+	_, err := port.PRContext(context.Background(), bs, PROptions{})
+	if !errors.Is(err, ErrFullChipImage) || !strings.Contains(err.Error(), "flash") {
+		t.Errorf("expected ErrFullChipImage suggesting flash update, but got %v", err)
+	}
+}
+
+func TestPRAndVerify(t *testing.T) {
+	afuSettleTimeout = 500 * time.Millisecond
+
+	tcases := []struct {
+		name        string
+		wantAFU     string
+		loadedAFU   string
+		delay       time.Duration
+		expectedErr error
+	}{
+		{
+			name:      "bitstream AFU",
+			loadedAFU: testAFUNew,
+		},
+		{
+			name:      "explicit AFU",
+			wantAFU:   testAFUNew,
+			loadedAFU: testAFUNew,
+		},
+		{
+			name:      "delayed update",
+			loadedAFU: testAFUNew,
+			delay:     50 * time.Millisecond,
+		},
+		{
+			name:        "mismatch",
+			loadedAFU:   testAFUOld,
+			expectedErr: ErrAFUMismatch,
+		},
+		{
+			name:        "unexpected AFU wanted",
+			wantAFU:     "ce48969398f05f33946d560708be108a",
+			loadedAFU:   testAFUNew,
+			expectedErr: ErrAFUMismatch,
+		},
+	}
+	for _, tc := range tcases {
+		t.Run(tc.name, func(t *testing.T) {
+			fme := &testFME{interfaceUUID: testInterface}
+			port := newTestIntelFpgaPort(t, fme, testAFUOld)
+			fme.portPR = func(uint32, []byte) error {
+				go func() {
+					time.Sleep(tc.delay)
+
+					_ = os.WriteFile(filepath.Join(port.SysFsPath, "afu_id"), []byte(tc.loadedAFU), 0600)
+				}()
+
+				return nil
+			}
+
+			err := port.PRAndVerify(context.Background(), newTestGBS(t, testInterface, testAFUNew), tc.wantAFU)
+			if tc.expectedErr != nil {
+				if !errors.Is(err, tc.expectedErr) {
+					t.Errorf("expected error %v, but got %+v", tc.expectedErr, err)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Errorf("unexpected error: %+v", err)
+			}
+		})
+	}
+}
+
+func TestPRRollback(t *testing.T) {
+	tcases := []struct {
+		name             string
+		resolveErr       error
+		failedCalls      int
+		noRollback       bool
+		expectedCalls    int
+		expectedResolved string
+		expectedAFU      string
+		expectedRollback bool
+	}{
+		{
+			name:             "successful rollback",
+			failedCalls:      1,
+			expectedCalls:    2,
+			expectedResolved: testAFUOld,
+			expectedAFU:      testAFUOld,
+			expectedRollback: true,
+		},
+		{
+			name:          "rollback disabled",
+			failedCalls:   1,
+			noRollback:    true,
+			expectedCalls: 1,
+		},
+		{
+			name:             "previous bitstream not available",
+			failedCalls:      1,
+			resolveErr:       errors.New("bitstream not found"),
+			expectedCalls:    1,
+			expectedResolved: testAFUOld,
+		},
+		{
+			name:             "rollback fails too",
+			failedCalls:      2,
+			expectedCalls:    2,
+			expectedResolved: testAFUOld,
+		},
+	}
+	for _, tc := range tcases {
+		t.Run(tc.name, func(t *testing.T) {
+			calls := 0
+			fme := &testFME{interfaceUUID: testInterface}
+			fme.portPR = func(id uint32, data []byte) error {
+				calls++
+				if calls <= tc.failedCalls {
+					return errors.New("PR failed")
+				}
+
+				return nil
+			}
+			port := newTestIntelFpgaPort(t, fme, testAFUOld)
+
+			var resolved string
+
+			opts := PROptions{}
+			if !tc.noRollback {
+				opts.Rollback = func(interfaceUUID, afuUUID string) (bitstream.File, error) {
+					resolved = afuUUID
+					if tc.resolveErr != nil {
+						return nil, tc.resolveErr
+					}
+
+					return newTestGBS(t, interfaceUUID, afuUUID), nil
+				}
+			}
+
+			res, err := port.PRWithOptions(newTestGBS(t, testInterface, testAFUNew), opts)
+			if err == nil {
+				t.Error("PR failure must be reported even after rollback")
+			}
+
+			if calls != tc.expectedCalls {
+				t.Errorf("expected %d PR calls, but got %d", tc.expectedCalls, calls)
+			}
+
+			if resolved != tc.expectedResolved {
+				t.Errorf("expected rollback to %q, but got %q", tc.expectedResolved, resolved)
+			}
+
+			if res.RolledBack != tc.expectedRollback || res.AFU != tc.expectedAFU {
+				t.Errorf("unexpected result %+v", res)
+			}
+		})
+	}
+}
+
+func TestPRRollbackExpiredContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	calls := 0
+	fme := &testFME{interfaceUUID: testInterface}
+	fme.portPR = func(id uint32, data []byte) error {
+		calls++
+		if calls == 1 {
+			// The deadline expires while the PR fails.
+			cancel()
+
+			return errors.New("PR failed")
+		}
+
+		return nil
+	}
+	port := newTestIntelFpgaPort(t, fme, testAFUOld)
+
+	opts := PROptions{
+		Rollback: func(interfaceUUID, afuUUID string) (bitstream.File, error) {
+			return newTestGBS(t, interfaceUUID, afuUUID), nil
+		},
+	}
+
+	res, err := port.PRContext(ctx, newTestGBS(t, testInterface, testAFUNew), opts)
+	if err == nil {
+		t.Error("PR failure must be reported even after rollback")
+	}
+
+	if calls != 2 || !res.RolledBack || res.AFU != testAFUOld {
+		t.Errorf("expected rollback to %s after the context expired, but got %+v after %d PR calls", testAFUOld, res, calls)
+	}
+}
+
+func TestAllRegions(t *testing.T) {
+	regions := []PortRegionInfo{
+		{Index: 0, Flags: 3, Size: 0x40000, Offset: 0},
+		{Index: 1, Flags: 3, Size: 0x1000, Offset: 0x40000},
+		{Index: 2, Flags: 1, Size: 0x1000, Offset: 0x41000},
+	}
+
+	tcases := []struct {
+		name        string
+		port        *testPort
+		expected    []PortRegionInfo
+		expectedErr bool
+	}{
+		{
+			name:     "all regions",
+			port:     &testPort{regions: regions, numRegion: 3, failAt: -1},
+			expected: regions,
+		},
+		{
+			name:     "no regions",
+			port:     &testPort{numRegion: 0, failAt: -1},
+			expected: []PortRegionInfo{},
+		},
+		{
+			name:        "region fails",
+			port:        &testPort{regions: regions, numRegion: 3, failAt: 2},
+			expected:    regions[:2],
+			expectedErr: true,
+		},
+		{
+			name:        "port info fails",
+			port:        &testPort{infoErr: errors.New("ioctl failed")},
+			expectedErr: true,
+		},
+	}
+	for _, tc := range tcases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := allRegions(tc.port)
+			if tc.expectedErr && err == nil {
+				t.Error("no error returned")
+			}
+
+			if !tc.expectedErr && err != nil {
+				t.Errorf("unexpected error: %+v", err)
+			}
+
+			if !reflect.DeepEqual(got, tc.expected) {
+				t.Errorf("expected %+v, but got %+v", tc.expected, got)
+			}
+		})
+	}
+}
+
+func TestRegionMap(t *testing.T) {
+	regions := []PortRegionInfo{
+		{Index: FPGA_PORT_INDEX_UAFU, Flags: FPGA_REGION_READ | FPGA_REGION_WRITE | FPGA_REGION_MMAP, Size: 0x40000, Offset: 0},
+		{Index: FPGA_PORT_INDEX_STP, Flags: FPGA_REGION_READ | FPGA_REGION_MMAP, Size: 0x1000, Offset: 0x40000},
+		{Index: 2, Flags: FPGA_REGION_WRITE, Size: 0x1000, Offset: 0x41000},
+	}
+
+	got, err := regionMap(&testPort{regions: regions, numRegion: 3, failAt: -1})
+	if err != nil {
+		t.Fatalf("unexpected error: %+v", err)
+	}
+
+	expected := map[uint32]PortRegionInfo{
+		FPGA_PORT_INDEX_UAFU: regions[0],
+		FPGA_PORT_INDEX_STP:  regions[1],
+		2:                    regions[2],
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected %+v, but got %+v", expected, got)
+	}
+
+	flags := map[uint32][3]bool{
+		FPGA_PORT_INDEX_UAFU: {true, true, true},
+		FPGA_PORT_INDEX_STP:  {true, false, true},
+		2:                    {false, true, false},
+	}
+	for index, expectedFlags := range flags {
+		region := got[index]
+		if decoded := [3]bool{region.Readable(), region.Writable(), region.Mmappable()}; decoded != expectedFlags {
+			t.Errorf("region %d: expected readable, writable, mmappable %v, but got %v", index, expectedFlags, decoded)
+		}
+	}
+
+	got, err = regionMap(&testPort{regions: regions, numRegion: 3, failAt: 1})
+	if err == nil {
+		t.Error("no error returned")
+	}
+
+	if !reflect.DeepEqual(got, map[uint32]PortRegionInfo{FPGA_PORT_INDEX_UAFU: regions[0]}) {
+		t.Errorf("expected the region before the failure, but got %+v", got)
+	}
+
+	if got, err = regionMap(&testPort{infoErr: errors.New("ioctl failed")}); err == nil || got != nil {
+		t.Errorf("expected no regions and error, but got %+v, %v", got, err)
+	}
+}
+
+func TestGetPRRegionSize(t *testing.T) {
+	regions := []PortRegionInfo{
+		{Index: FPGA_PORT_INDEX_UAFU, Flags: 3, Size: 0x40000, Offset: 0},
+		{Index: FPGA_PORT_INDEX_STP, Flags: 3, Size: 0x1000, Offset: 0x40000},
+	}
+
+	sysfs := t.TempDir()
+	if err := createTestFiles(sysfs, nil, map[string]string{prRegionSizeFile: "0x2000000\n"}); err != nil {
+		t.Fatal(err)
+	}
+
+	tcases := []struct {
+		name        string
+		port        *testPort
+		expectedErr error
+		expected    uint64
+	}{
+		{
+			name:     "sysfs attribute",
+			port:     &testPort{sysfs: sysfs, infoErr: errors.New("must not be called")},
+			expected: 0x2000000,
+		},
+		{
+			name:     "derived from region info",
+			port:     &testPort{sysfs: t.TempDir(), regions: regions, numRegion: 2, failAt: -1},
+			expected: 0x40000,
+		},
+		{
+			name:        "no AFU region",
+			port:        &testPort{regions: regions[1:], numRegion: 1, failAt: -1},
+			expectedErr: ErrNotSupported,
+		},
+		{
+			name:        "region info fails",
+			port:        &testPort{infoErr: errors.New("ioctl failed")},
+			expectedErr: ErrNotSupported,
+		},
+	}
+	for _, tc := range tcases {
+		t.Run(tc.name, func(t *testing.T) {
+			size, err := getPRRegionSize(tc.port)
+			if tc.expectedErr != nil {
+				if !errors.Is(err, tc.expectedErr) {
+					t.Errorf("expected error %v, but got %v", tc.expectedErr, err)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("unexpected error: %+v", err)
+			}
+
+			if size != tc.expected {
+				t.Errorf("expected size %#x, but got %#x", tc.expected, size)
+			}
+		})
+	}
+}
+
+func TestGetDMACapabilities(t *testing.T) {
+	tcases := []struct {
+		port        *testPort
+		expectedErr error
+		name        string
+	}{
+		{
+			name:        "not reported",
+			port:        &testPort{sysfs: t.TempDir(), numRegion: 2},
+			expectedErr: ErrNotSupported,
+		},
+		{
+			name: "port info fails",
+			port: &testPort{infoErr: errors.New("ioctl failed")},
+		},
+	}
+	for _, tc := range tcases {
+		t.Run(tc.name, func(t *testing.T) {
+			caps, err := getDMACapabilities(tc.port)
+
+			switch {
+			case tc.expectedErr != nil:
+				if !errors.Is(err, tc.expectedErr) {
+					t.Errorf("expected error %v, but got %v", tc.expectedErr, err)
+				}
+			case err == nil || errors.Is(err, ErrNotSupported):
+				t.Errorf("expected port info error, but got %v", err)
+			}
+
+			if caps != (DMACapabilities{}) {
+				t.Errorf("expected no capabilities, but got %+v", caps)
+			}
+		})
+	}
+}
+
+func TestGetNUMANode(t *testing.T) {
+	tcases := []struct {
+		name     string
+		numa     string
+		socketID string
+		expected int
+	}{
+		{
+			name:     "PCI NUMA node",
+			numa:     "1",
+			socketID: "0",
+			expected: 1,
+		},
+		{
+			name:     "socket fallback",
+			numa:     "-1",
+			socketID: "1",
+			expected: 1,
+		},
+		{
+			name:     "no NUMA node reported",
+			socketID: "0",
+			expected: 0,
+		},
+		{
+			name:     "unknown",
+			numa:     "-1",
+			expected: -1,
+		},
+	}
+	for _, tc := range tcases {
+		t.Run(tc.name, func(t *testing.T) {
+			fme := &IntelFpgaFME{
+				DevPath:   "/dev/intel-fpga-fme.0",
+				SocketID:  tc.socketID,
+				PCIDevice: &PCIDevice{BDF: testBDF, NUMA: tc.numa},
+			}
+
+			if node := fme.GetNUMANode(); node != tc.expected {
+				t.Errorf("expected NUMA node %d, but got %d", tc.expected, node)
+			}
+		})
+	}
+}
+
+func TestPingDevice(t *testing.T) {
+	latency, err := pingDevice(&testFME{})
+	if err != nil {
+		t.Fatalf("unexpected error: %+v", err)
+	}
+
+	if latency < time.Millisecond {
+		t.Errorf("expected latency of at least 1ms, but got %v", latency)
+	}
+
+	if _, err = pingDevice(&testFME{apiErr: errors.New("ioctl failed")}); err == nil {
+		t.Error("no error returned")
+	}
+
+	// The real device node can't be opened in the test environment.
+	if _, err = (&IntelFpgaPort{DevPath: filepath.Join(t.TempDir(), "intel-fpga-port.0")}).PingDevice(); err == nil {
+		t.Error("no error returned for missing device")
+	}
+}
+
+func TestGetName(t *testing.T) {
+	sysfs := filepath.Join(t.TempDir(), "dev-from-sysfs.0")
+	missing := filepath.Join(t.TempDir(), "missing")
+
+	tcases := []struct {
+		name     string
+		dev      commonFpgaAPI
+		expected string
+	}{
+		{
+			name:     "DFL FME with sysfs",
+			dev:      &DflFME{DevPath: "/dev/dfl-fme.0", SysFsPath: sysfs},
+			expected: "dev-from-sysfs.0",
+		},
+		{
+			name:     "DFL FME without sysfs",
+			dev:      &DflFME{DevPath: filepath.Join(missing, "dfl-fme.1")},
+			expected: "dfl-fme.1",
+		},
+		{
+			name:     "DFL port with sysfs",
+			dev:      &DflPort{DevPath: "/dev/dfl-port.0", SysFsPath: sysfs},
+			expected: "dev-from-sysfs.0",
+		},
+		{
+			name:     "DFL port without sysfs",
+			dev:      &DflPort{DevPath: filepath.Join(missing, "dfl-port.2")},
+			expected: "dfl-port.2",
+		},
+		{
+			name:     "intel-fpga FME with sysfs",
+			dev:      &IntelFpgaFME{DevPath: "/dev/intel-fpga-fme.0", SysFsPath: sysfs},
+			expected: "dev-from-sysfs.0",
+		},
+		{
+			name:     "intel-fpga FME without sysfs",
+			dev:      &IntelFpgaFME{DevPath: filepath.Join(missing, "intel-fpga-fme.3")},
+			expected: "intel-fpga-fme.3",
+		},
+		{
+			name:     "intel-fpga port with sysfs",
+			dev:      &IntelFpgaPort{DevPath: "/dev/intel-fpga-port.0", SysFsPath: sysfs},
+			expected: "dev-from-sysfs.0",
+		},
+		{
+			name:     "intel-fpga port without sysfs",
+			dev:      &IntelFpgaPort{DevPath: filepath.Join(missing, "intel-fpga-port.4")},
+			expected: "intel-fpga-port.4",
+		},
+		{
+			name: "nothing known",
+			dev:  &IntelFpgaPort{},
+		},
+	}
+	for _, tc := range tcases {
+		t.Run(tc.name, func(t *testing.T) {
+			if name := tc.dev.GetName(); name != tc.expected {
+				t.Errorf("expected name %q, but got %q", tc.expected, name)
+			}
+		})
+	}
+}
+
+func TestGetDriverType(t *testing.T) {
+	for name, expected := range map[string]DriverType{
+		"/dev/intel-fpga-port.0": DriverIntelFpga,
+		"intel-fpga-fme.1":       DriverIntelFpga,
+		"/dev/dfl-port.2":        DriverDFL,
+		"dfl-fme.0":              DriverDFL,
+		"/dev/null":              DriverUnknown,
+	} {
+		if got := GetDriverType(name); got != expected {
+			t.Errorf("%s: expected %s, but got %s", name, expected, got)
+		}
+	}
+}
+
+func TestGetDeviceType(t *testing.T) {
+	tcases := []struct {
+		name        string
+		files       map[string]string
+		dev         func(sysfs string) commonFpgaAPI
+		expected    string
+		expectedErr bool
+	}{
+		{
+			name:     "intel-fpga FME",
+			files:    map[string]string{"modalias": "platform:intel-fpga-fme\n"},
+			dev:      func(sysfs string) commonFpgaAPI { return &IntelFpgaFME{SysFsPath: sysfs} },
+			expected: DeviceTypeFME,
+		},
+		{
+			name:     "intel-fpga port",
+			files:    map[string]string{"modalias": "platform:intel-fpga-port\n"},
+			dev:      func(sysfs string) commonFpgaAPI { return &IntelFpgaPort{SysFsPath: sysfs} },
+			expected: DeviceTypePort,
+		},
+		{
+			name:     "DFL FME",
+			files:    map[string]string{"modalias": "platform:dfl-fme\n"},
+			dev:      func(sysfs string) commonFpgaAPI { return &DflFME{SysFsPath: sysfs} },
+			expected: DeviceTypeFME,
+		},
+		{
+			name:     "DFL bus FME",
+			files:    map[string]string{"type": "0x0\n", "modalias": "dfl:t0000f0000\n"},
+			dev:      func(sysfs string) commonFpgaAPI { return &DflFME{SysFsPath: sysfs} },
+			expected: DeviceTypeFME,
+		},
+		{
+			name:     "DFL bus port",
+			files:    map[string]string{"type": "0x1\n", "modalias": "dfl:t0001f0000\n"},
+			dev:      func(sysfs string) commonFpgaAPI { return &DflPort{SysFsPath: sysfs} },
+			expected: DeviceTypePort,
+		},
+		{
+			name:     "unknown DFL bus type",
+			files:    map[string]string{"type": "0x2\n", "modalias": "dfl:t0002f0000\n"},
+			dev:      func(sysfs string) commonFpgaAPI { return &DflPort{SysFsPath: sysfs} },
+			expected: "0x2",
+		},
+		{
+			name:     "other feature container",
+			files:    map[string]string{"modalias": "platform:DFL-EMIF\n"},
+			dev:      func(sysfs string) commonFpgaAPI { return &DflPort{SysFsPath: sysfs} },
+			expected: "dfl-emif",
+		},
+		{
+			name:        "no type published",
+			dev:         func(sysfs string) commonFpgaAPI { return &DflPort{SysFsPath: sysfs} },
+			expectedErr: true,
+		},
+	}
+	for _, tc := range tcases {
+		t.Run(tc.name, func(t *testing.T) {
+			sysfs := t.TempDir()
+			if err := createTestFiles(sysfs, nil, tc.files); err != nil {
+				t.Fatal(err)
+			}
+
+			devType, err := tc.dev(sysfs).GetDeviceType()
+			if tc.expectedErr {
+				if err == nil {
+					t.Errorf("expected error, but got type %q", devType)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("unexpected error: %+v", err)
+			}
+
+			if devType != tc.expected {
+				t.Errorf("expected %q, but got %q", tc.expected, devType)
+			}
+		})
+	}
+}
+
+func TestGetStableID(t *testing.T) {
+	fme := newTestIntelFpgaFME(t, map[string]string{"pr/interface_id": strings.ToUpper(testInterface) + "\n"})
+	port := newTestIntelFpgaPort(t, fme, testAFUOld)
+	port.PCIDevice = fme.PCIDevice
+
+	for expected, dev := range map[string]commonFpgaAPI{
+		"0000-5e-00-0." + testInterface:            fme,
+		"0000-5e-00-0." + testInterface + ".port0": port,
+	} {
+		id, err := dev.GetStableID()
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %+v", dev.GetDevPath(), err)
+		}
+
+		if id != expected {
+			t.Errorf("%s: expected %q, but got %q", dev.GetDevPath(), expected, id)
+		}
+
+		if again, _ := dev.GetStableID(); again != id {
+			t.Errorf("%s: ID isn't deterministic: %q and %q", dev.GetDevPath(), id, again)
+		}
+	}
+
+	// Another handle of the same device gets the same ID.
+	reopened := &IntelFpgaPort{FME: fme, SysFsPath: port.SysFsPath, PCIDevice: &PCIDevice{BDF: testBDF}}
+	if id, _ := reopened.GetStableID(); id != "0000-5e-00-0."+testInterface+".port0" {
+		t.Errorf("unexpected ID of reopened port %q", id)
+	}
+
+	if _, err := (&IntelFpgaFME{PCIDevice: &PCIDevice{}}).GetStableID(); err == nil {
+		t.Error("expected error for unknown PCI address")
+	}
+}
+
+func TestReadAFUIDLayouts(t *testing.T) {
+	tcases := []struct {
+		name  string
+		files map[string]string
+		port  func(sysfs string) Port
+	}{
+		{
+			name:  "intel-fpga",
+			files: map[string]string{"id": "0", "afu_id": strings.ToUpper(testAFUOld) + "\n"},
+			port:  func(sysfs string) Port { return &IntelFpgaPort{DevPath: "/dev/intel-fpga-port.0", SysFsPath: sysfs} },
+		},
+		{
+			name:  "dfl",
+			files: map[string]string{"id": "0", "afu_id": testAFUOld + "\n"},
+			port:  func(sysfs string) Port { return &DflPort{DevPath: "/dev/dfl-port.0", SysFsPath: sysfs} },
+		},
+		{
+			name:  "dfl with AFU device below port",
+			files: map[string]string{"id": "0", "dfl_dev.1/afu_id": "f7df405c-bd7a-cf72-22f1-44b0b93acd18\n"},
+			port:  func(sysfs string) Port { return &DflPort{DevPath: "/dev/dfl-port.0", SysFsPath: sysfs} },
+		},
+	}
+	for _, tc := range tcases {
+		t.Run(tc.name, func(t *testing.T) {
+			sysfs := t.TempDir()
+			if err := createTestFiles(sysfs, nil, tc.files); err != nil {
+				t.Fatal(err)
+			}
+
+			afu, err := tc.port(sysfs).ReadAcceleratorTypeUUIDFresh()
+			if err != nil {
+				t.Fatalf("unexpected error: %+v", err)
+			}
+
+			if afu != testAFUOld {
+				t.Errorf("expected %q, but got %q", testAFUOld, afu)
+			}
+		})
+	}
+}
+
+func TestAcceleratorTypeUUIDCache(t *testing.T) {
+	fme := &testFME{interfaceUUID: testInterface}
+	port := newTestIntelFpgaPort(t, fme, testAFUOld)
+	afuFile := filepath.Join(port.SysFsPath, "afu_id")
+	fme.portPR = func(id uint32, data []byte) error {
+		return os.WriteFile(afuFile, []byte(testAFUOld), 0600)
+	}
+
+	if afu := port.GetAcceleratorTypeUUID(); afu != testAFUOld {
+		t.Fatalf("expected AFU %q, but got %q", testAFUOld, afu)
+	}
+
+	if err := os.WriteFile(afuFile, []byte(testAFUNew), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	if afu := port.GetAcceleratorTypeUUID(); afu != testAFUOld {
+		t.Errorf("expected cached AFU %q, but got %q", testAFUOld, afu)
+	}
+
+	afu, err := port.ReadAcceleratorTypeUUIDFresh()
+	if err != nil {
+		t.Fatalf("unexpected error: %+v", err)
+	}
+
+	if afu != testAFUNew {
+		t.Errorf("expected fresh AFU %q, but got %q", testAFUNew, afu)
+	}
+
+	if afu := port.GetAcceleratorTypeUUID(); afu != testAFUNew {
+		t.Errorf("expected cache to be updated to %q, but got %q", testAFUNew, afu)
+	}
+
+	// PR without readback must invalidate the cache.
+	if err := port.PR(newTestGBS(t, testInterface, testAFUOld), false); err != nil {
+		t.Fatalf("unexpected error: %+v", err)
+	}
+
+	if afu := port.GetAcceleratorTypeUUID(); afu != testAFUOld {
+		t.Errorf("expected AFU %q after PR, but got %q", testAFUOld, afu)
+	}
+}
+
+func BenchmarkGetAcceleratorTypeUUID(b *testing.B) {
+	sysfs := b.TempDir()
+	if err := createTestFiles(sysfs, nil, map[string]string{"id": "0", "afu_id": testAFUOld, "dev": "240:1"}); err != nil {
+		b.Fatal(err)
+	}
+
+	port := &IntelFpgaPort{DevPath: "/dev/intel-fpga-port.0", SysFsPath: sysfs}
+
+	b.Run("cached", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			if port.GetAcceleratorTypeUUID() != testAFUOld {
+				b.Fatal("unexpected AFU")
+			}
+		}
+	})
+
+	b.Run("fresh", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			if afu, err := port.ReadAcceleratorTypeUUIDFresh(); err != nil || afu != testAFUOld {
+				b.Fatal("unexpected AFU")
+			}
+		}
+	})
+}
+
+func TestNewByPCIAddress(t *testing.T) {
+	const (
+		fpgaBDF  = "0000:3b:00.0"
+		otherBDF = "0000:3c:00.0"
+	)
+
+	sysfs := setTestSysfsRoot(t)
+	devfs := setTestDevfsRoot(t)
+
+	fpgaPath := filepath.Join("devices/pci0000:3a", fpgaBDF)
+	otherPath := filepath.Join("devices/pci0000:3a", otherBDF)
+	files := map[string]string{
+		filepath.Join(fpgaPath, "vendor"):                                         "0x8086\n",
+		filepath.Join(fpgaPath, "device"):                                         "0x09c4\n",
+		filepath.Join(fpgaPath, "class"):                                          "0x120000\n",
+		filepath.Join(fpgaPath, testIntelFME, "dev"):                              "240:0\n",
+		filepath.Join(fpgaPath, testIntelFME, "bitstream_id"):                     testBitstreamA + "\n",
+		filepath.Join(fpgaPath, testIntelFME, "pr/interface_id"):                  testInterface + "\n",
+		filepath.Join(fpgaPath, "fpga/intel-fpga-dev.0/intel-fpga-port.0/dev"):    "240:1\n",
+		filepath.Join(fpgaPath, "fpga/intel-fpga-dev.0/intel-fpga-port.0/afu_id"): testAFUOld + "\n",
+		filepath.Join(otherPath, "vendor"):                                        "0x8086\n",
+		filepath.Join(otherPath, "device"):                                        "0x1572\n",
+		filepath.Join(otherPath, "class"):                                         "0x020000\n",
+	}
+
+	if err := createTestFiles(sysfs, []string{"bus/pci/devices"}, files); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, bdf := range []string{fpgaBDF, otherBDF} {
+		if err := os.Symlink(filepath.Join("../../../devices/pci0000:3a", bdf), filepath.Join(sysfs, "bus/pci/devices", bdf)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := createTestFiles(devfs, []string{"char"}, map[string]string{"intel-fpga-fme.0": "", "intel-fpga-port.0": ""}); err != nil {
+		t.Fatal(err)
+	}
+
+	for devNum, name := range map[string]string{"240:0": "intel-fpga-fme.0", "240:1": "intel-fpga-port.0"} {
+		if err := os.Symlink(filepath.Join("..", name), filepath.Join(devfs, "char", devNum)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tcases := []struct {
+		expectedErrIs error
+		name          string
+		addr          string
+		expectedErr   bool
+	}{
+		{
+			name: "FPGA device",
+			addr: fpgaBDF,
+		},
+		{
+			name:          "non-FPGA device",
+			addr:          otherBDF,
+			expectedErr:   true,
+			expectedErrIs: ErrNotFound,
+		},
+		{
+			name:          "no such device",
+			addr:          "0000:af:00.0",
+			expectedErr:   true,
+			expectedErrIs: ErrNotFound,
+		},
+		{
+			name:        "malformed address",
+			addr:        "3b:00.0",
+			expectedErr: true,
+		},
+	}
+	for _, tc := range tcases {
+		t.Run(tc.name, func(t *testing.T) {
+			fme, fmeErr := NewFMEByPCIAddress(tc.addr)
+			port, portErr := NewPortByPCIAddress(tc.addr)
+
+			if tc.expectedErr {
+				for _, err := range []error{fmeErr, portErr} {
+					if err == nil {
+						t.Error("no error returned")
+					}
+
+					if tc.expectedErrIs != nil && !errors.Is(err, tc.expectedErrIs) {
+						t.Errorf("expected %v, but got %+v", tc.expectedErrIs, err)
+					}
+				}
+
+				return
+			}
+
+			if fmeErr != nil || portErr != nil {
+				t.Fatalf("unexpected errors: %+v, %+v", fmeErr, portErr)
+			}
+
+			if fme.GetDevPath() != filepath.Join(devfs, "intel-fpga-fme.0") || fme.GetBitstreamID() != testBitstreamA {
+				t.Errorf("unexpected FME %s with bitstream %s", fme.GetDevPath(), fme.GetBitstreamID())
+			}
+
+			if port.GetDevPath() != filepath.Join(devfs, "intel-fpga-port.0") || port.GetAcceleratorTypeUUID() != testAFUOld {
+				t.Errorf("unexpected port %s with AFU %s", port.GetDevPath(), port.GetAcceleratorTypeUUID())
+			}
+		})
+	}
+}
+
+func TestReadOnly(t *testing.T) {
+	ioctls := 0
+
+	setTestIoctl(t, func(dev string, req uint, arg unsafe.Pointer) (uintptr, error) {
+		ioctls++
+
+		return 0, nil
+	})
+
+	programmed := false
+	fme := &testFME{interfaceUUID: testInterface}
+	fme.portPR = func(uint32, []byte) error {
+		programmed = true
+
+		return nil
+	}
+
+	port := newTestIntelFpgaPort(t, fme, testAFUOld)
+	if err := createTestFiles(port.SysFsPath, []string{"errors"}, map[string]string{"errors/errors": "0x1"}); err != nil {
+		t.Fatal(err)
+	}
+
+	intelFME := newTestIntelFpgaFME(t, nil)
+	dflFME := &DflFME{DevPath: "/dev/dfl-fme.0"}
+	dflPort := &DflPort{DevPath: "/dev/dfl-port.0"}
+	pci := &PCIDevice{SysFsPath: t.TempDir(), BDF: testBDF}
+	bs := newTestGBS(t, testInterface, testAFUNew)
+
+	AllowDriverRebinding(true)
+	SetReadOnly(true)
+	t.Cleanup(func() {
+		SetReadOnly(false)
+		AllowDriverRebinding(false)
+	})
+
+	mutations := map[string]func() error{
+		"intel-fpga PortPR":      func() error { return intelFME.PortPR(0, []byte{0}) },
+		"intel-fpga PortRelease": func() error { return intelFME.PortRelease(0) },
+		"intel-fpga PortAssign":  func() error { return intelFME.PortAssign(0) },
+		"intel-fpga PortReset":   port.PortReset,
+		"intel-fpga SetOnline":   func() error { return port.SetOnline(false) },
+		"intel-fpga ClearErrors": port.ClearErrors,
+		"intel-fpga PR":          func() error { return port.PR(bs, false) },
+		"DFL PortPR":             func() error { return dflFME.PortPR(0, []byte{0}) },
+		"DFL PortRelease":        func() error { return dflFME.PortRelease(0) },
+		"DFL PortAssign":         func() error { return dflFME.PortAssign(0) },
+		"DFL PortReset":          dflPort.PortReset,
+		"UnbindDriver":           pci.UnbindDriver,
+		"BindDriver":             func() error { return pci.BindDriver("dfl-pci") },
+	}
+	for name, mutate := range mutations {
+		if err := mutate(); !errors.Is(err, ErrReadOnly) {
+			t.Errorf("%s: expected ErrReadOnly, but got %v", name, err)
+		}
+	}
+
+	if ioctls != 0 || programmed {
+		t.Errorf("device touched in read-only mode: %d ioctls, PR called: %t", ioctls, programmed)
+	}
+
+	if devErrors, err := port.GetErrors(); err != nil || devErrors.Registers["errors"] != 1 {
+		t.Errorf("expected the latched error to stay, but got %+v, %v", devErrors, err)
+	}
+
+	if id, err := port.GetPortID(); err != nil || id != 0 {
+		t.Errorf("expected port ID 0, but got %d, %v", id, err)
+	}
+
+	if err := port.PR(bs, true); err != nil {
+		t.Errorf("unexpected dry run error: %+v", err)
+	}
+
+	SetReadOnly(false)
+
+	if err := port.PortReset(); err != nil || ioctls != 1 {
+		t.Errorf("expected port reset to be done, but got %v with %d ioctls", err, ioctls)
+	}
+}
+
+func TestPropertiesGeneration(t *testing.T) {
+	const refreshes = 200
+
+	fme := newTestIntelFpgaFME(t, nil)
+	port := newTestIntelFpgaPort(t, fme, testAFUOld)
+
+	devices := []struct {
+		dev     commonFpgaAPI
+		refresh func() error
+	}{
+		{fme, fme.RefreshProperties},
+		{port, port.RefreshProperties},
+	}
+	for _, d := range devices {
+		dev := d.dev
+		done := make(chan struct{})
+		errs := make(chan string, 4)
+
+		for i := 0; i < cap(errs); i++ {
+			go func() {
+				last := uint64(0)
+
+				for {
+					gen := dev.PropertiesGeneration()
+					if gen < last {
+						errs <- fmt.Sprintf("generation went back from %d to %d", last, gen)
+
+						return
+					}
+
+					last = gen
+
+					select {
+					case <-done:
+						errs <- ""
+
+						return
+					default:
+					}
+				}
+			}()
+		}
+
+		for i := 0; i < refreshes; i++ {
+			// Failures advance the generation as well.
+			_ = d.refresh()
+		}
+
+		close(done)
+
+		for i := 0; i < cap(errs); i++ {
+			if msg := <-errs; msg != "" {
+				t.Errorf("%s: %s", dev.GetName(), msg)
+			}
+		}
+
+		if gen := dev.PropertiesGeneration(); gen != refreshes {
+			t.Errorf("%s: expected generation %d, but got %d", dev.GetName(), refreshes, gen)
+		}
+	}
+}
+
+func TestDevNodePath(t *testing.T) {
+	root := setTestDevfsRoot(t)
+
+	for fname, expected := range map[string]string{
+		"intel-fpga-fme.0":    filepath.Join(root, "intel-fpga-fme.0"),
+		"/dev/dfl-port.1":     "/dev/dfl-port.1",
+		"./intel-fpga-port.0": "./intel-fpga-port.0",
+		root + "/dfl-fme.0":   root + "/dfl-fme.0",
+	} {
+		if path := devNodePath(fname); path != expected {
+			t.Errorf("%s: expected %q, but got %q", fname, expected, path)
+		}
+	}
+}
@@ -0,0 +1,34 @@
+// Copyright 2023 Intel Corporation. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fpga
+
+// InterfaceGenerations maps canonical interface UUIDs of known FIMs, see
+// CanonizeID, to their generation names. The names start with the FPGA
+// family. Entries are added as new FIMs are released.
+var InterfaceGenerations = map[string]string{
+	"ce48969398f05f33946d560708be108a": "Arria 10 GX DCP 1.0",
+	"9926ab6d6c925a68aabca7d84c545738": "Arria 10 GX DCP 1.1",
+	"69528db6eb31577a8c3668f9faa081f6": "Arria 10 GX DCP 1.2",
+	"bfac4d851ee856fe8c95865ce1bbaa2d": "Stratix 10 SX D5005",
+}
+
+// InterfaceGeneration returns generation name of the FIM with interface uuid,
+// e.g. to make logs more readable than with raw UUIDs. It returns false if
+// the interface isn't listed in InterfaceGenerations.
+func InterfaceGeneration(uuid string) (string, bool) {
+	name, ok := InterfaceGenerations[CanonizeID(uuid)]
+
+	return name, ok
+}
@@ -0,0 +1,51 @@
+// Copyright 2023 Intel Corporation. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fpga
+
+import "testing"
+
+func TestInterfaceGeneration(t *testing.T) {
+	tcases := []struct {
+		name         string
+		uuid         string
+		expectedName string
+		expectedOK   bool
+	}{
+		{
+			name:         "Arria 10",
+			uuid:         "69528db6eb31577a8c3668f9faa081f6",
+			expectedName: "Arria 10 GX DCP 1.2",
+			expectedOK:   true,
+		},
+		{
+			name:         "Stratix 10 in non-canonical form",
+			uuid:         " BFAC4D85-1EE8-56FE-8C95-865CE1BBAA2D\n",
+			expectedName: "Stratix 10 SX D5005",
+			expectedOK:   true,
+		},
+		{
+			name: "unknown",
+			uuid: "00000000000000000000000000000000",
+		},
+	}
+	for _, tc := range tcases {
+		t.Run(tc.name, func(t *testing.T) {
+			name, ok := InterfaceGeneration(tc.uuid)
+			if name != tc.expectedName || ok != tc.expectedOK {
+				t.Errorf("expected %q, %v, but got %q, %v", tc.expectedName, tc.expectedOK, name, ok)
+			}
+		})
+	}
+}
@@ -0,0 +1,261 @@
+// Copyright 2023 Intel Corporation. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fpga
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"k8s.io/klog/v2"
+)
+
+// HealthUpdate is a health transition of a device reported by MonitorHealth.
+type HealthUpdate struct {
+	// Reasons are the problems found if the device is unhealthy.
+	Reasons []string
+	// Device is the name of the FME or port, see GetName.
+	Device  string
+	Healthy bool
+}
+
+// checkHealth reports whether the FME is healthy. The board is unhealthy if
+// its driver doesn't respond, any FME error register is set or the power
+// consumption reaches the limit. All the found problems are returned.
+func checkHealth(fme FME) (bool, []string) {
+	var reasons []string
+
+	if _, err := fme.GetAPIVersion(); err != nil {
+		reasons = append(reasons, fmt.Sprintf("driver doesn't respond: %v", err))
+	}
+
+	devErrors, err := fme.GetFmeErrors()
+
+	switch {
+	case errors.Is(err, ErrNotSupported):
+	case err != nil:
+		reasons = append(reasons, fmt.Sprintf("unable to read errors: %v", err))
+	case devErrors.Active():
+		reasons = append(reasons, errorReasons(devErrors)...)
+	}
+
+	if power, powerErr := fme.GetPowerInfo(); powerErr == nil && power.Limit() > 0 && power.Consumed >= power.Limit() {
+		reasons = append(reasons, fmt.Sprintf("power consumption %dW reached the limit %dW", power.Consumed, power.Limit()))
+	}
+
+	return len(reasons) == 0, reasons
+}
+
+// checkPortHealth reports whether the port is healthy, that is it has no
+// error registers set.
+func checkPortHealth(port Port) (bool, []string) {
+	devErrors, err := port.GetErrors()
+
+	switch {
+	case errors.Is(err, ErrNotSupported):
+		return true, nil
+	case err != nil:
+		return false, []string{fmt.Sprintf("unable to read errors: %v", err)}
+	case devErrors.Active():
+		return false, errorReasons(devErrors)
+	}
+
+	return true, nil
+}
+
+// errorReasons describes the set error registers, sorted by name.
+func errorReasons(devErrors DeviceErrors) []string {
+	names := make([]string, 0, len(devErrors.Registers))
+	for name, value := range devErrors.Registers {
+		if value != 0 {
+			names = append(names, name)
+		}
+	}
+
+	sort.Strings(names)
+
+	reasons := make([]string, 0, len(names))
+	for _, name := range names {
+		reasons = append(reasons, fmt.Sprintf("error %s is %#x", name, devErrors.Registers[name]))
+	}
+
+	return reasons
+}
+
+// WaitForHealthy polls fme.Healthy until the board is healthy, e.g. when it
+// settles after boot. If ctx is done first, the returned error lists the
+// reasons of the last failed check.
+func WaitForHealthy(ctx context.Context, fme FME) error {
+	b := newBackoff(Backoff{})
+
+	for {
+		healthy, reasons := fme.Healthy()
+		if healthy {
+			return nil
+		}
+
+		if err := b.wait(ctx); err != nil {
+			return errors.Wrapf(err, "%s is unhealthy: %s", fme.GetName(), strings.Join(reasons, "; "))
+		}
+	}
+}
+
+// MonitorHealth polls the health of all the FMEs and ports of the host every
+// interval and sends an update to the returned channel whenever a device
+// becomes unhealthy or healthy again. The devices are assumed healthy until
+// they are found otherwise, so only the unhealthy ones are reported by the
+// first poll. The devices are opened and checked one by one to keep the load
+// on sysfs low. A device which disappears or can't be opened is unhealthy.
+// The channel is closed once ctx is done. An error is returned if the devices
+// can't be listed.
+func MonitorHealth(ctx context.Context, interval time.Duration) (<-chan HealthUpdate, error) {
+	if interval <= 0 {
+		return nil, errors.Errorf("invalid health polling interval %v", interval)
+	}
+
+	current, err := pollHealth(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	updates := make(chan HealthUpdate)
+
+	go func() {
+		defer close(updates)
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		known := map[string]bool{}
+
+		for {
+			for _, update := range healthTransitions(known, current) {
+				select {
+				case updates <- update:
+				case <-ctx.Done():
+					return
+				}
+			}
+
+			select {
+			case <-ticker.C:
+			case <-ctx.Done():
+				return
+			}
+
+			// On failure current is nil, which keeps the last known
+			// health instead of reporting all the devices gone.
+			if current, err = pollHealth(ctx); err != nil && ctx.Err() == nil {
+				klog.Warningf("unable to poll FPGA health: %+v", err)
+			}
+		}
+	}()
+
+	return updates, nil
+}
+
+// pollHealth checks the health of all the devices of the host sequentially.
+func pollHealth(ctx context.Context) ([]HealthUpdate, error) {
+	fmes, ports, err := ListFpgaDevicesContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	ret := make([]HealthUpdate, 0, len(fmes)+len(ports))
+
+	for _, name := range fmes {
+		if err = ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		update := HealthUpdate{Device: name}
+
+		if fme, openErr := openFME(name); openErr != nil {
+			update.Reasons = []string{fmt.Sprintf("unable to open: %v", openErr)}
+		} else {
+			update.Healthy, update.Reasons = fme.Healthy()
+			fme.Close()
+		}
+
+		ret = append(ret, update)
+	}
+
+	for _, name := range ports {
+		if err = ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		update := HealthUpdate{Device: name}
+
+		if port, openErr := openPort(name); openErr != nil {
+			update.Reasons = []string{fmt.Sprintf("unable to open: %v", openErr)}
+		} else {
+			update.Healthy, update.Reasons = checkPortHealth(port)
+			port.Close()
+		}
+
+		ret = append(ret, update)
+	}
+
+	return ret, nil
+}
+
+// healthTransitions returns the updates of current which change the health
+// recorded in known, and records the new health. Devices of known missing
+// from current are reported gone. If current is nil, nothing changes.
+func healthTransitions(known map[string]bool, current []HealthUpdate) []HealthUpdate {
+	if current == nil {
+		return nil
+	}
+
+	var ret []HealthUpdate
+
+	seen := make(map[string]bool, len(current))
+
+	for _, update := range current {
+		seen[update.Device] = true
+
+		healthy, ok := known[update.Device]
+		if !ok {
+			healthy = true
+		}
+
+		if healthy != update.Healthy {
+			ret = append(ret, update)
+		}
+
+		known[update.Device] = update.Healthy
+	}
+
+	gone := []string{}
+
+	for name, healthy := range known {
+		if !seen[name] && healthy {
+			gone = append(gone, name)
+		}
+	}
+
+	sort.Strings(gone)
+
+	for _, name := range gone {
+		known[name] = false
+		ret = append(ret, HealthUpdate{Device: name, Reasons: []string{"device is gone"}})
+	}
+
+	return ret
+}
@@ -0,0 +1,195 @@
+// Copyright 2023 Intel Corporation. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fpga
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"time"
+	"unsafe"
+
+	"github.com/pkg/errors"
+)
+
+func TestHealthy(t *testing.T) {
+	tcases := []struct {
+		name            string
+		files           map[string]string
+		ioctlErr        error
+		expectedReasons []string
+	}{
+		{
+			name: "healthy",
+			files: map[string]string{
+				"errors/fme-errors/errors": "0x0\n",
+				"power_mgmt/consumed":      "31\n",
+				"power_mgmt/threshold2":    "66\n",
+			},
+		},
+		{
+			name: "latched errors",
+			files: map[string]string{
+				"errors/fme-errors/errors": "0x4\n",
+				"errors/pcie0_errors":      "0x1\n",
+			},
+			expectedReasons: []string{"error fme-errors/errors is 0x4", "error pcie0_errors is 0x1"},
+		},
+		{
+			name:            "power limit reached",
+			files:           map[string]string{"power_mgmt/consumed": "66\n", "power_mgmt/threshold2": "66\n"},
+			expectedReasons: []string{"power consumption 66W reached the limit 66W"},
+		},
+		{
+			name:            "driver doesn't respond",
+			ioctlErr:        syscall.EIO,
+			expectedReasons: []string{"driver doesn't respond: " + syscall.EIO.Error()},
+		},
+	}
+	for _, tc := range tcases {
+		t.Run(tc.name, func(t *testing.T) {
+			setTestIoctl(t, func(dev string, req uint, arg unsafe.Pointer) (uintptr, error) {
+				return 0, tc.ioctlErr
+			})
+
+			healthy, reasons := newTestIntelFpgaFME(t, tc.files).Healthy()
+			if healthy != (len(tc.expectedReasons) == 0) {
+				t.Errorf("unexpected health %t", healthy)
+			}
+
+			if !reflect.DeepEqual(reasons, tc.expectedReasons) {
+				t.Errorf("expected reasons %q, but got %q", tc.expectedReasons, reasons)
+			}
+		})
+	}
+}
+
+func TestWaitForHealthy(t *testing.T) {
+	checks := 0
+	fme := &testFME{name: "intel-fpga-fme.0"}
+	fme.healthy = func() (bool, []string) {
+		checks++
+		if checks < 3 {
+			return false, []string{"link training"}
+		}
+
+		return true, nil
+	}
+
+	if err := WaitForHealthy(context.Background(), fme); err != nil {
+		t.Errorf("unexpected error: %+v", err)
+	}
+
+	if checks != 3 {
+		t.Errorf("expected 3 health checks, but got %d", checks)
+	}
+
+	fme.healthy = func() (bool, []string) {
+		return false, []string{"error fme-errors/errors is 0x4"}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	err := WaitForHealthy(ctx, fme)
+	if !errors.Is(err, context.DeadlineExceeded) || !strings.Contains(err.Error(), "error fme-errors/errors is 0x4") {
+		t.Errorf("expected timeout with the unhealthy reasons, but got %v", err)
+	}
+}
+
+func TestMonitorHealth(t *testing.T) {
+	sysfs := setTestSysfsRoot(t)
+
+	for _, dev := range []string{"intel-fpga-fme.0", "intel-fpga-port.0"} {
+		if err := createTestFiles(sysfs, []string{"bus/platform/devices/" + dev}, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// FME health reasons, no reasons means healthy.
+	var fmeReasons atomic.Value
+
+	fmeReasons.Store([]string{})
+
+	fme := &testFME{name: "intel-fpga-fme.0"}
+	fme.healthy = func() (bool, []string) {
+		reasons := fmeReasons.Load().([]string)
+
+		return len(reasons) == 0, reasons
+	}
+
+	port := newTestIntelFpgaPort(t, fme, testAFUOld)
+	portErrors := filepath.Join(port.SysFsPath, "errors/errors")
+
+	if err := createTestFiles(port.SysFsPath, []string{"errors"}, map[string]string{"errors/errors": "0x0\n"}); err != nil {
+		t.Fatal(err)
+	}
+
+	setTestOpeners(t, map[string]FME{"intel-fpga-fme.0": fme}, map[string]Port{"intel-fpga-port.0": port})
+
+	if _, err := MonitorHealth(context.Background(), 0); err == nil {
+		t.Error("no error returned for zero interval")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	updates, err := MonitorHealth(ctx, time.Millisecond)
+	if err != nil {
+		t.Fatalf("unexpected error: %+v", err)
+	}
+
+	expect := func(expected HealthUpdate) {
+		t.Helper()
+
+		select {
+		case update := <-updates:
+			if !reflect.DeepEqual(update, expected) {
+				t.Errorf("expected update %+v, but got %+v", expected, update)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("no update %+v received", expected)
+		}
+	}
+
+	fmeReasons.Store([]string{"error fme-errors/errors is 0x4"})
+	expect(HealthUpdate{Device: "intel-fpga-fme.0", Reasons: []string{"error fme-errors/errors is 0x4"}})
+
+	if err = os.WriteFile(portErrors, []byte("0x1\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	expect(HealthUpdate{Device: "intel-fpga-port.0", Reasons: []string{"error errors is 0x1"}})
+
+	fmeReasons.Store([]string{})
+	expect(HealthUpdate{Device: "intel-fpga-fme.0", Healthy: true, Reasons: []string{}})
+
+	if err = os.Remove(filepath.Join(sysfs, "bus/platform/devices/intel-fpga-fme.0")); err != nil {
+		t.Fatal(err)
+	}
+
+	expect(HealthUpdate{Device: "intel-fpga-fme.0", Reasons: []string{"device is gone"}})
+
+	cancel()
+
+	for update := range updates {
+		t.Errorf("unexpected update %+v", update)
+	}
+}
@@ -0,0 +1,137 @@
+// Copyright 2023 Intel Corporation. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fpga
+
+import (
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Types of hwmon sensors returned by GetHWMon.
+const (
+	HWMonTemperature = "temp"
+	HWMonVoltage     = "in"
+	HWMonPower       = "power"
+	HWMonCurrent     = "curr"
+)
+
+// hwmonDirs are Glob patterns, relative to the FME sysfs entry, of the hwmon
+// devices of the board, including those of the board management controller (BMC).
+var hwmonDirs = []string{
+	"hwmon/hwmon*",
+	"bmc*/hwmon/hwmon*",
+}
+
+// hwmonScale maps sensor types to the divisors converting the values the
+// kernel reports, e.g. millidegrees or microwatts, to the base units.
+var hwmonScale = map[string]float64{
+	HWMonTemperature: 1000,
+	HWMonVoltage:     1000,
+	HWMonPower:       1000000,
+	HWMonCurrent:     1000,
+}
+
+// hwmonInputRE matches the sensor input attributes with the supported types.
+var hwmonInputRE = regexp.MustCompile(`^(temp|in|power|curr)[0-9]+_input$`)
+
+// HWMonSensor is a single sensor of a hwmon device.
+type HWMonSensor struct {
+	// Device is the name of the hwmon device, e.g. "dfl_fme_power".
+	Device string
+	// Name is the name of the sensor within the device, e.g. "temp1".
+	Name string
+	// Label is the label of the sensor or empty string if it has none.
+	Label string
+	// Type is one of HWMonTemperature, HWMonVoltage, HWMonPower or HWMonCurrent.
+	Type string
+	// Value is in degrees Celsius, volts, watts or amperes depending on Type.
+	Value float64
+}
+
+// getHWMon returns all the temperature, voltage, power and current sensors of
+// the hwmon devices of the FME, keyed by "<hwmon device name>/<sensor name>".
+// The hwmon directory names are used if the devices have no names.
+func getHWMon(fme FME) (map[string]HWMonSensor, error) {
+	sensors := map[string]HWMonSensor{}
+
+	sysfs := fme.GetSysFsPath()
+	if sysfs == "" {
+		return sensors, errors.Wrapf(ErrNotSupported, "%s: no sysfs entry", fme.GetName())
+	}
+
+	found := false
+
+	for _, pattern := range hwmonDirs {
+		hwmons, _ := filepath.Glob(filepath.Join(sysfs, pattern))
+		for _, hwmon := range hwmons {
+			found = true
+
+			if err := readHWMonSensors(hwmon, sensors); err != nil {
+				return map[string]HWMonSensor{}, err
+			}
+		}
+	}
+
+	if !found {
+		return sensors, errors.Wrapf(ErrNotSupported, "%s: no hwmon devices", fme.GetName())
+	}
+
+	return sensors, nil
+}
+
+// readHWMonSensors adds the sensors of the hwmon device to sensors.
+func readHWMonSensors(hwmon string, sensors map[string]HWMonSensor) error {
+	inputs, _ := filepath.Glob(filepath.Join(hwmon, "*_input"))
+	if len(inputs) == 0 {
+		return nil
+	}
+
+	var device string
+	if err := readFilesInDirectory(map[string]*string{"name": &device}, hwmon); err != nil {
+		return err
+	}
+
+	if device == "" {
+		device = filepath.Base(hwmon)
+	}
+
+	for _, input := range inputs {
+		subs := hwmonInputRE.FindStringSubmatch(filepath.Base(input))
+		if subs == nil {
+			continue
+		}
+
+		sensor := HWMonSensor{Device: device, Name: strings.TrimSuffix(subs[0], "_input"), Type: subs[1]}
+
+		var value string
+		if err := readFilesInDirectory(map[string]*string{sensor.Name + "_label": &sensor.Label, sensor.Name + "_input": &value}, hwmon); err != nil {
+			return err
+		}
+
+		raw, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return errors.Wrapf(err, "%s: unable to parse %s", hwmon, filepath.Base(input))
+		}
+
+		sensor.Value = float64(raw) / hwmonScale[sensor.Type]
+		sensors[device+"/"+sensor.Name] = sensor
+	}
+
+	return nil
+}
@@ -0,0 +1,68 @@
+// Copyright 2023 Intel Corporation. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fpga
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/pkg/errors"
+)
+
+func TestGetHWMon(t *testing.T) {
+	// Layout of a PAC N3000 with the FME thermal/power and the BMC hwmons.
+	fme := newTestIntelFpgaFME(t, map[string]string{
+		"hwmon/hwmon2/name":                  "dfl_fme_thermal\n",
+		"hwmon/hwmon2/temp1_input":           "45000\n",
+		"hwmon/hwmon2/temp1_max":             "90000\n",
+		"hwmon/hwmon2/temp1_label":           "FPGA Die Temperature\n",
+		"hwmon/hwmon3/name":                  "dfl_fme_power\n",
+		"hwmon/hwmon3/power1_input":          "31500000\n",
+		"hwmon/hwmon3/power1_max":            "60000000\n",
+		"bmc0/hwmon/hwmon4/name":             "n3000bmc-hwmon\n",
+		"bmc0/hwmon/hwmon4/in0_input":        "12096\n",
+		"bmc0/hwmon/hwmon4/in0_label":        "12V Backplane Voltage\n",
+		"bmc0/hwmon/hwmon4/curr0_input":      "2560\n",
+		"bmc0/hwmon/hwmon4/curr0_label":      "12V Backplane Current\n",
+		"bmc0/hwmon/hwmon4/temp2_input":      "38500\n",
+		"bmc0/hwmon/hwmon4/fan1_input":       "2400\n",
+		"bmc0/hwmon/hwmon4/intrusion0_alarm": "0\n",
+	})
+
+	sensors, err := fme.GetHWMon()
+	if err != nil {
+		t.Fatalf("unexpected error: %+v", err)
+	}
+
+	expected := map[string]HWMonSensor{
+		"dfl_fme_thermal/temp1": {Device: "dfl_fme_thermal", Name: "temp1", Label: "FPGA Die Temperature", Type: HWMonTemperature, Value: 45},
+		"dfl_fme_power/power1":  {Device: "dfl_fme_power", Name: "power1", Type: HWMonPower, Value: 31.5},
+		"n3000bmc-hwmon/in0":    {Device: "n3000bmc-hwmon", Name: "in0", Label: "12V Backplane Voltage", Type: HWMonVoltage, Value: 12.096},
+		"n3000bmc-hwmon/curr0":  {Device: "n3000bmc-hwmon", Name: "curr0", Label: "12V Backplane Current", Type: HWMonCurrent, Value: 2.56},
+		"n3000bmc-hwmon/temp2":  {Device: "n3000bmc-hwmon", Name: "temp2", Type: HWMonTemperature, Value: 38.5},
+	}
+	if !reflect.DeepEqual(sensors, expected) {
+		t.Errorf("expected %+v, but got %+v", expected, sensors)
+	}
+
+	if _, err = newTestIntelFpgaFME(t, nil).GetHWMon(); !errors.Is(err, ErrNotSupported) {
+		t.Errorf("expected ErrNotSupported, but got %v", err)
+	}
+
+	sensors, err = newTestIntelFpgaFME(t, map[string]string{"hwmon/hwmon2/temp1_input": "45000\n"}).GetHWMon()
+	if err != nil || len(sensors) != 1 || sensors["hwmon2/temp1"].Value != 45 {
+		t.Errorf("expected sensor of unnamed device, but got %+v (%v)", sensors, err)
+	}
+}
@@ -15,9 +15,15 @@
 package fpga
 
 import (
+	"context"
 	"math"
+	"net"
+	"os"
 	"path/filepath"
 	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
 	"unsafe"
 
 	"github.com/intel/intel-device-plugins-for-kubernetes/pkg/fpga/bitstream"
@@ -25,14 +31,21 @@ import (
 )
 
 const (
-	intelFpgaFmePrefix  = "intel-fpga-fme."
-	intelFpgaPortPrefix = "intel-fpga-port."
-	intelFpgaFmeGlobPCI = "fpga/intel-fpga-dev.*/intel-fpga-fme.*"
+	intelFpgaFmePrefix   = "intel-fpga-fme."
+	intelFpgaPortPrefix  = "intel-fpga-port."
+	intelFpgaFmeGlobPCI  = "fpga/intel-fpga-dev.*/intel-fpga-fme.*"
+	intelFpgaPortGlobPCI = "fpga/intel-fpga-dev.*/intel-fpga-port.*"
+
+	// intelFpgaPortOnlineFile is an optional port attribute with administrative state of the port.
+	intelFpgaPortOnlineFile = "online"
 )
 
 // IntelFpgaFME represent Intel FPGA FME device.
 type IntelFpgaFME struct {
 	FME
+	// prCount and generation are accessed atomically, keep them 64-bit aligned.
+	prCount           uint64
+	generation        uint64
 	DevPath           string
 	SysFsPath         string
 	Name              string
@@ -52,7 +65,12 @@ func (f *IntelFpgaFME) Close() error {
 
 // NewIntelFpgaFME Opens device.
 func NewIntelFpgaFME(dev string) (FME, error) {
-	fme := &IntelFpgaFME{DevPath: dev}
+	return newIntelFpgaFME(dev, "")
+}
+
+// newIntelFpgaFME opens device with already known sysfs entry.
+func newIntelFpgaFME(dev, sysfs string) (FME, error) {
+	fme := &IntelFpgaFME{DevPath: dev, SysFsPath: sysfs}
 	if err := checkPCIDeviceType(fme); err != nil {
 		return nil, err
 	}
@@ -67,14 +85,19 @@ func NewIntelFpgaFME(dev string) (FME, error) {
 // IntelFpgaPort represent IntelFpga FPGA Port device.
 type IntelFpgaPort struct {
 	Port
-	FME       FME
-	DevPath   string
-	SysFsPath string
-	Name      string
-	PCIDevice *PCIDevice
-	Dev       string
-	AFUID     string
-	ID        string
+	// resetCount and generation are accessed atomically, keep them 64-bit aligned.
+	resetCount uint64
+	generation uint64
+	FME        FME
+	DevPath    string
+	SysFsPath  string
+	Name       string
+	PCIDevice  *PCIDevice
+	Dev        string
+	AFUID      string
+	ID         string
+	// offline is the state set by SetOnline when the port has no online attribute.
+	offline bool
 }
 
 // Close closes open device.
@@ -88,7 +111,12 @@ func (f *IntelFpgaPort) Close() error {
 
 // NewIntelFpgaPort Opens device.
 func NewIntelFpgaPort(dev string) (Port, error) {
-	port := &IntelFpgaPort{DevPath: dev}
+	return newIntelFpgaPort(dev, "")
+}
+
+// newIntelFpgaPort opens device with already known sysfs entry.
+func newIntelFpgaPort(dev, sysfs string) (Port, error) {
+	port := &IntelFpgaPort{DevPath: dev, SysFsPath: sysfs}
 	if err := checkPCIDeviceType(port); err != nil {
 		port.Close()
 		return nil, err
@@ -104,11 +132,11 @@ func NewIntelFpgaPort(dev string) (Port, error) {
 
 // common ioctls for FME and Port.
 func commonIntelFpgaGetAPIVersion(fd string) (int, error) {
-	v, err := ioctlDev(fd, FPGA_GET_API_VERSION, 0)
+	v, err := ioctlDev(fd, FPGA_GET_API_VERSION, nil)
 	return int(v), err
 }
 func commonIntelFpgaCheckExtension(fd string) (int, error) {
-	v, err := ioctlDev(fd, FPGA_CHECK_EXTENSION, 0)
+	v, err := ioctlDev(fd, FPGA_CHECK_EXTENSION, nil)
 	return int(v), err
 }
 
@@ -124,6 +152,30 @@ func (f *IntelFpgaFME) CheckExtension() (int, error) {
 	return commonIntelFpgaCheckExtension(f.DevPath)
 }
 
+// PingDevice measures round-trip latency of GetAPIVersion ioctl. It has no
+// side effects and can be used as a lightweight liveness probe.
+func (f *IntelFpgaFME) PingDevice() (time.Duration, error) {
+	return pingDevice(f)
+}
+
+// IsVirtualFunction reports whether the device belongs to a PCI virtual function.
+func (f *IntelFpgaFME) IsVirtualFunction() (bool, error) {
+	return isVirtualFunction(f)
+}
+
+// GetDeviceType returns device type reported by sysfs. See getDeviceType for details.
+func (f *IntelFpgaFME) GetDeviceType() (string, error) {
+	return getDeviceType(f)
+}
+
+// GetStableID returns ID of the device derived from its PCI address and interface UUID.
+// The ID survives reboots, so it's suitable as the device ID reported to the
+// kubelet, but it changes if the card is moved to another PCI slot or the
+// FIM is updated.
+func (f *IntelFpgaFME) GetStableID() (string, error) {
+	return getFMEStableID(f)
+}
+
 // GetAPIVersion  Report the version of the driver API.
 // * Return: Driver API Version.
 func (f *IntelFpgaPort) GetAPIVersion() (int, error) {
@@ -136,6 +188,30 @@ func (f *IntelFpgaPort) CheckExtension() (int, error) {
 	return commonIntelFpgaCheckExtension(f.DevPath)
 }
 
+// PingDevice measures round-trip latency of GetAPIVersion ioctl. It has no
+// side effects and can be used as a lightweight liveness probe.
+func (f *IntelFpgaPort) PingDevice() (time.Duration, error) {
+	return pingDevice(f)
+}
+
+// IsVirtualFunction reports whether the device belongs to a PCI virtual function.
+func (f *IntelFpgaPort) IsVirtualFunction() (bool, error) {
+	return isVirtualFunction(f)
+}
+
+// GetDeviceType returns device type reported by sysfs. See getDeviceType for details.
+func (f *IntelFpgaPort) GetDeviceType() (string, error) {
+	return getDeviceType(f)
+}
+
+// GetStableID returns ID of the device derived from its PCI address, interface UUID and port id.
+// The ID survives reboots, so it's suitable as the device ID reported to the
+// kubelet, but it changes if the card is moved to another PCI slot or the
+// FIM is updated.
+func (f *IntelFpgaPort) GetStableID() (string, error) {
+	return getPortStableID(f)
+}
+
 // FME interfaces
 
 // PortPR does Partial Reconfiguration based on Port ID and Buffer (Image)
@@ -145,6 +221,20 @@ func (f *IntelFpgaPort) CheckExtension() (int, error) {
 //     some errors during PR, under this case, the user can fetch HW error info
 //     from the status of FME's fpga manager.
 func (f *IntelFpgaFME) PortPR(port uint32, bitstream []byte) error {
+	return f.PortPRContext(context.Background(), port, bitstream)
+}
+
+// PortPRContext is the same as PortPR, but the operation is abandoned
+// if ctx is done before the request is submitted to the driver.
+func (f *IntelFpgaFME) PortPRContext(ctx context.Context, port uint32, bitstream []byte) error {
+	if err := requireWritable(f.GetName(), "PR"); err != nil {
+		return err
+	}
+
+	if err := requirePhysicalFunction(f, "PR"); err != nil {
+		return err
+	}
+
 	var value IntelFpgaFmePortPR
 
 	value.Argsz = uint32(unsafe.Sizeof(value))
@@ -152,20 +242,46 @@ func (f *IntelFpgaFME) PortPR(port uint32, bitstream []byte) error {
 	value.Buffer_size = uint32(len(bitstream))
 	value.Buffer_address = uint64(uintptr(unsafe.Pointer(&bitstream[0])))
 
-	_, err := ioctlDev(f.DevPath, FPGA_FME_PORT_PR, uintptr(unsafe.Pointer(&value)))
+	_, err := ioctlDevContext(ctx, f.DevPath, FPGA_FME_PORT_PR, unsafe.Pointer(&value))
 
-	return err
+	recordPRAudit(ctx, f.DevPath, port, bitstream, err)
+
+	if err != nil {
+		return err
+	}
+
+	atomic.AddUint64(&f.prCount, 1)
+
+	return nil
+}
+
+// GetPRCount returns number of partial reconfigurations done by the FME,
+// e.g. for tracking reconfiguration wear of the board. See getPRCount for
+// details. Note that unless the driver publishes the counter, the value
+// isn't persistent: it's counted per FME object from the process start.
+func (f *IntelFpgaFME) GetPRCount() (uint64, error) {
+	return getPRCount(f.GetSysFsPath(), &f.prCount)
 }
 
 // PortRelease releases the port per Port ID provided by caller.
 // * Return: 0 on success, -errno on failure.
 func (f *IntelFpgaFME) PortRelease(port uint32) error {
+	if err := requireWritable(f.GetName(), "port release"); err != nil {
+		return err
+	}
+
+	if err := requirePhysicalFunction(f, "port release"); err != nil {
+		return err
+	}
+
 	var value IntelFpgaFmePortRelease
 
 	value.Argsz = uint32(unsafe.Sizeof(value))
 	value.Id = port
 
-	_, err := ioctlDev(f.DevPath, FPGA_FME_PORT_RELEASE, uintptr(unsafe.Pointer(&value)))
+	_, err := ioctlDev(f.DevPath, FPGA_FME_PORT_RELEASE, unsafe.Pointer(&value))
+
+	recordPortAudit(AuditPortRelease, f.DevPath, port, err)
 
 	return err
 }
@@ -173,16 +289,83 @@ func (f *IntelFpgaFME) PortRelease(port uint32) error {
 // PortAssign assigns the port back per Port ID provided by caller.
 // * Return: 0 on success, -errno on failure.
 func (f *IntelFpgaFME) PortAssign(port uint32) error {
+	if err := requireWritable(f.GetName(), "port assign"); err != nil {
+		return err
+	}
+
+	if err := requirePhysicalFunction(f, "port assign"); err != nil {
+		return err
+	}
+
 	var value IntelFpgaFmePortAssign
 
 	value.Argsz = uint32(unsafe.Sizeof(value))
 	value.Id = port
 
-	_, err := ioctlDev(f.DevPath, FPGA_FME_PORT_ASSIGN, uintptr(unsafe.Pointer(&value)))
+	_, err := ioctlDev(f.DevPath, FPGA_FME_PORT_ASSIGN, unsafe.Pointer(&value))
+
+	recordPortAudit(AuditPortAssign, f.DevPath, port, err)
 
 	return err
 }
 
+// OwnsPort reports whether port belongs to this FME, i.e. whether both
+// devices share the same PCI physical function. The port may be attached
+// to one of the virtual functions of the FME's card.
+func (f *IntelFpgaFME) OwnsPort(port Port) (bool, error) {
+	return ownsPort(f, port)
+}
+
+// AssignToVF releases port from the FME's physical function, so that
+// it can be used by a virtual function. It fails if port doesn't belong
+// to the FME.
+func (f *IntelFpgaFME) AssignToVF(port Port) error {
+	id, err := f.ownedPortID(port)
+	if err != nil {
+		return err
+	}
+
+	return f.PortRelease(id)
+}
+
+// ReleaseToHost assigns port back to the FME's physical function.
+// It fails if port doesn't belong to the FME.
+func (f *IntelFpgaFME) ReleaseToHost(port Port) error {
+	id, err := f.ownedPortID(port)
+	if err != nil {
+		return err
+	}
+
+	return f.PortAssign(id)
+}
+
+// ownedPortID returns ID of the port after checking it belongs to the FME.
+func (f *IntelFpgaFME) ownedPortID(port Port) (uint32, error) {
+	owns, err := f.OwnsPort(port)
+	if err != nil {
+		return math.MaxUint32, err
+	}
+
+	if !owns {
+		return math.MaxUint32, errors.Errorf("port %s doesn't belong to FME %s", port.GetDevPath(), f.DevPath)
+	}
+
+	return port.GetPortID()
+}
+
+// SetAnnotation attaches user metadata to the device, e.g. its rack or purpose.
+// Annotations are kept in memory per device node and are shared by all
+// handles of the device. They aren't persisted across process restarts.
+// Setting an empty value removes the key.
+func (f *IntelFpgaFME) SetAnnotation(key, value string) {
+	setAnnotation(f.GetDevPath(), key, value)
+}
+
+// Annotations returns copy of the user metadata attached to the device.
+func (f *IntelFpgaFME) Annotations() map[string]string {
+	return getAnnotations(f.GetDevPath())
+}
+
 // GetDevPath returns path to device node.
 func (f *IntelFpgaFME) GetDevPath() string {
 	return f.DevPath
@@ -210,7 +393,12 @@ func (f *IntelFpgaFME) GetName() string {
 		return f.Name
 	}
 
-	f.Name = filepath.Base(f.GetSysFsPath())
+	sysfs := f.GetSysFsPath()
+	if sysfs == "" {
+		return devNodeName(f.DevPath)
+	}
+
+	f.Name = filepath.Base(sysfs)
 
 	return f.Name
 }
@@ -231,6 +419,37 @@ func (f *IntelFpgaFME) GetPCIDevice() (*PCIDevice, error) {
 	return f.PCIDevice, nil
 }
 
+// ListVFs returns the PCI virtual functions spawned by the board of FME.
+// The slice is empty if SR-IOV is disabled.
+func (f *IntelFpgaFME) ListVFs() ([]*PCIDevice, error) {
+	pci, err := f.GetPCIDevice()
+	if err != nil {
+		return nil, err
+	}
+
+	return pci.physicalFunction().GetVFs()
+}
+
+// GetPCIIDs returns numeric PCI vendor and device IDs of this device.
+func (f *IntelFpgaFME) GetPCIIDs() (vendor, device uint16, err error) {
+	ids, err := getPCIIDs(f)
+
+	return ids.Vendor, ids.Device, err
+}
+
+// GetPCIIDsExtended returns numeric PCI IDs of this device including subsystem IDs.
+func (f *IntelFpgaFME) GetPCIIDsExtended() (PCIIDs, error) {
+	return getPCIIDs(f)
+}
+
+// VerifyPortCount returns the number of ports the FME reports and the number
+// of its port devices that can be opened, e.g. to detect a board enumerated
+// only partially during startup self-check. ErrPortCountMismatch is returned
+// if they differ.
+func (f *IntelFpgaFME) VerifyPortCount() (expected, actual int, err error) {
+	return verifyPortCount(context.Background(), f)
+}
+
 // GetPortsNum returns amount of FPGA Ports associated to this FME.
 func (f *IntelFpgaFME) GetPortsNum() int {
 	if f.PortsNum == "" {
@@ -271,6 +490,12 @@ func (f *IntelFpgaFME) GetSocketID() (uint32, error) {
 	return uint32(id), err
 }
 
+// GetNUMANode returns NUMA node the FPGA is attached to or -1 if it's unknown.
+// See getNUMANode for the sources it's determined from.
+func (f *IntelFpgaFME) GetNUMANode() int {
+	return getNUMANode(f)
+}
+
 // GetBitstreamID returns FME bitstream id.
 func (f *IntelFpgaFME) GetBitstreamID() string {
 	return f.BitstreamID
@@ -281,6 +506,135 @@ func (f *IntelFpgaFME) GetBitstreamMetadata() string {
 	return f.BitstreamMetadata
 }
 
+// GetFIMSeed returns the synthesis seed of the FIM from the bitstream
+// metadata, e.g. to check the nodes run identical FIM builds.
+// ErrNotSupported is returned if the metadata has no seed.
+func (f *IntelFpgaFME) GetFIMSeed() (string, error) {
+	return getFIMSeed(f)
+}
+
+// GetMode returns the operating mode of the board, ResourceModeRegion if its
+// ports can be reprogrammed, ResourceModeAF if they run fixed AFUs or
+// ModeUnknown, e.g. to choose the resources the device plugin advertises.
+func (f *IntelFpgaFME) GetMode() (string, error) {
+	return boardMode(f)
+}
+
+// GetModelName returns model name of the FPGA card or empty string if it's unknown.
+func (f *IntelFpgaFME) GetModelName() string {
+	return modelName(f)
+}
+
+// GetPowerInfo returns power consumption and thresholds of the FPGA.
+// ErrNotSupported is returned if the driver doesn't report them.
+func (f *IntelFpgaFME) GetPowerInfo() (PowerInfo, error) {
+	return getIntelFpgaPowerInfo(f)
+}
+
+// GetHWMon returns all the temperature, voltage, power and current sensors
+// of the board's hwmon devices. ErrNotSupported is returned if the board has
+// no hwmon devices.
+func (f *IntelFpgaFME) GetHWMon() (map[string]HWMonSensor, error) {
+	return getHWMon(f)
+}
+
+// GetVoltageRails returns the voltages of the board's rails in volts, keyed
+// by the rail names. ErrNotSupported is returned if the board doesn't report
+// them.
+func (f *IntelFpgaFME) GetVoltageRails() (map[string]float64, error) {
+	return getVoltageRails(f)
+}
+
+// GetFmeErrors returns the error registers of the FME.
+func (f *IntelFpgaFME) GetFmeErrors() (DeviceErrors, error) {
+	return readDeviceErrors(f)
+}
+
+// ClearFmeErrors clears the errors latched by the FME.
+func (f *IntelFpgaFME) ClearFmeErrors() error {
+	return clearDeviceErrors(f)
+}
+
+// Healthy reports whether the board is healthy. See checkHealth for details.
+func (f *IntelFpgaFME) Healthy() (bool, []string) {
+	return checkHealth(f)
+}
+
+// RefreshProperties re-reads FME properties from sysfs.
+func (f *IntelFpgaFME) RefreshProperties() error {
+	return refreshProperties(f.updateProperties, &f.generation)
+}
+
+// PropertiesGeneration returns the generation of the FME properties. See
+// refreshProperties for details.
+func (f *IntelFpgaFME) PropertiesGeneration() uint64 {
+	return atomic.LoadUint64(&f.generation)
+}
+
+// WaitForBitstreamID polls FME bitstream id until it becomes equal to want or ctx expires.
+// Delays between polls are defined by bo.
+func (f *IntelFpgaFME) WaitForBitstreamID(ctx context.Context, want string, bo Backoff) error {
+	return waitForBitstreamID(ctx, f, want, bo)
+}
+
+// GetFlashUpdateProgress returns progress and status of the card's flash update.
+// ErrNotSupported is returned if the card has no flash update interface.
+func (f *IntelFpgaFME) GetFlashUpdateProgress() (percent int, status string, err error) {
+	return getFlashUpdateProgress(f)
+}
+
+// GetFlashInfo returns identity of the card's flash part, e.g. to check it
+// before a flash update. ErrNotSupported is returned if the card exposes
+// no flash.
+func (f *IntelFpgaFME) GetFlashInfo() (FlashInfo, error) {
+	return getFlashInfo(f)
+}
+
+// GetLastResetReason returns the cause of the last reset of the board, one
+// of the ResetReason constants, e.g. to tell why a board keeps resetting.
+// ResetReasonUnknown is returned for the causes the package doesn't know.
+// ErrNotSupported is returned if the driver doesn't record the cause.
+func (f *IntelFpgaFME) GetLastResetReason() (string, error) {
+	return getLastResetReason(f)
+}
+
+// GetManufacturingInfo returns the manufacturing date, revision and
+// provisioning ID of the board the BMC exposes, e.g. for asset lifecycle
+// tracking. ErrNotSupported is returned if the board exposes none of them.
+func (f *IntelFpgaFME) GetManufacturingInfo() (ManufacturingInfo, error) {
+	return getManufacturingInfo(f)
+}
+
+// GetFpgaManagerStatus returns state and errors of the FME's FPGA manager.
+// It's useful to find out what went wrong after PortPR failed with EIO.
+func (f *IntelFpgaFME) GetFpgaManagerStatus() (ManagerStatus, error) {
+	return getFpgaManagerStatus(f)
+}
+
+// CheckCompatibility compares the driver API version with the FIM version
+// encoded in the bitstream id and the versions in KnownGoodFIMs. It returns
+// true if the FIM is known to work with the driver, otherwise the returned
+// warnings describe the mismatches. The bitstream id is re-read with
+// RefreshProperties, as the FIM may have been updated since.
+func (f *IntelFpgaFME) CheckCompatibility() (bool, []string, error) {
+	apiVersion, err := f.GetAPIVersion()
+	if err != nil {
+		return false, nil, errors.Wrapf(err, "%s: unable to get driver API version", f.GetName())
+	}
+
+	if err = f.RefreshProperties(); err != nil {
+		return false, nil, err
+	}
+
+	return checkCompatibility(apiVersion, f.GetBitstreamID(), KnownGoodFIMs)
+}
+
+// GetMACAddresses returns MAC addresses assigned to the board. The slice
+// is empty if the board has no networking features.
+func (f *IntelFpgaFME) GetMACAddresses() ([]net.HardwareAddr, error) {
+	return getMACAddresses(f)
+}
+
 // Update properties from sysfs.
 func (f *IntelFpgaFME) updateProperties() error {
 	pci, err := f.GetPCIDevice()
@@ -308,8 +662,205 @@ func (f *IntelFpgaFME) updateProperties() error {
 // (e.g. DMA or PR operation failure) and be recoverable from the failure.
 // * Return: 0 on success, -errno of failure.
 func (f *IntelFpgaPort) PortReset() error {
-	_, err := ioctlDev(f.DevPath, FPGA_PORT_RESET, 0)
-	return err
+	if err := requireWritable(f.GetName(), "port reset"); err != nil {
+		return err
+	}
+
+	_, err := ioctlDev(f.DevPath, FPGA_PORT_RESET, nil)
+
+	recordAudit(AuditEvent{Operation: AuditPortReset, Device: f.DevPath, Err: err})
+
+	if err != nil {
+		return err
+	}
+
+	atomic.AddUint64(&f.resetCount, 1)
+
+	return nil
+}
+
+// AFUReset resets only the AFU logic, keeping the port level state such as
+// the error masks, the power state and the port assignment intact. Unlike
+// PortReset it wouldn't disturb the port clients other than the AFU user.
+// Neither the intel-fpga nor the DFL driver uAPI has separate AFU reset,
+// their reset ioctls always reset the whole port, so ErrNotSupported is
+// returned. The port isn't reset in that case, callers have to use
+// PortReset explicitly if full reset is acceptable.
+func (f *IntelFpgaPort) AFUReset() error {
+	return errors.Wrapf(ErrNotSupported, "%s: AFU-only reset", f.GetName())
+}
+
+// GetErrors returns the error registers of the port.
+func (f *IntelFpgaPort) GetErrors() (DeviceErrors, error) {
+	return readDeviceErrors(f)
+}
+
+// ClearErrors clears the errors latched by the port.
+func (f *IntelFpgaPort) ClearErrors() error {
+	return clearDeviceErrors(f)
+}
+
+// GetErrorInjection returns the mask of the errors injected to the port with
+// SetErrorInjection. ErrNotSupported is returned if the port has no error
+// injection register.
+func (f *IntelFpgaPort) GetErrorInjection() (uint64, error) {
+	return getErrorInjection(f)
+}
+
+// SetErrorInjection makes the port report the errors of mask, as if they
+// happened, so that error handling can be tested end-to-end. Zero mask stops
+// the injection. It's meant for testing only: the injected errors are handled
+// by the driver as real ones, e.g. the port may need reset. ErrNotSupported
+// is returned if the port has no error injection register.
+func (f *IntelFpgaPort) SetErrorInjection(mask uint64) error {
+	return setErrorInjection(f, mask)
+}
+
+// GetUtilization returns the fraction, from 0 to 1, of the time the AFU
+// was busy, e.g. for autoscaling the FPGA workloads. The activity counters
+// of the port are sampled twice the window set by SetUtilizationWindow
+// apart, so the call blocks for the window. ErrNotSupported is returned if the port has no activity
+// counters.
+func (f *IntelFpgaPort) GetUtilization() (float64, error) {
+	return getUtilization(f)
+}
+
+// GetIRQAffinity returns the CPUs the interrupts of the port are delivered
+// to, keyed by interrupt number. The interrupts are the MSI-X vectors of
+// the PCI function of the port. ErrNotSupported is returned if it has no
+// interrupts registered.
+func (f *IntelFpgaPort) GetIRQAffinity() (map[int][]int, error) {
+	return getIRQAffinity(f)
+}
+
+// SetIRQAffinity pins the interrupt of the port to the CPUs, e.g. the ones
+// allocated to a latency sensitive workload. It requires root privileges.
+func (f *IntelFpgaPort) SetIRQAffinity(irq int, cpus []int) error {
+	return setIRQAffinity(f, irq, cpus)
+}
+
+// GetResetCount returns number of port resets. See getResetCount for details.
+func (f *IntelFpgaPort) GetResetCount() (uint64, error) {
+	return getResetCount(f.GetSysFsPath(), &f.resetCount)
+}
+
+// SetOnline administratively brings the port in or out of service.
+// If the driver publishes the "online" port attribute, the state is written
+// there and the driver defines what an offline port is. Otherwise, taking
+// the port offline releases it from its FME with PortRelease: the port is
+// detached from the physical function and can't be used through it until
+// it's brought online again, which assigns it back with PortAssign.
+func (f *IntelFpgaPort) SetOnline(online bool) error {
+	if err := requireWritable(f.GetName(), "online state change"); err != nil {
+		return err
+	}
+
+	if attr := f.onlineAttr(); attr != "" {
+		if _, err := os.Stat(attr); err == nil {
+			value := "0"
+			if online {
+				value = "1"
+			}
+
+			return errors.Wrapf(os.WriteFile(attr, []byte(value), 0600), "%s: unable to set online state", f.GetName())
+		}
+	}
+
+	fme, err := f.GetFME()
+	if err != nil {
+		return err
+	}
+
+	id, err := f.GetPortID()
+	if err != nil {
+		return err
+	}
+
+	if online {
+		err = fme.PortAssign(id)
+	} else {
+		err = fme.PortRelease(id)
+	}
+
+	if err != nil {
+		return errors.Wrapf(err, "%s: unable to set online state to %t", f.GetName(), online)
+	}
+
+	f.offline = !online
+
+	return nil
+}
+
+// IsOnline reports whether the port is in service. If the port has no
+// "online" attribute, the release state isn't visible in sysfs, so the port
+// is considered online unless it has been taken offline with SetOnline of
+// this object.
+func (f *IntelFpgaPort) IsOnline() (bool, error) {
+	if attr := f.onlineAttr(); attr != "" {
+		data, err := readSysfsFile(attr)
+
+		switch {
+		case err == nil:
+			online, perr := strconv.ParseBool(strings.TrimSpace(string(data)))
+
+			return online, errors.Wrapf(perr, "%s: unable to parse online state", f.GetName())
+		case !os.IsNotExist(err):
+			return false, errors.Wrapf(err, "%s: unable to read online state", f.GetName())
+		}
+	}
+
+	return !f.offline, nil
+}
+
+// PortStatus returns online state, AFU UUID, latched errors and reset count
+// of the port collected at once. See getPortStatus for details.
+func (f *IntelFpgaPort) PortStatus() (PortStatus, error) {
+	return getPortStatus(f)
+}
+
+// Programmable reports whether the port can be partially reconfigured.
+// That's the case if all of the following hold:
+//   - the FME of the port has the PR feature, i.e. it reports the interface UUID,
+//   - the port is a PR slot, i.e. it has a PR region of non-zero size,
+//     see GetPRRegionSize,
+//   - the port is online, i.e. assigned to the physical function, because
+//     the FME can't program released ports, see IsOnline.
+//
+// Ports failing any of the conditions are fixed-function or unavailable
+// for PR, which isn't an error.
+func (f *IntelFpgaPort) Programmable() (bool, error) {
+	fme, err := f.GetFME()
+	if err != nil {
+		return false, errors.Wrapf(err, "%s: unable to get FME", f.GetName())
+	}
+
+	if fme.GetInterfaceUUID() == "" {
+		return false, nil
+	}
+
+	size, err := f.GetPRRegionSize()
+
+	switch {
+	case errors.Is(err, ErrNotSupported):
+		return false, nil
+	case err != nil:
+		return false, err
+	case size == 0:
+		return false, nil
+	}
+
+	return f.IsOnline()
+}
+
+// onlineAttr returns path to the port online attribute or empty string
+// if port sysfs entry is unknown.
+func (f *IntelFpgaPort) onlineAttr() string {
+	sysfs := f.GetSysFsPath()
+	if sysfs == "" {
+		return ""
+	}
+
+	return filepath.Join(sysfs, intelFpgaPortOnlineFile)
 }
 
 // PortGetInfo Retrieve information about the fpga port.
@@ -320,7 +871,7 @@ func (f *IntelFpgaPort) PortGetInfo() (ret PortInfo, err error) {
 
 	value.Argsz = uint32(unsafe.Sizeof(value))
 
-	_, err = ioctlDev(f.DevPath, FPGA_PORT_GET_INFO, uintptr(unsafe.Pointer(&value)))
+	_, err = ioctlDev(f.DevPath, FPGA_PORT_GET_INFO, unsafe.Pointer(&value))
 	if err == nil {
 		ret.Flags = value.Flags
 		ret.Regions = value.Regions
@@ -330,6 +881,33 @@ func (f *IntelFpgaPort) PortGetInfo() (ret PortInfo, err error) {
 	return
 }
 
+// GetIRQCapabilities returns the number of error and user AFU interrupts
+// the port supports, as reported by the driver in the port info. Zeros and
+// ErrNotSupported are returned if the port has no interrupt support.
+func (f *IntelFpgaPort) GetIRQCapabilities() (numErrorIRQs, numUserIRQs int, err error) {
+	var value IntelFpgaPortInfo
+
+	value.Argsz = uint32(unsafe.Sizeof(value))
+
+	if _, err = ioctlDev(f.DevPath, FPGA_PORT_GET_INFO, unsafe.Pointer(&value)); err != nil {
+		return 0, 0, errors.Wrapf(err, "%s: unable to get port info", f.GetName())
+	}
+
+	if value.Capability&FPGA_PORT_CAP_ERR_IRQ != 0 {
+		numErrorIRQs = 1
+	}
+
+	if value.Capability&FPGA_PORT_CAP_UAFU_IRQ != 0 {
+		numUserIRQs = int(value.Uafu_irqs)
+	}
+
+	if numErrorIRQs == 0 && numUserIRQs == 0 {
+		return 0, 0, errors.Wrapf(ErrNotSupported, "%s: no interrupts", f.GetName())
+	}
+
+	return numErrorIRQs, numUserIRQs, nil
+}
+
 // PortGetRegionInfo Retrieve information about the fpga port.
 // * Retrieve information about a device memory region.
 // * Caller provides struct IntelFpga_fpga_port_region_info with index value set.
@@ -341,7 +919,7 @@ func (f *IntelFpgaPort) PortGetRegionInfo(index uint32) (ret PortRegionInfo, err
 	value.Argsz = uint32(unsafe.Sizeof(value))
 	value.Index = index
 
-	_, err = ioctlDev(f.DevPath, FPGA_PORT_GET_REGION_INFO, uintptr(unsafe.Pointer(&value)))
+	_, err = ioctlDev(f.DevPath, FPGA_PORT_GET_REGION_INFO, unsafe.Pointer(&value))
 	if err == nil {
 		ret.Flags = value.Flags
 		ret.Index = value.Index
@@ -352,6 +930,79 @@ func (f *IntelFpgaPort) PortGetRegionInfo(index uint32) (ret PortRegionInfo, err
 	return
 }
 
+// GetUserClockStatus reports whether the user clock PLL is locked and the
+// measured frequencies of the high and the low user clocks in Hz. An unlocked
+// PLL means the requested user clock frequency couldn't be achieved.
+// ErrNotSupported is returned if the port has no user clock interface.
+// Measuring selects the clock for the frequency counter, so ErrReadOnly is
+// returned in read-only mode, see SetReadOnly.
+func (f *IntelFpgaPort) GetUserClockStatus() (locked bool, actualHigh, actualLow uint64, err error) {
+	return getUserClockStatus(f)
+}
+
+// AllRegions returns information about all memory regions of the port.
+// If retrieving a region fails, the regions collected so far are returned
+// along with the error.
+func (f *IntelFpgaPort) AllRegions() ([]PortRegionInfo, error) {
+	return allRegions(f)
+}
+
+// RegionMap returns information about all memory regions of the port keyed
+// by region index, e.g. FPGA_PORT_INDEX_UAFU. If retrieving a region fails,
+// the regions collected so far are returned along with the error.
+func (f *IntelFpgaPort) RegionMap() (map[uint32]PortRegionInfo, error) {
+	return regionMap(f)
+}
+
+// ReadAFUDescriptor reads the AFU Device Feature Header and AFU ID from
+// the AFU MMIO region. Unlike GetAcceleratorTypeUUID it reports what
+// the hardware actually exposes, independent of sysfs.
+func (f *IntelFpgaPort) ReadAFUDescriptor() (AFUDescriptor, error) {
+	return readAFUDescriptor(f)
+}
+
+// WalkDFH returns the features of the AFU found by following the Device
+// Feature Header list in the AFU MMIO region. See walkDFH for details.
+func (f *IntelFpgaPort) WalkDFH() ([]DFHEntry, error) {
+	return walkDFH(f)
+}
+
+// GetPRRegionSize returns size of the port's partial reconfiguration region.
+// If the driver doesn't publish it in sysfs, it's derived from the AFU region
+// info. ErrNotSupported is returned if the size can't be determined.
+func (f *IntelFpgaPort) GetPRRegionSize() (uint64, error) {
+	return getPRRegionSize(f)
+}
+
+// GetPortPowerState returns power state of the port, e.g. PortPowerStateAP6
+// when the AFU clock is gated. See getPortPowerState for details.
+// ErrNotSupported is returned if the driver doesn't publish the state.
+func (f *IntelFpgaPort) GetPortPowerState() (string, error) {
+	return getPortPowerState(f)
+}
+
+// GetDMACapabilities returns host DMA constraints of the port: address width,
+// maximum buffer size (zero if unlimited) and buffer alignment. Neither the
+// intel-fpga driver of OPAE releases up to 2.x nor the upstream DFL driver
+// report them, in FPGA_PORT_GET_INFO or in sysfs, so ErrNotSupported is
+// returned for these drivers.
+func (f *IntelFpgaPort) GetDMACapabilities() (DMACapabilities, error) {
+	return getDMACapabilities(f)
+}
+
+// SetAnnotation attaches user metadata to the device, e.g. its rack or purpose.
+// Annotations are kept in memory per device node and are shared by all
+// handles of the device. They aren't persisted across process restarts.
+// Setting an empty value removes the key.
+func (f *IntelFpgaPort) SetAnnotation(key, value string) {
+	setAnnotation(f.GetDevPath(), key, value)
+}
+
+// Annotations returns copy of the user metadata attached to the device.
+func (f *IntelFpgaPort) Annotations() map[string]string {
+	return getAnnotations(f.GetDevPath())
+}
+
 // GetDevPath returns path to device node.
 func (f *IntelFpgaPort) GetDevPath() string {
 	return f.DevPath
@@ -379,7 +1030,12 @@ func (f *IntelFpgaPort) GetName() string {
 		return f.Name
 	}
 
-	f.Name = filepath.Base(f.GetSysFsPath())
+	sysfs := f.GetSysFsPath()
+	if sysfs == "" {
+		return devNodeName(f.DevPath)
+	}
+
+	f.Name = filepath.Base(sysfs)
 
 	return f.Name
 }
@@ -400,6 +1056,18 @@ func (f *IntelFpgaPort) GetPCIDevice() (*PCIDevice, error) {
 	return f.PCIDevice, nil
 }
 
+// GetPCIIDs returns numeric PCI vendor and device IDs of this device.
+func (f *IntelFpgaPort) GetPCIIDs() (vendor, device uint16, err error) {
+	ids, err := getPCIIDs(f)
+
+	return ids.Vendor, ids.Device, err
+}
+
+// GetPCIIDsExtended returns numeric PCI IDs of this device including subsystem IDs.
+func (f *IntelFpgaPort) GetPCIIDsExtended() (PCIIDs, error) {
+	return getPCIIDs(f)
+}
+
 // GetFME returns FPGA FME device for this port.
 func (f *IntelFpgaPort) GetFME() (fme FME, err error) {
 	if f.FME != nil {
@@ -424,7 +1092,7 @@ func (f *IntelFpgaPort) GetFME() (fme FME, err error) {
 		return
 	}
 
-	realDev, err := filepath.EvalSymlinks(filepath.Join("/dev/char", dev))
+	realDev, err := resolveCharDev(dev)
 	if err != nil {
 		return
 	}
@@ -439,6 +1107,14 @@ func (f *IntelFpgaPort) GetFME() (fme FME, err error) {
 	return fme, err
 }
 
+// WhichVF returns the PCI virtual function the port is assigned to, e.g. to
+// check which VF backs the port before passing the VF through to a VM.
+// It's nil if the port is assigned to the physical function. ErrNotSupported
+// is returned if the board has no SR-IOV support.
+func (f *IntelFpgaPort) WhichVF() (*PCIDevice, error) {
+	return portVF(f, intelFpgaPortGlobPCI)
+}
+
 // GetPortID returns ID of the FPGA port within physical device.
 func (f *IntelFpgaPort) GetPortID() (uint32, error) {
 	if f.ID == "" {
@@ -453,16 +1129,38 @@ func (f *IntelFpgaPort) GetPortID() (uint32, error) {
 	return uint32(id), err
 }
 
-// GetAcceleratorTypeUUID returns AFU UUID for port.
+// GetAcceleratorTypeUUID returns AFU UUID for port. The value is cached:
+// it's read from sysfs on the first call and after the cache is invalidated
+// by PR. Use RefreshProperties or ReadAcceleratorTypeUUIDFresh to re-read it.
 func (f *IntelFpgaPort) GetAcceleratorTypeUUID() string {
-	err := f.updateProperties()
-	if err != nil || f.AFUID == "" {
-		return ""
+	if f.AFUID == "" {
+		_ = f.updateProperties()
 	}
 
 	return f.AFUID
 }
 
+// ReadAcceleratorTypeUUIDFresh re-reads AFU UUID of the port from sysfs.
+func (f *IntelFpgaPort) ReadAcceleratorTypeUUIDFresh() (string, error) {
+	f.AFUID = ""
+	if err := f.updateProperties(); err != nil {
+		return "", err
+	}
+
+	return f.AFUID, nil
+}
+
+// RefreshProperties re-reads port properties from sysfs.
+func (f *IntelFpgaPort) RefreshProperties() error {
+	return refreshProperties(f.updateProperties, &f.generation)
+}
+
+// PropertiesGeneration returns the generation of the port properties. See
+// refreshProperties for details.
+func (f *IntelFpgaPort) PropertiesGeneration() uint64 {
+	return atomic.LoadUint64(&f.generation)
+}
+
 // GetInterfaceUUID returns Interface UUID for FME.
 func (f *IntelFpgaPort) GetInterfaceUUID() (id string) {
 	fme, err := f.GetFME()
@@ -476,16 +1174,59 @@ func (f *IntelFpgaPort) GetInterfaceUUID() (id string) {
 
 // PR programs specified bitstream to port.
 func (f *IntelFpgaPort) PR(bs bitstream.File, dryRun bool) error {
-	return genericPortPR(f, bs, dryRun)
+	_, err := f.PRContext(context.Background(), bs, PROptions{DryRun: dryRun, SkipReadback: true})
+
+	return err
+}
+
+// PRWithOptions programs specified bitstream to port according to options.
+func (f *IntelFpgaPort) PRWithOptions(bs bitstream.File, opts PROptions) (PRResult, error) {
+	return f.PRContext(context.Background(), bs, opts)
+}
+
+// PRContext programs specified bitstream to port according to options.
+// The context is checked before each step that talks to the device.
+func (f *IntelFpgaPort) PRContext(ctx context.Context, bs bitstream.File, opts PROptions) (PRResult, error) {
+	res, err := genericPortPR(ctx, f, bs, opts)
+	if !opts.DryRun {
+		// The cached AFU UUID is stale whatever the outcome. The result has
+		// the fresh one if it was read back, otherwise it's re-read on demand.
+		f.AFUID = res.AFU
+	}
+
+	return res, err
+}
+
+// WaitForAFU polls the port until it reports the AFU want or ctx expires.
+// Delays between polls are defined by bo.
+func (f *IntelFpgaPort) WaitForAFU(ctx context.Context, want string, bo Backoff) error {
+	return waitForAFU(ctx, f, want, bo)
+}
+
+// PRAndVerify programs bs to the port and confirms the port then reports
+// wantAFU or, if wantAFU is empty, the AFU of the bitstream. ErrAFUMismatch
+// is returned if the port keeps reporting another AFU.
+func (f *IntelFpgaPort) PRAndVerify(ctx context.Context, bs bitstream.File, wantAFU string) error {
+	return genericPRAndVerify(ctx, f, bs, wantAFU)
 }
 
 // Update properties from sysfs.
 func (f *IntelFpgaPort) updateProperties() error {
 	fileMap := map[string]*string{
-		"afu_id": &f.AFUID,
-		"dev":    &f.Dev,
-		"id":     &f.ID,
+		"dev": &f.Dev,
+		"id":  &f.ID,
 	}
 
-	return readFilesInDirectory(fileMap, f.GetSysFsPath())
+	if err := readFilesInDirectory(fileMap, f.GetSysFsPath()); err != nil {
+		return err
+	}
+
+	afuID, err := readAFUID(f.GetSysFsPath(), DriverIntelFpga)
+	if err != nil {
+		return err
+	}
+
+	f.AFUID = afuID
+
+	return nil
 }
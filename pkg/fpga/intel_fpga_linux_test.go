@@ -0,0 +1,519 @@
+// Copyright 2023 Intel Corporation. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fpga
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+	"unsafe"
+
+	"github.com/pkg/errors"
+)
+
+func TestIntelFpgaPortSetOnline(t *testing.T) {
+	t.Run("online attribute", func(t *testing.T) {
+		fme := &testFME{} // must not be used
+		port := newTestIntelFpgaPort(t, fme, testAFUOld)
+		attr := filepath.Join(port.SysFsPath, intelFpgaPortOnlineFile)
+
+		if err := os.WriteFile(attr, []byte("1\n"), 0600); err != nil {
+			t.Fatal(err)
+		}
+
+		for _, online := range []bool{false, true, false} {
+			if err := port.SetOnline(online); err != nil {
+				t.Fatalf("unexpected error: %+v", err)
+			}
+
+			data, err := os.ReadFile(attr)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			expected := map[bool]string{true: "1", false: "0"}[online]
+			if strings.TrimSpace(string(data)) != expected {
+				t.Errorf("expected attribute value %q, but got %q", expected, data)
+			}
+
+			got, err := port.IsOnline()
+			if err != nil {
+				t.Fatalf("unexpected error: %+v", err)
+			}
+
+			if got != online {
+				t.Errorf("expected online state %t, but got %t", online, got)
+			}
+		}
+
+		if err := os.WriteFile(attr, []byte("maybe"), 0600); err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := port.IsOnline(); err == nil {
+			t.Error("no error returned for malformed attribute")
+		}
+	})
+
+	t.Run("release and assign", func(t *testing.T) {
+		var calls []string
+
+		fme := &testFME{}
+		fme.portRelease = func(id uint32) error {
+			calls = append(calls, "release")
+			return nil
+		}
+		fme.portAssign = func(id uint32) error {
+			calls = append(calls, "assign")
+			return nil
+		}
+		port := newTestIntelFpgaPort(t, fme, testAFUOld)
+
+		if online, err := port.IsOnline(); err != nil || !online {
+			t.Fatalf("expected port to be online initially, but got %t, %v", online, err)
+		}
+
+		for _, online := range []bool{false, true} {
+			if err := port.SetOnline(online); err != nil {
+				t.Fatalf("unexpected error: %+v", err)
+			}
+
+			got, err := port.IsOnline()
+			if err != nil {
+				t.Fatalf("unexpected error: %+v", err)
+			}
+
+			if got != online {
+				t.Errorf("expected online state %t, but got %t", online, got)
+			}
+		}
+
+		if strings.Join(calls, ",") != "release,assign" {
+			t.Errorf("unexpected FME calls: %v", calls)
+		}
+
+		fme.portRelease = func(id uint32) error {
+			return errors.New("device busy")
+		}
+
+		if err := port.SetOnline(false); err == nil {
+			t.Error("no error returned")
+		}
+
+		if online, _ := port.IsOnline(); !online {
+			t.Error("failed release must not take the port offline")
+		}
+	})
+}
+
+func TestIntelFpgaFMEGetMACAddresses(t *testing.T) {
+	const bmc = "spi-altera.0.auto/spi_master/spi0/spi0.0"
+
+	tcases := []struct {
+		name        string
+		files       map[string]string
+		expected    []string
+		expectedErr bool
+	}{
+		{
+			name:     "no networking",
+			expected: []string{},
+		},
+		{
+			name:     "single address",
+			files:    map[string]string{bmc + "/mac_address": "64:4c:36:00:2f:20\n"},
+			expected: []string{"64:4c:36:00:2f:20"},
+		},
+		{
+			name: "consecutive addresses",
+			files: map[string]string{
+				bmc + "/mac_address": "64:4c:36:00:2f:fe\n",
+				bmc + "/mac_count":   "4\n",
+			},
+			expected: []string{"64:4c:36:00:2f:fe", "64:4c:36:00:2f:ff", "64:4c:36:00:30:00", "64:4c:36:00:30:01"},
+		},
+		{
+			name:        "malformed address",
+			files:       map[string]string{bmc + "/mac_address": "64:4c:36:00:2f\n"},
+			expectedErr: true,
+		},
+		{
+			name:        "EUI-64 address",
+			files:       map[string]string{bmc + "/mac_address": "64:4c:36:00:2f:20:00:01\n"},
+			expectedErr: true,
+		},
+		{
+			name: "malformed count",
+			files: map[string]string{
+				bmc + "/mac_address": "64:4c:36:00:2f:20\n",
+				bmc + "/mac_count":   "many\n",
+			},
+			expectedErr: true,
+		},
+	}
+	for _, tc := range tcases {
+		t.Run(tc.name, func(t *testing.T) {
+			fme := newTestIntelFpgaFME(t, nil)
+			if err := createTestFiles(fme.PCIDevice.SysFsPath, nil, tc.files); err != nil {
+				t.Fatal(err)
+			}
+
+			macs, err := fme.GetMACAddresses()
+			if tc.expectedErr {
+				if err == nil {
+					t.Error("no error returned")
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("unexpected error: %+v", err)
+			}
+
+			if macs == nil {
+				t.Error("nil slice returned")
+			}
+
+			got := make([]string, 0, len(macs))
+			for _, mac := range macs {
+				got = append(got, mac.String())
+			}
+
+			if strings.Join(got, ",") != strings.Join(tc.expected, ",") {
+				t.Errorf("expected %v, but got %v", tc.expected, got)
+			}
+		})
+	}
+}
+
+func TestIntelFpgaFMEGetFpgaManagerStatus(t *testing.T) {
+	const mgr = "intel-fpga-fme-mgr.0/fpga_manager/fpga0"
+
+	tcases := []struct {
+		name           string
+		files          map[string]string
+		expectedState  ManagerState
+		expectedString string
+		expectedErrors []string
+		expectedErr    bool
+	}{
+		{
+			name:           "operating",
+			files:          map[string]string{mgr + "/state": "operating\n", mgr + "/status": ""},
+			expectedState:  ManagerStateOperating,
+			expectedString: "operating",
+		},
+		{
+			name:           "power up",
+			files:          map[string]string{mgr + "/state": "power up\n"},
+			expectedState:  ManagerStatePowerUp,
+			expectedString: "power-up",
+		},
+		{
+			name: "write complete error",
+			files: map[string]string{
+				mgr + "/state":  "write complete error\n",
+				mgr + "/status": "reconfig CRC error\nreconfig incompatible image\n",
+			},
+			expectedState:  ManagerStateWriteCompleteError,
+			expectedString: "write-complete-error",
+			expectedErrors: []string{"reconfig CRC error", "reconfig incompatible image"},
+		},
+		{
+			name:           "unexpected state",
+			files:          map[string]string{mgr + "/state": "dancing\n"},
+			expectedState:  ManagerStateUnknown,
+			expectedString: "unknown",
+		},
+		{
+			name:        "no state",
+			files:       map[string]string{mgr + "/name": "Intel FPGA Manager\n"},
+			expectedErr: true,
+		},
+		{
+			name:        "no manager",
+			expectedErr: true,
+		},
+	}
+	for _, tc := range tcases {
+		t.Run(tc.name, func(t *testing.T) {
+			fme := newTestIntelFpgaFME(t, nil)
+			if err := createTestFiles(fme.SysFsPath, nil, tc.files); err != nil {
+				t.Fatal(err)
+			}
+
+			status, err := fme.GetFpgaManagerStatus()
+			if tc.expectedErr {
+				if err == nil {
+					t.Error("no error returned")
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("unexpected error: %+v", err)
+			}
+
+			if status.State != tc.expectedState || status.State.String() != tc.expectedString {
+				t.Errorf("expected state %v, but got %v (%q)", tc.expectedState, status.State, status.RawState)
+			}
+
+			if status.State.IsError() != (len(tc.expectedErrors) > 0) {
+				t.Errorf("unexpected error state for %v", status.State)
+			}
+
+			if strings.Join(status.Errors, ",") != strings.Join(tc.expectedErrors, ",") {
+				t.Errorf("expected errors %v, but got %v", tc.expectedErrors, status.Errors)
+			}
+		})
+	}
+}
+
+func TestIntelFpgaPortGetUserClockStatus(t *testing.T) {
+	tcases := []struct {
+		name           string
+		files          map[string]string
+		expectedHigh   uint64
+		expectedLow    uint64
+		expectedLocked bool
+		expectedErr    bool
+		notSupported   bool
+	}{
+		{
+			name: "locked",
+			files: map[string]string{
+				"userclk/userclk_freqsts":     "0x1000000000000000\n",
+				"userclk/userclk_freqcntrcmd": "",
+				"userclk/userclk_freqcntrsts": "0x0000000100004e20\n",
+			},
+			expectedLocked: true,
+			expectedHigh:   200000000,
+			expectedLow:    200000000,
+		},
+		{
+			name: "unlocked",
+			files: map[string]string{
+				"userclk/userclk_freqsts":     "0x0000000000000000\n",
+				"userclk/userclk_freqcntrcmd": "",
+				"userclk/userclk_freqcntrsts": "0x0000000000000000\n",
+			},
+		},
+		{
+			name: "malformed status",
+			files: map[string]string{
+				"userclk/userclk_freqsts": "locked\n",
+			},
+			expectedErr: true,
+		},
+		{
+			name:         "no user clock",
+			expectedErr:  true,
+			notSupported: true,
+		},
+	}
+	for _, tc := range tcases {
+		t.Run(tc.name, func(t *testing.T) {
+			port := newTestIntelFpgaPort(t, nil, testAFUOld)
+			if err := createTestFiles(port.SysFsPath, nil, tc.files); err != nil {
+				t.Fatal(err)
+			}
+
+			locked, high, low, err := port.GetUserClockStatus()
+			if tc.expectedErr {
+				if err == nil {
+					t.Error("no error returned")
+				}
+
+				if tc.notSupported && !errors.Is(err, ErrNotSupported) {
+					t.Errorf("expected ErrNotSupported, but got %+v", err)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("unexpected error: %+v", err)
+			}
+
+			if locked != tc.expectedLocked || high != tc.expectedHigh || low != tc.expectedLow {
+				t.Errorf("expected %t %d/%d, but got %t %d/%d", tc.expectedLocked, tc.expectedHigh, tc.expectedLow, locked, high, low)
+			}
+
+			// The low clock is measured last.
+			cmd, err := os.ReadFile(filepath.Join(port.SysFsPath, "userclk/userclk_freqcntrcmd"))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if string(cmd) != "0x100000000" {
+				t.Errorf("unexpected clock selection %q", cmd)
+			}
+		})
+	}
+}
+
+func TestIntelFpgaPortGetUserClockStatusReadOnly(t *testing.T) {
+	port := newTestIntelFpgaPort(t, nil, testAFUOld)
+	if err := createTestFiles(port.SysFsPath, nil, map[string]string{
+		"userclk/userclk_freqsts":     "0x1000000000000000\n",
+		"userclk/userclk_freqcntrcmd": "",
+		"userclk/userclk_freqcntrsts": "0x0000000100004e20\n",
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	SetReadOnly(true)
+	t.Cleanup(func() { SetReadOnly(false) })
+
+	if _, _, _, err := port.GetUserClockStatus(); !errors.Is(err, ErrReadOnly) {
+		t.Errorf("expected ErrReadOnly, but got %v", err)
+	}
+
+	cmd, err := os.ReadFile(filepath.Join(port.SysFsPath, "userclk/userclk_freqcntrcmd"))
+	if err != nil || len(cmd) != 0 {
+		t.Errorf("clock selected in read-only mode: %q, %v", cmd, err)
+	}
+}
+
+func TestIntelFpgaFMEOwnsPort(t *testing.T) {
+	pf := &PCIDevice{BDF: "0000:5e:00.0"}
+	fme := &IntelFpgaFME{DevPath: "/dev/intel-fpga-fme.0", PCIDevice: pf}
+
+	tcases := []struct {
+		port        *IntelFpgaPort
+		name        string
+		expected    bool
+		expectedErr bool
+	}{
+		{
+			name:     "port of the physical function",
+			port:     &IntelFpgaPort{DevPath: "/dev/intel-fpga-port.0", PCIDevice: &PCIDevice{BDF: pf.BDF}},
+			expected: true,
+		},
+		{
+			name:     "port of a virtual function",
+			port:     &IntelFpgaPort{DevPath: "/dev/intel-fpga-port.1", PCIDevice: &PCIDevice{BDF: "0000:5e:00.1", PhysFn: pf}},
+			expected: true,
+		},
+		{
+			name: "port of another card",
+			port: &IntelFpgaPort{DevPath: "/dev/intel-fpga-port.2", PCIDevice: &PCIDevice{BDF: "0000:af:00.0"}},
+		},
+		{
+			name: "virtual function of another card",
+			port: &IntelFpgaPort{DevPath: "/dev/intel-fpga-port.3", PCIDevice: &PCIDevice{
+				BDF:    "0000:af:00.1",
+				PhysFn: &PCIDevice{BDF: "0000:af:00.0"},
+			}},
+		},
+		{
+			name:        "unresolvable port",
+			port:        &IntelFpgaPort{DevPath: "/dev/intel-fpga-port.4", SysFsPath: t.TempDir()},
+			expectedErr: true,
+		},
+	}
+	for _, tc := range tcases {
+		t.Run(tc.name, func(t *testing.T) {
+			owns, err := fme.OwnsPort(tc.port)
+			if tc.expectedErr {
+				if err == nil {
+					t.Error("no error returned")
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("unexpected error: %+v", err)
+			}
+
+			if owns != tc.expected {
+				t.Errorf("expected %t, but got %t", tc.expected, owns)
+			}
+
+			if owns {
+				return
+			}
+
+			// Release and assign must be refused before any ioctl is attempted.
+			for _, op := range []func(Port) error{fme.AssignToVF, fme.ReleaseToHost} {
+				if err := op(tc.port); err == nil || !strings.Contains(err.Error(), "doesn't belong") {
+					t.Errorf("expected ownership error, but got %v", err)
+				}
+			}
+		})
+	}
+}
+
+func TestIntelFpgaPortProgrammable(t *testing.T) {
+	tcases := []struct {
+		files         map[string]string
+		name          string
+		interfaceUUID string
+		expected      bool
+	}{
+		{
+			name:          "programmable port",
+			interfaceUUID: testInterface,
+			files:         map[string]string{"pr_region_size": "0x1000000\n", "online": "1\n"},
+			expected:      true,
+		},
+		{
+			name:          "fixed-function port",
+			interfaceUUID: testInterface,
+			files:         map[string]string{"pr_region_size": "0\n", "online": "1\n"},
+		},
+		{
+			name:          "port without PR region",
+			interfaceUUID: testInterface,
+			files:         map[string]string{"online": "1\n"},
+		},
+		{
+			name:  "FME without PR",
+			files: map[string]string{"pr_region_size": "0x1000000\n", "online": "1\n"},
+		},
+		{
+			name:          "released port",
+			interfaceUUID: testInterface,
+			files:         map[string]string{"pr_region_size": "0x1000000\n", "online": "0\n"},
+		},
+	}
+	for _, tc := range tcases {
+		t.Run(tc.name, func(t *testing.T) {
+			// the AFU region can't be queried, so only sysfs is used
+			setTestIoctl(t, func(string, uint, unsafe.Pointer) (uintptr, error) {
+				return 0, syscall.ENOTTY
+			})
+
+			port := newTestIntelFpgaPort(t, &testFME{interfaceUUID: tc.interfaceUUID}, testAFUOld)
+			if err := createTestFiles(port.SysFsPath, nil, tc.files); err != nil {
+				t.Fatal(err)
+			}
+
+			programmable, err := port.Programmable()
+			if err != nil {
+				t.Fatalf("unexpected error: %+v", err)
+			}
+
+			if programmable != tc.expected {
+				t.Errorf("expected %t, but got %t", tc.expected, programmable)
+			}
+		})
+	}
+}
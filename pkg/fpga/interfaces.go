@@ -15,7 +15,9 @@
 package fpga
 
 import (
+	"context"
 	"io"
+	"time"
 
 	"github.com/intel/intel-device-plugins-for-kubernetes/pkg/fpga/bitstream"
 )
@@ -29,6 +31,20 @@ type commonFpgaAPI interface {
 	// CheckExtension Check whether an extension is supported.
 	// * Return: 0 if not supported, otherwise the extension is supported.
 	CheckExtension() (int, error)
+	// PingDevice measures round-trip latency of a cheap read-only ioctl
+	PingDevice() (time.Duration, error)
+	// IsVirtualFunction reports whether the device belongs to a PCI virtual function
+	IsVirtualFunction() (bool, error)
+	// GetDeviceType returns normalized lowercase device type reported by sysfs, e.g. "fme" or "port"
+	GetDeviceType() (string, error)
+	// GetStableID returns device ID derived from its PCI address, stable across reboots
+	GetStableID() (string, error)
+	// SetAnnotation attaches in-memory user metadata to the device, empty value removes the key
+	SetAnnotation(key, value string)
+	// Annotations returns user metadata attached to the device
+	Annotations() map[string]string
+	// PropertiesGeneration returns number of RefreshProperties calls, it changes whenever properties may have changed
+	PropertiesGeneration() uint64
 
 	// Interfaces for device discovery and accessing properties
 
@@ -40,6 +56,10 @@ type commonFpgaAPI interface {
 	GetName() string
 	// GetPCIDevice returns PCIDevice for this device
 	GetPCIDevice() (*PCIDevice, error)
+	// GetPCIIDs returns numeric PCI vendor and device IDs of this device
+	GetPCIIDs() (vendor, device uint16, err error)
+	// GetPCIIDsExtended returns numeric PCI IDs of this device including subsystem IDs
+	GetPCIIDsExtended() (PCIIDs, error)
 }
 
 // FME represent interfaces provided by management interface of FPGA.
@@ -53,6 +73,9 @@ type FME interface {
 	//   some errors during PR, under this case, the user can fetch HW error info
 	//   from the status of FME's fpga manager.
 	PortPR(uint32, []byte) error
+	// PortPRContext is the same as PortPR, but checks for context cancellation
+	// before submitting the request to the driver
+	PortPRContext(context.Context, uint32, []byte) error
 	// PortRelease releases the port per Port ID provided by caller.
 	// * Return: 0 on success, -errno on failure.
 	PortRelease(uint32) error
@@ -72,10 +95,26 @@ type FME interface {
 	GetInterfaceUUID() string
 	// GetSocketID returns physical socket number, in case NUMA enumeration fails
 	GetSocketID() (uint32, error)
+	// GetNUMANode returns NUMA node of the FPGA, falling back to socket id, or -1 if it's unknown
+	GetNUMANode() int
 	// GetBitstreamID returns FME bitstream id
 	GetBitstreamID() string
 	// GetBitstreamMetadata returns FME bitstream metadata
 	GetBitstreamMetadata() string
+	// GetModelName returns model name of the FPGA card
+	GetModelName() string
+	// GetPowerInfo returns power consumption and thresholds of the FPGA
+	GetPowerInfo() (PowerInfo, error)
+	// RefreshProperties re-reads FME properties from sysfs
+	RefreshProperties() error
+	// GetFmeErrors returns error registers of the FME
+	GetFmeErrors() (DeviceErrors, error)
+	// Healthy reports whether the board is healthy and, if it isn't, the reasons
+	Healthy() (bool, []string)
+	// ClearFmeErrors clears errors latched by the FME
+	ClearFmeErrors() error
+	// WaitForBitstreamID polls FME until it reports the desired bitstream id or context expires, polling with the given backoff
+	WaitForBitstreamID(context.Context, string, Backoff) error
 	// GetPort returns FpgaPort of the desired FPGA port index within that FME
 	// GetPort(uint32) (FpgaPort, error)
 }
@@ -111,12 +150,31 @@ type Port interface {
 	GetFME() (FME, error)
 	// GetPortID returns ID of the FPGA port within physical device
 	GetPortID() (uint32, error)
-	// GetAcceleratorTypeUUID returns AFU UUID for port
+	// GetAcceleratorTypeUUID returns cached AFU UUID for port
 	GetAcceleratorTypeUUID() string
+	// ReadAcceleratorTypeUUIDFresh re-reads AFU UUID for port from sysfs
+	ReadAcceleratorTypeUUIDFresh() (string, error)
+	// RefreshProperties re-reads port properties from sysfs
+	RefreshProperties() error
 	// InterfaceUUID returns Interface UUID for FME
 	GetInterfaceUUID() string
+	// GetResetCount returns how many times the port has been reset
+	GetResetCount() (uint64, error)
+	// GetErrors returns error registers of the port
+	GetErrors() (DeviceErrors, error)
+	// ClearErrors clears errors latched by the port
+	ClearErrors() error
 	// PR programs specified bitstream to port
 	PR(bitstream.File, bool) error
+	// PRWithOptions programs specified bitstream to port according to options
+	PRWithOptions(bitstream.File, PROptions) (PRResult, error)
+	// PRContext programs specified bitstream to port according to options
+	// and gives up as soon as the context is done
+	PRContext(context.Context, bitstream.File, PROptions) (PRResult, error)
+	// PRAndVerify programs specified bitstream to port and confirms the port reports the expected AFU
+	PRAndVerify(context.Context, bitstream.File, string) error
+	// WaitForAFU polls port until it reports the desired AFU or context expires, polling with the given backoff
+	WaitForAFU(context.Context, string, Backoff) error
 }
 
 // PortInfo is a unified port info between drivers.
@@ -126,6 +184,16 @@ type PortInfo struct {
 	Umsgs   uint32
 }
 
+// DMACapabilities describes host DMA constraints of the port.
+type DMACapabilities struct {
+	// AddressWidth is the number of address bits the AFU can use for DMA.
+	AddressWidth uint
+	// MaxBufferSize is the largest buffer that can be mapped at once, in bytes.
+	MaxBufferSize uint64
+	// Alignment is the required alignment of buffer address and length, in bytes.
+	Alignment uint64
+}
+
 // PortRegionInfo is a unified Port Region info between drivers.
 type PortRegionInfo struct {
 	Flags  uint32
@@ -133,3 +201,70 @@ type PortRegionInfo struct {
 	Size   uint64
 	Offset uint64
 }
+
+// Readable reports whether the region can be read. The flag bits of the
+// intel-fpga and the dfl drivers are the same.
+func (r PortRegionInfo) Readable() bool {
+	return r.Flags&FPGA_REGION_READ != 0
+}
+
+// Writable reports whether the region can be written.
+func (r PortRegionInfo) Writable() bool {
+	return r.Flags&FPGA_REGION_WRITE != 0
+}
+
+// Mmappable reports whether the region can be memory mapped.
+func (r PortRegionInfo) Mmappable() bool {
+	return r.Flags&FPGA_REGION_MMAP != 0
+}
+
+// PROptions holds optional parameters of partial reconfiguration.
+type PROptions struct {
+	// DryRun does all the checks, but doesn't program the bitstream.
+	DryRun bool
+	// SkipReadback skips reading AFU UUID from the port after programming.
+	// On some boards that sysfs read is slow or unreliable right after PR,
+	// so callers that don't use the result can avoid the extra round-trip.
+	// PRResult.AFU is left empty in that case.
+	SkipReadback bool
+	// RequirePowerHeadroom refuses programming with
+	// ErrInsufficientPowerHeadroom unless the board can supply the power
+	// the AFU declares on top of its current consumption, see
+	// checkPowerHeadroom for the details.
+	RequirePowerHeadroom bool
+	// VerifyLoadedCRC compares the CRC the hardware reports for the image
+	// loaded to the port after programming with the CRC of the bitstream.
+	// ErrPostPRCRCMismatch is returned if they differ, the port stays
+	// programmed then. The check is skipped if the board doesn't report
+	// the CRC, see PRResult.CRCVerified.
+	VerifyLoadedCRC bool
+	// Rollback, if set, enables best-effort rollback: the AFU loaded to the
+	// port is recorded before programming and, if programming fails, the
+	// port is reprogrammed with the bitstream Rollback returns for that AFU.
+	// Rollback is only possible if the previous bitstream is still available,
+	// otherwise the resolver should return an error and the port is left
+	// as the failed programming left it.
+	Rollback RollbackResolver
+	// Backoff defines retries of reading AFU UUID after programming.
+	Backoff Backoff
+	// Retry defines retries of programming that fails with a transient
+	// error, e.g. EBUSY. Retry.Steps is the number of retries, zero
+	// disables them. Other failures aren't retried.
+	Retry Backoff
+}
+
+// RollbackResolver returns bitstream for the given FME interface UUID and AFU UUID.
+type RollbackResolver func(interfaceUUID, afuUUID string) (bitstream.File, error)
+
+// PRResult describes the outcome of partial reconfiguration.
+type PRResult struct {
+	// AFU is the AFU UUID reported by the port after programming.
+	AFU string
+	// RolledBack is true if PR failed and the port has been reprogrammed
+	// with the AFU it had before.
+	RolledBack bool
+	// CRCVerified is true if PROptions.VerifyLoadedCRC was set and the CRC
+	// of the loaded image matched. It's false if the board doesn't report
+	// the CRC and the check was skipped.
+	CRCVerified bool
+}
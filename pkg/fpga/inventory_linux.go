@@ -0,0 +1,257 @@
+// Copyright 2023 Intel Corporation. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fpga
+
+import (
+	"context"
+	"sort"
+
+	"github.com/pkg/errors"
+)
+
+var (
+	// openFME and openPort open devices found during enumeration.
+	openFME  = NewFME
+	openPort = NewPort
+)
+
+// Inventory is a snapshot of all FPGA devices of the host.
+// Errors met while collecting an item are recorded in the item's Errors
+// field keyed by the name of the field that couldn't be collected.
+type Inventory struct {
+	// Errors has the devices which couldn't be opened at all.
+	Errors map[string]string `json:"errors,omitempty"`
+	Boards []*InventoryBoard `json:"boards"`
+}
+
+// InventoryBoard describes FPGA card, i.e. physical PCI function.
+type InventoryBoard struct {
+	Errors     map[string]string `json:"errors,omitempty"`
+	PCIAddress string            `json:"pciAddress"`
+	Vendor     string            `json:"vendor"`
+	Device     string            `json:"device"`
+	Model      string            `json:"model,omitempty"`
+	NUMA       string            `json:"numa,omitempty"`
+	FMEs       []*InventoryFME   `json:"fmes"`
+}
+
+// InventoryFME describes FME of the board.
+type InventoryFME struct {
+	Errors            map[string]string `json:"errors,omitempty"`
+	Annotations       map[string]string `json:"annotations,omitempty"`
+	Power             *PowerInfo        `json:"power,omitempty"`
+	SocketID          *uint32           `json:"socketID,omitempty"`
+	Name              string            `json:"name"`
+	DevPath           string            `json:"devPath"`
+	InterfaceUUID     string            `json:"interfaceUUID"`
+	BitstreamID       string            `json:"bitstreamID"`
+	BitstreamMetadata string            `json:"bitstreamMetadata"`
+	Ports             []*InventoryPort  `json:"ports"`
+	PortsNum          int               `json:"portsNum"`
+}
+
+// InventoryPort describes port of the FME and the AFU programmed to it.
+type InventoryPort struct {
+	Errors      map[string]string `json:"errors,omitempty"`
+	Annotations map[string]string `json:"annotations,omitempty"`
+	ID          *uint32           `json:"id,omitempty"`
+	ResetCount  *uint64           `json:"resetCount,omitempty"`
+	Name        string            `json:"name"`
+	DevPath     string            `json:"devPath"`
+	PCIAddress  string            `json:"pciAddress,omitempty"`
+	AFU         string            `json:"afu"`
+}
+
+// recordError saves error of the field in errs, allocating the map if needed.
+func recordError(errs *map[string]string, field string, err error) {
+	if *errs == nil {
+		*errs = map[string]string{}
+	}
+
+	(*errs)[field] = err.Error()
+}
+
+func collectFME(fme FME) *InventoryFME {
+	item := &InventoryFME{
+		Name:              fme.GetName(),
+		DevPath:           fme.GetDevPath(),
+		InterfaceUUID:     fme.GetInterfaceUUID(),
+		BitstreamID:       fme.GetBitstreamID(),
+		BitstreamMetadata: fme.GetBitstreamMetadata(),
+		PortsNum:          fme.GetPortsNum(),
+		Annotations:       fme.Annotations(),
+		Ports:             []*InventoryPort{},
+	}
+
+	if id, err := fme.GetSocketID(); err == nil {
+		item.SocketID = &id
+	} else {
+		recordError(&item.Errors, "socketID", err)
+	}
+
+	if power, err := fme.GetPowerInfo(); err == nil {
+		item.Power = &power
+	} else {
+		recordError(&item.Errors, "power", err)
+	}
+
+	return item
+}
+
+func collectPort(port Port) *InventoryPort {
+	item := &InventoryPort{
+		Name:        port.GetName(),
+		DevPath:     port.GetDevPath(),
+		AFU:         port.GetAcceleratorTypeUUID(),
+		Annotations: port.Annotations(),
+	}
+
+	if id, err := port.GetPortID(); err == nil {
+		item.ID = &id
+	} else {
+		recordError(&item.Errors, "id", err)
+	}
+
+	if count, err := port.GetResetCount(); err == nil {
+		item.ResetCount = &count
+	} else {
+		recordError(&item.Errors, "resetCount", err)
+	}
+
+	if pci, err := port.GetPCIDevice(); err == nil {
+		item.PCIAddress = pci.BDF
+	} else {
+		recordError(&item.Errors, "pciAddress", err)
+	}
+
+	return item
+}
+
+// boardOf returns board the FME belongs to, adding it to boards if needed.
+func boardOf(boards map[string]*InventoryBoard, fme FME) *InventoryBoard {
+	pci, err := fme.GetPCIDevice()
+	if err != nil {
+		board := &InventoryBoard{PCIAddress: "unknown:" + fme.GetName()}
+		recordError(&board.Errors, "pciAddress", err)
+		boards[board.PCIAddress] = board
+
+		return board
+	}
+
+	if pci.PhysFn != nil {
+		pci = pci.PhysFn
+	}
+
+	if board, ok := boards[pci.BDF]; ok {
+		return board
+	}
+
+	board := &InventoryBoard{
+		PCIAddress: pci.BDF,
+		Vendor:     pci.Vendor,
+		Device:     pci.Device,
+		Model:      fme.GetModelName(),
+		NUMA:       pci.NUMA,
+	}
+	boards[pci.BDF] = board
+
+	return board
+}
+
+// GetInventory collects information about all FPGA devices of the host.
+// It's the same as GetInventoryContext with the background context.
+func GetInventory() (*Inventory, error) {
+	return GetInventoryContext(context.Background())
+}
+
+// GetInventoryContext collects information about all FPGA devices of the host.
+// Failures to collect particular devices or fields are recorded in the
+// inventory and don't stop the collection. An error is only returned if
+// the devices can't be enumerated or the context is done.
+func GetInventoryContext(ctx context.Context) (*Inventory, error) {
+	fmeNames, portNames, err := ListFpgaDevicesContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	inv := &Inventory{Boards: []*InventoryBoard{}}
+	boards := map[string]*InventoryBoard{}
+	fmes := map[string]*InventoryFME{}
+
+	for _, name := range fmeNames {
+		if err = ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		if err = inv.addFME(boards, fmes, name); err != nil {
+			recordError(&inv.Errors, name, err)
+		}
+	}
+
+	for _, name := range portNames {
+		if err = ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		if err = inv.addPort(fmes, name); err != nil {
+			recordError(&inv.Errors, name, err)
+		}
+	}
+
+	for _, board := range boards {
+		inv.Boards = append(inv.Boards, board)
+	}
+
+	sort.Slice(inv.Boards, func(i, j int) bool { return inv.Boards[i].PCIAddress < inv.Boards[j].PCIAddress })
+
+	return inv, nil
+}
+
+func (inv *Inventory) addFME(boards map[string]*InventoryBoard, fmes map[string]*InventoryFME, name string) error {
+	fme, err := openFME(name)
+	if err != nil {
+		return err
+	}
+	defer fme.Close()
+
+	item := collectFME(fme)
+	board := boardOf(boards, fme)
+	board.FMEs = append(board.FMEs, item)
+	fmes[item.DevPath] = item
+
+	return nil
+}
+
+func (inv *Inventory) addPort(fmes map[string]*InventoryFME, name string) error {
+	port, err := openPort(name)
+	if err != nil {
+		return err
+	}
+	defer port.Close()
+
+	fme, err := port.GetFME()
+	if err != nil {
+		return err
+	}
+
+	parent, ok := fmes[fme.GetDevPath()]
+	if !ok {
+		return errors.Errorf("FME %s of the port is not in the inventory", fme.GetDevPath())
+	}
+
+	parent.Ports = append(parent.Ports, collectPort(port))
+
+	return nil
+}
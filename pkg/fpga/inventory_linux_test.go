@@ -0,0 +1,174 @@
+// Copyright 2023 Intel Corporation. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fpga
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/pkg/errors"
+)
+
+// setTestOpeners replaces device openers used by enumeration for the duration of the test.
+func setTestOpeners(t *testing.T, fmes map[string]FME, ports map[string]Port) {
+	t.Helper()
+
+	origFME, origPort := openFME, openPort
+
+	openFME = func(name string) (FME, error) {
+		if fme, ok := fmes[name]; ok {
+			return fme, nil
+		}
+
+		return nil, errors.Errorf("unable to open %s", name)
+	}
+	openPort = func(name string) (Port, error) {
+		if port, ok := ports[name]; ok {
+			return port, nil
+		}
+
+		return nil, errors.Errorf("unable to open %s", name)
+	}
+
+	t.Cleanup(func() { openFME, openPort = origFME, origPort })
+}
+
+func TestGetInventory(t *testing.T) {
+	sysfs := setTestSysfsRoot(t)
+
+	devices := []string{"intel-fpga-fme.0", "intel-fpga-fme.1", "intel-fpga-port.0", "intel-fpga-port.1", "eeprom.0"}
+	for _, dev := range devices {
+		if err := createTestFiles(sysfs, []string{"bus/platform/devices/" + dev}, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	fme := newTestIntelFpgaFME(t, map[string]string{
+		"bitstream_id":        testBitstreamA,
+		"ports_num":           "1",
+		"socket_id":           "0",
+		"pr/interface_id":     testInterface,
+		"power_mgmt/consumed": "31",
+	})
+	fme.PCIDevice.Vendor = "0x8086"
+	fme.PCIDevice.Device = "0x09c4"
+
+	port := newTestIntelFpgaPort(t, fme, testAFUOld)
+	port.PCIDevice = fme.PCIDevice
+
+	orphan := newTestIntelFpgaPort(t, &IntelFpgaFME{DevPath: "/dev/intel-fpga-fme.7"}, testAFUNew)
+
+	setTestOpeners(t,
+		map[string]FME{"intel-fpga-fme.0": fme},
+		map[string]Port{"intel-fpga-port.0": port, "intel-fpga-port.1": orphan})
+
+	inv, err := GetInventory()
+	if err != nil {
+		t.Fatalf("unexpected error: %+v", err)
+	}
+
+	if len(inv.Boards) != 1 {
+		t.Fatalf("expected 1 board, but got %d", len(inv.Boards))
+	}
+
+	board := inv.Boards[0]
+	if board.PCIAddress != testBDF || board.Model != "Intel PAC with Arria 10 GX FPGA" || len(board.FMEs) != 1 {
+		t.Fatalf("unexpected board %+v", board)
+	}
+
+	fmeItem := board.FMEs[0]
+	if fmeItem.BitstreamID != testBitstreamA || fmeItem.InterfaceUUID != testInterface || fmeItem.PortsNum != 1 {
+		t.Errorf("unexpected FME %+v", fmeItem)
+	}
+
+	if fmeItem.SocketID == nil || *fmeItem.SocketID != 0 || fmeItem.Power == nil || fmeItem.Power.Consumed != 31 {
+		t.Errorf("unexpected FME health fields %+v", fmeItem)
+	}
+
+	if len(fmeItem.Ports) != 1 {
+		t.Fatalf("expected 1 port, but got %d", len(fmeItem.Ports))
+	}
+
+	portItem := fmeItem.Ports[0]
+	if portItem.AFU != testAFUOld || portItem.ID == nil || *portItem.ID != 0 || portItem.PCIAddress != testBDF || len(portItem.Errors) != 0 {
+		t.Errorf("unexpected port %+v", portItem)
+	}
+
+	for _, name := range []string{"intel-fpga-fme.1", "intel-fpga-port.1"} {
+		if _, ok := inv.Errors[name]; !ok {
+			t.Errorf("expected error recorded for %s, but got %v", name, inv.Errors)
+		}
+	}
+
+	if len(inv.Errors) != 2 {
+		t.Errorf("unexpected errors %v", inv.Errors)
+	}
+}
+
+func TestGetInventoryAnnotations(t *testing.T) {
+	sysfs := setTestSysfsRoot(t)
+
+	if err := createTestFiles(sysfs, []string{"bus/platform/devices/intel-fpga-fme.0", "bus/platform/devices/intel-fpga-port.0"}, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	fme := newTestIntelFpgaFME(t, map[string]string{"ports_num": "1", "pr/interface_id": testInterface})
+	port := newTestIntelFpgaPort(t, fme, testAFUOld)
+	port.PCIDevice = fme.PCIDevice
+
+	clearTestAnnotations(t, fme.GetDevPath(), port.GetDevPath())
+	fme.SetAnnotation("rack", "r12")
+	port.SetAnnotation("purpose", "inference")
+
+	setTestOpeners(t, map[string]FME{"intel-fpga-fme.0": fme}, map[string]Port{"intel-fpga-port.0": port})
+
+	inv, err := GetInventory()
+	if err != nil {
+		t.Fatalf("unexpected error: %+v", err)
+	}
+
+	data, err := json.Marshal(inv)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, expected := range []string{`"annotations":{"rack":"r12"}`, `"annotations":{"purpose":"inference"}`} {
+		if !strings.Contains(string(data), expected) {
+			t.Errorf("expected %s in %s", expected, data)
+		}
+	}
+}
+
+func TestGetInventoryContext(t *testing.T) {
+	setTestSysfsRoot(t)
+
+	if _, err := GetInventory(); err == nil {
+		t.Error("no error returned without platform devices")
+	}
+
+	sysfs := setTestSysfsRoot(t)
+	if err := createTestFiles(sysfs, []string{"bus/platform/devices/intel-fpga-fme.0"}, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if _, err := GetInventoryContext(ctx); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context error, but got %+v", err)
+	}
+}
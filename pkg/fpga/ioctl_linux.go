@@ -15,8 +15,10 @@
 package fpga
 
 import (
+	"context"
 	"os"
 	"syscall"
+	"unsafe"
 )
 
 // TODO(rojkov): drop this function when it lands in x/sys/unix.
@@ -31,13 +33,44 @@ func ioctl(fd uintptr, req uint, arg uintptr) (uintptr, error) {
 	return ret, nil
 }
 
+// ioctlDevice opens device only for single operation and submits the request
+// unless the context is done. arg points to the request's argument or is nil.
+// Tests replace it to simulate driver responses without real devices.
+var ioctlDevice = ioctlDevSyscall
+
 // Same as above, but open device only for single operation.
-func ioctlDev(dev string, req uint, arg uintptr) (ret uintptr, err error) {
+func ioctlDev(dev string, req uint, arg unsafe.Pointer) (ret uintptr, err error) {
+	return ioctlDevContext(context.Background(), dev, req, arg)
+}
+
+// Same as ioctlDev, but don't submit the request if the context is done.
+// Once submitted, the request can't be interrupted and runs to completion.
+// Errors caused by removal of the device are marked with ErrDeviceGone.
+func ioctlDevContext(ctx context.Context, dev string, req uint, arg unsafe.Pointer) (ret uintptr, err error) {
+	ret, err = ioctlDevice(ctx, dev, req, arg)
+
+	return ret, classifyDeviceError(err)
+}
+
+func ioctlDevSyscall(ctx context.Context, dev string, req uint, arg unsafe.Pointer) (ret uintptr, err error) {
+	if err = ctx.Err(); err != nil {
+		return
+	}
+
 	f, err := os.OpenFile(dev, os.O_RDWR, 0644)
+	if os.IsNotExist(err) {
+		// The device node is removed along with the device.
+		return 0, &deviceGoneError{err: err}
+	}
+
 	if err != nil {
 		return
 	}
 	defer f.Close()
 
-	return ioctl(f.Fd(), req, arg)
+	if err = ctx.Err(); err != nil {
+		return
+	}
+
+	return ioctl(f.Fd(), req, uintptr(arg))
 }
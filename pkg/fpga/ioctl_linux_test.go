@@ -0,0 +1,389 @@
+// Copyright 2023 Intel Corporation. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fpga
+
+import (
+	"context"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+	"unsafe"
+
+	"github.com/pkg/errors"
+)
+
+// setTestIoctl makes all ioctls be handled by fake instead of the driver.
+func setTestIoctl(t *testing.T, fake func(dev string, req uint, arg unsafe.Pointer) (uintptr, error)) {
+	t.Helper()
+
+	origIoctl := ioctlDevice
+
+	ioctlDevice = func(ctx context.Context, dev string, req uint, arg unsafe.Pointer) (uintptr, error) {
+		if err := ctx.Err(); err != nil {
+			return 0, err
+		}
+
+		return fake(dev, req, arg)
+	}
+
+	t.Cleanup(func() { ioctlDevice = origIoctl })
+}
+
+func TestPortPRIoctl(t *testing.T) {
+	tcases := []struct {
+		ioctlErr       error
+		name           string
+		expectedErrStr string
+		expectedErr    bool
+	}{
+		{
+			name: "success",
+		},
+		{
+			name:           "EIO",
+			ioctlErr:       syscall.EIO,
+			expectedErr:    true,
+			expectedErrStr: syscall.EIO.Error(),
+		},
+		{
+			name:           "other errno",
+			ioctlErr:       syscall.EBUSY,
+			expectedErr:    true,
+			expectedErrStr: syscall.EBUSY.Error(),
+		},
+	}
+	for _, tc := range tcases {
+		t.Run(tc.name, func(t *testing.T) {
+			fme := newTestIntelFpgaFME(t, nil)
+			data := []byte("bitstream")
+
+			setTestIoctl(t, func(dev string, req uint, arg unsafe.Pointer) (uintptr, error) {
+				if dev != fme.DevPath || req != FPGA_FME_PORT_PR {
+					t.Errorf("unexpected ioctl %#x on %s", req, dev)
+				}
+
+				pr := (*IntelFpgaFmePortPR)(arg)
+				if pr.Argsz != uint32(unsafe.Sizeof(*pr)) || pr.Port_id != 1 || pr.Buffer_size != uint32(len(data)) {
+					t.Errorf("unexpected PR request %+v", *pr)
+				}
+
+				return 0, tc.ioctlErr
+			})
+
+			err := fme.PortPR(1, data)
+			if !tc.expectedErr {
+				if err != nil {
+					t.Errorf("unexpected error: %+v", err)
+				}
+
+				return
+			}
+
+			if !errors.Is(err, tc.ioctlErr) {
+				t.Errorf("expected %v, but got %v", tc.ioctlErr, err)
+			}
+
+			if !strings.HasPrefix(err.Error(), tc.expectedErrStr) {
+				t.Errorf("expected error %q, but got %q", tc.expectedErrStr, err)
+			}
+		})
+	}
+}
+
+func TestPortGetRegionInfoIoctl(t *testing.T) {
+	expected := PortRegionInfo{Index: FPGA_PORT_INDEX_STP, Flags: 3, Size: 0x1000, Offset: 0x40000}
+
+	setTestIoctl(t, func(dev string, req uint, arg unsafe.Pointer) (uintptr, error) {
+		switch req {
+		case FPGA_PORT_GET_REGION_INFO:
+			info := (*IntelFpgaPortRegionInfo)(arg)
+			if info.Argsz != uint32(unsafe.Sizeof(*info)) || info.Index != expected.Index {
+				return 0, syscall.EINVAL
+			}
+
+			info.Flags, info.Size, info.Offset = expected.Flags, expected.Size, expected.Offset
+		case DFL_FPGA_PORT_GET_REGION_INFO:
+			info := (*DflFpgaPortRegionInfo)(arg)
+			if info.Argsz != uint32(unsafe.Sizeof(*info)) || info.Index != expected.Index {
+				return 0, syscall.EINVAL
+			}
+
+			info.Flags, info.Size, info.Offset = expected.Flags, expected.Size, expected.Offset
+		default:
+			return 0, syscall.ENOTTY
+		}
+
+		return 0, nil
+	})
+
+	for _, port := range []Port{&IntelFpgaPort{DevPath: "/dev/intel-fpga-port.0"}, &DflPort{DevPath: "/dev/dfl-port.0"}} {
+		got, err := port.PortGetRegionInfo(expected.Index)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %+v", port.GetDevPath(), err)
+		}
+
+		if got != expected {
+			t.Errorf("%s: expected %+v, but got %+v", port.GetDevPath(), expected, got)
+		}
+
+		if _, err = port.PortGetRegionInfo(FPGA_PORT_INDEX_UAFU); !errors.Is(err, syscall.EINVAL) {
+			t.Errorf("%s: expected EINVAL, but got %v", port.GetDevPath(), err)
+		}
+	}
+}
+
+func TestPortResetIoctl(t *testing.T) {
+	var resetErr error
+
+	setTestIoctl(t, func(dev string, req uint, arg unsafe.Pointer) (uintptr, error) {
+		if req != FPGA_PORT_RESET || arg != nil {
+			t.Errorf("unexpected ioctl %#x with argument %v", req, arg)
+		}
+
+		return 0, resetErr
+	})
+
+	port := &IntelFpgaPort{DevPath: "/dev/intel-fpga-port.0", SysFsPath: t.TempDir()}
+
+	if err := port.PortReset(); err != nil {
+		t.Errorf("unexpected error: %+v", err)
+	}
+
+	resetErr = syscall.EIO
+	if err := port.PortReset(); !errors.Is(err, syscall.EIO) {
+		t.Errorf("expected EIO, but got %v", err)
+	}
+
+	if count, err := port.GetResetCount(); err != nil || count != 1 {
+		t.Errorf("expected only the successful reset to be counted, but got %d (%v)", count, err)
+	}
+}
+
+func TestGetIRQCapabilities(t *testing.T) {
+	tcases := []struct {
+		ioctlErr         error
+		expectedErr      error
+		name             string
+		capability       uint32
+		uafuIRQs         uint32
+		expectedErrIRQs  int
+		expectedUserIRQs int
+	}{
+		{
+			name:             "error and user interrupts",
+			capability:       FPGA_PORT_CAP_ERR_IRQ | FPGA_PORT_CAP_UAFU_IRQ,
+			uafuIRQs:         4,
+			expectedErrIRQs:  1,
+			expectedUserIRQs: 4,
+		},
+		{
+			name:            "error interrupt only",
+			capability:      FPGA_PORT_CAP_ERR_IRQ,
+			uafuIRQs:        4,
+			expectedErrIRQs: 1,
+		},
+		{
+			name:             "user interrupts only",
+			capability:       FPGA_PORT_CAP_UAFU_IRQ,
+			uafuIRQs:         2,
+			expectedUserIRQs: 2,
+		},
+		{
+			name:        "no interrupts",
+			uafuIRQs:    4,
+			expectedErr: ErrNotSupported,
+		},
+		{
+			name:        "ioctl failure",
+			capability:  FPGA_PORT_CAP_ERR_IRQ,
+			ioctlErr:    syscall.EINVAL,
+			expectedErr: syscall.EINVAL,
+		},
+	}
+	for _, tc := range tcases {
+		t.Run(tc.name, func(t *testing.T) {
+			setTestIoctl(t, func(dev string, req uint, arg unsafe.Pointer) (uintptr, error) {
+				if req != FPGA_PORT_GET_INFO {
+					t.Fatalf("unexpected ioctl %#x", req)
+				}
+
+				info := (*IntelFpgaPortInfo)(arg)
+				if info.Argsz != uint32(unsafe.Sizeof(*info)) {
+					t.Errorf("unexpected argsz %d", info.Argsz)
+				}
+
+				info.Flags = 0
+				info.Capability = tc.capability
+				info.Regions = 2
+				info.Uafu_irqs = tc.uafuIRQs
+
+				return 0, tc.ioctlErr
+			})
+
+			port := &IntelFpgaPort{DevPath: "/dev/intel-fpga-port.0", SysFsPath: t.TempDir()}
+
+			numErr, numUser, err := port.GetIRQCapabilities()
+			if tc.expectedErr != nil {
+				if !errors.Is(err, tc.expectedErr) {
+					t.Errorf("expected error %v, but got %v", tc.expectedErr, err)
+				}
+
+				if numErr != 0 || numUser != 0 {
+					t.Errorf("expected zeros on error, but got %d/%d", numErr, numUser)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("unexpected error: %+v", err)
+			}
+
+			if numErr != tc.expectedErrIRQs || numUser != tc.expectedUserIRQs {
+				t.Errorf("expected %d/%d interrupts, but got %d/%d", tc.expectedErrIRQs, tc.expectedUserIRQs, numErr, numUser)
+			}
+		})
+	}
+}
+
+func TestAFUResetIoctl(t *testing.T) {
+	ioctls := 0
+
+	setTestIoctl(t, func(dev string, req uint, arg unsafe.Pointer) (uintptr, error) {
+		ioctls++
+
+		return 0, nil
+	})
+
+	port := &IntelFpgaPort{DevPath: "/dev/intel-fpga-port.0", SysFsPath: t.TempDir()}
+
+	if err := port.AFUReset(); !errors.Is(err, ErrNotSupported) {
+		t.Errorf("expected ErrNotSupported, but got %v", err)
+	}
+
+	// AFU-only reset must not fall back to the full port reset
+	if ioctls != 0 {
+		t.Errorf("expected no ioctls, but got %d", ioctls)
+	}
+
+	if count, err := port.GetResetCount(); err != nil || count != 0 {
+		t.Errorf("expected no port resets, but got %d (%v)", count, err)
+	}
+}
+
+func TestIoctlDeviceGone(t *testing.T) {
+	for _, errno := range []syscall.Errno{syscall.ENODEV, syscall.ENXIO} {
+		setTestIoctl(t, func(dev string, req uint, arg unsafe.Pointer) (uintptr, error) {
+			return 0, errno
+		})
+
+		fme := newTestIntelFpgaFME(t, nil)
+		port := &IntelFpgaPort{DevPath: "/dev/intel-fpga-port.0", SysFsPath: t.TempDir()}
+
+		_, infoErr := port.PortGetInfo()
+
+		for name, err := range map[string]error{
+			"PortPR":      fme.PortPR(0, []byte("bitstream")),
+			"PortReset":   port.PortReset(),
+			"PortGetInfo": infoErr,
+		} {
+			if !errors.Is(err, ErrDeviceGone) || !errors.Is(err, errno) {
+				t.Errorf("%s: expected ErrDeviceGone caused by %v, but got %v", name, errno, err)
+			}
+		}
+	}
+
+	setTestIoctl(t, func(dev string, req uint, arg unsafe.Pointer) (uintptr, error) {
+		return 0, syscall.EIO
+	})
+
+	if err := (&IntelFpgaPort{DevPath: "/dev/intel-fpga-port.0"}).PortReset(); errors.Is(err, ErrDeviceGone) {
+		t.Errorf("EIO must not be reported as ErrDeviceGone: %v", err)
+	}
+}
+
+func TestPortPRRetry(t *testing.T) {
+	tcases := []struct {
+		name          string
+		ioctlErrs     []error
+		maxRetries    int
+		expectedCalls int
+		expectedErr   error
+	}{
+		{
+			name:          "EBUSY then success",
+			ioctlErrs:     []error{syscall.EBUSY, syscall.EAGAIN, nil},
+			maxRetries:    3,
+			expectedCalls: 3,
+		},
+		{
+			name:          "retries exhausted",
+			ioctlErrs:     []error{syscall.EBUSY, syscall.EBUSY, syscall.EBUSY},
+			maxRetries:    2,
+			expectedCalls: 3,
+			expectedErr:   syscall.EBUSY,
+		},
+		{
+			name:          "permanent error",
+			ioctlErrs:     []error{syscall.EINVAL, nil},
+			maxRetries:    3,
+			expectedCalls: 1,
+			expectedErr:   syscall.EINVAL,
+		},
+		{
+			name:          "retries disabled",
+			ioctlErrs:     []error{syscall.EBUSY, nil},
+			expectedCalls: 1,
+			expectedErr:   syscall.EBUSY,
+		},
+	}
+	for _, tc := range tcases {
+		t.Run(tc.name, func(t *testing.T) {
+			fme := newTestIntelFpgaFME(t, map[string]string{"pr/interface_id": testInterface})
+			port := newTestIntelFpgaPort(t, fme, testAFUOld)
+			calls := 0
+
+			setTestIoctl(t, func(dev string, req uint, arg unsafe.Pointer) (uintptr, error) {
+				if req != FPGA_FME_PORT_PR {
+					return 0, syscall.ENOTTY
+				}
+
+				err := tc.ioctlErrs[calls]
+				calls++
+
+				return 0, err
+			})
+
+			opts := PROptions{
+				SkipReadback: true,
+				Retry:        Backoff{Initial: time.Millisecond, Max: time.Millisecond, Steps: tc.maxRetries},
+			}
+
+			_, err := port.PRContext(context.Background(), newTestGBS(t, testInterface, testAFUNew), opts)
+			if tc.expectedErr == nil && err != nil {
+				t.Errorf("unexpected error: %+v", err)
+			}
+
+			if tc.expectedErr != nil && !errors.Is(err, tc.expectedErr) {
+				t.Errorf("expected %v, but got %v", tc.expectedErr, err)
+			}
+
+			if calls != tc.expectedCalls {
+				t.Errorf("expected %d PR attempts, but got %d", tc.expectedCalls, calls)
+			}
+		})
+	}
+}
@@ -0,0 +1,156 @@
+// Copyright 2023 Intel Corporation. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fpga
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// pciMSIIRQsDir lists the MSI and MSI-X interrupts of a PCI device.
+	pciMSIIRQsDir = "msi_irqs"
+	// irqAffinityListFile is the procfs attribute with the CPUs an interrupt
+	// is delivered to, e.g. "0-3,8".
+	irqAffinityListFile = "smp_affinity_list"
+)
+
+// portIRQs returns the sorted numbers of the interrupts registered by the PCI
+// function of the port. ErrNotSupported is returned if there are none.
+func portIRQs(port Port) ([]int, error) {
+	pci, err := port.GetPCIDevice()
+	if err != nil {
+		return nil, errors.Wrapf(err, "%s: unable to get PCI device", port.GetName())
+	}
+
+	entries, err := os.ReadDir(filepath.Join(pci.SysFsPath, pciMSIIRQsDir))
+	if err != nil && !os.IsNotExist(err) {
+		return nil, errors.Wrapf(err, "%s: unable to list interrupts", port.GetName())
+	}
+
+	irqs := make([]int, 0, len(entries))
+
+	for _, entry := range entries {
+		irq, convErr := strconv.Atoi(entry.Name())
+		if convErr != nil {
+			return nil, errors.Wrapf(convErr, "%s: malformed interrupt %q", port.GetName(), entry.Name())
+		}
+
+		irqs = append(irqs, irq)
+	}
+
+	if len(irqs) == 0 {
+		return nil, errors.Wrapf(ErrNotSupported, "%s: no interrupts registered", port.GetName())
+	}
+
+	sort.Ints(irqs)
+
+	return irqs, nil
+}
+
+// parseCPUList parses CPU list in the kernel format, e.g. "0-3,8".
+func parseCPUList(list string) ([]int, error) {
+	cpus := []int{}
+
+	for _, part := range strings.Split(strings.TrimSpace(list), ",") {
+		if part == "" {
+			continue
+		}
+
+		from, to, isRange := strings.Cut(part, "-")
+
+		first, err := strconv.Atoi(from)
+		if err != nil {
+			return nil, errors.Wrapf(err, "malformed CPU list %q", list)
+		}
+
+		last := first
+
+		if isRange {
+			if last, err = strconv.Atoi(to); err != nil || last < first {
+				return nil, errors.Errorf("malformed CPU range %q in %q", part, list)
+			}
+		}
+
+		for cpu := first; cpu <= last; cpu++ {
+			cpus = append(cpus, cpu)
+		}
+	}
+
+	return cpus, nil
+}
+
+// getIRQAffinity returns the CPUs the interrupts of the port are delivered
+// to, keyed by interrupt number.
+func getIRQAffinity(port Port) (map[int][]int, error) {
+	irqs, err := portIRQs(port)
+	if err != nil {
+		return nil, err
+	}
+
+	ret := make(map[int][]int, len(irqs))
+
+	for _, irq := range irqs {
+		data, readErr := readSysfsFile(filepath.Join(procfsRoot, "irq", strconv.Itoa(irq), irqAffinityListFile))
+		if readErr != nil {
+			return nil, errors.Wrapf(readErr, "%s: unable to read affinity of interrupt %d", port.GetName(), irq)
+		}
+
+		if ret[irq], err = parseCPUList(string(data)); err != nil {
+			return nil, errors.Wrapf(err, "%s: interrupt %d", port.GetName(), irq)
+		}
+	}
+
+	return ret, nil
+}
+
+// setIRQAffinity makes the interrupt of the port delivered to the CPUs.
+func setIRQAffinity(port Port, irq int, cpus []int) error {
+	if err := requireWritable(port.GetName(), "setting interrupt affinity"); err != nil {
+		return err
+	}
+
+	if len(cpus) == 0 {
+		return errors.Errorf("%s: no CPUs for interrupt %d", port.GetName(), irq)
+	}
+
+	irqs, err := portIRQs(port)
+	if err != nil {
+		return err
+	}
+
+	if i := sort.SearchInts(irqs, irq); i == len(irqs) || irqs[i] != irq {
+		return errors.Errorf("%s: interrupt %d doesn't belong to the port, its interrupts are %v", port.GetName(), irq, irqs)
+	}
+
+	list := make([]string, 0, len(cpus))
+
+	for _, cpu := range cpus {
+		if cpu < 0 {
+			return errors.Errorf("%s: invalid CPU %d", port.GetName(), cpu)
+		}
+
+		list = append(list, strconv.Itoa(cpu))
+	}
+
+	err = os.WriteFile(filepath.Join(procfsRoot, "irq", strconv.Itoa(irq), irqAffinityListFile), []byte(strings.Join(list, ",")), 0600)
+
+	return errors.Wrapf(err, "%s: unable to set affinity of interrupt %d", port.GetName(), irq)
+}
@@ -0,0 +1,112 @@
+// Copyright 2023 Intel Corporation. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fpga
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/pkg/errors"
+)
+
+func TestIRQAffinity(t *testing.T) {
+	proc := t.TempDir()
+	origProc := procfsRoot
+	procfsRoot = proc
+
+	t.Cleanup(func() { procfsRoot = origProc })
+
+	if err := createTestFiles(proc, nil, map[string]string{
+		"irq/120/smp_affinity_list": "0-3,8\n",
+		"irq/121/smp_affinity_list": "5\n",
+		"irq/122/smp_affinity_list": "0-7\n",
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	pciPath := filepath.Join(t.TempDir(), testBDF)
+	if err := createTestFiles(pciPath, []string{"msi_irqs/121", "msi_irqs/120"}, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	port := newTestIntelFpgaPort(t, &testFME{}, testAFUOld)
+	port.PCIDevice = &PCIDevice{SysFsPath: pciPath, BDF: testBDF}
+
+	affinity, err := port.GetIRQAffinity()
+	if err != nil {
+		t.Fatalf("unexpected error: %+v", err)
+	}
+
+	if expected := map[int][]int{120: {0, 1, 2, 3, 8}, 121: {5}}; !reflect.DeepEqual(affinity, expected) {
+		t.Errorf("expected affinity %v, but got %v", expected, affinity)
+	}
+
+	if err = port.SetIRQAffinity(121, []int{2, 3}); err != nil {
+		t.Fatalf("unexpected error: %+v", err)
+	}
+
+	assertFileContent(t, filepath.Join(proc, "irq/121/smp_affinity_list"), "2,3")
+
+	for name, cpus := range map[string][]int{"no CPUs": nil, "negative CPU": {-1}} {
+		if err = port.SetIRQAffinity(121, cpus); err == nil {
+			t.Errorf("%s: no error returned", name)
+		}
+	}
+
+	// Interrupt 122 belongs to another device.
+	if err = port.SetIRQAffinity(122, []int{0}); err == nil {
+		t.Error("no error returned for foreign interrupt")
+	}
+
+	assertFileContent(t, filepath.Join(proc, "irq/122/smp_affinity_list"), "0-7\n")
+
+	noIRQs := newTestIntelFpgaPort(t, &testFME{}, testAFUOld)
+	noIRQs.PCIDevice = &PCIDevice{SysFsPath: t.TempDir(), BDF: testBDF}
+
+	if _, err = noIRQs.GetIRQAffinity(); !errors.Is(err, ErrNotSupported) {
+		t.Errorf("expected ErrNotSupported, but got %v", err)
+	}
+
+	if err = noIRQs.SetIRQAffinity(120, []int{0}); !errors.Is(err, ErrNotSupported) {
+		t.Errorf("expected ErrNotSupported, but got %v", err)
+	}
+}
+
+func TestParseCPUList(t *testing.T) {
+	tcases := map[string][]int{
+		"0":        {0},
+		"0-2,7\n":  {0, 1, 2, 7},
+		"1,3-4,10": {1, 3, 4, 10},
+		"":         {},
+		"3-1":      nil,
+		"a":        nil,
+		"0-":       nil,
+	}
+	for list, expected := range tcases {
+		cpus, err := parseCPUList(list)
+		if expected == nil {
+			if err == nil {
+				t.Errorf("%q: no error returned", list)
+			}
+
+			continue
+		}
+
+		if err != nil || !reflect.DeepEqual(cpus, expected) {
+			t.Errorf("%q: expected %v, but got %v, %v", list, expected, cpus, err)
+		}
+	}
+}
@@ -0,0 +1,206 @@
+// Copyright 2023 Intel Corporation. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fpga
+
+import (
+	"context"
+	"runtime"
+	"sort"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// ListFMEs opens all FMEs of the host using up to workers goroutines.
+// If workers isn't positive, GOMAXPROCS goroutines are used. The FMEs are
+// sorted by device path. If some of the FMEs can't be opened, the rest
+// are returned along with an error wrapping MultiError of the failures.
+// If ctx is done meanwhile, the FMEs opened so far are closed and only
+// the context error is returned.
+func ListFMEs(ctx context.Context, workers int) ([]FME, error) {
+	names, _, err := ListFpgaDevicesContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	devs, err := openDevices(ctx, names, workers, func(name string) (commonFpgaAPI, error) {
+		return openFME(name)
+	})
+	if devs == nil {
+		return nil, err
+	}
+
+	fmes := make([]FME, 0, len(devs))
+	for _, dev := range devs {
+		fmes = append(fmes, dev.(FME))
+	}
+
+	return fmes, err
+}
+
+// ListPorts opens all ports of the host using up to workers goroutines.
+// If workers isn't positive, GOMAXPROCS goroutines are used. The ports are
+// sorted by device path. If some of the ports can't be opened, the rest
+// are returned along with an error wrapping MultiError of the failures.
+// If ctx is done meanwhile, the ports opened so far are closed and only
+// the context error is returned.
+func ListPorts(ctx context.Context, workers int) ([]Port, error) {
+	_, names, err := ListFpgaDevicesContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	devs, err := openDevices(ctx, names, workers, func(name string) (commonFpgaAPI, error) {
+		return openPort(name)
+	})
+	if devs == nil {
+		return nil, err
+	}
+
+	ports := make([]Port, 0, len(devs))
+	for _, dev := range devs {
+		ports = append(ports, dev.(Port))
+	}
+
+	return ports, err
+}
+
+// DeduplicateFMEs returns fmes without the duplicates, i.e. FMEs having the
+// same stable ID as an earlier one, see GetStableID. That's what happens when
+// the same device is visible through several device nodes, e.g. in containers.
+// The first FME of the duplicates is kept and the other ones are closed.
+// FMEs whose stable ID can't be determined, are kept.
+func DeduplicateFMEs(fmes []FME) []FME {
+	devs := make([]commonFpgaAPI, 0, len(fmes))
+	for _, fme := range fmes {
+		devs = append(devs, fme)
+	}
+
+	ret := []FME{}
+	for _, dev := range deduplicateDevices(devs) {
+		ret = append(ret, dev.(FME))
+	}
+
+	return ret
+}
+
+// DeduplicatePorts is the same as DeduplicateFMEs for ports.
+func DeduplicatePorts(ports []Port) []Port {
+	devs := make([]commonFpgaAPI, 0, len(ports))
+	for _, port := range ports {
+		devs = append(devs, port)
+	}
+
+	ret := []Port{}
+	for _, dev := range deduplicateDevices(devs) {
+		ret = append(ret, dev.(Port))
+	}
+
+	return ret
+}
+
+func deduplicateDevices(devs []commonFpgaAPI) []commonFpgaAPI {
+	seen := map[string]bool{}
+	ret := make([]commonFpgaAPI, 0, len(devs))
+
+	for _, dev := range devs {
+		id, err := dev.GetStableID()
+		if err != nil {
+			ret = append(ret, dev)
+
+			continue
+		}
+
+		if seen[id] {
+			dev.Close()
+
+			continue
+		}
+
+		seen[id] = true
+		ret = append(ret, dev)
+	}
+
+	return ret
+}
+
+// openDevices opens named devices concurrently. If the context is done,
+// no more devices are handed to the workers, the workers don't open the
+// devices they already got, and all the opened devices, including the ones
+// opened after the context was done, are closed. Only the context error is
+// returned then.
+func openDevices(ctx context.Context, names []string, workers int, open func(string) (commonFpgaAPI, error)) ([]commonFpgaAPI, error) {
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+
+	// Every index is written by a single worker, so no locking is needed.
+	devs := make([]commonFpgaAPI, len(names))
+	errs := make([]error, len(names))
+	jobs := make(chan int)
+
+	var wg sync.WaitGroup
+
+	for w := 0; w < workers && w < len(names); w++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			for i := range jobs {
+				if errs[i] = ctx.Err(); errs[i] == nil {
+					devs[i], errs[i] = open(names[i])
+				}
+			}
+		}()
+	}
+
+	for i := 0; i < len(names) && ctx.Err() == nil; i++ {
+		jobs <- i
+	}
+
+	close(jobs)
+	wg.Wait()
+
+	if err := ctx.Err(); err != nil {
+		for _, dev := range devs {
+			if dev != nil {
+				dev.Close()
+			}
+		}
+
+		return nil, err
+	}
+
+	ret := make([]commonFpgaAPI, 0, len(names))
+	failures := []error{}
+
+	for i, dev := range devs {
+		if errs[i] != nil {
+			failures = append(failures, errors.Wrap(errs[i], names[i]))
+			continue
+		}
+
+		ret = append(ret, dev)
+	}
+
+	sort.Slice(ret, func(i, j int) bool { return ret[i].GetDevPath() < ret[j].GetDevPath() })
+
+	if len(failures) > 0 {
+		return ret, errors.Wrapf(joinErrors(failures...), "unable to open %d of %d devices", len(failures), len(names))
+	}
+
+	return ret, nil
+}
@@ -0,0 +1,285 @@
+// Copyright 2023 Intel Corporation. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fpga
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// setTestBoards creates num fake boards with one FME and one port each and
+// makes the openers sleep for delay before returning the devices. The last
+// board's FME can't be opened if broken is set. The maximum number of
+// concurrent opens is tracked in maxOpens.
+func setTestBoards(tb testing.TB, num int, delay time.Duration, broken bool, maxOpens *int32) {
+	tb.Helper()
+
+	sysfs := tb.TempDir()
+	origSysfs, origFME, origPort := sysfsRoot, openFME, openPort
+	sysfsRoot = sysfs
+
+	tb.Cleanup(func() { sysfsRoot, openFME, openPort = origSysfs, origFME, origPort })
+
+	for i := 0; i < num; i++ {
+		dirs := []string{
+			fmt.Sprintf("bus/platform/devices/intel-fpga-fme.%d", i),
+			fmt.Sprintf("bus/platform/devices/intel-fpga-port.%d", i),
+		}
+		if err := createTestFiles(sysfs, dirs, nil); err != nil {
+			tb.Fatal(err)
+		}
+	}
+
+	var opens int32
+
+	// Devices with lower numbers take longer to open, so completion order
+	// is the reverse of the device order.
+	open := func(name string) error {
+		cur := atomic.AddInt32(&opens, 1)
+		defer atomic.AddInt32(&opens, -1)
+
+		for {
+			highest := atomic.LoadInt32(maxOpens)
+			if cur <= highest || atomic.CompareAndSwapInt32(maxOpens, highest, cur) {
+				break
+			}
+		}
+
+		var n int
+
+		_, _ = fmt.Sscanf(name[len(name)-1:], "%d", &n)
+		time.Sleep(delay * time.Duration(num-n) / time.Duration(num))
+
+		if broken && name == fmt.Sprintf("intel-fpga-fme.%d", num-1) {
+			return errors.New("device is busy")
+		}
+
+		return nil
+	}
+
+	openFME = func(name string) (FME, error) {
+		if err := open(name); err != nil {
+			return nil, err
+		}
+
+		return &IntelFpgaFME{DevPath: "/dev/" + name}, nil
+	}
+	openPort = func(name string) (Port, error) {
+		if err := open(name); err != nil {
+			return nil, err
+		}
+
+		return &IntelFpgaPort{DevPath: "/dev/" + name}, nil
+	}
+}
+
+func TestListFMEs(t *testing.T) {
+	tcases := []struct {
+		name        string
+		workers     int
+		broken      bool
+		expectedErr bool
+	}{
+		{
+			name:    "default workers",
+			workers: 0,
+		},
+		{
+			name:    "single worker",
+			workers: 1,
+		},
+		{
+			name:    "bounded workers",
+			workers: 3,
+		},
+		{
+			name:        "broken device",
+			workers:     4,
+			broken:      true,
+			expectedErr: true,
+		},
+	}
+	for _, tc := range tcases {
+		t.Run(tc.name, func(t *testing.T) {
+			var maxOpens int32
+
+			setTestBoards(t, 8, 10*time.Millisecond, tc.broken, &maxOpens)
+
+			fmes, err := ListFMEs(context.Background(), tc.workers)
+			if tc.expectedErr {
+				var multi *MultiError
+				if !errors.As(err, &multi) || len(multi.Errors()) != 1 {
+					t.Errorf("expected MultiError with a single failure, but got %v", err)
+				}
+			}
+
+			if !tc.expectedErr && err != nil {
+				t.Errorf("unexpected error: %+v", err)
+			}
+
+			expected := 8
+			if tc.broken {
+				expected--
+			}
+
+			if len(fmes) != expected {
+				t.Fatalf("expected %d FMEs, but got %d", expected, len(fmes))
+			}
+
+			for i, fme := range fmes {
+				if devPath := fmt.Sprintf("/dev/intel-fpga-fme.%d", i); fme.GetDevPath() != devPath {
+					t.Errorf("expected %s at position %d, but got %s", devPath, i, fme.GetDevPath())
+				}
+			}
+
+			if tc.workers > 0 && int(maxOpens) > tc.workers {
+				t.Errorf("expected at most %d concurrent opens, but got %d", tc.workers, maxOpens)
+			}
+
+			ports, err := ListPorts(context.Background(), tc.workers)
+			if err != nil || len(ports) != 8 {
+				t.Errorf("unexpected ports %v, error %+v", ports, err)
+			}
+		})
+	}
+}
+
+func TestListFMEsCancelled(t *testing.T) {
+	var maxOpens int32
+
+	setTestBoards(t, 4, 0, false, &maxOpens)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if fmes, err := ListFMEs(ctx, 2); !errors.Is(err, context.Canceled) || fmes != nil {
+		t.Errorf("expected no FMEs and context error, but got %v, %+v", fmes, err)
+	}
+}
+
+// countingFME tracks the number of FMEs open.
+type countingFME struct {
+	*IntelFpgaFME
+	open *int32
+}
+
+func (f *countingFME) Close() error {
+	atomic.AddInt32(f.open, -1)
+
+	return nil
+}
+
+func TestListFMEsCancelledMidway(t *testing.T) {
+	const (
+		boards   = 16
+		workers  = 2
+		cancelAt = 3
+	)
+
+	var maxOpens, opened, open int32
+
+	setTestBoards(t, boards, 0, false, &maxOpens)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	openFME = func(name string) (FME, error) {
+		if atomic.AddInt32(&opened, 1) == cancelAt {
+			cancel()
+		}
+
+		// Opens in flight complete after the cancellation.
+		time.Sleep(time.Millisecond)
+		atomic.AddInt32(&open, 1)
+
+		return &countingFME{IntelFpgaFME: &IntelFpgaFME{DevPath: "/dev/" + name}, open: &open}, nil
+	}
+
+	fmes, err := ListFMEs(ctx, workers)
+	if !errors.Is(err, context.Canceled) || fmes != nil {
+		t.Errorf("expected no FMEs and context error, but got %v, %+v", fmes, err)
+	}
+
+	if n := atomic.LoadInt32(&open); n != 0 {
+		t.Errorf("%d FMEs left open", n)
+	}
+
+	// Every worker may have picked one more device before the cancellation.
+	if n := atomic.LoadInt32(&opened); n > cancelAt+workers {
+		t.Errorf("expected enumeration to stop after the cancellation, but %d of %d FMEs were opened", n, boards)
+	}
+}
+
+func BenchmarkListFMEs(b *testing.B) {
+	var maxOpens int32
+
+	setTestBoards(b, 12, 2*time.Millisecond, false, &maxOpens)
+
+	for _, workers := range []int{1, 4, 0} {
+		b.Run(fmt.Sprintf("workers=%d", workers), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				if _, err := ListFMEs(context.Background(), workers); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func TestDeduplicateDevices(t *testing.T) {
+	fme := newTestIntelFpgaFME(t, map[string]string{"pr/interface_id": testInterface + "\n"})
+	// The same board visible under another device node.
+	alias := &IntelFpgaFME{DevPath: "/dev/fpga/fme0", SysFsPath: fme.SysFsPath, PCIDevice: fme.PCIDevice}
+	other := newTestIntelFpgaFME(t, map[string]string{"pr/interface_id": testInterface + "\n"})
+	other.PCIDevice = &PCIDevice{SysFsPath: other.PCIDevice.SysFsPath, BDF: "0000:af:00.0"}
+	unknown := &IntelFpgaFME{DevPath: "/dev/intel-fpga-fme.9", PCIDevice: &PCIDevice{}}
+
+	fmes := DeduplicateFMEs([]FME{fme, alias, other, unknown, unknown})
+
+	expectedFMEs := []FME{fme, other, unknown, unknown}
+	if len(fmes) != len(expectedFMEs) {
+		t.Fatalf("expected %d FMEs, but got %d", len(expectedFMEs), len(fmes))
+	}
+
+	for i, dev := range fmes {
+		if dev != expectedFMEs[i] {
+			t.Errorf("FME %d: expected %s, but got %s", i, expectedFMEs[i].GetDevPath(), dev.GetDevPath())
+		}
+	}
+
+	port := newTestIntelFpgaPort(t, fme, testAFUOld)
+	port.PCIDevice = fme.PCIDevice
+	portAlias := &IntelFpgaPort{FME: fme, DevPath: "/dev/fpga/port0", SysFsPath: port.SysFsPath, PCIDevice: fme.PCIDevice}
+	otherPort := newTestIntelFpgaPort(t, other, testAFUOld)
+	otherPort.PCIDevice = other.PCIDevice
+
+	ports := DeduplicatePorts([]Port{portAlias, port, otherPort})
+
+	expectedPorts := []Port{portAlias, otherPort}
+	if len(ports) != len(expectedPorts) {
+		t.Fatalf("expected %d ports, but got %d", len(expectedPorts), len(ports))
+	}
+
+	for i, dev := range ports {
+		if dev != expectedPorts[i] {
+			t.Errorf("port %d: expected %s, but got %s", i, expectedPorts[i].GetDevPath(), dev.GetDevPath())
+		}
+	}
+}
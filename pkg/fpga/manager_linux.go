@@ -0,0 +1,143 @@
+// Copyright 2023 Intel Corporation. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fpga
+
+import (
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// fpgaManagerGlob matches the fpga_manager class device relative to FME sysfs entry.
+const fpgaManagerGlob = "*/fpga_manager/fpga*"
+
+// ManagerState is the state of the FPGA manager as reported by the kernel.
+type ManagerState int
+
+// States of the FPGA manager, see enum fpga_mgr_states in the kernel.
+const (
+	ManagerStateUnknown ManagerState = iota
+	ManagerStatePowerOff
+	ManagerStatePowerUp
+	ManagerStateReset
+	ManagerStateFirmwareRequest
+	ManagerStateFirmwareRequestError
+	ManagerStateWriteInit
+	ManagerStateWriteInitError
+	ManagerStateWrite
+	ManagerStateWriteError
+	ManagerStateWriteComplete
+	ManagerStateWriteCompleteError
+	ManagerStateOperating
+)
+
+// managerStates maps the kernel's state strings to ManagerState values.
+var managerStates = map[string]ManagerState{
+	"unknown":                ManagerStateUnknown,
+	"power off":              ManagerStatePowerOff,
+	"power up":               ManagerStatePowerUp,
+	"reset":                  ManagerStateReset,
+	"firmware request":       ManagerStateFirmwareRequest,
+	"firmware request error": ManagerStateFirmwareRequestError,
+	"write init":             ManagerStateWriteInit,
+	"write init error":       ManagerStateWriteInitError,
+	"write":                  ManagerStateWrite,
+	"write error":            ManagerStateWriteError,
+	"write complete":         ManagerStateWriteComplete,
+	"write complete error":   ManagerStateWriteCompleteError,
+	"operating":              ManagerStateOperating,
+}
+
+// String returns name of the state, e.g. "write-complete-error".
+func (s ManagerState) String() string {
+	for name, state := range managerStates {
+		if state == s {
+			return strings.ReplaceAll(name, " ", "-")
+		}
+	}
+
+	return "unknown"
+}
+
+// IsError returns true if the state indicates a failed operation.
+func (s ManagerState) IsError() bool {
+	switch s {
+	case ManagerStateFirmwareRequestError, ManagerStateWriteInitError,
+		ManagerStateWriteError, ManagerStateWriteCompleteError:
+		return true
+	}
+
+	return false
+}
+
+// ManagerStatus holds the state and the errors of FME's FPGA manager.
+type ManagerStatus struct {
+	// Name is the name of the FPGA manager.
+	Name string
+	// RawState is the state string as reported by the kernel.
+	RawState string
+	// Errors lists reconfiguration errors reported by the manager,
+	// e.g. "reconfig CRC error". It's empty if there are none or
+	// the kernel doesn't report them.
+	Errors []string
+	// State is the decoded state.
+	State ManagerState
+}
+
+// getFpgaManagerStatus reads status of the FPGA manager belonging to the FME.
+func getFpgaManagerStatus(fme FME) (ManagerStatus, error) {
+	sysfs := fme.GetSysFsPath()
+	if sysfs == "" {
+		return ManagerStatus{}, errors.Errorf("%s: unknown sysfs entry", fme.GetName())
+	}
+
+	mgrs, err := filepath.Glob(filepath.Join(sysfs, fpgaManagerGlob))
+	if err != nil {
+		return ManagerStatus{}, errors.WithStack(err)
+	}
+
+	if len(mgrs) != 1 {
+		return ManagerStatus{}, errors.Wrapf(ErrNotSupported, "%s: found %d FPGA managers", fme.GetName(), len(mgrs))
+	}
+
+	var (
+		status ManagerStatus
+		errs   string
+	)
+
+	fileMap := map[string]*string{
+		"name":   &status.Name,
+		"state":  &status.RawState,
+		"status": &errs,
+	}
+	if err = readFilesInDirectory(fileMap, mgrs[0]); err != nil {
+		return ManagerStatus{}, err
+	}
+
+	if status.RawState == "" {
+		return ManagerStatus{}, errors.Errorf("%s: FPGA manager state is not available", fme.GetName())
+	}
+
+	status.State = managerStates[status.RawState]
+
+	for _, line := range strings.Split(errs, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			status.Errors = append(status.Errors, line)
+		}
+	}
+
+	return status, nil
+}
@@ -0,0 +1,143 @@
+// Copyright 2023 Intel Corporation. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fpga
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// bmcDir is Glob pattern, relative to the FME sysfs entry, of the board
+// management controller (BMC) devices holding the manufacturing data.
+const bmcDir = "bmc*"
+
+// Manufacturing data attributes of the BMC. The first existing one is used.
+var (
+	bmcDateFiles           = []string{"manufacture_date", "mfg_date", "provision_time"}
+	bmcRevisionFiles       = []string{"board_revision", "revision"}
+	bmcProvisioningIDFiles = []string{"provision_id", "provisioning_id"}
+)
+
+// manufacturingDateLayouts are the date formats the BMC firmware versions use.
+var manufacturingDateLayouts = []string{
+	time.RFC3339,
+	"2006-01-02 15:04:05",
+	"2006-01-02",
+	"20060102",
+	"01/02/2006",
+}
+
+// ManufacturingInfo is the manufacturing and provisioning data of a board.
+// Fields the board doesn't expose are left empty.
+type ManufacturingInfo struct {
+	// Date is the manufacturing or provisioning date, zero if it's missing
+	// or in unknown format.
+	Date time.Time
+	// RawDate is the date as reported by the BMC.
+	RawDate string
+	// Revision is the board revision.
+	Revision string
+	// ProvisioningID identifies the board provisioning.
+	ProvisioningID string
+}
+
+// getManufacturingInfo reads the manufacturing data of the board of the FME,
+// see GetManufacturingInfo.
+func getManufacturingInfo(fme FME) (ManufacturingInfo, error) {
+	info := ManufacturingInfo{}
+
+	sysfs := fme.GetSysFsPath()
+	if sysfs == "" {
+		return info, errors.Wrapf(ErrNotSupported, "%s: no sysfs entry", fme.GetName())
+	}
+
+	dirs, _ := filepath.Glob(filepath.Join(sysfs, bmcDir))
+	if len(dirs) != 1 {
+		return info, errors.Wrapf(ErrNotSupported, "%s: no unique BMC found", fme.GetName())
+	}
+
+	found := false
+
+	for _, attr := range []struct {
+		value *string
+		files []string
+	}{
+		{&info.RawDate, bmcDateFiles},
+		{&info.Revision, bmcRevisionFiles},
+		{&info.ProvisioningID, bmcProvisioningIDFiles},
+	} {
+		ok, err := readFirstAttribute(dirs[0], attr.files, attr.value)
+		if err != nil {
+			return ManufacturingInfo{}, errors.Wrapf(err, "%s: unable to read manufacturing data", fme.GetName())
+		}
+
+		found = found || ok
+	}
+
+	if !found {
+		return info, errors.Wrapf(ErrNotSupported, "%s: no manufacturing data", fme.GetName())
+	}
+
+	info.Date = parseManufacturingDate(info.RawDate)
+
+	return info, nil
+}
+
+// readFirstAttribute reads the first existing of the files in dir to value.
+// It returns false if none exists.
+func readFirstAttribute(dir string, files []string, value *string) (bool, error) {
+	for _, name := range files {
+		data, err := readSysfsFile(filepath.Join(dir, name))
+		if os.IsNotExist(err) {
+			continue
+		}
+
+		if err != nil {
+			return false, err
+		}
+
+		*value = strings.TrimSpace(string(data))
+
+		return true, nil
+	}
+
+	return false, nil
+}
+
+// parseManufacturingDate parses the date in any of manufacturingDateLayouts
+// or as seconds since the epoch. Zero time is returned for unknown formats.
+func parseManufacturingDate(date string) time.Time {
+	if date == "" {
+		return time.Time{}
+	}
+
+	for _, layout := range manufacturingDateLayouts {
+		if t, err := time.Parse(layout, date); err == nil {
+			return t
+		}
+	}
+
+	// Eight digits are taken by the YYYYMMDD layout above.
+	if secs, err := strconv.ParseInt(date, 10, 64); err == nil {
+		return time.Unix(secs, 0).UTC()
+	}
+
+	return time.Time{}
+}
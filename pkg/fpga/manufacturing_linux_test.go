@@ -0,0 +1,90 @@
+// Copyright 2023 Intel Corporation. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fpga
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestGetManufacturingInfo(t *testing.T) {
+	tcases := []struct {
+		files     map[string]string
+		name      string
+		expected  ManufacturingInfo
+		noSupport bool
+	}{
+		{
+			name:      "no BMC",
+			noSupport: true,
+		},
+		{
+			name:      "no manufacturing data",
+			files:     map[string]string{"bmc.0/bmc_version": "0x1\n"},
+			noSupport: true,
+		},
+		{
+			name: "all data",
+			files: map[string]string{
+				"bmc.0/manufacture_date": "2021-03-15\n",
+				"bmc.0/board_revision":   "C\n",
+				"bmc.0/provision_id":     "PRV-0042\n",
+			},
+			expected: ManufacturingInfo{
+				Date:           time.Date(2021, 3, 15, 0, 0, 0, 0, time.UTC),
+				RawDate:        "2021-03-15",
+				Revision:       "C",
+				ProvisioningID: "PRV-0042",
+			},
+		},
+		{
+			name:     "compact date",
+			files:    map[string]string{"bmc.0/mfg_date": "20200101\n"},
+			expected: ManufacturingInfo{Date: time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC), RawDate: "20200101"},
+		},
+		{
+			name:     "provisioning timestamp",
+			files:    map[string]string{"bmc.0/provision_time": "1600000000\n", "bmc.0/revision": "2\n"},
+			expected: ManufacturingInfo{Date: time.Unix(1600000000, 0).UTC(), RawDate: "1600000000", Revision: "2"},
+		},
+		{
+			name:     "unknown date format",
+			files:    map[string]string{"bmc.0/manufacture_date": "week 12\n"},
+			expected: ManufacturingInfo{RawDate: "week 12"},
+		},
+	}
+	for _, tc := range tcases {
+		t.Run(tc.name, func(t *testing.T) {
+			info, err := newTestIntelFpgaFME(t, tc.files).GetManufacturingInfo()
+			if tc.noSupport {
+				if !errors.Is(err, ErrNotSupported) {
+					t.Errorf("expected ErrNotSupported, but got %v", err)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("unexpected error: %+v", err)
+			}
+
+			if !info.Date.Equal(tc.expected.Date) || info.RawDate != tc.expected.RawDate ||
+				info.Revision != tc.expected.Revision || info.ProvisioningID != tc.expected.ProvisioningID {
+				t.Errorf("expected %+v, but got %+v", tc.expected, info)
+			}
+		})
+	}
+}
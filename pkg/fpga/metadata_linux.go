@@ -0,0 +1,74 @@
+// Copyright 2023 Intel Corporation. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fpga
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// fimSeedKeys are the keys of the FIM synthesis seed in the bitstream
+// metadata, in the order of preference.
+var fimSeedKeys = []string{"seed", "synthesis-seed"}
+
+// ParseBitstreamMetadata parses the FME bitstream metadata the FIM build
+// stores as JSON object, see GetBitstreamMetadata. The numbers are kept as
+// json.Number to preserve hashes and seeds exactly. ErrNotSupported is
+// returned if the metadata isn't JSON object, e.g. it's the plain number
+// older FIMs have.
+func ParseBitstreamMetadata(metadata string) (map[string]interface{}, error) {
+	metadata = strings.TrimSpace(metadata)
+	if !strings.HasPrefix(metadata, "{") {
+		return nil, errors.Wrapf(ErrNotSupported, "bitstream metadata %q isn't JSON object", metadata)
+	}
+
+	dec := json.NewDecoder(strings.NewReader(metadata))
+	dec.UseNumber()
+
+	var ret map[string]interface{}
+	if err := dec.Decode(&ret); err != nil {
+		return nil, errors.Wrap(err, "malformed bitstream metadata")
+	}
+
+	return ret, nil
+}
+
+// getFIMSeed returns the FIM synthesis seed from the bitstream metadata.
+func getFIMSeed(fme FME) (string, error) {
+	fields, err := ParseBitstreamMetadata(fme.GetBitstreamMetadata())
+	if err != nil {
+		return "", errors.Wrap(err, fme.GetName())
+	}
+
+	for _, key := range fimSeedKeys {
+		switch seed := fields[key].(type) {
+		case nil:
+			continue
+		case string:
+			if seed = strings.TrimSpace(seed); seed != "" {
+				return seed, nil
+			}
+		case json.Number:
+			return seed.String(), nil
+		default:
+			return "", errors.Errorf("%s: unexpected %s %s in bitstream metadata", fme.GetName(), key, fmt.Sprint(seed))
+		}
+	}
+
+	return "", errors.Wrapf(ErrNotSupported, "%s: no FIM seed in bitstream metadata", fme.GetName())
+}
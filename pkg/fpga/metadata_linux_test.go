@@ -0,0 +1,82 @@
+// Copyright 2023 Intel Corporation. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fpga
+
+import (
+	"testing"
+
+	"github.com/pkg/errors"
+)
+
+func TestGetFIMSeed(t *testing.T) {
+	tcases := []struct {
+		name        string
+		metadata    string
+		expected    string
+		noSupport   bool
+		expectedErr bool
+	}{
+		{
+			name:     "string seed",
+			metadata: `{"build": "2023.3", "seed": "0x5eed1234", "synthesis-seed": "ignored"}`,
+			expected: "0x5eed1234",
+		},
+		{
+			name:     "numeric seed kept exact",
+			metadata: `{"synthesis-seed": 18446744073709551615}`,
+			expected: "18446744073709551615",
+		},
+		{
+			name:      "no seed",
+			metadata:  `{"build": "2023.3"}`,
+			noSupport: true,
+		},
+		{
+			name:      "not JSON",
+			metadata:  "0x2306150000000003",
+			noSupport: true,
+		},
+		{
+			name:        "malformed JSON",
+			metadata:    `{"seed": `,
+			expectedErr: true,
+		},
+		{
+			name:        "unexpected seed type",
+			metadata:    `{"seed": [1, 2]}`,
+			expectedErr: true,
+		},
+	}
+	for _, tc := range tcases {
+		t.Run(tc.name, func(t *testing.T) {
+			fme := &IntelFpgaFME{DevPath: "/dev/intel-fpga-fme.0", BitstreamMetadata: tc.metadata}
+
+			seed, err := fme.GetFIMSeed()
+
+			switch {
+			case tc.noSupport:
+				if !errors.Is(err, ErrNotSupported) {
+					t.Errorf("expected ErrNotSupported, but got %v", err)
+				}
+			case tc.expectedErr:
+				if err == nil || errors.Is(err, ErrNotSupported) {
+					t.Errorf("expected error, but got %q, %v", seed, err)
+				}
+			case err != nil || seed != tc.expected:
+				t.Errorf("expected %q, but got %q, %v", tc.expected, seed, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,68 @@
+// Copyright 2023 Intel Corporation. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fpga
+
+import (
+	"os"
+	"syscall"
+	"unsafe"
+
+	"github.com/pkg/errors"
+)
+
+// mmioRegion is a read-only view of device MMIO region.
+type mmioRegion struct {
+	unmap func([]byte) error
+	data  []byte
+}
+
+// mapRegion maps size bytes of the device's MMIO space starting from offset,
+// as returned by the region info ioctl. Tests replace it with in-memory regions.
+var mapRegion = mmapRegion
+
+func mmapRegion(dev string, offset, size uint64) (*mmioRegion, error) {
+	f, err := os.OpenFile(dev, os.O_RDWR, 0644)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	// The mapping stays valid after the file is closed.
+	defer f.Close()
+
+	data, err := syscall.Mmap(int(f.Fd()), int64(offset), int(size), syscall.PROT_READ, syscall.MAP_SHARED)
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to map %d bytes of %s at %#x", size, dev, offset)
+	}
+
+	return &mmioRegion{data: data, unmap: syscall.Munmap}, nil
+}
+
+// readUint64 reads 64-bit register at offset. The register is read with
+// a single aligned load as MMIO doesn't necessarily support narrower reads.
+func (r *mmioRegion) readUint64(offset uint64) (uint64, error) {
+	if offset%8 != 0 || offset+8 > uint64(len(r.data)) {
+		return 0, errors.Errorf("invalid MMIO offset %#x for region of %d bytes", offset, len(r.data))
+	}
+
+	return *(*uint64)(unsafe.Pointer(&r.data[offset])), nil
+}
+
+// Close unmaps the region.
+func (r *mmioRegion) Close() error {
+	if r.unmap == nil {
+		return nil
+	}
+
+	return errors.WithStack(r.unmap(r.data))
+}
@@ -0,0 +1,43 @@
+// Copyright 2023 Intel Corporation. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fpga
+
+import "strings"
+
+// fpgaModels maps "vendor:device" PCI IDs of known FPGA cards to their
+// model names. Both physical and virtual functions are listed.
+var fpgaModels = map[string]string{
+	"0x8086:0xbcbd": "Intel Xeon CPU with Integrated FPGA",
+	"0x8086:0xbcbf": "Intel Xeon CPU with Integrated FPGA",
+	"0x8086:0xbcc0": "Intel Xeon CPU with Integrated FPGA",
+	"0x8086:0xbcc1": "Intel Xeon CPU with Integrated FPGA",
+	"0x8086:0x09c4": "Intel PAC with Arria 10 GX FPGA",
+	"0x8086:0x09c5": "Intel PAC with Arria 10 GX FPGA",
+	"0x8086:0x0b2b": "Intel FPGA PAC D5005",
+	"0x8086:0x0b2c": "Intel FPGA PAC D5005",
+	"0x8086:0x0b30": "Intel FPGA PAC N3000",
+	"0x8086:0x0b31": "Intel FPGA PAC N3000",
+}
+
+// modelName returns model name of the FPGA card the device belongs to
+// or empty string if the card is unknown.
+func modelName(dev commonFpgaAPI) string {
+	pci, err := dev.GetPCIDevice()
+	if err != nil {
+		return ""
+	}
+
+	return fpgaModels[strings.ToLower(pci.Vendor+":"+pci.Device)]
+}
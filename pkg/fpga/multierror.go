@@ -0,0 +1,91 @@
+// Copyright 2023 Intel Corporation. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fpga
+
+import (
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// MultiError combines failures of batch operations, e.g. a failure per device.
+// Functions returning it usually wrap it with a summary, use errors.As to
+// get to the individual errors.
+type MultiError struct {
+	errs []error
+}
+
+// joinErrors returns MultiError of the non-nil errs or nil if there're none.
+func joinErrors(errs ...error) error {
+	var nonNil []error
+
+	for _, err := range errs {
+		if err != nil {
+			nonNil = append(nonNil, err)
+		}
+	}
+
+	if len(nonNil) == 0 {
+		return nil
+	}
+
+	return &MultiError{errs: nonNil}
+}
+
+// Error returns messages of the errors separated by semicolons.
+func (e *MultiError) Error() string {
+	msgs := make([]string, len(e.errs))
+	for i, err := range e.errs {
+		msgs[i] = err.Error()
+	}
+
+	return strings.Join(msgs, "; ")
+}
+
+// Errors returns the combined errors.
+func (e *MultiError) Errors() []error {
+	return append([]error(nil), e.errs...)
+}
+
+// Unwrap returns the first error or nil if there're none.
+func (e *MultiError) Unwrap() error {
+	if len(e.errs) == 0 {
+		return nil
+	}
+
+	return e.errs[0]
+}
+
+// Is reports whether any of the errors matches target.
+func (e *MultiError) Is(target error) bool {
+	for _, err := range e.errs {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// As finds the first of the errors that matches target.
+func (e *MultiError) As(target interface{}) bool {
+	for _, err := range e.errs {
+		if errors.As(err, target) {
+			return true
+		}
+	}
+
+	return false
+}
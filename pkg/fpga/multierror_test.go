@@ -0,0 +1,84 @@
+// Copyright 2023 Intel Corporation. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fpga
+
+import (
+	"os"
+	"testing"
+
+	"github.com/pkg/errors"
+)
+
+func TestMultiError(t *testing.T) {
+	if err := joinErrors(); err != nil {
+		t.Errorf("expected nil for no errors, but got %v", err)
+	}
+
+	if err := joinErrors(nil, nil); err != nil {
+		t.Errorf("expected nil for nil errors, but got %v", err)
+	}
+
+	pathErr := &os.PathError{Op: "open", Path: "/dev/intel-fpga-port.1", Err: os.ErrNotExist}
+	err := errors.Wrap(joinErrors(errors.New("first"), nil, errors.Wrap(ErrNotSupported, "second"), pathErr), "batch failed")
+
+	if msg := err.Error(); msg != "batch failed: first; second: not supported; open /dev/intel-fpga-port.1: file does not exist" {
+		t.Errorf("unexpected message %q", msg)
+	}
+
+	var multi *MultiError
+	if !errors.As(err, &multi) {
+		t.Fatalf("expected MultiError, but got %T", err)
+	}
+
+	if n := len(multi.Errors()); n != 3 {
+		t.Errorf("expected 3 errors, but got %d", n)
+	}
+
+	for _, target := range []error{ErrNotSupported, os.ErrNotExist} {
+		if !errors.Is(err, target) {
+			t.Errorf("expected %v to be matched", target)
+		}
+	}
+
+	if errors.Is(err, ErrNotFound) {
+		t.Error("unexpected match of ErrNotFound")
+	}
+
+	var gotPathErr *os.PathError
+	if !errors.As(err, &gotPathErr) || gotPathErr.Path != "/dev/intel-fpga-port.1" {
+		t.Errorf("expected *os.PathError, but got %v", gotPathErr)
+	}
+}
+
+func TestMultiErrorZeroValue(t *testing.T) {
+	err := &MultiError{}
+
+	if msg := err.Error(); msg != "" {
+		t.Errorf("expected empty message, but got %q", msg)
+	}
+
+	if len(err.Errors()) != 0 {
+		t.Errorf("expected no errors, but got %v", err.Errors())
+	}
+
+	if errors.Is(err, ErrNotSupported) {
+		t.Error("unexpected match of ErrNotSupported")
+	}
+
+	var pathErr *os.PathError
+	if errors.As(err, &pathErr) {
+		t.Errorf("unexpected match of %v", pathErr)
+	}
+}
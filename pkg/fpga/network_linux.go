@@ -0,0 +1,109 @@
+// Copyright 2023 Intel Corporation. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fpga
+
+import (
+	"net"
+	"path/filepath"
+	"strconv"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// macAddressFile holds the first MAC address assigned to the board.
+	// It's published by the board management controller driver
+	// somewhere below the PCI device of boards with networking features.
+	macAddressFile = "mac_address"
+	// macCountFile holds number of consecutive MAC addresses assigned to the board.
+	macCountFile = "mac_count"
+	// macSearchDepth limits how deep below the PCI device the MAC address is searched.
+	macSearchDepth = 6
+)
+
+// getMACAddresses returns MAC addresses assigned to the board or an empty
+// slice if the board doesn't have networking features.
+func getMACAddresses(dev commonFpgaAPI) ([]net.HardwareAddr, error) {
+	pci, err := dev.GetPCIDevice()
+	if err != nil {
+		return nil, err
+	}
+
+	if pci.PhysFn != nil {
+		pci = pci.PhysFn
+	}
+
+	fname, err := findInSubtree(pci.SysFsPath, macAddressFile, macSearchDepth)
+	if err != nil {
+		return nil, err
+	}
+
+	if fname == "" {
+		return []net.HardwareAddr{}, nil
+	}
+
+	var address, count string
+
+	fileMap := map[string]*string{
+		macAddressFile: &address,
+		macCountFile:   &count,
+	}
+	if err = readFilesInDirectory(fileMap, filepath.Dir(fname)); err != nil {
+		return nil, err
+	}
+
+	base, err := net.ParseMAC(address)
+	if err != nil {
+		return nil, errors.Wrapf(err, "%s: malformed MAC address", fname)
+	}
+
+	if len(base) != 6 {
+		return nil, errors.Errorf("%s: %q is not an EUI-48 MAC address", fname, address)
+	}
+
+	num := uint64(1)
+	if count != "" {
+		if num, err = strconv.ParseUint(count, 10, 8); err != nil {
+			return nil, errors.Wrapf(err, "%s: malformed MAC address count", filepath.Dir(fname))
+		}
+	}
+
+	return consecutiveMACs(base, int(num)), nil
+}
+
+// consecutiveMACs returns num EUI-48 addresses starting from base.
+func consecutiveMACs(base net.HardwareAddr, num int) []net.HardwareAddr {
+	var start uint64
+
+	for _, b := range base {
+		start = start<<8 | uint64(b)
+	}
+
+	ret := make([]net.HardwareAddr, 0, num)
+
+	for i := 0; i < num; i++ {
+		val := start + uint64(i)
+		mac := make(net.HardwareAddr, len(base))
+
+		for j := len(mac) - 1; j >= 0; j-- {
+			mac[j] = byte(val)
+			val >>= 8
+		}
+
+		ret = append(ret, mac)
+	}
+
+	return ret
+}
@@ -0,0 +1,249 @@
+// Copyright 2023 Intel Corporation. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fpga
+
+import (
+	"context"
+	"fmt"
+	"sort"
+)
+
+// NodeAuditReport is the outcome of AuditNode.
+type NodeAuditReport struct {
+	// Problems are the problems not specific to a board, e.g. devices
+	// which can't be opened.
+	Problems []string `json:"problems,omitempty"`
+	// Boards are the audited boards sorted by PCI address.
+	Boards []*BoardAudit `json:"boards"`
+	// Pass is true if there are boards and none of them is degraded,
+	// and there are no other problems.
+	Pass bool `json:"pass"`
+}
+
+// BoardAudit is the audit of a board, i.e. PCI physical function.
+type BoardAudit struct {
+	// Problems are the failed checks of the board and its devices.
+	Problems []string `json:"problems,omitempty"`
+	// FMEs and Ports are the names of the devices of the board.
+	FMEs  []string `json:"fmes"`
+	Ports []string `json:"ports"`
+	// PCIAddress is the address of the physical function.
+	PCIAddress string `json:"pciAddress"`
+	// Driver is the driver bound to the physical function.
+	Driver string `json:"driver"`
+	// Degraded is true if any of the checks failed.
+	Degraded bool `json:"degraded"`
+}
+
+func (b *BoardAudit) fail(format string, args ...interface{}) {
+	b.Problems = append(b.Problems, fmt.Sprintf(format, args...))
+	b.Degraded = true
+}
+
+// AuditNode checks whether the node is ready for FPGA workloads. For every
+// board it checks that a driver is bound, the FIMs are known to work with
+// the driver, see KnownGoodFIMs, the FMEs and ports are healthy, all the
+// ports the FMEs report are present and the number of VFs matches the
+// SR-IOV configuration. A port released to a VF bound to another driver
+// than the board, e.g. vfio-pci, counts as present as it's passed through
+// to a VM. Failures of the checks mark the boards degraded and don't stop
+// the audit, an error is only returned if the devices can't be enumerated.
+func AuditNode() (NodeAuditReport, error) {
+	return auditNode(context.Background())
+}
+
+func auditNode(ctx context.Context) (NodeAuditReport, error) {
+	report := NodeAuditReport{Boards: []*BoardAudit{}}
+
+	fmes, err := ListFMEs(ctx, 0)
+	if fmes == nil {
+		return report, err
+	}
+
+	defer func() {
+		for _, fme := range fmes {
+			fme.Close()
+		}
+	}()
+
+	if err != nil {
+		report.Problems = append(report.Problems, err.Error())
+	}
+
+	ports, err := ListPorts(ctx, 0)
+	if ports == nil {
+		return report, err
+	}
+
+	defer closePorts(ports)
+
+	if err != nil {
+		report.Problems = append(report.Problems, err.Error())
+	}
+
+	boards := map[string]*BoardAudit{}
+	portsPerBoard := map[string]int{}
+
+	board := func(pci *PCIDevice) *BoardAudit {
+		pf := pci.physicalFunction()
+		if b, ok := boards[pf.BDF]; ok {
+			return b
+		}
+
+		b := &BoardAudit{PCIAddress: pf.BDF, FMEs: []string{}, Ports: []string{}}
+		boards[pf.BDF] = b
+
+		portsPerBoard[pf.BDF] += auditPhysicalFunction(b, pf)
+
+		return b
+	}
+
+	for _, port := range ports {
+		pci, pciErr := port.GetPCIDevice()
+		if pciErr != nil {
+			report.Problems = append(report.Problems, fmt.Sprintf("%s: unable to get PCI device: %v", port.GetName(), pciErr))
+
+			continue
+		}
+
+		b := board(pci)
+		b.Ports = append(b.Ports, port.GetName())
+		portsPerBoard[b.PCIAddress]++
+
+		if healthy, reasons := checkPortHealth(port); !healthy {
+			for _, reason := range reasons {
+				b.fail("%s: %s", port.GetName(), reason)
+			}
+		}
+	}
+
+	for _, fme := range fmes {
+		pci, pciErr := fme.GetPCIDevice()
+		if pciErr != nil {
+			report.Problems = append(report.Problems, fmt.Sprintf("%s: unable to get PCI device: %v", fme.GetName(), pciErr))
+
+			continue
+		}
+
+		b := board(pci)
+		b.FMEs = append(b.FMEs, fme.GetName())
+
+		auditFME(b, fme, portsPerBoard[b.PCIAddress])
+	}
+
+	for _, b := range boards {
+		sort.Strings(b.FMEs)
+		sort.Strings(b.Ports)
+
+		if len(b.FMEs) == 0 {
+			b.fail("no FME found")
+		}
+
+		report.Boards = append(report.Boards, b)
+	}
+
+	sort.Slice(report.Boards, func(i, j int) bool { return report.Boards[i].PCIAddress < report.Boards[j].PCIAddress })
+
+	if len(report.Boards) == 0 {
+		report.Problems = append(report.Problems, "no FPGA boards found")
+	}
+
+	report.Pass = len(report.Problems) == 0
+
+	for _, b := range report.Boards {
+		report.Pass = report.Pass && !b.Degraded
+	}
+
+	return report, nil
+}
+
+// auditPhysicalFunction checks the driver binding and the SR-IOV
+// configuration of the board. It returns the number of VFs bound to a
+// driver other than the one of the board, e.g. vfio-pci. The ports released
+// to those VFs are passed through to VMs and have no device on the host.
+func auditPhysicalFunction(b *BoardAudit, pf *PCIDevice) int {
+	driver, err := pf.GetBoundDriver()
+
+	switch {
+	case err != nil:
+		b.fail("unable to get bound driver: %v", err)
+	case driver == "":
+		b.fail("no driver bound")
+	default:
+		b.Driver = driver
+	}
+
+	numVFs := pf.NumVFs()
+	if numVFs <= 0 {
+		return 0
+	}
+
+	vfs, err := pf.GetVFs()
+	if err != nil {
+		b.fail("unable to list VFs: %v", err)
+
+		return 0
+	}
+
+	if int64(len(vfs)) != numVFs {
+		b.fail("%d VFs configured, but %d present", numVFs, len(vfs))
+	}
+
+	passedThrough := 0
+
+	for _, vf := range vfs {
+		vfDriver, vfErr := vf.GetBoundDriver()
+
+		switch {
+		case vfErr != nil:
+			b.fail("%v", vfErr)
+		case vfDriver != "" && b.Driver != "" && vfDriver != b.Driver:
+			passedThrough++
+		}
+	}
+
+	return passedThrough
+}
+
+// auditFME checks the FIM compatibility, the health and the number of ports
+// of the FME. ports is the number of ports of the board found, including
+// the ports passed through to VMs.
+func auditFME(b *BoardAudit, fme FME, ports int) {
+	if healthy, reasons := fme.Healthy(); !healthy {
+		for _, reason := range reasons {
+			b.fail("%s: %s", fme.GetName(), reason)
+		}
+	}
+
+	// Unresponsive driver is reported by the health check already.
+	if apiVersion, err := fme.GetAPIVersion(); err == nil {
+		known, warnings, compatErr := checkCompatibility(apiVersion, fme.GetBitstreamID(), KnownGoodFIMs)
+
+		switch {
+		case compatErr != nil:
+			b.fail("%s: unable to check FIM compatibility: %v", fme.GetName(), compatErr)
+		case !known:
+			for _, warning := range warnings {
+				b.fail("%s: %s", fme.GetName(), warning)
+			}
+		}
+	}
+
+	if expected := fme.GetPortsNum(); expected < 0 {
+		b.fail("%s: unable to get number of ports", fme.GetName())
+	} else if expected != ports {
+		b.fail("%s: %d ports reported, but %d present", fme.GetName(), expected, ports)
+	}
+}
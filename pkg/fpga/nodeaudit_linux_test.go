@@ -0,0 +1,205 @@
+// Copyright 2023 Intel Corporation. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fpga
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"unsafe"
+)
+
+func TestAuditNode(t *testing.T) {
+	const otherBDF = "0000:af:00.0"
+
+	sysfs := setTestSysfsRoot(t)
+	setTestIoctl(t, func(string, uint, unsafe.Pointer) (uintptr, error) { return 0, nil })
+
+	devices := []string{"intel-fpga-fme.0", "intel-fpga-fme.1", "intel-fpga-fme.2", "intel-fpga-port.0", "intel-fpga-port.1"}
+	for _, dev := range devices {
+		if err := createTestFiles(sysfs, []string{"bus/platform/devices/" + dev}, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Board 0 is ready.
+	good := newTestIntelFpgaFME(t, map[string]string{
+		"ports_num":                "1",
+		"errors/fme-errors/errors": "0x0\n",
+	})
+	good.Name = "intel-fpga-fme.0"
+	good.BitstreamID = testBitstreamA
+
+	if err := os.Symlink("../../../bus/pci/drivers/intel-fpga-pci", filepath.Join(good.PCIDevice.SysFsPath, "driver")); err != nil {
+		t.Fatal(err)
+	}
+
+	goodPort := newTestIntelFpgaPort(t, good, testAFUOld)
+	goodPort.Name = "intel-fpga-port.0"
+	goodPort.PCIDevice = good.PCIDevice
+
+	// Board 1 has no driver, latched errors, a missing port and a missing VF.
+	bad := newTestIntelFpgaFME(t, map[string]string{
+		"ports_num":                "2",
+		"errors/fme-errors/errors": "0x4\n",
+	})
+	bad.Name = "intel-fpga-fme.1"
+	bad.BitstreamID = testBitstreamA
+	bad.PCIDevice.BDF = otherBDF
+	bad.PCIDevice.VFs = "2"
+
+	vf := filepath.Join(sysfs, "devices/pci0000:af/0000:af:00.1")
+	if err := createTestFiles(vf, nil, map[string]string{"vendor": "0x8086\n", "device": "0x0b31\n"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.Symlink(vf, filepath.Join(bad.PCIDevice.SysFsPath, "virtfn0")); err != nil {
+		t.Fatal(err)
+	}
+
+	badPort := newTestIntelFpgaPort(t, bad, testAFUOld)
+	badPort.Name = "intel-fpga-port.1"
+	badPort.PCIDevice = bad.PCIDevice
+
+	if err := createTestFiles(badPort.SysFsPath, []string{"errors"}, map[string]string{"errors/errors": "0x1\n"}); err != nil {
+		t.Fatal(err)
+	}
+
+	// intel-fpga-fme.2 can't be opened.
+	setTestOpeners(t,
+		map[string]FME{"intel-fpga-fme.0": good, "intel-fpga-fme.1": bad},
+		map[string]Port{"intel-fpga-port.0": goodPort, "intel-fpga-port.1": badPort})
+
+	report, err := AuditNode()
+	if err != nil {
+		t.Fatalf("unexpected error: %+v", err)
+	}
+
+	if report.Pass {
+		t.Error("node with degraded board passed")
+	}
+
+	if len(report.Problems) != 1 || !strings.Contains(report.Problems[0], "unable to open intel-fpga-fme.2") {
+		t.Errorf("expected the FME failing to open reported, but got %v", report.Problems)
+	}
+
+	if len(report.Boards) != 2 {
+		t.Fatalf("expected 2 boards, but got %+v", report.Boards)
+	}
+
+	expectedGood := &BoardAudit{
+		PCIAddress: testBDF,
+		Driver:     "intel-fpga-pci",
+		FMEs:       []string{"intel-fpga-fme.0"},
+		Ports:      []string{"intel-fpga-port.0"},
+	}
+	if !reflect.DeepEqual(report.Boards[0], expectedGood) {
+		t.Errorf("expected %+v, but got %+v", expectedGood, report.Boards[0])
+	}
+
+	badBoard := report.Boards[1]
+	if badBoard.PCIAddress != otherBDF || !badBoard.Degraded || badBoard.Driver != "" {
+		t.Errorf("expected degraded board %s without driver, but got %+v", otherBDF, badBoard)
+	}
+
+	for _, problem := range []string{
+		"no driver bound",
+		"2 VFs configured, but 1 present",
+		"intel-fpga-port.1: error errors is 0x1",
+		"intel-fpga-fme.1: error fme-errors/errors is 0x4",
+		"intel-fpga-fme.1: 2 ports reported, but 1 present",
+	} {
+		found := false
+
+		for _, reported := range badBoard.Problems {
+			found = found || reported == problem
+		}
+
+		if !found {
+			t.Errorf("problem %q not in %v", problem, badBoard.Problems)
+		}
+	}
+
+	// Without the broken devices the node passes.
+	setTestOpeners(t, map[string]FME{"intel-fpga-fme.0": good}, map[string]Port{"intel-fpga-port.0": goodPort})
+
+	for _, dev := range []string{"intel-fpga-fme.1", "intel-fpga-fme.2", "intel-fpga-port.1"} {
+		if err = os.Remove(filepath.Join(sysfs, "bus/platform/devices", dev)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if report, err = AuditNode(); err != nil || !report.Pass {
+		t.Errorf("expected the node to pass, but got %+v, %v", report, err)
+	}
+}
+
+func TestAuditNodePassedThroughPort(t *testing.T) {
+	sysfs := setTestSysfsRoot(t)
+	setTestIoctl(t, func(string, uint, unsafe.Pointer) (uintptr, error) { return 0, nil })
+
+	for _, dev := range []string{"intel-fpga-fme.0", "intel-fpga-port.0"} {
+		if err := createTestFiles(sysfs, []string{"bus/platform/devices/" + dev}, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Port 1 is released to the VF which is passed through to a VM.
+	fme := newTestIntelFpgaFME(t, map[string]string{
+		"ports_num":                "2",
+		"errors/fme-errors/errors": "0x0\n",
+	})
+	fme.Name = "intel-fpga-fme.0"
+	fme.BitstreamID = testBitstreamA
+	fme.PCIDevice.VFs = "1"
+
+	if err := os.Symlink("../../../bus/pci/drivers/intel-fpga-pci", filepath.Join(fme.PCIDevice.SysFsPath, "driver")); err != nil {
+		t.Fatal(err)
+	}
+
+	vf := filepath.Join(sysfs, "devices/pci0000:5e/0000:5e:00.1")
+	if err := createTestFiles(vf, nil, map[string]string{"vendor": "0x8086\n", "device": "0x0b31\n"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.Symlink(vf, filepath.Join(fme.PCIDevice.SysFsPath, "virtfn0")); err != nil {
+		t.Fatal(err)
+	}
+
+	port := newTestIntelFpgaPort(t, fme, testAFUOld)
+	port.Name = "intel-fpga-port.0"
+	port.PCIDevice = fme.PCIDevice
+
+	setTestOpeners(t, map[string]FME{"intel-fpga-fme.0": fme}, map[string]Port{"intel-fpga-port.0": port})
+
+	report, err := AuditNode()
+	if err != nil {
+		t.Fatalf("unexpected error: %+v", err)
+	}
+
+	if len(report.Boards) != 1 || !strings.Contains(strings.Join(report.Boards[0].Problems, "\n"), "2 ports reported, but 1 present") {
+		t.Errorf("expected the port of the unbound VF missing, but got %+v", report.Boards)
+	}
+
+	if err = os.Symlink("../../../bus/pci/drivers/vfio-pci", filepath.Join(vf, "driver")); err != nil {
+		t.Fatal(err)
+	}
+
+	if report, err = AuditNode(); err != nil || !report.Pass {
+		t.Errorf("expected the node to pass, but got %+v, %v", report.Boards, err)
+	}
+}
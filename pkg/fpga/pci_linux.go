@@ -19,8 +19,10 @@ import (
 	"os"
 	"path/filepath"
 	"regexp"
+	"sort"
 	"strconv"
 	"strings"
+	"sync/atomic"
 	"syscall"
 
 	"github.com/pkg/errors"
@@ -34,21 +36,72 @@ const (
 
 var (
 	pciAddressRE = regexp.MustCompile(pciAddressRegex)
+
+	// ErrRebindNotAllowed is returned by UnbindDriver and BindDriver unless
+	// allowed with AllowDriverRebinding.
+	ErrRebindNotAllowed = errors.New("driver rebinding not allowed")
+
+	// driverRebinding is non-zero if driver rebinding is allowed.
+	driverRebinding int32
 )
 
 // PCIDevice represents most valuable sysfs information about PCI device.
 type PCIDevice struct {
-	PhysFn    *PCIDevice
-	SysFsPath string
-	BDF       string
-	Vendor    string
-	Device    string
-	Class     string
-	CPUs      string
-	NUMA      string
-	VFs       string
-	TotalVFs  string
-	Driver    string
+	PhysFn          *PCIDevice
+	SysFsPath       string
+	BDF             string
+	Vendor          string
+	Device          string
+	SubsystemVendor string
+	SubsystemDevice string
+	Class           string
+	CPUs            string
+	NUMA            string
+	VFs             string
+	TotalVFs        string
+	Driver          string
+}
+
+// PCIIDs holds numeric PCI identifiers of a device.
+type PCIIDs struct {
+	Vendor          uint16
+	Device          uint16
+	SubsystemVendor uint16
+	SubsystemDevice uint16
+}
+
+// PCIResource represents single resource (BAR, expansion ROM or SR-IOV BAR)
+// of PCI device as reported by the kernel.
+type PCIResource struct {
+	Start uint64
+	End   uint64
+	Flags uint64
+}
+
+// Size returns size of the resource in bytes or zero for unused resource.
+func (r PCIResource) Size() uint64 {
+	if r.End == 0 && r.Start == 0 {
+		return 0
+	}
+
+	return r.End - r.Start + 1
+}
+
+// AERCounters are the counters of a class of PCIe errors reported by
+// the advanced error reporting of a device.
+type AERCounters struct {
+	// Errors maps the error names, e.g. "BadTLP", to their counters.
+	Errors map[string]uint64
+	// Total is the number of the errors of the class.
+	Total uint64
+}
+
+// AERStatus holds PCIe error counters of a device. The counters of
+// a class the kernel doesn't report are empty.
+type AERStatus struct {
+	Correctable AERCounters
+	NonFatal    AERCounters
+	Fatal       AERCounters
 }
 
 // NewPCIDevice returns sysfs entry for specified PCI device.
@@ -61,7 +114,7 @@ func NewPCIDevice(devPath string) (*PCIDevice, error) {
 
 	pci := new(PCIDevice)
 
-	for p := realDevPath; strings.HasPrefix(p, "/sys/devices/pci"); p = filepath.Dir(p) {
+	for p := realDevPath; strings.HasPrefix(p, filepath.Join(sysfsRoot, "devices/pci")); p = filepath.Dir(p) {
 		subs := pciAddressRE.FindStringSubmatch(filepath.Base(p))
 		if subs == nil || len(subs) != 5 {
 			continue
@@ -78,13 +131,15 @@ func NewPCIDevice(devPath string) (*PCIDevice, error) {
 	}
 
 	fileMap := map[string]*string{
-		"vendor":         &pci.Vendor,
-		"device":         &pci.Device,
-		"class":          &pci.Class,
-		"local_cpulist":  &pci.CPUs,
-		"numa_node":      &pci.NUMA,
-		"sriov_numvfs":   &pci.VFs,
-		"sriov_totalvfs": &pci.TotalVFs,
+		"vendor":           &pci.Vendor,
+		"device":           &pci.Device,
+		"subsystem_vendor": &pci.SubsystemVendor,
+		"subsystem_device": &pci.SubsystemDevice,
+		"class":            &pci.Class,
+		"local_cpulist":    &pci.CPUs,
+		"numa_node":        &pci.NUMA,
+		"sriov_numvfs":     &pci.VFs,
+		"sriov_totalvfs":   &pci.TotalVFs,
 	}
 
 	if err = readFilesInDirectory(fileMap, pci.SysFsPath); err != nil {
@@ -115,21 +170,361 @@ func (pci *PCIDevice) NumVFs() int64 {
 	return -1
 }
 
-// GetVFs returns array of PCI device sysfs entries for VFs.
-func (pci *PCIDevice) GetVFs() (ret []*PCIDevice, err error) {
-	if pci.NumVFs() > 0 {
-		dirs, _ := filepath.Glob(filepath.Join(pci.SysFsPath, "virtfn*"))
-		for _, dir := range dirs {
-			vf, er := NewPCIDevice(dir)
-			if er != nil {
-				return nil, er
+// GetBoundDriver returns name of the kernel driver currently bound to the
+// device. Unlike Driver, which is read once by NewPCIDevice, it reflects
+// binds and unbinds done since. The name is empty if no driver is bound.
+func (pci *PCIDevice) GetBoundDriver() (string, error) {
+	target, err := os.Readlink(filepath.Join(pci.SysFsPath, "driver"))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return "", nil
+		}
+
+		return "", errors.Wrapf(err, "%s: unable to read driver link", pci.BDF)
+	}
+
+	return filepath.Base(target), nil
+}
+
+// GetModalias returns the modalias of the device the kernel matches against
+// the aliases of the driver modules, e.g.
+// "pci:v00008086d00000B30sv00008086sd00000000bc12sc00i00". Only the trailing
+// newline is stripped.
+func (pci *PCIDevice) GetModalias() (string, error) {
+	data, err := readSysfsFile(filepath.Join(pci.SysFsPath, "modalias"))
+	if err != nil {
+		return "", errors.Wrapf(err, "%s: unable to read modalias", pci.BDF)
+	}
+
+	return strings.TrimSuffix(string(data), "\n"), nil
+}
+
+// AllowDriverRebinding allows or forbids UnbindDriver and BindDriver for
+// the whole process. They are forbidden by default, so that only the tools
+// meant to do board recovery, e.g. when requested on their command line,
+// can unbind drivers.
+func AllowDriverRebinding(allow bool) {
+	var val int32
+	if allow {
+		val = 1
+	}
+
+	atomic.StoreInt32(&driverRebinding, val)
+}
+
+// checkRebindAllowed returns ErrRebindNotAllowed unless rebinding is allowed.
+func checkRebindAllowed(bdf string) error {
+	if atomic.LoadInt32(&driverRebinding) == 0 {
+		return errors.Wrapf(ErrRebindNotAllowed, "%s: see AllowDriverRebinding", bdf)
+	}
+
+	return nil
+}
+
+// UnbindDriver unbinds the driver from the device by writing its address
+// to the unbind file of the driver. Nothing is done if no driver is bound.
+// It requires root privileges and is disruptive: the FPGA devices of the
+// board disappear, any workload using them fails and the device nodes
+// opened before stay dead after the driver is bound again. Unless allowed
+// with AllowDriverRebinding, ErrRebindNotAllowed is returned.
+func (pci *PCIDevice) UnbindDriver() error {
+	if err := requireWritable(pci.BDF, "driver rebinding"); err != nil {
+		return err
+	}
+
+	if err := checkRebindAllowed(pci.BDF); err != nil {
+		return err
+	}
+
+	driver, err := pci.GetBoundDriver()
+	if err != nil || driver == "" {
+		return err
+	}
+
+	err = os.WriteFile(filepath.Join(pci.SysFsPath, "driver", "unbind"), []byte(pci.BDF), 0600)
+
+	return errors.Wrapf(err, "%s: unable to unbind driver %s", pci.BDF, driver)
+}
+
+// BindDriver binds the named driver, e.g. dfl-pci, to the device by writing
+// its address to the bind file of the driver. Nothing is done if the driver
+// is already bound, and an error is returned if another one is. Like
+// UnbindDriver, it's privileged, must be allowed with AllowDriverRebinding,
+// and the device nodes of the board appear anew once it succeeds.
+func (pci *PCIDevice) BindDriver(name string) error {
+	if err := requireWritable(pci.BDF, "driver rebinding"); err != nil {
+		return err
+	}
+
+	if err := checkRebindAllowed(pci.BDF); err != nil {
+		return err
+	}
+
+	if name == "" || strings.ContainsRune(name, '/') || name == "." || name == ".." {
+		return errors.Errorf("%s: invalid driver name %q", pci.BDF, name)
+	}
+
+	driver, err := pci.GetBoundDriver()
+	if err != nil {
+		return err
+	}
+
+	switch driver {
+	case name:
+		return nil
+	case "":
+	default:
+		return errors.Errorf("%s: driver %s is bound, unbind it first", pci.BDF, driver)
+	}
+
+	err = os.WriteFile(filepath.Join(sysfsRoot, "bus/pci/drivers", name, "bind"), []byte(pci.BDF), 0600)
+
+	return errors.Wrapf(err, "%s: unable to bind driver %s", pci.BDF, name)
+}
+
+// GetIOMMUGroup returns number of the IOMMU group of the device. All the
+// devices of the group can only be passed through to a VM together, see
+// GroupMembers. ErrNotSupported is returned if the IOMMU is disabled.
+func (pci *PCIDevice) GetIOMMUGroup() (int, error) {
+	target, err := os.Readlink(filepath.Join(pci.SysFsPath, "iommu_group"))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return 0, errors.Wrapf(ErrNotSupported, "%s: no IOMMU group", pci.BDF)
+		}
+
+		return 0, errors.Wrapf(err, "%s: unable to read IOMMU group link", pci.BDF)
+	}
+
+	group, err := strconv.Atoi(filepath.Base(target))
+	if err != nil {
+		return 0, errors.Wrapf(err, "%s: malformed IOMMU group link %q", pci.BDF, target)
+	}
+
+	return group, nil
+}
+
+// GroupMembers returns all the PCI devices of the IOMMU group of the device,
+// including the device itself, sorted by address.
+func (pci *PCIDevice) GroupMembers() ([]*PCIDevice, error) {
+	group, err := pci.GetIOMMUGroup()
+	if err != nil {
+		return nil, err
+	}
+
+	dir := filepath.Join(sysfsRoot, "kernel/iommu_groups", strconv.Itoa(group), "devices")
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, errors.Wrapf(err, "%s: unable to list IOMMU group %d", pci.BDF, group)
+	}
+
+	ret := make([]*PCIDevice, 0, len(entries))
+
+	for _, entry := range entries {
+		member, memberErr := NewPCIDevice(filepath.Join(dir, entry.Name()))
+		if memberErr != nil {
+			return nil, errors.Wrapf(memberErr, "%s: unable to get IOMMU group member %s", pci.BDF, entry.Name())
+		}
+
+		ret = append(ret, member)
+	}
+
+	return ret, nil
+}
+
+// GetAERStatus returns the PCIe error counters of the device collected by
+// the kernel since boot. ErrNotSupported is returned if the device or the
+// kernel lacks the advanced error reporting.
+func (pci *PCIDevice) GetAERStatus() (AERStatus, error) {
+	var status AERStatus
+
+	counters := []struct {
+		counters *AERCounters
+		file     string
+		total    string
+	}{
+		{&status.Correctable, "aer_dev_correctable", "TOTAL_ERR_COR"},
+		{&status.NonFatal, "aer_dev_nonfatal", "TOTAL_ERR_NONFATAL"},
+		{&status.Fatal, "aer_dev_fatal", "TOTAL_ERR_FATAL"},
+	}
+
+	found := false
+
+	for _, c := range counters {
+		data, err := readSysfsFile(filepath.Join(pci.SysFsPath, c.file))
+		if os.IsNotExist(err) {
+			continue
+		}
+
+		if err != nil {
+			return AERStatus{}, errors.Wrapf(err, "%s: unable to read %s", pci.BDF, c.file)
+		}
+
+		if *c.counters, err = parseAERCounters(string(data), c.total); err != nil {
+			return AERStatus{}, errors.Wrapf(err, "%s: malformed %s", pci.BDF, c.file)
+		}
+
+		found = true
+	}
+
+	if !found {
+		return AERStatus{}, errors.Wrapf(ErrNotSupported, "%s: no AER", pci.BDF)
+	}
+
+	return status, nil
+}
+
+// parseAERCounters parses lines of "name count" pairs. The total is taken
+// from the line named total, or summed up if there's none.
+func parseAERCounters(data, total string) (AERCounters, error) {
+	ret := AERCounters{Errors: map[string]uint64{}}
+	hasTotal := false
+	sum := uint64(0)
+
+	for _, line := range strings.Split(strings.TrimSpace(data), "\n") {
+		if line == "" {
+			continue
+		}
+
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			return AERCounters{}, errors.Errorf("unexpected line %q", line)
+		}
+
+		count, err := strconv.ParseUint(fields[1], 10, 64)
+		if err != nil {
+			return AERCounters{}, errors.Wrapf(err, "unexpected line %q", line)
+		}
+
+		if fields[0] == total {
+			ret.Total, hasTotal = count, true
+
+			continue
+		}
+
+		ret.Errors[fields[0]] = count
+		sum += count
+	}
+
+	if !hasTotal {
+		ret.Total = sum
+	}
+
+	return ret, nil
+}
+
+// physicalFunction returns the PCI physical function of the device, that is
+// the device itself unless it's a virtual function.
+func (pci *PCIDevice) physicalFunction() *PCIDevice {
+	if pci.PhysFn != nil {
+		return pci.PhysFn
+	}
+
+	return pci
+}
+
+// IDs returns numeric PCI IDs of the device. Subsystem IDs are zero
+// if they are not exposed by the kernel.
+func (pci *PCIDevice) IDs() (ids PCIIDs, err error) {
+	fields := []struct {
+		val      *uint16
+		name     string
+		str      string
+		optional bool
+	}{
+		{&ids.Vendor, "vendor", pci.Vendor, false},
+		{&ids.Device, "device", pci.Device, false},
+		{&ids.SubsystemVendor, "subsystem_vendor", pci.SubsystemVendor, true},
+		{&ids.SubsystemDevice, "subsystem_device", pci.SubsystemDevice, true},
+	}
+
+	for _, field := range fields {
+		if field.str == "" && field.optional {
+			continue
+		}
+
+		id, err := strconv.ParseUint(strings.TrimPrefix(field.str, "0x"), 16, 16)
+		if err != nil {
+			return PCIIDs{}, errors.Wrapf(err, "%s: unable to parse %s %q", pci.BDF, field.name, field.str)
+		}
+
+		*field.val = uint16(id)
+	}
+
+	return ids, nil
+}
+
+// Resources returns resources of the device parsed from sysfs "resource" file.
+// The slice is indexed by resource number, so it has entries for unused
+// resources as well.
+func (pci *PCIDevice) Resources() ([]PCIResource, error) {
+	data, err := readSysfsFile(filepath.Join(pci.SysFsPath, "resource"))
+	if err != nil {
+		return nil, errors.Wrapf(err, "%s: unable to read resources", pci.BDF)
+	}
+
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	ret := make([]PCIResource, 0, len(lines))
+
+	for i, line := range lines {
+		fields := strings.Fields(line)
+		if len(fields) != 3 {
+			return nil, errors.Errorf("%s: malformed resource %d: %q", pci.BDF, i, line)
+		}
+
+		var vals [3]uint64
+
+		for j, field := range fields {
+			vals[j], err = strconv.ParseUint(strings.TrimPrefix(field, "0x"), 16, 64)
+			if err != nil {
+				return nil, errors.Wrapf(err, "%s: malformed resource %d", pci.BDF, i)
 			}
+		}
+
+		ret = append(ret, PCIResource{Start: vals[0], End: vals[1], Flags: vals[2]})
+	}
+
+	return ret, nil
+}
+
+// GetVFs returns the VFs the physical function has spawned in the order of
+// their virtfnN symlinks. The slice is empty if SR-IOV is disabled, i.e. no
+// VFs are configured.
+func (pci *PCIDevice) GetVFs() ([]*PCIDevice, error) {
+	if pci.NumVFs() <= 0 {
+		return []*PCIDevice{}, nil
+	}
+
+	links, err := filepath.Glob(filepath.Join(pci.SysFsPath, "virtfn*"))
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	indices := make(map[string]int, len(links))
+
+	for _, link := range links {
+		idx, convErr := strconv.Atoi(strings.TrimPrefix(filepath.Base(link), "virtfn"))
+		if convErr != nil {
+			return nil, errors.Wrapf(convErr, "%s: malformed VF link %q", pci.BDF, filepath.Base(link))
+		}
 
-			ret = append(ret, vf)
+		indices[link] = idx
+	}
+
+	sort.Slice(links, func(i, j int) bool { return indices[links[i]] < indices[links[j]] })
+
+	ret := make([]*PCIDevice, 0, len(links))
+
+	for _, link := range links {
+		vf, vfErr := NewPCIDevice(link)
+		if vfErr != nil {
+			return nil, errors.Wrapf(vfErr, "%s: unable to get VF %s", pci.BDF, filepath.Base(link))
 		}
+
+		ret = append(ret, vf)
 	}
 
-	return
+	return ret, nil
 }
 
 // FindSysFsDevice returns sysfs entry for specified device node or device that holds specified file
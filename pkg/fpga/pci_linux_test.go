@@ -0,0 +1,693 @@
+// Copyright 2023 Intel Corporation. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fpga
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"unsafe"
+
+	"github.com/pkg/errors"
+)
+
+func TestGetPCIIDs(t *testing.T) {
+	tcases := []struct {
+		name        string
+		pci         PCIDevice
+		expected    PCIIDs
+		expectedErr bool
+	}{
+		{
+			name: "N3000 with subsystem IDs",
+			pci: PCIDevice{
+				Vendor:          "0x8086",
+				Device:          "0x0b30",
+				SubsystemVendor: "0x8086",
+				SubsystemDevice: "0x12a0",
+			},
+			expected: PCIIDs{Vendor: 0x8086, Device: 0x0b30, SubsystemVendor: 0x8086, SubsystemDevice: 0x12a0},
+		},
+		{
+			name:     "no subsystem IDs",
+			pci:      PCIDevice{Vendor: "0x8086", Device: "0xBCC0"},
+			expected: PCIIDs{Vendor: 0x8086, Device: 0xbcc0},
+		},
+		{
+			name:        "garbage device ID",
+			pci:         PCIDevice{Vendor: "0x8086", Device: "0xzzzz"},
+			expectedErr: true,
+		},
+		{
+			name:        "too large vendor ID",
+			pci:         PCIDevice{Vendor: "0x18086", Device: "0x0b30"},
+			expectedErr: true,
+		},
+		{
+			name:        "missing vendor ID",
+			pci:         PCIDevice{Device: "0x0b30"},
+			expectedErr: true,
+		},
+		{
+			name:        "garbage subsystem ID",
+			pci:         PCIDevice{Vendor: "0x8086", Device: "0x0b30", SubsystemDevice: "n/a"},
+			expectedErr: true,
+		},
+	}
+	for _, tc := range tcases {
+		t.Run(tc.name, func(t *testing.T) {
+			pci := tc.pci
+			fme := &IntelFpgaFME{PCIDevice: &pci}
+
+			ids, err := fme.GetPCIIDsExtended()
+			if tc.expectedErr {
+				if err == nil {
+					t.Error("no error returned")
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("unexpected error: %+v", err)
+			}
+
+			if ids != tc.expected {
+				t.Errorf("expected %+v, but got %+v", tc.expected, ids)
+			}
+
+			vendor, device, err := fme.GetPCIIDs()
+			if err != nil {
+				t.Fatalf("unexpected error: %+v", err)
+			}
+
+			if vendor != tc.expected.Vendor || device != tc.expected.Device {
+				t.Errorf("expected %04x:%04x, but got %04x:%04x", tc.expected.Vendor, tc.expected.Device, vendor, device)
+			}
+		})
+	}
+}
+
+func TestPCIDeviceResources(t *testing.T) {
+	tcases := []struct {
+		name        string
+		resource    string
+		expected    []PCIResource
+		expectedErr bool
+	}{
+		{
+			name: "N3000 PF",
+			resource: `0x00000000c5800000 0x00000000c587ffff 0x000000000014220c
+0x0000000000000000 0x0000000000000000 0x0000000000000000
+0x00000000c5880000 0x00000000c58fffff 0x000000000014220c
+0x0000000000000000 0x0000000000000000 0x0000000000000000
+0x0000000000000000 0x0000000000000000 0x0000000000000000
+0x0000000000000000 0x0000000000000000 0x0000000000000000
+0x0000000000000000 0x0000000000000000 0x0000000000000000
+`,
+			expected: []PCIResource{
+				{Start: 0xc5800000, End: 0xc587ffff, Flags: 0x14220c},
+				{},
+				{Start: 0xc5880000, End: 0xc58fffff, Flags: 0x14220c},
+				{}, {}, {}, {},
+			},
+		},
+		{
+			name:        "missing column",
+			resource:    "0x00000000c5800000 0x00000000c587ffff\n",
+			expectedErr: true,
+		},
+		{
+			name:        "not a number",
+			resource:    "0x00000000c5800000 0x00000000c587ffff flags\n",
+			expectedErr: true,
+		},
+	}
+	for _, tc := range tcases {
+		t.Run(tc.name, func(t *testing.T) {
+			pci := &PCIDevice{SysFsPath: t.TempDir(), BDF: testBDF}
+			if err := createTestFiles(pci.SysFsPath, nil, map[string]string{"resource": tc.resource}); err != nil {
+				t.Fatal(err)
+			}
+
+			res, err := pci.Resources()
+			if tc.expectedErr {
+				if err == nil {
+					t.Error("no error returned")
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("unexpected error: %+v", err)
+			}
+
+			if !reflect.DeepEqual(res, tc.expected) {
+				t.Errorf("expected %+v, but got %+v", tc.expected, res)
+			}
+
+			if size := res[0].Size(); size != 512*1024 {
+				t.Errorf("expected BAR0 size 512K, but got %d", size)
+			}
+
+			if size := res[1].Size(); size != 0 {
+				t.Errorf("expected unused BAR1, but got size %d", size)
+			}
+		})
+	}
+}
+
+func TestIsVirtualFunction(t *testing.T) {
+	root := setTestSysfsRoot(t)
+	pf := filepath.Join(root, "devices/pci0000:5e/0000:5e:00.0")
+	vf := filepath.Join(root, "devices/pci0000:5e/0000:5e:00.1")
+
+	files := map[string]string{}
+	for _, dev := range []string{pf, vf} {
+		files[filepath.Join(dev, "vendor")] = "0x8086\n"
+		files[filepath.Join(dev, "device")] = "0x09c4\n"
+	}
+
+	if err := createTestFiles("/", []string{
+		filepath.Join(pf, "fpga/intel-fpga-dev.0/intel-fpga-fme.0"),
+		filepath.Join(vf, "fpga/intel-fpga-dev.1/intel-fpga-port.1"),
+	}, files); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.Symlink("../0000:5e:00.0", filepath.Join(vf, "physfn")); err != nil {
+		t.Fatal(err)
+	}
+
+	ioctls := 0
+
+	setTestIoctl(t, func(string, uint, unsafe.Pointer) (uintptr, error) {
+		ioctls++
+
+		return 0, nil
+	})
+
+	tcases := []struct {
+		name     string
+		sysfs    string
+		expected bool
+	}{
+		{
+			name:  "physical function",
+			sysfs: filepath.Join(pf, "fpga/intel-fpga-dev.0/intel-fpga-fme.0"),
+		},
+		{
+			name:     "virtual function",
+			sysfs:    filepath.Join(vf, "fpga/intel-fpga-dev.1/intel-fpga-port.1"),
+			expected: true,
+		},
+	}
+	for _, tc := range tcases {
+		t.Run(tc.name, func(t *testing.T) {
+			ioctls = 0
+			fme := &IntelFpgaFME{DevPath: "/dev/intel-fpga-fme.0", SysFsPath: tc.sysfs}
+			port := &DflPort{DevPath: "/dev/dfl-port.0", SysFsPath: tc.sysfs}
+
+			for _, dev := range []commonFpgaAPI{fme, port} {
+				vf, err := dev.IsVirtualFunction()
+				if err != nil {
+					t.Fatalf("unexpected error: %+v", err)
+				}
+
+				if vf != tc.expected {
+					t.Errorf("%s: expected %t, but got %t", dev.GetDevPath(), tc.expected, vf)
+				}
+			}
+
+			errs := []error{fme.PortPR(0, []byte("bitstream")), fme.PortRelease(0), fme.PortAssign(0)}
+			for i, err := range errs {
+				if tc.expected != errors.Is(err, ErrRequiresPhysicalFunction) {
+					t.Errorf("operation %d: unexpected error %v", i, err)
+				}
+			}
+
+			if expected := map[bool]int{false: 3, true: 0}[tc.expected]; ioctls != expected {
+				t.Errorf("expected %d ioctls, but got %d", expected, ioctls)
+			}
+		})
+	}
+}
+
+func TestListVFs(t *testing.T) {
+	root := setTestSysfsRoot(t)
+	bus := filepath.Join(root, "devices/pci0000:5e")
+	pf := filepath.Join(bus, "0000:5e:00.0")
+	vfs := []string{"0000:5e:00.1", "0000:5e:00.2", "0000:5e:01.3"}
+
+	files := map[string]string{}
+	for _, dev := range append([]string{"0000:5e:00.0"}, vfs...) {
+		files[filepath.Join(bus, dev, "vendor")] = "0x8086\n"
+		files[filepath.Join(bus, dev, "device")] = "0x09c4\n"
+	}
+
+	files[filepath.Join(pf, "sriov_numvfs")] = "3\n"
+
+	if err := createTestFiles("/", []string{filepath.Join(pf, "fpga/intel-fpga-dev.0/intel-fpga-fme.0")}, files); err != nil {
+		t.Fatal(err)
+	}
+
+	// virtfn10 is listed before virtfn2 by Glob, but must come last
+	for i, link := range []string{"virtfn0", "virtfn2", "virtfn10"} {
+		if err := os.Symlink("../"+vfs[i], filepath.Join(pf, link)); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := os.Symlink("../0000:5e:00.0", filepath.Join(bus, vfs[i], "physfn")); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	fme := &IntelFpgaFME{DevPath: "/dev/intel-fpga-fme.0", SysFsPath: filepath.Join(pf, "fpga/intel-fpga-dev.0/intel-fpga-fme.0")}
+
+	ret, err := fme.ListVFs()
+	if err != nil {
+		t.Fatalf("unexpected error: %+v", err)
+	}
+
+	bdfs := make([]string, 0, len(ret))
+	for _, vf := range ret {
+		bdfs = append(bdfs, vf.BDF)
+
+		if vf.PhysFn == nil || vf.PhysFn.BDF != "0000:5e:00.0" {
+			t.Errorf("%s: unexpected physical function %+v", vf.BDF, vf.PhysFn)
+		}
+	}
+
+	if !reflect.DeepEqual(bdfs, vfs) {
+		t.Errorf("expected VFs %v, but got %v", vfs, bdfs)
+	}
+
+	ret, err = newTestIntelFpgaFME(t, nil).ListVFs()
+	if err != nil || ret == nil || len(ret) != 0 {
+		t.Errorf("expected empty slice without SR-IOV, but got %v (%v)", ret, err)
+	}
+}
+
+func TestWhichVF(t *testing.T) {
+	root := setTestSysfsRoot(t)
+	bus := filepath.Join(root, "devices/pci0000:5e")
+	pf := filepath.Join(bus, "0000:5e:00.0")
+	vfs := []string{"0000:5e:00.1", "0000:5e:00.2"}
+	pfPort := filepath.Join(pf, "fpga/intel-fpga-dev.0/intel-fpga-port.0")
+	vfPort := filepath.Join(bus, vfs[1], "fpga/intel-fpga-dev.2/intel-fpga-port.2")
+
+	files := map[string]string{
+		filepath.Join(pf, "sriov_totalvfs"): "2\n",
+		filepath.Join(pf, "sriov_numvfs"):   "2\n",
+		filepath.Join(pfPort, "id"):         "0\n",
+		filepath.Join(vfPort, "id"):         "1\n",
+	}
+	for _, dev := range append([]string{"0000:5e:00.0"}, vfs...) {
+		files[filepath.Join(bus, dev, "vendor")] = "0x8086\n"
+		files[filepath.Join(bus, dev, "device")] = "0x09c4\n"
+	}
+
+	if err := createTestFiles("/", []string{pfPort, vfPort, filepath.Join(bus, vfs[0])}, files); err != nil {
+		t.Fatal(err)
+	}
+
+	for i, vf := range vfs {
+		if err := os.Symlink("../"+vf, filepath.Join(pf, fmt.Sprintf("virtfn%d", i))); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := os.Symlink("../0000:5e:00.0", filepath.Join(bus, vf, "physfn")); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	pfPCI, err := NewPCIDevice(pf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tcases := []struct {
+		port      *IntelFpgaPort
+		name      string
+		expected  string
+		noSupport bool
+	}{
+		{
+			name: "assigned to physical function",
+			port: &IntelFpgaPort{DevPath: "/dev/intel-fpga-port.0", SysFsPath: pfPort},
+		},
+		{
+			name:     "released to VF",
+			port:     &IntelFpgaPort{DevPath: "/dev/intel-fpga-port.1", PCIDevice: pfPCI, ID: "1"},
+			expected: vfs[1],
+		},
+		{
+			name:     "opened on VF",
+			port:     &IntelFpgaPort{DevPath: "/dev/intel-fpga-port.2", SysFsPath: vfPort},
+			expected: vfs[1],
+		},
+		{
+			name:      "no SR-IOV",
+			port:      &IntelFpgaPort{DevPath: "/dev/intel-fpga-port.0", PCIDevice: &PCIDevice{BDF: testBDF}, ID: "0"},
+			noSupport: true,
+		},
+	}
+	for _, tc := range tcases {
+		t.Run(tc.name, func(t *testing.T) {
+			vf, vfErr := tc.port.WhichVF()
+			if tc.noSupport {
+				if !errors.Is(vfErr, ErrNotSupported) {
+					t.Errorf("expected ErrNotSupported, but got %v", vfErr)
+				}
+
+				return
+			}
+
+			if vfErr != nil {
+				t.Fatalf("unexpected error: %+v", vfErr)
+			}
+
+			bdf := ""
+			if vf != nil {
+				bdf = vf.BDF
+			}
+
+			if bdf != tc.expected {
+				t.Errorf("expected VF %q, but got %q", tc.expected, bdf)
+			}
+		})
+	}
+}
+
+func TestGetBoundDriver(t *testing.T) {
+	root := t.TempDir()
+	pci := &PCIDevice{SysFsPath: filepath.Join(root, "devices/pci0000:5e/0000:5e:00.0"), BDF: testBDF}
+
+	if err := createTestFiles(root, []string{"devices/pci0000:5e/0000:5e:00.0", "bus/pci/drivers/dfl-pci"}, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	driver, err := pci.GetBoundDriver()
+	if err != nil || driver != "" {
+		t.Errorf("expected no driver, but got %q, %v", driver, err)
+	}
+
+	if err = os.Symlink("../../../bus/pci/drivers/dfl-pci", filepath.Join(pci.SysFsPath, "driver")); err != nil {
+		t.Fatal(err)
+	}
+
+	driver, err = pci.GetBoundDriver()
+	if err != nil || driver != "dfl-pci" {
+		t.Errorf("expected driver dfl-pci, but got %q, %v", driver, err)
+	}
+
+	if err = os.Remove(filepath.Join(pci.SysFsPath, "driver")); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = os.WriteFile(filepath.Join(pci.SysFsPath, "driver"), nil, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = pci.GetBoundDriver(); err == nil {
+		t.Error("no error returned for malformed driver link")
+	}
+}
+
+func TestGetModalias(t *testing.T) {
+	const modalias = "pci:v00008086d00000B30sv00008086sd00000000bc12sc00i00"
+
+	root := t.TempDir()
+	pci := &PCIDevice{SysFsPath: filepath.Join(root, testBDF), BDF: testBDF}
+
+	if _, err := pci.GetModalias(); err == nil {
+		t.Error("no error returned for missing modalias")
+	}
+
+	if err := createTestFiles(root, []string{testBDF}, map[string]string{testBDF + "/modalias": modalias + "\n"}); err != nil {
+		t.Fatal(err)
+	}
+
+	alias, err := pci.GetModalias()
+	if err != nil || alias != modalias {
+		t.Errorf("expected %q, but got %q, %v", modalias, alias, err)
+	}
+}
+
+func TestRebindDriver(t *testing.T) {
+	root := setTestSysfsRoot(t)
+	pci := &PCIDevice{SysFsPath: filepath.Join(root, "devices/pci0000:5e/0000:5e:00.0"), BDF: testBDF}
+	drivers := filepath.Join(root, "bus/pci/drivers")
+	files := map[string]string{
+		"bus/pci/drivers/dfl-pci/bind":   "",
+		"bus/pci/drivers/dfl-pci/unbind": "",
+	}
+
+	if err := createTestFiles(root, []string{"devices/pci0000:5e/0000:5e:00.0", "bus/pci/drivers/dfl-pci"}, files); err != nil {
+		t.Fatal(err)
+	}
+
+	expectWritten := func(file, expected string) {
+		t.Helper()
+
+		data, err := os.ReadFile(filepath.Join(drivers, "dfl-pci", file))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if string(data) != expected {
+			t.Errorf("expected %q in %s, but got %q", expected, file, data)
+		}
+	}
+
+	if err := pci.UnbindDriver(); !errors.Is(err, ErrRebindNotAllowed) {
+		t.Errorf("expected ErrRebindNotAllowed, but got %v", err)
+	}
+
+	if err := pci.BindDriver("dfl-pci"); !errors.Is(err, ErrRebindNotAllowed) {
+		t.Errorf("expected ErrRebindNotAllowed, but got %v", err)
+	}
+
+	AllowDriverRebinding(true)
+	t.Cleanup(func() { AllowDriverRebinding(false) })
+
+	if err := pci.UnbindDriver(); err != nil {
+		t.Errorf("unexpected error without driver: %+v", err)
+	}
+
+	expectWritten("unbind", "")
+
+	if err := os.Symlink("../../../bus/pci/drivers/dfl-pci", filepath.Join(pci.SysFsPath, "driver")); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := pci.BindDriver("dfl-pci"); err != nil {
+		t.Errorf("unexpected error with driver already bound: %+v", err)
+	}
+
+	expectWritten("bind", "")
+
+	if err := pci.BindDriver("intel-fpga-pci"); err == nil {
+		t.Error("no error returned with another driver bound")
+	}
+
+	if err := pci.UnbindDriver(); err != nil {
+		t.Errorf("unexpected error: %+v", err)
+	}
+
+	expectWritten("unbind", testBDF)
+
+	// The fake driver doesn't remove the link on unbind.
+	if err := os.Remove(filepath.Join(pci.SysFsPath, "driver")); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, name := range []string{"", "..", "../dfl-pci"} {
+		if err := pci.BindDriver(name); err == nil {
+			t.Errorf("no error returned for driver name %q", name)
+		}
+	}
+
+	if err := pci.BindDriver("dfl-pci"); err != nil {
+		t.Errorf("unexpected error: %+v", err)
+	}
+
+	expectWritten("bind", testBDF)
+}
+
+func TestIOMMUGroup(t *testing.T) {
+	root := setTestSysfsRoot(t)
+	bus := filepath.Join(root, "devices/pci0000:5e")
+	devs := []string{"0000:5e:00.0", "0000:5e:00.1", "0000:5e:01.0"}
+	groups := map[string]string{"0000:5e:00.0": "12", "0000:5e:00.1": "12", "0000:5e:01.0": "13"}
+
+	files := map[string]string{}
+	for _, dev := range devs {
+		files[filepath.Join(bus, dev, "vendor")] = "0x8086\n"
+		files[filepath.Join(bus, dev, "device")] = "0x0b30\n"
+	}
+
+	if err := createTestFiles("/", []string{filepath.Join(root, "kernel/iommu_groups/12/devices"), filepath.Join(root, "kernel/iommu_groups/13/devices")}, files); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, dev := range devs {
+		if err := os.Symlink("../../../kernel/iommu_groups/"+groups[dev], filepath.Join(bus, dev, "iommu_group")); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := os.Symlink("../../../../devices/pci0000:5e/"+dev, filepath.Join(root, "kernel/iommu_groups", groups[dev], "devices", dev)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	pci := &PCIDevice{SysFsPath: filepath.Join(bus, "0000:5e:00.0"), BDF: "0000:5e:00.0"}
+
+	group, err := pci.GetIOMMUGroup()
+	if err != nil || group != 12 {
+		t.Errorf("expected group 12, but got %d, %v", group, err)
+	}
+
+	members, err := pci.GroupMembers()
+	if err != nil {
+		t.Fatalf("unexpected error: %+v", err)
+	}
+
+	bdfs := make([]string, 0, len(members))
+	for _, member := range members {
+		bdfs = append(bdfs, member.BDF)
+	}
+
+	if expected := []string{"0000:5e:00.0", "0000:5e:00.1"}; !reflect.DeepEqual(bdfs, expected) {
+		t.Errorf("expected members %v, but got %v", expected, bdfs)
+	}
+
+	noIOMMU := &PCIDevice{SysFsPath: t.TempDir(), BDF: testBDF}
+
+	if _, err = noIOMMU.GetIOMMUGroup(); !errors.Is(err, ErrNotSupported) {
+		t.Errorf("expected ErrNotSupported without IOMMU, but got %v", err)
+	}
+
+	if _, err = noIOMMU.GroupMembers(); !errors.Is(err, ErrNotSupported) {
+		t.Errorf("expected ErrNotSupported without IOMMU, but got %v", err)
+	}
+}
+
+func TestGetAERStatus(t *testing.T) {
+	const correctable = `RxErr 0
+BadTLP 3
+BadDLLP 1
+Rollover 0
+Timeout 2
+NonFatalErr 0
+CorrIntErr 0
+HeaderOF 0
+TOTAL_ERR_COR 6
+`
+	const fatal = `Undefined 0
+DLP 0
+SDES 0
+TLP 0
+FCP 0
+CmpltTO 0
+CmpltAbrt 0
+UnxCmplt 0
+RxOF 0
+MalfTLP 1
+ECRC 0
+UnsupReq 0
+ACSViol 0
+UncorrIntErr 0
+BlockedTLP 0
+AtomicOpBlocked 0
+TLPBlockedErr 0
+PoisonTLPBlocked 0
+TOTAL_ERR_FATAL 1
+`
+	tcases := []struct {
+		files              map[string]string
+		expected           AERStatus
+		name               string
+		expectNotSupported bool
+		expectedErr        bool
+	}{
+		{
+			name:               "no AER",
+			expectNotSupported: true,
+		},
+		{
+			name:  "correctable and fatal",
+			files: map[string]string{"aer_dev_correctable": correctable, "aer_dev_fatal": fatal},
+			expected: AERStatus{
+				Correctable: AERCounters{
+					Errors: map[string]uint64{
+						"RxErr": 0, "BadTLP": 3, "BadDLLP": 1, "Rollover": 0, "Timeout": 2,
+						"NonFatalErr": 0, "CorrIntErr": 0, "HeaderOF": 0,
+					},
+					Total: 6,
+				},
+				Fatal: AERCounters{
+					Errors: map[string]uint64{
+						"Undefined": 0, "DLP": 0, "SDES": 0, "TLP": 0, "FCP": 0, "CmpltTO": 0,
+						"CmpltAbrt": 0, "UnxCmplt": 0, "RxOF": 0, "MalfTLP": 1, "ECRC": 0,
+						"UnsupReq": 0, "ACSViol": 0, "UncorrIntErr": 0, "BlockedTLP": 0,
+						"AtomicOpBlocked": 0, "TLPBlockedErr": 0, "PoisonTLPBlocked": 0,
+					},
+					Total: 1,
+				},
+			},
+		},
+		{
+			name:  "no total",
+			files: map[string]string{"aer_dev_nonfatal": "CmpltTO 2\nUnsupReq 5\n"},
+			expected: AERStatus{
+				NonFatal: AERCounters{Errors: map[string]uint64{"CmpltTO": 2, "UnsupReq": 5}, Total: 7},
+			},
+		},
+		{
+			name:        "malformed",
+			files:       map[string]string{"aer_dev_correctable": "RxErr zero\n"},
+			expectedErr: true,
+		},
+	}
+	for _, tc := range tcases {
+		t.Run(tc.name, func(t *testing.T) {
+			pci := &PCIDevice{SysFsPath: t.TempDir(), BDF: testBDF}
+			if err := createTestFiles(pci.SysFsPath, nil, tc.files); err != nil {
+				t.Fatal(err)
+			}
+
+			status, err := pci.GetAERStatus()
+
+			switch {
+			case tc.expectNotSupported:
+				if !errors.Is(err, ErrNotSupported) {
+					t.Errorf("expected ErrNotSupported, but got %v", err)
+				}
+			case tc.expectedErr:
+				if err == nil {
+					t.Error("no error returned")
+				}
+			case err != nil:
+				t.Errorf("unexpected error: %+v", err)
+			case !reflect.DeepEqual(status, tc.expected):
+				t.Errorf("expected %+v, but got %+v", tc.expected, status)
+			}
+		})
+	}
+}
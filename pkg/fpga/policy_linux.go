@@ -0,0 +1,69 @@
+// Copyright 2023 Intel Corporation. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fpga
+
+import (
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// ErrPRNotAllowed is returned when the PR policy refuses the bitstream.
+var ErrPRNotAllowed = errors.New("partial reconfiguration not allowed")
+
+// prAllowlist holds interface UUIDs accepted for partial reconfiguration.
+// Nil or empty set allows all interfaces.
+var prAllowlist = struct {
+	uuids map[string]struct{}
+	sync.RWMutex
+}{}
+
+// SetPRAllowlist restricts partial reconfiguration done by Port.PR and the
+// functions built on it to bitstreams with one of the given interface UUIDs.
+// Empty list removes the restriction. The allowlist applies to the whole
+// process, e.g. it can be set once by the CRI hook from its configuration.
+func SetPRAllowlist(interfaceUUIDs []string) {
+	var uuids map[string]struct{}
+
+	if len(interfaceUUIDs) > 0 {
+		uuids = make(map[string]struct{}, len(interfaceUUIDs))
+		for _, id := range interfaceUUIDs {
+			uuids[strings.ToLower(id)] = struct{}{}
+		}
+	}
+
+	prAllowlist.Lock()
+	defer prAllowlist.Unlock()
+
+	prAllowlist.uuids = uuids
+}
+
+// checkPRAllowed returns ErrPRNotAllowed if the allowlist is set and
+// the interface UUID isn't in it.
+func checkPRAllowed(interfaceUUID string) error {
+	prAllowlist.RLock()
+	defer prAllowlist.RUnlock()
+
+	if len(prAllowlist.uuids) == 0 {
+		return nil
+	}
+
+	if _, ok := prAllowlist.uuids[strings.ToLower(interfaceUUID)]; !ok {
+		return errors.Wrapf(ErrPRNotAllowed, "interface UUID %q isn't in the allowlist", interfaceUUID)
+	}
+
+	return nil
+}
@@ -0,0 +1,107 @@
+// Copyright 2023 Intel Corporation. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fpga
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/pkg/errors"
+
+	"github.com/intel/intel-device-plugins-for-kubernetes/pkg/fpga/bitstream"
+)
+
+func TestPRAllowlist(t *testing.T) {
+	tcases := []struct {
+		name        string
+		allowlist   []string
+		expectedErr error
+		expectedPR  bool
+	}{
+		{
+			name:       "unset",
+			expectedPR: true,
+		},
+		{
+			name:       "allowed",
+			allowlist:  []string{"ce48969398f05f33946d560708be108a", strings.ToUpper(testInterface)},
+			expectedPR: true,
+		},
+		{
+			name:        "denied",
+			allowlist:   []string{"ce48969398f05f33946d560708be108a"},
+			expectedErr: ErrPRNotAllowed,
+		},
+	}
+	for _, tc := range tcases {
+		t.Run(tc.name, func(t *testing.T) {
+			SetPRAllowlist(tc.allowlist)
+			t.Cleanup(func() { SetPRAllowlist(nil) })
+
+			programmed := false
+			fme := &testFME{interfaceUUID: testInterface}
+			fme.portPR = func(id uint32, data []byte) error {
+				programmed = true
+
+				return nil
+			}
+
+			port := newTestIntelFpgaPort(t, fme, testAFUOld)
+
+			_, err := port.PRContext(context.Background(), newTestGBS(t, testInterface, testAFUNew), PROptions{SkipReadback: true})
+			if tc.expectedErr != nil {
+				if !errors.Is(err, tc.expectedErr) {
+					t.Errorf("expected error %v, but got %v", tc.expectedErr, err)
+				}
+			} else if err != nil {
+				t.Errorf("unexpected error: %+v", err)
+			}
+
+			if programmed != tc.expectedPR {
+				t.Errorf("expected PR to be called: %t, called: %t", tc.expectedPR, programmed)
+			}
+		})
+	}
+}
+
+func TestPRAllowlistRollback(t *testing.T) {
+	SetPRAllowlist([]string{testInterface})
+	t.Cleanup(func() { SetPRAllowlist(nil) })
+
+	calls := 0
+	fme := &testFME{interfaceUUID: testInterface}
+	fme.portPR = func(id uint32, data []byte) error {
+		calls++
+
+		return errors.New("PR failed")
+	}
+
+	port := newTestIntelFpgaPort(t, fme, testAFUOld)
+	opts := PROptions{
+		Rollback: func(_, afuUUID string) (bitstream.File, error) {
+			return newTestGBS(t, "ce48969398f05f33946d560708be108a", afuUUID), nil
+		},
+	}
+
+	res, err := port.PRContext(context.Background(), newTestGBS(t, testInterface, testAFUNew), opts)
+	if err == nil || !strings.Contains(err.Error(), "allowlist") {
+		t.Errorf("expected rollback refused by the allowlist, but got %v", err)
+	}
+
+	if calls != 1 || res.RolledBack {
+		t.Errorf("rollback bitstream not in the allowlist programmed: %d PR calls, result %+v", calls, res)
+	}
+}
@@ -0,0 +1,308 @@
+// Copyright 2023 Intel Corporation. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fpga
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/intel/intel-device-plugins-for-kubernetes/pkg/fpga/bitstream"
+	"github.com/pkg/errors"
+)
+
+const (
+	// intelFpgaPowerMgmtDir is the power management feature of intel-fpga FME.
+	// Its attributes are in watts.
+	intelFpgaPowerMgmtDir = "power_mgmt"
+	// dflPowerHwmonName is the name of the DFL FME hwmon device reporting power.
+	// Its attributes are in microwatts.
+	dflPowerHwmonName = "dfl_fme_power"
+	// portPowerStateFile is the port attribute with the AFU power state (AP state)
+	// set by the board power management.
+	portPowerStateFile = "power_state"
+	// runtimePMStatusFile is the runtime power management status of any device.
+	runtimePMStatusFile = "power/runtime_status"
+)
+
+// ErrInsufficientPowerHeadroom is returned by PR with
+// PROptions.RequirePowerHeadroom if the AFU could push the board power
+// over its threshold.
+var ErrInsufficientPowerHeadroom = errors.New("insufficient power headroom")
+
+// Normalized port power states returned by GetPortPowerState. The AP states
+// throttle the AFU clock by 50% (AP1), 90% (AP2) or gate it completely (AP6).
+const (
+	PortPowerStateNormal    = "normal"
+	PortPowerStateAP1       = "ap1"
+	PortPowerStateAP2       = "ap2"
+	PortPowerStateAP6       = "ap6"
+	PortPowerStateActive    = "active"
+	PortPowerStateSuspended = "suspended"
+)
+
+// portAPStates maps values of the power_state attribute to the port power states.
+var portAPStates = map[uint64]string{
+	0: PortPowerStateNormal,
+	1: PortPowerStateAP1,
+	2: PortPowerStateAP2,
+	6: PortPowerStateAP6,
+}
+
+// PowerInfo describes power consumption and thresholds of the FPGA in watts.
+type PowerInfo struct {
+	// Consumed is the current power consumption.
+	Consumed uint64
+	// Threshold1 is the power level at which the board starts to throttle the AFU.
+	Threshold1 uint64
+	// Threshold2 is the maximum power level the board allows for the AFU.
+	Threshold2 uint64
+}
+
+// Limit returns the maximum power the board can supply to the AFU
+// or zero if it's unknown.
+func (p PowerInfo) Limit() uint64 {
+	if p.Threshold2 > 0 {
+		return p.Threshold2
+	}
+
+	return p.Threshold1
+}
+
+// readPowerInfo reads power attributes of the given names from dir and scales
+// them down to watts by dividing by div.
+func readPowerInfo(dir string, consumed, threshold1, threshold2 string, div uint64) (PowerInfo, error) {
+	var info PowerInfo
+
+	for name, val := range map[string]*uint64{
+		consumed:   &info.Consumed,
+		threshold1: &info.Threshold1,
+		threshold2: &info.Threshold2,
+	} {
+		data, err := readSysfsFile(filepath.Join(dir, name))
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+
+			return PowerInfo{}, errors.Wrapf(err, "%s: unable to read %s", dir, name)
+		}
+
+		num, err := strconv.ParseUint(strings.TrimSpace(string(data)), 10, 64)
+		if err != nil {
+			return PowerInfo{}, errors.Wrapf(err, "%s: unable to parse %s", dir, name)
+		}
+
+		*val = num / div
+	}
+
+	return info, nil
+}
+
+// getIntelFpgaPowerInfo reads power management feature of intel-fpga FME.
+func getIntelFpgaPowerInfo(fme FME) (PowerInfo, error) {
+	dir := filepath.Join(fme.GetSysFsPath(), intelFpgaPowerMgmtDir)
+	if _, err := os.Stat(dir); err != nil {
+		return PowerInfo{}, errors.Wrapf(ErrNotSupported, "%s: no power management", fme.GetName())
+	}
+
+	return readPowerInfo(dir, "consumed", "threshold1", "threshold2", 1)
+}
+
+// getDflPowerInfo reads power hwmon device of DFL FME.
+func getDflPowerInfo(fme FME) (PowerInfo, error) {
+	hwmons, _ := filepath.Glob(filepath.Join(fme.GetSysFsPath(), "hwmon/hwmon*"))
+	for _, hwmon := range hwmons {
+		var name string
+		if err := readFilesInDirectory(map[string]*string{"name": &name}, hwmon); err != nil || name != dflPowerHwmonName {
+			continue
+		}
+
+		return readPowerInfo(hwmon, "power1_input", "power1_max", "power1_crit", 1000000)
+	}
+
+	return PowerInfo{}, errors.Wrapf(ErrNotSupported, "%s: no power hwmon", fme.GetName())
+}
+
+// getVoltageRails returns the voltage sensors of the FME hwmon devices. The
+// rails are named by their labels or, if there are none, as "<hwmon name>_inN".
+func getVoltageRails(fme FME) (map[string]float64, error) {
+	sensors, err := getHWMon(fme)
+	if err != nil {
+		return map[string]float64{}, err
+	}
+
+	rails := map[string]float64{}
+
+	for _, sensor := range sensors {
+		if sensor.Type != HWMonVoltage {
+			continue
+		}
+
+		name := sensor.Label
+		if name == "" {
+			name = sensor.Device + "_" + sensor.Name
+		}
+
+		rails[name] = sensor.Value
+	}
+
+	if len(rails) == 0 {
+		return rails, errors.Wrapf(ErrNotSupported, "%s: no voltage sensors", fme.GetName())
+	}
+
+	return rails, nil
+}
+
+// getPortPowerState returns power state of the port. The AP state published
+// by both intel-fpga and DFL drivers takes precedence, otherwise the runtime
+// power management status of the port device is used, i.e. "active",
+// "suspended" or a transitional state.
+func getPortPowerState(port Port) (string, error) {
+	var apState, rpmStatus string
+
+	sysfs := port.GetSysFsPath()
+	if sysfs == "" {
+		return "", errors.Errorf("%s: unknown sysfs entry", port.GetName())
+	}
+
+	fileMap := map[string]*string{
+		portPowerStateFile:  &apState,
+		runtimePMStatusFile: &rpmStatus,
+	}
+	if err := readFilesInDirectory(fileMap, sysfs); err != nil {
+		return "", err
+	}
+
+	if apState != "" {
+		value, err := strconv.ParseUint(apState, 0, 8)
+		if err != nil {
+			return "", errors.Wrapf(err, "%s: unable to parse %s", port.GetName(), portPowerStateFile)
+		}
+
+		if state, ok := portAPStates[value]; ok {
+			return state, nil
+		}
+
+		return "", errors.Errorf("%s: unknown power state %#x", port.GetName(), value)
+	}
+
+	if rpmStatus == "" || rpmStatus == "unsupported" {
+		return "", errors.Wrapf(ErrNotSupported, "%s: no power state", port.GetName())
+	}
+
+	return strings.ToLower(rpmStatus), nil
+}
+
+// bitstreamPowerClass returns the power in watts the AFU of the bitstream
+// declares, zero if it declares none.
+func bitstreamPowerClass(bs bitstream.File) int {
+	switch f := bs.(type) {
+	case *bitstream.FileGBS:
+		return f.PowerClass()
+	case *bitstream.FileAOCX:
+		if f.GBS != nil {
+			return f.GBS.PowerClass()
+		}
+	}
+
+	return 0
+}
+
+// checkPowerHeadroom returns ErrInsufficientPowerHeadroom if programming
+// the bitstream could make the board exceed its power threshold. The power
+// the AFU being replaced draws can't be told from the board consumption, so
+// the heuristic is conservative: the AFU's declared power class must fit
+// between the current consumption and the power limit of the board, see
+// PowerInfo.Limit, which CompatibleWith checks the power class against too.
+// AFUs declaring no power class always fit. If the board doesn't report its consumption and
+// thresholds, headroom can't be verified and the error is returned as well.
+func checkPowerHeadroom(fme FME, bs bitstream.File) error {
+	power := bitstreamPowerClass(bs)
+	if power <= 0 {
+		return nil
+	}
+
+	info, err := fme.GetPowerInfo()
+	if err != nil {
+		return errors.Wrapf(ErrInsufficientPowerHeadroom, "%s: unable to get power info: %v", fme.GetName(), err)
+	}
+
+	threshold := info.Limit()
+	if threshold == 0 {
+		return errors.Wrapf(ErrInsufficientPowerHeadroom, "%s: no power threshold reported", fme.GetName())
+	}
+
+	if info.Consumed+uint64(power) > threshold {
+		return errors.Wrapf(ErrInsufficientPowerHeadroom, "AFU %s requires %d W, %s consumes %d W of %d W",
+			bs.AcceleratorTypeUUID(), power, fme.GetName(), info.Consumed, threshold)
+	}
+
+	return nil
+}
+
+// CompatibleWith checks that the bitstream can be programmed to a port of the FME.
+// The FME interface must match the bitstream, the board's FIM must not be older
+// than the bitstream requires and, for GBS bitstreams, the metadata must be valid
+// and the board must be able to supply the power the AFU requires.
+// The power check is skipped if the board doesn't report its power thresholds.
+// The platform name of the bitstream is compared with the board's model as
+// configured by SetPlatformNameCheck.
+func CompatibleWith(fme FME, bs bitstream.File) error {
+	ifID := fme.GetInterfaceUUID()
+	bsID := bs.InterfaceUUID()
+
+	if ifID != bsID {
+		return errors.Errorf("FME interface UUID %q is not compatible with bitstream UUID %q ", ifID, bsID)
+	}
+
+	if err := checkInterfaceVersion(fme, bs); err != nil {
+		return err
+	}
+
+	if err := checkPlatformName(fme, bs); err != nil {
+		return err
+	}
+
+	gbs, ok := bs.(*bitstream.FileGBS)
+	if !ok {
+		return nil
+	}
+
+	if magic := gbs.MagicNo(); magic != 0 && magic != bitstream.GBSMagicNo {
+		return errors.Errorf("bitstream %s has unexpected magic-no %d", bs.UniqueUUID(), magic)
+	}
+
+	power := gbs.PowerClass()
+	if power <= 0 {
+		return nil
+	}
+
+	info, err := fme.GetPowerInfo()
+	if errors.Is(err, ErrNotSupported) {
+		return nil
+	}
+
+	if err != nil {
+		return err
+	}
+
+	if limit := info.Limit(); limit > 0 && uint64(power) > limit {
+		return errors.Errorf("AFU %s requires %d W, but %s can supply only %d W", bs.AcceleratorTypeUUID(), power, fme.GetName(), limit)
+	}
+
+	return nil
+}
@@ -0,0 +1,473 @@
+// Copyright 2023 Intel Corporation. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fpga
+
+import (
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/pkg/errors"
+
+	"github.com/intel/intel-device-plugins-for-kubernetes/pkg/fpga/bitstream"
+)
+
+func TestGetPowerInfo(t *testing.T) {
+	t.Run("intel-fpga", func(t *testing.T) {
+		fme := newTestIntelFpgaFME(t, map[string]string{
+			"power_mgmt/consumed":   "31\n",
+			"power_mgmt/threshold1": "60\n",
+			"power_mgmt/threshold2": "66\n",
+		})
+
+		info, err := fme.GetPowerInfo()
+		if err != nil {
+			t.Fatalf("unexpected error: %+v", err)
+		}
+
+		if expected := (PowerInfo{Consumed: 31, Threshold1: 60, Threshold2: 66}); info != expected {
+			t.Errorf("expected %+v, but got %+v", expected, info)
+		}
+
+		if _, err = newTestIntelFpgaFME(t, nil).GetPowerInfo(); !errors.Is(err, ErrNotSupported) {
+			t.Errorf("expected ErrNotSupported, but got %+v", err)
+		}
+	})
+
+	t.Run("DFL", func(t *testing.T) {
+		sysfs := t.TempDir()
+		files := map[string]string{
+			"hwmon/hwmon3/name":         "dfl_fme_thermal\n",
+			"hwmon/hwmon3/temp1_input":  "45000\n",
+			"hwmon/hwmon4/name":         "dfl_fme_power\n",
+			"hwmon/hwmon4/power1_input": "31000000\n",
+			"hwmon/hwmon4/power1_max":   "60000000\n",
+			"hwmon/hwmon4/power1_crit":  "66000000\n",
+		}
+
+		if err := createTestFiles(sysfs, nil, files); err != nil {
+			t.Fatal(err)
+		}
+
+		fme := &DflFME{DevPath: "/dev/dfl-fme.0", SysFsPath: sysfs}
+
+		info, err := fme.GetPowerInfo()
+		if err != nil {
+			t.Fatalf("unexpected error: %+v", err)
+		}
+
+		if expected := (PowerInfo{Consumed: 31, Threshold1: 60, Threshold2: 66}); info != expected {
+			t.Errorf("expected %+v, but got %+v", expected, info)
+		}
+
+		fme = &DflFME{DevPath: "/dev/dfl-fme.0", SysFsPath: filepath.Join(sysfs, "hwmon/hwmon3")}
+		if _, err = fme.GetPowerInfo(); !errors.Is(err, ErrNotSupported) {
+			t.Errorf("expected ErrNotSupported, but got %+v", err)
+		}
+	})
+}
+
+func TestGetVoltageRails(t *testing.T) {
+	fme := newTestIntelFpgaFME(t, map[string]string{
+		"hwmon/hwmon2/name":           "intel_fpga_fme\n",
+		"hwmon/hwmon2/temp1_input":    "45000\n",
+		"bmc0/hwmon/hwmon3/name":      "n3000bmc-hwmon\n",
+		"bmc0/hwmon/hwmon3/in0_input": "12096\n",
+		"bmc0/hwmon/hwmon3/in0_label": "12V Backplane Voltage\n",
+		"bmc0/hwmon/hwmon3/in1_input": "3287\n",
+		"bmc0/hwmon/hwmon3/in1_label": "3.3V Voltage\n",
+		"bmc0/hwmon/hwmon3/in2_input": "853\n",
+	})
+
+	rails, err := fme.GetVoltageRails()
+	if err != nil {
+		t.Fatalf("unexpected error: %+v", err)
+	}
+
+	expected := map[string]float64{
+		"12V Backplane Voltage": 12.096,
+		"3.3V Voltage":          3.287,
+		"n3000bmc-hwmon_in2":    0.853,
+	}
+	if !reflect.DeepEqual(rails, expected) {
+		t.Errorf("expected %v, but got %v", expected, rails)
+	}
+
+	rails, err = newTestIntelFpgaFME(t, map[string]string{"hwmon/hwmon2/temp1_input": "45000\n"}).GetVoltageRails()
+	if !errors.Is(err, ErrNotSupported) || rails == nil || len(rails) != 0 {
+		t.Errorf("expected empty map and ErrNotSupported, but got %v (%v)", rails, err)
+	}
+
+	_, err = newTestIntelFpgaFME(t, map[string]string{"hwmon/hwmon2/in0_input": "n/a\n"}).GetVoltageRails()
+	if err == nil || errors.Is(err, ErrNotSupported) {
+		t.Errorf("expected parse error, but got %v", err)
+	}
+}
+
+func TestGetPortPowerState(t *testing.T) {
+	tcases := []struct {
+		files       map[string]string
+		expectedErr error
+		name        string
+		expected    string
+		invalid     bool
+	}{
+		{
+			name:     "normal",
+			files:    map[string]string{"power_state": "0\n", "power/runtime_status": "active\n"},
+			expected: PortPowerStateNormal,
+		},
+		{
+			name:     "AP1",
+			files:    map[string]string{"power_state": "0x1\n"},
+			expected: PortPowerStateAP1,
+		},
+		{
+			name:     "AP2",
+			files:    map[string]string{"power_state": "2\n"},
+			expected: PortPowerStateAP2,
+		},
+		{
+			name:     "clock gated",
+			files:    map[string]string{"power_state": "6\n"},
+			expected: PortPowerStateAP6,
+		},
+		{
+			name:    "unknown AP state",
+			files:   map[string]string{"power_state": "3\n"},
+			invalid: true,
+		},
+		{
+			name:     "runtime suspended",
+			files:    map[string]string{"power/runtime_status": "Suspended\n"},
+			expected: PortPowerStateSuspended,
+		},
+		{
+			name:        "runtime PM unsupported",
+			files:       map[string]string{"power/runtime_status": "unsupported\n"},
+			expectedErr: ErrNotSupported,
+		},
+		{
+			name:        "no attributes",
+			expectedErr: ErrNotSupported,
+		},
+	}
+	for _, tc := range tcases {
+		t.Run(tc.name, func(t *testing.T) {
+			sysfs := t.TempDir()
+			if err := createTestFiles(sysfs, nil, tc.files); err != nil {
+				t.Fatal(err)
+			}
+
+			state, err := (&IntelFpgaPort{SysFsPath: sysfs}).GetPortPowerState()
+
+			switch {
+			case tc.expectedErr != nil:
+				if !errors.Is(err, tc.expectedErr) {
+					t.Errorf("expected error %v, but got %v", tc.expectedErr, err)
+				}
+			case tc.invalid:
+				if err == nil {
+					t.Errorf("expected error, but got state %q", state)
+				}
+			case err != nil:
+				t.Errorf("unexpected error: %+v", err)
+			case state != tc.expected:
+				t.Errorf("expected state %q, but got %q", tc.expected, state)
+			}
+		})
+	}
+}
+
+func TestCompatibleWith(t *testing.T) {
+	tcases := []struct {
+		powerErr    error
+		name        string
+		bsInterface string
+		fimVersion  string
+		bitstreamID string
+		platform    string
+		model       string
+		power       PowerInfo
+		magicNo     int
+		powerClass  int
+		check       PlatformCheck
+		expectedErr bool
+	}{
+		{
+			name:        "no power requirements",
+			bsInterface: testInterface,
+			magicNo:     bitstream.GBSMagicNo,
+			power:       PowerInfo{Threshold1: 60, Threshold2: 66},
+		},
+		{
+			name:        "low power AFU",
+			bsInterface: testInterface,
+			magicNo:     bitstream.GBSMagicNo,
+			powerClass:  25,
+			power:       PowerInfo{Threshold1: 60, Threshold2: 66},
+		},
+		{
+			name:        "AFU at board limit",
+			bsInterface: testInterface,
+			powerClass:  66,
+			power:       PowerInfo{Threshold1: 60, Threshold2: 66},
+		},
+		{
+			name:        "AFU over board limit",
+			bsInterface: testInterface,
+			powerClass:  90,
+			power:       PowerInfo{Threshold1: 60, Threshold2: 66},
+			expectedErr: true,
+		},
+		{
+			name:        "AFU over single threshold",
+			bsInterface: testInterface,
+			powerClass:  66,
+			power:       PowerInfo{Threshold1: 60},
+			expectedErr: true,
+		},
+		{
+			name:        "board without power management",
+			bsInterface: testInterface,
+			powerClass:  90,
+			powerErr:    ErrNotSupported,
+		},
+		{
+			name:        "broken power management",
+			bsInterface: testInterface,
+			powerClass:  25,
+			powerErr:    errors.New("unable to read"),
+			expectedErr: true,
+		},
+		{
+			name:        "unexpected magic-no",
+			bsInterface: testInterface,
+			magicNo:     42,
+			expectedErr: true,
+		},
+		{
+			name:        "incompatible interface",
+			bsInterface: "ce48969398f05f33946d560708be108a",
+			expectedErr: true,
+		},
+		{
+			name:        "FIM newer than required",
+			bsInterface: testInterface,
+			fimVersion:  "1.2.0",
+			bitstreamID: testBitstreamA,
+		},
+		{
+			name:        "FIM equal to required",
+			bsInterface: testInterface,
+			fimVersion:  "1.2.3",
+			bitstreamID: testBitstreamA,
+		},
+		{
+			name:        "FIM older than required",
+			bsInterface: testInterface,
+			fimVersion:  "1.3",
+			bitstreamID: testBitstreamA,
+			expectedErr: true,
+		},
+		{
+			name:        "invalid required FIM version",
+			bsInterface: testInterface,
+			fimVersion:  "latest",
+			bitstreamID: testBitstreamA,
+			expectedErr: true,
+		},
+		{
+			name:        "unknown FIM version of the board",
+			bsInterface: testInterface,
+			fimVersion:  "1.3",
+		},
+		{
+			name:        "matching platform name",
+			bsInterface: testInterface,
+			platform:    "Intel PAC with Arria 10",
+			model:       "Intel PAC with Arria 10 GX FPGA",
+			check:       PlatformCheckStrict,
+		},
+		{
+			name:        "platform name differing in punctuation",
+			bsInterface: testInterface,
+			platform:    "intel-fpga-pac-d5005",
+			model:       "Intel FPGA PAC D5005",
+			check:       PlatformCheckStrict,
+		},
+		{
+			name:        "mismatched platform name",
+			bsInterface: testInterface,
+			platform:    "Intel FPGA PAC N3000",
+			model:       "Intel PAC with Arria 10 GX FPGA",
+			check:       PlatformCheckStrict,
+			expectedErr: true,
+		},
+		{
+			name:        "mismatched platform name with warning",
+			bsInterface: testInterface,
+			platform:    "Intel FPGA PAC N3000",
+			model:       "Intel PAC with Arria 10 GX FPGA",
+			check:       PlatformCheckWarn,
+		},
+		{
+			name:        "mismatched platform name without check",
+			bsInterface: testInterface,
+			platform:    "Intel FPGA PAC N3000",
+			model:       "Intel PAC with Arria 10 GX FPGA",
+			check:       PlatformCheckOff,
+		},
+		{
+			name:        "unknown board model",
+			bsInterface: testInterface,
+			platform:    "Intel FPGA PAC N3000",
+			check:       PlatformCheckStrict,
+		},
+	}
+	for _, tc := range tcases {
+		t.Run(tc.name, func(t *testing.T) {
+			origCheck := getPlatformNameCheck()
+			SetPlatformNameCheck(tc.check)
+
+			t.Cleanup(func() { SetPlatformNameCheck(origCheck) })
+
+			fme := &testFME{name: "intel-fpga-fme.0", interfaceUUID: testInterface, power: tc.power, powerErr: tc.powerErr, bitstreamID: tc.bitstreamID, model: tc.model}
+
+			extra := ""
+			if tc.fimVersion != "" {
+				extra = fmt.Sprintf(`, "min-interface-version": %q`, tc.fimVersion)
+			}
+
+			platform := ""
+			if tc.platform != "" {
+				platform = fmt.Sprintf(`"platform-name": %q, `, tc.platform)
+			}
+
+			bs := newTestGBSWithMetadata(t, fmt.Sprintf(`{"version": 1, %s"afu-image": {"interface-uuid": %q, "magic-no": %d, "power": %d, "accelerator-clusters": [{"accelerator-type-uuid": %q}]%s}}`,
+				platform, tc.bsInterface, tc.magicNo, tc.powerClass, testAFUNew, extra))
+
+			err := CompatibleWith(fme, bs)
+			if tc.expectedErr && err == nil {
+				t.Error("no error returned")
+			}
+
+			if !tc.expectedErr && err != nil {
+				t.Errorf("unexpected error: %+v", err)
+			}
+		})
+	}
+}
+
+func TestPowerHeadroom(t *testing.T) {
+	tcases := []struct {
+		powerErr   error
+		name       string
+		power      PowerInfo
+		powerClass int
+		dryRun     bool
+		noHeadroom bool
+	}{
+		{
+			name:       "enough headroom",
+			powerClass: 25,
+			power:      PowerInfo{Consumed: 30, Threshold1: 60, Threshold2: 66},
+		},
+		{
+			name:       "over throttling threshold",
+			powerClass: 31,
+			power:      PowerInfo{Consumed: 30, Threshold1: 60, Threshold2: 66},
+		},
+		{
+			name:       "exactly at maximum threshold",
+			powerClass: 36,
+			power:      PowerInfo{Consumed: 30, Threshold1: 60, Threshold2: 66},
+		},
+		{
+			name:       "over maximum threshold",
+			powerClass: 37,
+			power:      PowerInfo{Consumed: 30, Threshold1: 60, Threshold2: 66},
+			noHeadroom: true,
+		},
+		{
+			name:       "dry run",
+			powerClass: 37,
+			power:      PowerInfo{Consumed: 30, Threshold1: 60, Threshold2: 66},
+			dryRun:     true,
+			noHeadroom: true,
+		},
+		{
+			name:       "throttling threshold only",
+			powerClass: 31,
+			power:      PowerInfo{Consumed: 30, Threshold1: 60},
+			noHeadroom: true,
+		},
+		{
+			name:  "no power class",
+			power: PowerInfo{Consumed: 66, Threshold1: 60, Threshold2: 66},
+		},
+		{
+			name:       "no thresholds",
+			powerClass: 25,
+			power:      PowerInfo{Consumed: 30},
+			noHeadroom: true,
+		},
+		{
+			name:       "board without power management",
+			powerClass: 25,
+			powerErr:   ErrNotSupported,
+			noHeadroom: true,
+		},
+	}
+	for _, tc := range tcases {
+		t.Run(tc.name, func(t *testing.T) {
+			programmed := false
+			fme := &testFME{interfaceUUID: testInterface, power: tc.power, powerErr: tc.powerErr}
+			fme.portPR = func(uint32, []byte) error {
+				programmed = true
+
+				return nil
+			}
+
+			port := newTestIntelFpgaPort(t, fme, testAFUOld)
+			bs := newTestGBSWithMetadata(t, fmt.Sprintf(`{"version": 1, "afu-image": {"power": %d, "interface-uuid": %q, "accelerator-clusters": [{"accelerator-type-uuid": %q}]}}`,
+				tc.powerClass, testInterface, testAFUNew))
+
+			// The declared power fits the board limit, so the PR is refused
+			// by the headroom check only.
+			if err := CompatibleWith(fme, bs); err != nil {
+				t.Fatalf("unexpected compatibility error: %+v", err)
+			}
+
+			_, err := port.PRWithOptions(bs, PROptions{RequirePowerHeadroom: true, SkipReadback: true, DryRun: tc.dryRun})
+			if tc.noHeadroom != errors.Is(err, ErrInsufficientPowerHeadroom) {
+				t.Errorf("unexpected error %v", err)
+			}
+
+			if !tc.noHeadroom && err != nil {
+				t.Errorf("unexpected error: %+v", err)
+			}
+
+			if expected := !tc.noHeadroom && !tc.dryRun; programmed != expected {
+				t.Errorf("expected programmed %t, but got %t", expected, programmed)
+			}
+
+			if _, err = port.PRWithOptions(bs, PROptions{SkipReadback: true, DryRun: true}); err != nil {
+				t.Errorf("unexpected error without the headroom check: %+v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,315 @@
+// Copyright 2023 Intel Corporation. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fpga
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/pkg/errors"
+
+	"github.com/intel/intel-device-plugins-for-kubernetes/pkg/fpga/bitstream"
+)
+
+// ErrPortCountMismatch is returned by VerifyPortCount when the FME has
+// fewer or more port devices than it reports.
+var ErrPortCountMismatch = errors.New("port count mismatch")
+
+// IsEmptyAFU returns true if the AFU UUID reported by a port means that
+// no AFU is programmed to it.
+func IsEmptyAFU(afu string) bool {
+	return strings.Trim(afu, "0-") == ""
+}
+
+// EmptyPorts returns the ports of fme which have no AFU programmed.
+// The other ports of the host are closed. If some of the ports can't be
+// opened or checked, the empty ones found are returned along with
+// an error describing the failures.
+func EmptyPorts(ctx context.Context, fme FME) ([]Port, error) {
+	ports, failures, err := ownedPorts(ctx, fme)
+	if err != nil {
+		return nil, err
+	}
+
+	empty := []Port{}
+
+	for _, port := range ports {
+		afu, readErr := port.ReadAcceleratorTypeUUIDFresh()
+		if readErr != nil {
+			failures = append(failures, errors.Wrap(readErr, port.GetName()))
+		}
+
+		if readErr != nil || !IsEmptyAFU(afu) {
+			port.Close()
+
+			continue
+		}
+
+		empty = append(empty, port)
+	}
+
+	if len(failures) > 0 {
+		return empty, errors.Wrapf(joinErrors(failures...), "%s: unable to check all ports", fme.GetName())
+	}
+
+	return empty, nil
+}
+
+// ownedPorts returns the ports of fme and closes the other ports of the host.
+// Failures to open or check individual ports are returned as failures, the
+// error is only returned if ctx is done.
+func ownedPorts(ctx context.Context, fme FME) (owned []Port, failures []error, err error) {
+	ports, err := ListPorts(ctx, 0)
+	if err != nil && ctx.Err() != nil {
+		return nil, nil, err
+	}
+
+	if err != nil {
+		failures = append(failures, err)
+	}
+
+	owned = []Port{}
+
+	for _, port := range ports {
+		ok, ownErr := ownsPort(fme, port)
+		if ownErr != nil {
+			failures = append(failures, ownErr)
+		}
+
+		if !ok {
+			port.Close()
+
+			continue
+		}
+
+		owned = append(owned, port)
+	}
+
+	return owned, failures, nil
+}
+
+// verifyPortCount compares the number of ports fme reports with the number
+// of its port devices that can be opened. ErrPortCountMismatch is returned,
+// along with the failures to open the ports, if the numbers differ.
+func verifyPortCount(ctx context.Context, fme FME) (expected, actual int, err error) {
+	expected = fme.GetPortsNum()
+	if expected < 0 {
+		return expected, 0, errors.Errorf("%s: unable to get number of ports", fme.GetName())
+	}
+
+	ports, failures, err := ownedPorts(ctx, fme)
+	if err != nil {
+		return expected, 0, err
+	}
+
+	closePorts(ports)
+
+	actual = len(ports)
+	if actual == expected {
+		return expected, actual, nil
+	}
+
+	err = errors.Wrapf(ErrPortCountMismatch, "%s reports %d ports, but %d are present", fme.GetName(), expected, actual)
+	if len(failures) > 0 {
+		err = errors.Wrapf(err, "failures: %v", joinErrors(failures...))
+	}
+
+	return expected, actual, err
+}
+
+// ProgramEmptyPorts programs bs to every empty port of fme and returns the
+// ports it programmed. If bestEffort is false, it stops on the first failure,
+// otherwise it tries all the empty ports and returns the failures combined.
+// The caller is responsible for closing the returned ports.
+func ProgramEmptyPorts(ctx context.Context, fme FME, bs bitstream.File, bestEffort bool) ([]Port, error) {
+	empty, err := EmptyPorts(ctx, fme)
+	if err != nil && (!bestEffort || ctx.Err() != nil) {
+		closePorts(empty)
+
+		return nil, err
+	}
+
+	programmed, prErr := ProgramPorts(ctx, fme, empty, bs, ProgramOptions{BestEffort: bestEffort})
+
+	done := make(map[Port]bool, len(programmed))
+	for _, port := range programmed {
+		done[port] = true
+	}
+
+	for _, port := range empty {
+		if !done[port] {
+			port.Close()
+		}
+	}
+
+	if prErr != nil && !bestEffort {
+		return programmed, prErr
+	}
+
+	if failures := joinErrors(err, prErr); failures != nil {
+		return programmed, errors.Wrapf(failures, "%s: unable to program all empty ports", fme.GetName())
+	}
+
+	return programmed, nil
+}
+
+// ProgramOptions control how ProgramPorts programs the ports.
+type ProgramOptions struct {
+	// PR are the options of every port PR.
+	PR PROptions
+	// Workers limits the number of ports programmed in parallel.
+	// Zero means all the ports at once.
+	Workers int
+	// BestEffort makes ProgramPorts try all the ports instead of
+	// stopping on the first failure.
+	BestEffort bool
+	// Parallel allows programming the ports concurrently if the FME
+	// permits it, see concurrentPRAllowed.
+	Parallel bool
+}
+
+// ProgramPorts programs bs to the ports of fme and returns the ports it
+// programmed, in the order of ports. If opts.BestEffort is false, it stops
+// on the first failure, otherwise the failures are returned combined.
+// The ports aren't closed.
+func ProgramPorts(ctx context.Context, fme FME, ports []Port, bs bitstream.File, opts ProgramOptions) ([]Port, error) {
+	var (
+		errs   []error
+		failed int
+	)
+
+	if opts.Parallel && len(ports) > 1 && concurrentPRAllowed(fme) {
+		errs, failed = programPortsParallel(ctx, ports, bs, opts)
+	} else {
+		errs, failed = programPortsSequential(ctx, ports, bs, opts)
+	}
+
+	programmed := []Port{}
+	failures := []error{}
+
+	for i, port := range ports {
+		switch {
+		case errs[i] == nil:
+			programmed = append(programmed, port)
+		case opts.BestEffort:
+			failures = append(failures, errors.Wrap(errs[i], port.GetName()))
+		}
+	}
+
+	if failed >= 0 {
+		return programmed, errors.Wrapf(errs[failed], "%s: unable to program", ports[failed].GetName())
+	}
+
+	if len(failures) > 0 {
+		return programmed, errors.Wrapf(joinErrors(failures...), "%s: unable to program all ports", fme.GetName())
+	}
+
+	return programmed, nil
+}
+
+// errPRSkipped marks ports not programmed because of an earlier failure.
+var errPRSkipped = errors.New("skipped")
+
+// programPortsSequential programs the ports one by one and returns their
+// errors. Unless opts.BestEffort is set, it stops on the first failure and
+// returns its index, otherwise the index is -1.
+func programPortsSequential(ctx context.Context, ports []Port, bs bitstream.File, opts ProgramOptions) ([]error, int) {
+	errs := make([]error, len(ports))
+
+	for i, port := range ports {
+		if _, errs[i] = port.PRContext(ctx, bs, opts.PR); errs[i] != nil && !opts.BestEffort {
+			for j := i + 1; j < len(ports); j++ {
+				errs[j] = errPRSkipped
+			}
+
+			return errs, i
+		}
+	}
+
+	return errs, -1
+}
+
+// programPortsParallel is the same as programPortsSequential, but programs
+// the ports using a pool of workers. The first failure cancels PR of the
+// ports not yet submitted to the driver, others run to completion.
+func programPortsParallel(ctx context.Context, ports []Port, bs bitstream.File, opts ProgramOptions) ([]error, int) {
+	workers := opts.Workers
+	if workers <= 0 || workers > len(ports) {
+		workers = len(ports)
+	}
+
+	pctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// Every index is written by a single worker, so no locking is needed.
+	errs := make([]error, len(ports))
+	jobs := make(chan int)
+	failed := int32(-1)
+
+	var wg sync.WaitGroup
+
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			for i := range jobs {
+				_, errs[i] = ports[i].PRContext(pctx, bs, opts.PR)
+				if errs[i] != nil && !opts.BestEffort && atomic.CompareAndSwapInt32(&failed, -1, int32(i)) {
+					cancel()
+				}
+			}
+		}()
+	}
+
+	for i := range ports {
+		jobs <- i
+	}
+
+	close(jobs)
+	wg.Wait()
+
+	return errs, int(failed)
+}
+
+// concurrentPRAllowed reports whether several ports of fme can be programmed
+// at once. It's only allowed if the FME says so in the optional sysfs hint,
+// because some boards serialize PR in hardware and interleaved requests can
+// fail or, worse, corrupt the regions. Both drivers hold the FME lock while
+// programming, so even when allowed the bitstream transfers are serialized,
+// but AFU readback and verification of the ports overlap.
+func concurrentPRAllowed(fme FME) bool {
+	var hint string
+
+	sysfs := fme.GetSysFsPath()
+	if sysfs == "" {
+		return false
+	}
+
+	if err := readFilesInDirectory(map[string]*string{fmeConcurrentPRFile: &hint}, sysfs); err != nil {
+		return false
+	}
+
+	return hint == "1"
+}
+
+func closePorts(ports []Port) {
+	for _, port := range ports {
+		port.Close()
+	}
+}
@@ -0,0 +1,326 @@
+// Copyright 2023 Intel Corporation. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fpga
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const testAFUEmpty = "00000000000000000000000000000000"
+
+func TestProgramEmptyPorts(t *testing.T) {
+	tcases := []struct {
+		failPR             map[int]bool
+		name               string
+		expectedProgrammed []int
+		expectedTouched    []int
+		bestEffort         bool
+		expectedErr        bool
+	}{
+		{
+			name:               "all empty ports",
+			expectedProgrammed: []int{0, 2},
+			expectedTouched:    []int{0, 2},
+		},
+		{
+			name:               "stop on error",
+			failPR:             map[int]bool{0: true},
+			expectedProgrammed: []int{},
+			expectedTouched:    []int{0},
+			expectedErr:        true,
+		},
+		{
+			name:               "best effort",
+			failPR:             map[int]bool{0: true},
+			bestEffort:         true,
+			expectedProgrammed: []int{2},
+			expectedTouched:    []int{0, 2},
+			expectedErr:        true,
+		},
+	}
+	for _, tc := range tcases {
+		t.Run(tc.name, func(t *testing.T) {
+			sysfs := setTestSysfsRoot(t)
+			fme := &IntelFpgaFME{DevPath: "/dev/intel-fpga-fme.0", PCIDevice: &PCIDevice{BDF: testBDF}}
+
+			// Port 1 is loaded and port 3 is empty, but belongs to another card.
+			afus := []string{testAFUEmpty, testAFUOld, "", testAFUEmpty}
+			bdfs := []string{testBDF, testBDF, testBDF, "0000:af:00.0"}
+			ports := map[string]Port{}
+			touched := []int{}
+
+			for i := range afus {
+				name := fmt.Sprintf("intel-fpga-port.%d", i)
+				if err := createTestFiles(sysfs, []string{"bus/platform/devices/" + name}, nil); err != nil {
+					t.Fatal(err)
+				}
+
+				i := i
+				portFME := &testFME{interfaceUUID: testInterface}
+				port := newTestIntelFpgaPort(t, portFME, afus[i])
+				port.DevPath = "/dev/" + name
+				port.PCIDevice = &PCIDevice{BDF: bdfs[i]}
+				portFME.portPR = func(uint32, []byte) error {
+					touched = append(touched, i)
+					if tc.failPR[i] {
+						return errors.New("PR failed")
+					}
+
+					return os.WriteFile(filepath.Join(port.SysFsPath, "afu_id"), []byte(testAFUNew), 0600)
+				}
+				ports[name] = port
+			}
+
+			setTestOpeners(t, nil, ports)
+
+			programmed, err := ProgramEmptyPorts(context.Background(), fme, newTestGBS(t, testInterface, testAFUNew), tc.bestEffort)
+			if tc.expectedErr && err == nil {
+				t.Error("no error returned")
+			}
+
+			if !tc.expectedErr && err != nil {
+				t.Errorf("unexpected error: %+v", err)
+			}
+
+			got := []int{}
+			for _, port := range programmed {
+				var n int
+
+				_, _ = fmt.Sscanf(port.GetDevPath(), "/dev/intel-fpga-port.%d", &n)
+				got = append(got, n)
+
+				if afu := port.GetAcceleratorTypeUUID(); afu != testAFUNew {
+					t.Errorf("%s: expected AFU %s, but got %s", port.GetDevPath(), testAFUNew, afu)
+				}
+			}
+
+			if !reflect.DeepEqual(got, tc.expectedProgrammed) {
+				t.Errorf("expected programmed ports %v, but got %v", tc.expectedProgrammed, got)
+			}
+
+			if !reflect.DeepEqual(touched, tc.expectedTouched) {
+				t.Errorf("expected PR of ports %v, but got %v", tc.expectedTouched, touched)
+			}
+
+			if afu := ports["intel-fpga-port.1"].GetAcceleratorTypeUUID(); afu != testAFUOld {
+				t.Errorf("loaded port was reprogrammed with %s", afu)
+			}
+		})
+	}
+}
+
+func TestProgramPorts(t *testing.T) {
+	tcases := []struct {
+		name               string
+		concurrentHint     string
+		failPR             int
+		expectedProgrammed int
+		parallel           bool
+		expectParallel     bool
+		expectedErr        bool
+	}{
+		{
+			name:               "sequential",
+			failPR:             -1,
+			expectedProgrammed: 4,
+		},
+		{
+			name:               "parallel forced sequential without FME hint",
+			parallel:           true,
+			failPR:             -1,
+			expectedProgrammed: 4,
+		},
+		{
+			name:               "parallel",
+			parallel:           true,
+			concurrentHint:     "1\n",
+			failPR:             -1,
+			expectedProgrammed: 4,
+			expectParallel:     true,
+		},
+		{
+			name:               "sequential stops on error",
+			failPR:             1,
+			expectedProgrammed: 1,
+			expectedErr:        true,
+		},
+		{
+			name:           "parallel with error",
+			parallel:       true,
+			concurrentHint: "1",
+			failPR:         1,
+			// Ports submitted before the failure complete, the last one is cancelled.
+			expectedProgrammed: 2,
+			expectParallel:     true,
+			expectedErr:        true,
+		},
+	}
+	for _, tc := range tcases {
+		t.Run(tc.name, func(t *testing.T) {
+			files := map[string]string{}
+			if tc.concurrentHint != "" {
+				files[fmeConcurrentPRFile] = tc.concurrentHint
+			}
+
+			fme := newTestIntelFpgaFME(t, files)
+
+			var (
+				mu                sync.Mutex
+				active, maxActive int
+			)
+
+			ports := []Port{}
+
+			for i := 0; i < 4; i++ {
+				i := i
+				portFME := &testFME{interfaceUUID: testInterface}
+				portFME.portPR = func(uint32, []byte) error {
+					if i == tc.failPR {
+						// Fail while the ports submitted before are still programmed.
+						time.Sleep(10 * time.Millisecond)
+
+						return errors.New("PR failed")
+					}
+
+					mu.Lock()
+					active++
+					if active > maxActive {
+						maxActive = active
+					}
+					mu.Unlock()
+
+					time.Sleep(50 * time.Millisecond)
+
+					mu.Lock()
+					active--
+					mu.Unlock()
+
+					return nil
+				}
+
+				port := newTestIntelFpgaPort(t, portFME, testAFUEmpty)
+				port.DevPath = fmt.Sprintf("/dev/intel-fpga-port.%d", i)
+				ports = append(ports, port)
+			}
+
+			opts := ProgramOptions{Parallel: tc.parallel, Workers: 3, PR: PROptions{SkipReadback: true}}
+
+			programmed, err := ProgramPorts(context.Background(), fme, ports, newTestGBS(t, testInterface, testAFUNew), opts)
+			if tc.expectedErr && err == nil {
+				t.Error("no error returned")
+			}
+
+			if !tc.expectedErr && err != nil {
+				t.Errorf("unexpected error: %+v", err)
+			}
+
+			if len(programmed) != tc.expectedProgrammed {
+				t.Errorf("expected %d programmed ports, but got %d", tc.expectedProgrammed, len(programmed))
+			}
+
+			if tc.expectParallel != (maxActive > 1) {
+				t.Errorf("unexpected number of concurrent PRs %d", maxActive)
+			}
+
+			if maxActive > opts.Workers {
+				t.Errorf("more concurrent PRs (%d) than workers (%d)", maxActive, opts.Workers)
+			}
+		})
+	}
+}
+
+func TestVerifyPortCount(t *testing.T) {
+	sysfs := setTestSysfsRoot(t)
+	ports := map[string]Port{}
+
+	// Port 2 belongs to another card and port 3 fails to open.
+	bdfs := []string{testBDF, testBDF, "0000:af:00.0", testBDF}
+	for i, bdf := range bdfs {
+		name := fmt.Sprintf("intel-fpga-port.%d", i)
+		if err := createTestFiles(sysfs, []string{"bus/platform/devices/" + name}, nil); err != nil {
+			t.Fatal(err)
+		}
+
+		if i == 3 {
+			continue
+		}
+
+		port := newTestIntelFpgaPort(t, &testFME{}, testAFUEmpty)
+		port.DevPath = "/dev/" + name
+		port.PCIDevice = &PCIDevice{BDF: bdf}
+		ports[name] = port
+	}
+
+	setTestOpeners(t, nil, ports)
+
+	tcases := []struct {
+		name        string
+		portsNum    string
+		expected    int
+		mismatch    bool
+		expectedErr bool
+	}{
+		{
+			name:     "present ports match",
+			portsNum: "2",
+			expected: 2,
+		},
+		{
+			name:     "partial enumeration",
+			portsNum: "3",
+			expected: 3,
+			mismatch: true,
+		},
+		{
+			name:     "more ports than reported",
+			portsNum: "1",
+			expected: 1,
+			mismatch: true,
+		},
+		{
+			name:        "unknown number of ports",
+			expected:    -1,
+			expectedErr: true,
+		},
+	}
+	for _, tc := range tcases {
+		t.Run(tc.name, func(t *testing.T) {
+			fme := &IntelFpgaFME{DevPath: "/dev/intel-fpga-fme.0", PCIDevice: &PCIDevice{BDF: testBDF}, PortsNum: tc.portsNum}
+
+			expected, actual, err := fme.VerifyPortCount()
+			if tc.mismatch != errors.Is(err, ErrPortCountMismatch) || tc.expectedErr != (err != nil && !tc.mismatch) {
+				t.Errorf("unexpected error %v", err)
+			}
+
+			if tc.mismatch && tc.expected > 2 && !strings.Contains(err.Error(), "unable to open intel-fpga-port.3") {
+				t.Errorf("expected the open failure reported, but got %v", err)
+			}
+
+			if expected != tc.expected || (!tc.expectedErr && actual != 2) {
+				t.Errorf("expected %d reported and 2 present ports, but got %d and %d", tc.expected, expected, actual)
+			}
+		})
+	}
+}
@@ -0,0 +1,160 @@
+// Copyright 2023 Intel Corporation. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fpga
+
+import (
+	"context"
+	"sort"
+
+	"github.com/pkg/errors"
+
+	"github.com/intel/intel-device-plugins-for-kubernetes/pkg/fpga/bitstream"
+)
+
+// ReconcileOptions control how Reconcile programs the ports.
+type ReconcileOptions struct {
+	// PR are the options of every port PR.
+	PR PROptions
+	// BestEffort makes Reconcile try all the ports instead of stopping
+	// on the first failure.
+	BestEffort bool
+}
+
+// ReconcileResult describes the outcome of Reconcile. The port IDs are sorted.
+type ReconcileResult struct {
+	// Failed maps IDs of the ports Reconcile failed to check or program
+	// to the errors.
+	Failed map[uint32]error
+	// Changed lists the ports programmed with the desired bitstream.
+	Changed []uint32
+	// Skipped lists the ports which already have the desired AFU.
+	Skipped []uint32
+}
+
+// Reconcile makes the ports of fme run the AFUs of the desired bitstreams,
+// keyed by port ID. Only the ports whose current AFU differs from the AFU of
+// the desired bitstream are programmed, so calling Reconcile again with the
+// same desired state is a no-op. Ports of fme missing from desired aren't
+// touched. Unless opts.BestEffort is set, Reconcile stops on the first
+// failure and the ports not yet processed are reported in none of the lists.
+// The error combines the failures.
+func Reconcile(fme FME, desired map[uint32]bitstream.File, opts ReconcileOptions) (ReconcileResult, error) {
+	res, failures, err := reconcile(fme, desired, opts)
+	if err != nil {
+		return res, err
+	}
+
+	if len(failures) > 0 {
+		return res, errors.Wrapf(joinErrors(failures...), "%s: unable to reconcile all ports", fme.GetName())
+	}
+
+	return res, nil
+}
+
+// ReconcilePlan tells what Reconcile would do with the same arguments
+// without programming any port: Changed lists the ports Reconcile would
+// program, Skipped the ones already running the desired AFUs and Failed
+// the ones failing the checks done before programming, e.g. incompatible
+// bitstreams. All the ports are checked, as with ReconcileOptions.BestEffort.
+// The error is only returned if the ports can't be listed.
+func ReconcilePlan(fme FME, desired map[uint32]bitstream.File) (ReconcileResult, error) {
+	res, _, err := reconcile(fme, desired, ReconcileOptions{PR: PROptions{DryRun: true}, BestEffort: true})
+
+	return res, err
+}
+
+// reconcile does Reconcile. It returns all the failures, including the ones
+// of the ports not in desired, separately from the failure to list the ports.
+func reconcile(fme FME, desired map[uint32]bitstream.File, opts ReconcileOptions) (ReconcileResult, []error, error) {
+	res := ReconcileResult{
+		Changed: []uint32{},
+		Skipped: []uint32{},
+		Failed:  map[uint32]error{},
+	}
+
+	ports, failures, err := ownedPorts(context.Background(), fme)
+	if err != nil {
+		return res, nil, err
+	}
+
+	defer closePorts(ports)
+
+	byID := make(map[uint32]Port, len(ports))
+
+	for _, port := range ports {
+		id, idErr := port.GetPortID()
+		if idErr != nil {
+			failures = append(failures, errors.Wrap(idErr, port.GetName()))
+
+			continue
+		}
+
+		byID[id] = port
+	}
+
+	ids := make([]uint32, 0, len(desired))
+	for id := range desired {
+		ids = append(ids, id)
+	}
+
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+	for _, id := range ids {
+		changed, recErr := reconcilePort(byID[id], desired[id], opts.PR)
+
+		switch {
+		case recErr != nil:
+			res.Failed[id] = errors.Wrapf(recErr, "port %d", id)
+			failures = append(failures, res.Failed[id])
+		case changed:
+			res.Changed = append(res.Changed, id)
+		default:
+			res.Skipped = append(res.Skipped, id)
+		}
+
+		if recErr != nil && !opts.BestEffort {
+			break
+		}
+	}
+
+	return res, failures, nil
+}
+
+// reconcilePort programs bs to the port unless the port already runs its AFU.
+// It returns true if the port was programmed, or would be in dry run.
+func reconcilePort(port Port, bs bitstream.File, opts PROptions) (bool, error) {
+	if port == nil {
+		return false, errors.New("no such port")
+	}
+
+	if bs == nil {
+		return false, errors.New("no bitstream")
+	}
+
+	afu, err := port.ReadAcceleratorTypeUUIDFresh()
+	if err != nil {
+		return false, err
+	}
+
+	if CanonizeID(afu) == CanonizeID(bs.AcceleratorTypeUUID()) {
+		return false, nil
+	}
+
+	if _, err = port.PRContext(context.Background(), bs, opts); err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
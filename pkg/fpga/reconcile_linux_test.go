@@ -0,0 +1,225 @@
+// Copyright 2023 Intel Corporation. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fpga
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/pkg/errors"
+
+	"github.com/intel/intel-device-plugins-for-kubernetes/pkg/fpga/bitstream"
+)
+
+func TestReconcile(t *testing.T) {
+	tcases := []struct {
+		desired         map[uint32]string
+		failPR          map[uint32]bool
+		name            string
+		expectedChanged []uint32
+		expectedSkipped []uint32
+		expectedFailed  []uint32
+		bestEffort      bool
+		expectedErr     bool
+	}{
+		{
+			name:            "no-op",
+			desired:         map[uint32]string{0: testAFUOld, 1: testAFUNew},
+			expectedChanged: []uint32{},
+			expectedSkipped: []uint32{0, 1},
+		},
+		{
+			name:            "partial change",
+			desired:         map[uint32]string{0: testAFUNew, 1: testAFUNew, 2: testAFUOld},
+			expectedChanged: []uint32{0, 2},
+			expectedSkipped: []uint32{1},
+		},
+		{
+			name:            "stop on failure",
+			desired:         map[uint32]string{0: testAFUNew, 2: testAFUNew},
+			failPR:          map[uint32]bool{0: true},
+			expectedChanged: []uint32{},
+			expectedSkipped: []uint32{},
+			expectedFailed:  []uint32{0},
+			expectedErr:     true,
+		},
+		{
+			name:            "best effort",
+			desired:         map[uint32]string{0: testAFUNew, 1: testAFUNew, 2: testAFUNew},
+			failPR:          map[uint32]bool{0: true},
+			bestEffort:      true,
+			expectedChanged: []uint32{2},
+			expectedSkipped: []uint32{1},
+			expectedFailed:  []uint32{0},
+			expectedErr:     true,
+		},
+		{
+			name:            "unknown port",
+			desired:         map[uint32]string{1: testAFUNew, 5: testAFUNew},
+			bestEffort:      true,
+			expectedChanged: []uint32{},
+			expectedSkipped: []uint32{1},
+			expectedFailed:  []uint32{5},
+			expectedErr:     true,
+		},
+	}
+	for _, tc := range tcases {
+		t.Run(tc.name, func(t *testing.T) {
+			fme := &IntelFpgaFME{DevPath: "/dev/intel-fpga-fme.0", PCIDevice: &PCIDevice{BDF: testBDF}}
+			touched := setTestReconcilePorts(t, tc.desired, tc.failPR)
+
+			desired := map[uint32]bitstream.File{}
+			for id, afu := range tc.desired {
+				desired[id] = newTestGBS(t, testInterface, afu)
+			}
+
+			res, err := Reconcile(fme, desired, ReconcileOptions{BestEffort: tc.bestEffort})
+			if tc.expectedErr && err == nil {
+				t.Error("no error returned")
+			}
+
+			if !tc.expectedErr && err != nil {
+				t.Errorf("unexpected error: %+v", err)
+			}
+
+			if !reflect.DeepEqual(res.Changed, tc.expectedChanged) {
+				t.Errorf("expected changed ports %v, but got %v", tc.expectedChanged, res.Changed)
+			}
+
+			if !reflect.DeepEqual(res.Skipped, tc.expectedSkipped) {
+				t.Errorf("expected skipped ports %v, but got %v", tc.expectedSkipped, res.Skipped)
+			}
+
+			failed := []uint32{}
+			for id := range res.Failed {
+				failed = append(failed, id)
+			}
+
+			sort.Slice(failed, func(i, j int) bool { return failed[i] < failed[j] })
+
+			if len(failed) != 0 || len(tc.expectedFailed) != 0 {
+				if !reflect.DeepEqual(failed, tc.expectedFailed) {
+					t.Errorf("expected failed ports %v, but got %v", tc.expectedFailed, failed)
+				}
+			}
+
+			for _, id := range res.Skipped {
+				if touched[id] {
+					t.Errorf("port %d already matching was reprogrammed", id)
+				}
+			}
+		})
+	}
+}
+
+// setTestReconcilePorts sets up ports 0, 1 and 2 programmed with testAFUOld,
+// testAFUNew and no AFU. PR programs the AFU of desired to the port unless
+// failPR is set for it. The returned map records the programmed ports.
+func setTestReconcilePorts(t *testing.T, desired map[uint32]string, failPR map[uint32]bool) map[uint32]bool {
+	t.Helper()
+
+	sysfs := setTestSysfsRoot(t)
+	afus := []string{testAFUOld, testAFUNew, testAFUEmpty}
+	ports := map[string]Port{}
+	touched := map[uint32]bool{}
+
+	for i := range afus {
+		name := fmt.Sprintf("intel-fpga-port.%d", i)
+		if err := createTestFiles(sysfs, []string{"bus/platform/devices/" + name}, nil); err != nil {
+			t.Fatal(err)
+		}
+
+		id := uint32(i)
+		portFME := &testFME{interfaceUUID: testInterface}
+		port := newTestIntelFpgaPort(t, portFME, afus[i])
+		port.DevPath = "/dev/" + name
+		port.PCIDevice = &PCIDevice{BDF: testBDF}
+
+		if err := os.WriteFile(filepath.Join(port.SysFsPath, "id"), []byte(fmt.Sprint(i)), 0600); err != nil {
+			t.Fatal(err)
+		}
+
+		portFME.portPR = func(uint32, []byte) error {
+			touched[id] = true
+			if failPR[id] {
+				return errors.New("PR failed")
+			}
+
+			return os.WriteFile(filepath.Join(port.SysFsPath, "afu_id"), []byte(desired[id]), 0600)
+		}
+		ports[name] = port
+	}
+
+	setTestOpeners(t, nil, ports)
+
+	return touched
+}
+
+func TestReconcilePlan(t *testing.T) {
+	desired := map[uint32]string{0: testAFUNew, 1: testAFUNew, 2: testAFUOld, 5: testAFUNew}
+	touched := setTestReconcilePorts(t, desired, nil)
+	fme := &IntelFpgaFME{DevPath: "/dev/intel-fpga-fme.0", PCIDevice: &PCIDevice{BDF: testBDF}}
+
+	bitstreams := map[uint32]bitstream.File{}
+	for id, afu := range desired {
+		bitstreams[id] = newTestGBS(t, testInterface, afu)
+	}
+
+	// Port 2 fails the compatibility check.
+	bitstreams[2] = newTestGBS(t, "ce48969398f05f33946d560708be108a", testAFUOld)
+
+	plan, err := ReconcilePlan(fme, bitstreams)
+	if err != nil {
+		t.Fatalf("unexpected error: %+v", err)
+	}
+
+	if len(touched) != 0 {
+		t.Errorf("ports %v programmed by the plan", touched)
+	}
+
+	failedIDs := func(res ReconcileResult) []uint32 {
+		ids := []uint32{}
+		for id := range res.Failed {
+			ids = append(ids, id)
+		}
+
+		sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+		return ids
+	}
+
+	if !reflect.DeepEqual(plan.Changed, []uint32{0}) || !reflect.DeepEqual(plan.Skipped, []uint32{1}) ||
+		!reflect.DeepEqual(failedIDs(plan), []uint32{2, 5}) {
+		t.Errorf("unexpected plan %+v", plan)
+	}
+
+	res, err := Reconcile(fme, bitstreams, ReconcileOptions{BestEffort: true})
+	if err == nil {
+		t.Error("no error returned")
+	}
+
+	if !reflect.DeepEqual(res.Changed, plan.Changed) || !reflect.DeepEqual(res.Skipped, plan.Skipped) ||
+		!reflect.DeepEqual(failedIDs(res), failedIDs(plan)) {
+		t.Errorf("reconcile result %+v doesn't match the plan %+v", res, plan)
+	}
+
+	if !reflect.DeepEqual(touched, map[uint32]bool{0: true}) {
+		t.Errorf("expected only port 0 programmed, but got %v", touched)
+	}
+}
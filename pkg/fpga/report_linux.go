@@ -0,0 +1,284 @@
+// Copyright 2023 Intel Corporation. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fpga
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// ReportFormat is the format of the report written by WriteReport.
+type ReportFormat string
+
+// Formats of the report written by WriteReport.
+const (
+	// ReportFormatText is indented human readable text.
+	ReportFormatText ReportFormat = "text"
+	// ReportFormatJSON is the Inventory serialized as indented JSON.
+	ReportFormatJSON ReportFormat = "json"
+	// ReportFormatCompact has a line of space separated key=value pairs
+	// per device, e.g. for log pipelines. The lines of FMEs and ports
+	// repeat the keys of their board and FME.
+	ReportFormatCompact ReportFormat = "compact"
+)
+
+// reportMissing is written in text and compact reports for the fields
+// that are unsupported or couldn't be collected.
+const reportMissing = "n/a"
+
+// reportField is a field of a device in text and compact reports.
+type reportField struct {
+	label string
+	key   string
+	value string
+}
+
+// WriteReport collects information about all FPGA devices of the host with
+// GetInventory and writes it to w in the given format.
+func WriteReport(w io.Writer, format ReportFormat) error {
+	inv, err := GetInventory()
+	if err != nil {
+		return err
+	}
+
+	return inv.WriteReport(w, format)
+}
+
+// WriteReport writes the inventory to w in the given format. Text and compact
+// formats write the same fields, with reportMissing for the missing ones,
+// followed by the errors recorded for the device.
+func (inv *Inventory) WriteReport(w io.Writer, format ReportFormat) error {
+	var err error
+
+	switch format {
+	case ReportFormatText:
+		err = inv.writeText(w)
+	case ReportFormatJSON:
+		err = inv.writeJSON(w)
+	case ReportFormatCompact:
+		err = inv.writeCompact(w)
+	default:
+		return errors.Errorf("unknown report format %q", format)
+	}
+
+	return errors.Wrap(err, "unable to write report")
+}
+
+func (inv *Inventory) writeJSON(w io.Writer) error {
+	data, err := json.MarshalIndent(inv, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	_, err = w.Write(append(data, '\n'))
+
+	return err
+}
+
+func (inv *Inventory) writeText(w io.Writer) error {
+	var b strings.Builder
+
+	for _, board := range inv.Boards {
+		writeTextDevice(&b, "", "Board "+board.PCIAddress, boardFields(board), board.Errors)
+
+		for _, fme := range board.FMEs {
+			writeTextDevice(&b, "  ", "FME "+fme.Name, fmeFields(fme), fme.Errors)
+
+			for _, port := range fme.Ports {
+				writeTextDevice(&b, "    ", "Port "+port.Name, portFields(port), port.Errors)
+			}
+		}
+	}
+
+	if len(inv.Errors) > 0 {
+		b.WriteString("Unavailable devices:\n")
+
+		for _, name := range sortedKeys(inv.Errors) {
+			fmt.Fprintf(&b, "  %s: %s\n", name, inv.Errors[name])
+		}
+	}
+
+	_, err := io.WriteString(w, b.String())
+
+	return err
+}
+
+func writeTextDevice(b *strings.Builder, indent, title string, fields []reportField, errs map[string]string) {
+	fmt.Fprintf(b, "%s%s\n", indent, title)
+
+	for _, f := range fields {
+		fmt.Fprintf(b, "%s  %s: %s\n", indent, f.label, f.value)
+	}
+
+	if len(errs) == 0 {
+		return
+	}
+
+	fmt.Fprintf(b, "%s  Errors:\n", indent)
+
+	for _, field := range sortedKeys(errs) {
+		fmt.Fprintf(b, "%s    %s: %s\n", indent, field, errs[field])
+	}
+}
+
+func (inv *Inventory) writeCompact(w io.Writer) error {
+	var b strings.Builder
+
+	for _, board := range inv.Boards {
+		boardKey := reportField{key: "board", value: board.PCIAddress}
+		writeCompactDevice(&b, append([]reportField{boardKey}, boardFields(board)...), board.Errors)
+
+		for _, fme := range board.FMEs {
+			fmeKey := reportField{key: "fme", value: fme.Name}
+			writeCompactDevice(&b, append([]reportField{boardKey, fmeKey}, fmeFields(fme)...), fme.Errors)
+
+			for _, port := range fme.Ports {
+				portKey := reportField{key: "port", value: port.Name}
+				writeCompactDevice(&b, append([]reportField{boardKey, fmeKey, portKey}, portFields(port)...), port.Errors)
+			}
+		}
+	}
+
+	for _, name := range sortedKeys(inv.Errors) {
+		writeCompactDevice(&b, []reportField{{key: "unavailable", value: name}, {key: "error", value: inv.Errors[name]}}, nil)
+	}
+
+	_, err := io.WriteString(w, b.String())
+
+	return err
+}
+
+func writeCompactDevice(b *strings.Builder, fields []reportField, errs map[string]string) {
+	for i, f := range fields {
+		if i > 0 {
+			b.WriteByte(' ')
+		}
+
+		b.WriteString(f.key + "=" + compactValue(f.value))
+	}
+
+	for _, field := range sortedKeys(errs) {
+		b.WriteString(" error." + field + "=" + compactValue(errs[field]))
+	}
+
+	b.WriteByte('\n')
+}
+
+// compactValue quotes the value if it's not a single word.
+func compactValue(value string) string {
+	if value == "" || strings.ContainsAny(value, " \t\n\"=") {
+		return strconv.Quote(value)
+	}
+
+	return value
+}
+
+func boardFields(board *InventoryBoard) []reportField {
+	return []reportField{
+		{"Model", "model", orMissing(board.Model)},
+		{"Vendor", "vendor", orMissing(board.Vendor)},
+		{"Device", "device", orMissing(board.Device)},
+		{"NUMA node", "numa", orMissing(board.NUMA)},
+	}
+}
+
+func fmeFields(fme *InventoryFME) []reportField {
+	socket, power, powerLimit := reportMissing, reportMissing, reportMissing
+
+	if fme.SocketID != nil {
+		socket = strconv.FormatUint(uint64(*fme.SocketID), 10)
+	}
+
+	if fme.Power != nil {
+		power = strconv.FormatUint(fme.Power.Consumed, 10)
+
+		if limit := fme.Power.Limit(); limit > 0 {
+			powerLimit = strconv.FormatUint(limit, 10)
+		}
+	}
+
+	return []reportField{
+		{"Device path", "devPath", orMissing(fme.DevPath)},
+		{"Interface UUID", "interfaceUUID", orMissing(fme.InterfaceUUID)},
+		{"Bitstream ID", "bitstreamID", orMissing(fme.BitstreamID)},
+		{"Bitstream metadata", "bitstreamMetadata", orMissing(fme.BitstreamMetadata)},
+		{"Ports", "portsNum", strconv.Itoa(fme.PortsNum)},
+		{"Socket ID", "socketID", socket},
+		{"Power consumed", "power", power},
+		{"Power limit", "powerLimit", powerLimit},
+		{"Annotations", "annotations", annotationsValue(fme.Annotations)},
+	}
+}
+
+func portFields(port *InventoryPort) []reportField {
+	id, resets := reportMissing, reportMissing
+
+	if port.ID != nil {
+		id = strconv.FormatUint(uint64(*port.ID), 10)
+	}
+
+	if port.ResetCount != nil {
+		resets = strconv.FormatUint(*port.ResetCount, 10)
+	}
+
+	return []reportField{
+		{"Device path", "devPath", orMissing(port.DevPath)},
+		{"ID", "id", id},
+		{"PCI address", "pciAddress", orMissing(port.PCIAddress)},
+		{"AFU", "afu", orMissing(port.AFU)},
+		{"Reset count", "resetCount", resets},
+		{"Annotations", "annotations", annotationsValue(port.Annotations)},
+	}
+}
+
+func orMissing(value string) string {
+	if value == "" {
+		return reportMissing
+	}
+
+	return value
+}
+
+// annotationsValue joins the annotations as comma separated key=value pairs
+// sorted by key.
+func annotationsValue(annotations map[string]string) string {
+	if len(annotations) == 0 {
+		return reportMissing
+	}
+
+	pairs := make([]string, 0, len(annotations))
+	for _, key := range sortedKeys(annotations) {
+		pairs = append(pairs, key+"="+annotations[key])
+	}
+
+	return strings.Join(pairs, ",")
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+
+	return keys
+}
@@ -0,0 +1,125 @@
+// Copyright 2023 Intel Corporation. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fpga
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var updateGolden = flag.Bool("update", false, "update golden files in testdata")
+
+// newTestReportInventory returns inventory of a fixed board with a healthy
+// port and a port whose properties couldn't be read.
+func newTestReportInventory() *Inventory {
+	id, resets := uint32(0), uint64(3)
+
+	return &Inventory{
+		Errors: map[string]string{"intel-fpga-fme.1": "unable to open intel-fpga-fme.1"},
+		Boards: []*InventoryBoard{
+			{
+				PCIAddress: testBDF,
+				Vendor:     "0x8086",
+				Device:     "0x09c4",
+				Model:      "Intel PAC with Arria 10 GX FPGA",
+				FMEs: []*InventoryFME{
+					{
+						Errors:        map[string]string{"socketID": "not supported"},
+						Annotations:   map[string]string{"rack": "r12", "owner": "ml team"},
+						Power:         &PowerInfo{Consumed: 31, Threshold1: 40},
+						Name:          "intel-fpga-fme.0",
+						DevPath:       "/dev/intel-fpga-fme.0",
+						InterfaceUUID: testInterface,
+						BitstreamID:   testBitstreamA,
+						PortsNum:      2,
+						Ports: []*InventoryPort{
+							{
+								ID:         &id,
+								ResetCount: &resets,
+								Name:       "intel-fpga-port.0",
+								DevPath:    "/dev/intel-fpga-port.0",
+								PCIAddress: testBDF,
+								AFU:        testAFUOld,
+							},
+							{
+								Errors:  map[string]string{"id": "no such file", "resetCount": "not supported"},
+								Name:    "intel-fpga-port.1",
+								DevPath: "/dev/intel-fpga-port.1",
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+func TestWriteReport(t *testing.T) {
+	tcases := []struct {
+		format ReportFormat
+		golden string
+	}{
+		{ReportFormatText, "inventory.txt"},
+		{ReportFormatJSON, "inventory.json"},
+		{ReportFormatCompact, "inventory.compact"},
+	}
+	for _, tc := range tcases {
+		t.Run(string(tc.format), func(t *testing.T) {
+			var b bytes.Buffer
+			if err := newTestReportInventory().WriteReport(&b, tc.format); err != nil {
+				t.Fatalf("unexpected error: %+v", err)
+			}
+
+			golden := filepath.Join("testdata", "report", tc.golden)
+
+			if *updateGolden {
+				if err := os.WriteFile(golden, b.Bytes(), 0600); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			expected, err := os.ReadFile(golden)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if b.String() != string(expected) {
+				t.Errorf("report doesn't match %s:\n%s", golden, b.String())
+			}
+		})
+	}
+
+	t.Run("unknown format", func(t *testing.T) {
+		if err := newTestReportInventory().WriteReport(&bytes.Buffer{}, "yaml"); err == nil {
+			t.Error("no error returned")
+		}
+	})
+
+	t.Run("compact lines", func(t *testing.T) {
+		var b bytes.Buffer
+		if err := newTestReportInventory().WriteReport(&b, ReportFormatCompact); err != nil {
+			t.Fatalf("unexpected error: %+v", err)
+		}
+
+		// Board, FME, two ports and the unavailable FME.
+		if lines := strings.Split(strings.TrimSuffix(b.String(), "\n"), "\n"); len(lines) != 5 {
+			t.Errorf("expected 5 lines, but got %d", len(lines))
+		}
+	})
+}
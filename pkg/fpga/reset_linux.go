@@ -0,0 +1,81 @@
+// Copyright 2023 Intel Corporation. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fpga
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// fmeResetReasonFile is an optional FME attribute with the cause of the last
+// board reset.
+const fmeResetReasonFile = "reset_reason"
+
+// Normalized board reset reasons returned by GetLastResetReason.
+const (
+	ResetReasonPowerOn  = "power-on"
+	ResetReasonHost     = "host"
+	ResetReasonWatchdog = "watchdog"
+	ResetReasonThermal  = "thermal"
+	ResetReasonPRError  = "pr-error"
+	ResetReasonUnknown  = "unknown"
+)
+
+// resetReasons maps the reset causes reported by the drivers and the BMC
+// firmware versions to the normalized reasons.
+var resetReasons = map[string]string{
+	"power-on":         ResetReasonPowerOn,
+	"power_on":         ResetReasonPowerOn,
+	"por":              ResetReasonPowerOn,
+	"cold":             ResetReasonPowerOn,
+	"host":             ResetReasonHost,
+	"host-initiated":   ResetReasonHost,
+	"host_initiated":   ResetReasonHost,
+	"software":         ResetReasonHost,
+	"watchdog":         ResetReasonWatchdog,
+	"wdt":              ResetReasonWatchdog,
+	"thermal":          ResetReasonThermal,
+	"over-temperature": ResetReasonThermal,
+	"over_temperature": ResetReasonThermal,
+	"pr-error":         ResetReasonPRError,
+	"pr_error":         ResetReasonPRError,
+}
+
+// getLastResetReason reads the cause of the last reset of the board, see
+// GetLastResetReason.
+func getLastResetReason(fme FME) (string, error) {
+	sysfs := fme.GetSysFsPath()
+	if sysfs == "" {
+		return "", errors.Errorf("%s: unknown sysfs entry", fme.GetName())
+	}
+
+	data, err := readSysfsFile(filepath.Join(sysfs, fmeResetReasonFile))
+	if os.IsNotExist(err) {
+		return "", errors.Wrapf(ErrNotSupported, "%s: no reset reason", fme.GetName())
+	}
+
+	if err != nil {
+		return "", errors.Wrapf(err, "%s: unable to read reset reason", fme.GetName())
+	}
+
+	if reason, ok := resetReasons[strings.ToLower(strings.TrimSpace(string(data)))]; ok {
+		return reason, nil
+	}
+
+	return ResetReasonUnknown, nil
+}
@@ -0,0 +1,76 @@
+// Copyright 2023 Intel Corporation. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fpga
+
+import (
+	"testing"
+
+	"github.com/pkg/errors"
+)
+
+func TestGetLastResetReason(t *testing.T) {
+	tcases := []struct {
+		files     map[string]string
+		name      string
+		expected  string
+		noSupport bool
+	}{
+		{
+			name:      "not recorded",
+			noSupport: true,
+		},
+		{
+			name:     "watchdog",
+			files:    map[string]string{"reset_reason": "WDT\n"},
+			expected: ResetReasonWatchdog,
+		},
+		{
+			name:     "thermal",
+			files:    map[string]string{"reset_reason": "over_temperature\n"},
+			expected: ResetReasonThermal,
+		},
+		{
+			name:     "PR error",
+			files:    map[string]string{"reset_reason": "pr_error\n"},
+			expected: ResetReasonPRError,
+		},
+		{
+			name:     "host initiated",
+			files:    map[string]string{"reset_reason": "host-initiated\n"},
+			expected: ResetReasonHost,
+		},
+		{
+			name:     "unknown cause",
+			files:    map[string]string{"reset_reason": "brownout\n"},
+			expected: ResetReasonUnknown,
+		},
+	}
+	for _, tc := range tcases {
+		t.Run(tc.name, func(t *testing.T) {
+			reason, err := newTestIntelFpgaFME(t, tc.files).GetLastResetReason()
+			if tc.noSupport {
+				if !errors.Is(err, ErrNotSupported) {
+					t.Errorf("expected ErrNotSupported, but got %v", err)
+				}
+
+				return
+			}
+
+			if err != nil || reason != tc.expected {
+				t.Errorf("expected %q, but got %q, %v", tc.expected, reason, err)
+			}
+		})
+	}
+}
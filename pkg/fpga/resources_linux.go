@@ -0,0 +1,120 @@
+// Copyright 2023 Intel Corporation. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fpga
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+)
+
+// Modes of advertising FPGA resources, see the fpga_plugin -mode flag.
+const (
+	// ResourceModeAF advertises ports by the AFU programmed to them
+	// as "af-..." resources, see GetAfuDevType.
+	ResourceModeAF = "af"
+	// ResourceModeRegion advertises ports by the FME interface UUID
+	// as "region-<interface UUID>" resources.
+	ResourceModeRegion = "region"
+	// ResourceModeRegionDevel is the same as ResourceModeRegion, but
+	// the FME device node is also exposed to the container.
+	ResourceModeRegionDevel = "regiondevel"
+)
+
+// ModeUnknown is returned by GetMode when the operating mode can't be told.
+const ModeUnknown = "unknown"
+
+// AdvertisableResources returns the names of the extended resources, without
+// namespace, the board of fme contributes in the given mode and the number
+// of devices of each resource. In region modes every port of the board
+// counts towards the board's region. In af mode ports are counted per
+// distinct AFU, so boards with different AFUs in their ports contribute
+// several resources. If some of the ports can't be checked, the rest are
+// counted and an error describing the failures is returned.
+func AdvertisableResources(fme FME, mode string) (map[string]int, error) {
+	if mode != ResourceModeAF && mode != ResourceModeRegion && mode != ResourceModeRegionDevel {
+		return nil, errors.Errorf("unknown mode %q", mode)
+	}
+
+	ports, failures, err := ownedPorts(context.Background(), fme)
+	if err != nil {
+		return nil, err
+	}
+
+	resources := map[string]int{}
+	interfaceID := fme.GetInterfaceUUID()
+
+	for _, port := range ports {
+		name, nameErr := resourceName(mode, interfaceID, port)
+		port.Close()
+
+		if nameErr != nil {
+			failures = append(failures, errors.Wrap(nameErr, port.GetName()))
+
+			continue
+		}
+
+		resources[name]++
+	}
+
+	if len(failures) > 0 {
+		return resources, errors.Wrapf(joinErrors(failures...), "%s: unable to count all ports", fme.GetName())
+	}
+
+	return resources, nil
+}
+
+// resourceName returns name of the resource the port is advertised as.
+func resourceName(mode, interfaceID string, port Port) (string, error) {
+	if mode == ResourceModeAF {
+		return GetAfuDevType(interfaceID, port.GetAcceleratorTypeUUID())
+	}
+
+	return ResourceModeRegion + "-" + interfaceID, nil
+}
+
+// boardMode infers the operating mode of the board of fme:
+//   - ResourceModeRegion if the FME exposes PR interface, i.e. the ports
+//     can be reprogrammed, no matter which AFUs they run,
+//   - ResourceModeAF if there is no PR interface, but some ports run AFUs,
+//     i.e. the accelerator functions are fixed,
+//   - ModeUnknown otherwise.
+//
+// Failures to check individual ports are only returned if no AFU is found
+// in the rest.
+func boardMode(fme FME) (string, error) {
+	if fme.GetInterfaceUUID() != "" {
+		return ResourceModeRegion, nil
+	}
+
+	ports, failures, err := ownedPorts(context.Background(), fme)
+	if err != nil {
+		return ModeUnknown, err
+	}
+
+	defer closePorts(ports)
+
+	for _, port := range ports {
+		if afu := port.GetAcceleratorTypeUUID(); afu != "" && !IsEmptyAFU(afu) {
+			return ResourceModeAF, nil
+		}
+	}
+
+	if len(failures) > 0 {
+		return ModeUnknown, errors.Wrapf(joinErrors(failures...), "%s: unable to check all ports", fme.GetName())
+	}
+
+	return ModeUnknown, nil
+}
@@ -0,0 +1,195 @@
+// Copyright 2023 Intel Corporation. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fpga
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func TestAdvertisableResources(t *testing.T) {
+	afOld, _ := GetAfuDevType(testInterface, testAFUOld)
+	afNew, _ := GetAfuDevType(testInterface, testAFUNew)
+
+	tcases := []struct {
+		expected    map[string]int
+		name        string
+		mode        string
+		afus        []string
+		expectedErr bool
+	}{
+		{
+			name:     "region mode",
+			mode:     ResourceModeRegion,
+			afus:     []string{testAFUOld, testAFUNew, testAFUNew},
+			expected: map[string]int{"region-" + testInterface: 3},
+		},
+		{
+			name:     "region devel mode",
+			mode:     ResourceModeRegionDevel,
+			afus:     []string{testAFUOld},
+			expected: map[string]int{"region-" + testInterface: 1},
+		},
+		{
+			name:     "af mode with mixed AFUs",
+			mode:     ResourceModeAF,
+			afus:     []string{testAFUOld, testAFUNew, testAFUNew},
+			expected: map[string]int{afOld: 1, afNew: 2},
+		},
+		{
+			name:        "af mode with broken AFU",
+			mode:        ResourceModeAF,
+			afus:        []string{testAFUOld, "garbage"},
+			expected:    map[string]int{afOld: 1},
+			expectedErr: true,
+		},
+		{
+			name:        "unknown mode",
+			mode:        "dancing",
+			expectedErr: true,
+		},
+	}
+	for _, tc := range tcases {
+		t.Run(tc.name, func(t *testing.T) {
+			sysfs := setTestSysfsRoot(t)
+			fme := &IntelFpgaFME{DevPath: "/dev/intel-fpga-fme.0", CompatID: testInterface, PCIDevice: &PCIDevice{BDF: testBDF}}
+			ports := map[string]Port{}
+
+			// One more port belonging to another card must not be counted.
+			for i, afu := range append(tc.afus, testAFUOld) {
+				name := fmt.Sprintf("intel-fpga-port.%d", i)
+				if err := createTestFiles(sysfs, []string{"bus/platform/devices/" + name}, nil); err != nil {
+					t.Fatal(err)
+				}
+
+				port := newTestIntelFpgaPort(t, fme, afu)
+				port.DevPath = "/dev/" + name
+				port.PCIDevice = &PCIDevice{BDF: testBDF}
+
+				if i == len(tc.afus) {
+					port.PCIDevice = &PCIDevice{BDF: "0000:af:00.0"}
+				}
+
+				ports[name] = port
+			}
+
+			setTestOpeners(t, nil, ports)
+
+			resources, err := AdvertisableResources(fme, tc.mode)
+			if tc.expectedErr && err == nil {
+				t.Error("no error returned")
+			}
+
+			if !tc.expectedErr && err != nil {
+				t.Errorf("unexpected error: %+v", err)
+			}
+
+			if !reflect.DeepEqual(resources, tc.expected) {
+				t.Errorf("expected %v, but got %v", tc.expected, resources)
+			}
+		})
+	}
+}
+
+func TestGetMode(t *testing.T) {
+	tcases := []struct {
+		name         string
+		interfaceID  string
+		expectedMode string
+		afus         []string
+		brokenPort   bool
+		expectedErr  bool
+	}{
+		{
+			name:         "region with AFUs",
+			interfaceID:  testInterface,
+			afus:         []string{testAFUOld, testAFUEmpty},
+			expectedMode: ResourceModeRegion,
+		},
+		{
+			name:         "region without ports",
+			interfaceID:  testInterface,
+			expectedMode: ResourceModeRegion,
+		},
+		{
+			name:         "fixed AFUs",
+			afus:         []string{testAFUEmpty, testAFUNew},
+			expectedMode: ResourceModeAF,
+		},
+		{
+			name:         "fixed AFUs with broken port",
+			afus:         []string{testAFUOld},
+			brokenPort:   true,
+			expectedMode: ResourceModeAF,
+		},
+		{
+			name:         "no PR interface nor AFUs",
+			afus:         []string{testAFUEmpty},
+			expectedMode: ModeUnknown,
+		},
+		{
+			name:         "indeterminate with broken port",
+			afus:         []string{testAFUEmpty},
+			brokenPort:   true,
+			expectedMode: ModeUnknown,
+			expectedErr:  true,
+		},
+	}
+	for _, tc := range tcases {
+		t.Run(tc.name, func(t *testing.T) {
+			sysfs := setTestSysfsRoot(t)
+			fme := &IntelFpgaFME{DevPath: "/dev/intel-fpga-fme.0", CompatID: tc.interfaceID, PCIDevice: &PCIDevice{BDF: testBDF}}
+			ports := map[string]Port{}
+			names := len(tc.afus)
+
+			if tc.brokenPort {
+				// The last port is not openable.
+				names++
+			}
+
+			for i := 0; i < names; i++ {
+				name := fmt.Sprintf("intel-fpga-port.%d", i)
+				if err := createTestFiles(sysfs, []string{"bus/platform/devices/" + name}, nil); err != nil {
+					t.Fatal(err)
+				}
+
+				if i == len(tc.afus) {
+					continue
+				}
+
+				port := newTestIntelFpgaPort(t, fme, tc.afus[i])
+				port.DevPath = "/dev/" + name
+				port.PCIDevice = &PCIDevice{BDF: testBDF}
+				ports[name] = port
+			}
+
+			setTestOpeners(t, nil, ports)
+
+			mode, err := fme.GetMode()
+			if tc.expectedErr && err == nil {
+				t.Error("no error returned")
+			}
+
+			if !tc.expectedErr && err != nil {
+				t.Errorf("unexpected error: %+v", err)
+			}
+
+			if mode != tc.expectedMode {
+				t.Errorf("expected mode %q, but got %q", tc.expectedMode, mode)
+			}
+		})
+	}
+}
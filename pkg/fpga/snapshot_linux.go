@@ -0,0 +1,148 @@
+// Copyright 2023 Intel Corporation. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fpga
+
+import (
+	"context"
+	"sort"
+
+	"github.com/pkg/errors"
+
+	"github.com/intel/intel-device-plugins-for-kubernetes/pkg/fpga/bitstream"
+)
+
+// BoardSnapshot is the configuration of a board captured by SnapshotBoard,
+// e.g. to move it to another host or to restore it after debugging.
+type BoardSnapshot struct {
+	// PCIAddress is the address of the board's physical function.
+	PCIAddress    string `json:"pciAddress"`
+	Model         string `json:"model,omitempty"`
+	InterfaceUUID string `json:"interfaceUUID"`
+	BitstreamID   string `json:"bitstreamID"`
+	// Ports are sorted by ID.
+	Ports []PortSnapshot `json:"ports"`
+}
+
+// PortSnapshot is the AFU loaded to a port when the snapshot was taken.
+type PortSnapshot struct {
+	// AFU is the canonical AFU UUID, see CanonizeID.
+	AFU string `json:"afu"`
+	// ID is the port ID within the FME.
+	ID uint32 `json:"id"`
+}
+
+// SnapshotBoard captures the FME properties and the AFUs loaded to all the
+// ports of fme. The snapshot fails if any of the ports can't be checked,
+// as it couldn't be restored faithfully.
+func SnapshotBoard(fme FME) (*BoardSnapshot, error) {
+	pci, err := fme.GetPCIDevice()
+	if err != nil {
+		return nil, errors.Wrapf(err, "%s: unable to get PCI device", fme.GetName())
+	}
+
+	ports, failures, err := ownedPorts(context.Background(), fme)
+	if err != nil {
+		return nil, err
+	}
+
+	defer closePorts(ports)
+
+	snap := &BoardSnapshot{
+		PCIAddress:    pci.physicalFunction().BDF,
+		Model:         fme.GetModelName(),
+		InterfaceUUID: CanonizeID(fme.GetInterfaceUUID()),
+		BitstreamID:   fme.GetBitstreamID(),
+		Ports:         []PortSnapshot{},
+	}
+
+	for _, port := range ports {
+		id, idErr := port.GetPortID()
+		if idErr != nil {
+			failures = append(failures, errors.Wrap(idErr, port.GetName()))
+
+			continue
+		}
+
+		afu, afuErr := port.ReadAcceleratorTypeUUIDFresh()
+		if afuErr != nil {
+			failures = append(failures, errors.Wrap(afuErr, port.GetName()))
+
+			continue
+		}
+
+		snap.Ports = append(snap.Ports, PortSnapshot{ID: id, AFU: CanonizeID(afu)})
+	}
+
+	if len(failures) > 0 {
+		return nil, errors.Wrapf(joinErrors(failures...), "%s: unable to snapshot all ports", fme.GetName())
+	}
+
+	sort.Slice(snap.Ports, func(i, j int) bool { return snap.Ports[i].ID < snap.Ports[j].ID })
+
+	return snap, nil
+}
+
+// RestoreBoard reprograms the ports of fme to run the AFUs of the snapshot,
+// with the bitstreams resolver returns for the AFU UUIDs. Ports already
+// running the AFUs are not touched, see Reconcile, and ports empty in the
+// snapshot are left as they are. The FME must have the interface of the
+// snapshot, as AFUs built for other interfaces can't be programmed, and a
+// port can't be in the snapshot more than once.
+func RestoreBoard(fme FME, snap *BoardSnapshot, resolver func(uuid string) (bitstream.File, error)) error {
+	if snap == nil {
+		return errors.New("no snapshot")
+	}
+
+	if interfaceID := CanonizeID(fme.GetInterfaceUUID()); interfaceID != snap.InterfaceUUID {
+		return errors.Errorf("%s: interface %s doesn't match interface %s of the snapshot", fme.GetName(), interfaceID, snap.InterfaceUUID)
+	}
+
+	desired := map[uint32]bitstream.File{}
+
+	defer func() {
+		for _, bs := range desired {
+			bs.Close()
+		}
+	}()
+
+	seen := map[uint32]bool{}
+
+	for _, port := range snap.Ports {
+		if seen[port.ID] {
+			return errors.Errorf("%s: port %d is in the snapshot more than once", fme.GetName(), port.ID)
+		}
+
+		seen[port.ID] = true
+
+		if IsEmptyAFU(port.AFU) {
+			continue
+		}
+
+		bs, err := resolver(port.AFU)
+		if err != nil {
+			return errors.Wrapf(err, "%s: port %d: unable to find bitstream for AFU %s", fme.GetName(), port.ID, port.AFU)
+		}
+
+		if bs == nil {
+			return errors.Errorf("%s: port %d: no bitstream found for AFU %s", fme.GetName(), port.ID, port.AFU)
+		}
+
+		desired[port.ID] = bs
+	}
+
+	_, err := Reconcile(fme, desired, ReconcileOptions{})
+
+	return err
+}
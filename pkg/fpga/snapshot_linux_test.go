@@ -0,0 +1,210 @@
+// Copyright 2023 Intel Corporation. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fpga
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/intel/intel-device-plugins-for-kubernetes/pkg/fpga/bitstream"
+)
+
+func TestSnapshotRestoreBoard(t *testing.T) {
+	sysfs := setTestSysfsRoot(t)
+	fme := &IntelFpgaFME{
+		DevPath:     "/dev/intel-fpga-fme.0",
+		CompatID:    testInterface,
+		BitstreamID: testBitstreamA,
+		PCIDevice:   &PCIDevice{BDF: testBDF},
+	}
+	afus := map[uint32]string{}
+	portFME := &testFME{interfaceUUID: testInterface}
+	ports := map[string]Port{}
+	touched := map[uint32]bool{}
+
+	for i, afu := range []string{testAFUOld, testAFUNew, testAFUEmpty} {
+		name := fmt.Sprintf("intel-fpga-port.%d", i)
+		if err := createTestFiles(sysfs, []string{"bus/platform/devices/" + name}, nil); err != nil {
+			t.Fatal(err)
+		}
+
+		port := newTestIntelFpgaPort(t, portFME, afu)
+		port.DevPath = "/dev/" + name
+		port.PCIDevice = &PCIDevice{BDF: testBDF}
+		ports[name] = port
+
+		if err := os.WriteFile(filepath.Join(port.SysFsPath, "id"), []byte(fmt.Sprint(i)), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	setTestOpeners(t, nil, ports)
+
+	// PR programs the AFU set in afus.
+	writeAFU := func(id uint32, afu string) error {
+		return os.WriteFile(filepath.Join(ports[fmt.Sprintf("intel-fpga-port.%d", id)].GetSysFsPath(), "afu_id"), []byte(afu), 0600)
+	}
+	portFME.portPR = func(id uint32, _ []byte) error {
+		touched[id] = true
+
+		return writeAFU(id, afus[id])
+	}
+
+	snap, err := SnapshotBoard(fme)
+	if err != nil {
+		t.Fatalf("unexpected error: %+v", err)
+	}
+
+	expected := &BoardSnapshot{
+		PCIAddress:    testBDF,
+		InterfaceUUID: testInterface,
+		BitstreamID:   testBitstreamA,
+		Ports: []PortSnapshot{
+			{ID: 0, AFU: testAFUOld},
+			{ID: 1, AFU: testAFUNew},
+			{ID: 2, AFU: testAFUEmpty},
+		},
+	}
+	if !reflect.DeepEqual(snap, expected) {
+		t.Fatalf("expected snapshot %+v, but got %+v", expected, snap)
+	}
+
+	data, err := json.Marshal(snap)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	restored := &BoardSnapshot{}
+	if err = json.Unmarshal(data, restored); err != nil {
+		t.Fatal(err)
+	}
+
+	// Another workload reprograms port 0 and 1.
+	for id, afu := range map[uint32]string{0: testAFUEmpty, 1: testAFUOld} {
+		if err = writeAFU(id, afu); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	resolved := []string{}
+	resolver := func(uuid string) (bitstream.File, error) {
+		resolved = append(resolved, uuid)
+
+		return newTestGBS(t, testInterface, uuid), nil
+	}
+
+	for _, port := range restored.Ports {
+		afus[port.ID] = port.AFU
+	}
+
+	if err = RestoreBoard(fme, restored, resolver); err != nil {
+		t.Fatalf("unexpected error: %+v", err)
+	}
+
+	if !reflect.DeepEqual(resolved, []string{testAFUOld, testAFUNew}) {
+		t.Errorf("expected AFUs of non-empty ports resolved, but got %v", resolved)
+	}
+
+	if !reflect.DeepEqual(touched, map[uint32]bool{0: true, 1: true}) {
+		t.Errorf("expected ports 0 and 1 reprogrammed, but got %v", touched)
+	}
+
+	if snap, err = SnapshotBoard(fme); err != nil || !reflect.DeepEqual(snap, expected) {
+		t.Errorf("expected snapshot %+v after restore, but got %+v, %v", expected, snap, err)
+	}
+
+	// Snapshots of other interfaces are rejected.
+	restored.InterfaceUUID = "ce48969398f05f33946d560708be108a"
+	touched = map[uint32]bool{}
+
+	if err = RestoreBoard(fme, restored, resolver); err == nil {
+		t.Error("no error returned for interface mismatch")
+	}
+
+	if len(touched) != 0 {
+		t.Errorf("ports %v programmed for interface mismatch", touched)
+	}
+}
+
+// closeTrackingFile records whether the bitstream has been closed.
+type closeTrackingFile struct {
+	bitstream.File
+	closed bool
+}
+
+func (f *closeTrackingFile) Close() error {
+	f.closed = true
+
+	return f.File.Close()
+}
+
+func TestRestoreBoardInvalidSnapshot(t *testing.T) {
+	fme := &IntelFpgaFME{
+		DevPath:   "/dev/intel-fpga-fme.0",
+		CompatID:  testInterface,
+		PCIDevice: &PCIDevice{BDF: testBDF},
+	}
+
+	resolved := []*closeTrackingFile{}
+
+	tcases := []struct {
+		resolver    func(uuid string) (bitstream.File, error)
+		name        string
+		expectedErr string
+		ports       []PortSnapshot
+	}{
+		{
+			name: "duplicate port",
+			resolver: func(uuid string) (bitstream.File, error) {
+				bs := &closeTrackingFile{File: newTestGBS(t, testInterface, uuid)}
+				resolved = append(resolved, bs)
+
+				return bs, nil
+			},
+			ports:       []PortSnapshot{{ID: 0, AFU: testAFUOld}, {ID: 0, AFU: testAFUNew}},
+			expectedErr: "port 0 is in the snapshot more than once",
+		},
+		{
+			name:        "no bitstream",
+			resolver:    func(string) (bitstream.File, error) { return nil, nil },
+			ports:       []PortSnapshot{{ID: 0, AFU: testAFUOld}},
+			expectedErr: "no bitstream found for AFU " + testAFUOld,
+		},
+	}
+
+	for _, tc := range tcases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			snap := &BoardSnapshot{PCIAddress: testBDF, InterfaceUUID: testInterface, Ports: tc.ports}
+
+			err := RestoreBoard(fme, snap, tc.resolver)
+			if err == nil || !strings.Contains(err.Error(), tc.expectedErr) {
+				t.Errorf("expected error %q, but got %v", tc.expectedErr, err)
+			}
+		})
+	}
+
+	for _, bs := range resolved {
+		if !bs.closed {
+			t.Errorf("bitstream for AFU %s not closed", bs.AcceleratorTypeUUID())
+		}
+	}
+}
@@ -0,0 +1,232 @@
+// Copyright 2023 Intel Corporation. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fpga
+
+import (
+	"io"
+	"regexp"
+	"sort"
+
+	"github.com/pkg/errors"
+	"sigs.k8s.io/yaml"
+
+	"github.com/intel/intel-device-plugins-for-kubernetes/pkg/fpga/bitstream"
+)
+
+// afuUUIDRE matches canonical AFU UUIDs, see CanonizeID.
+var afuUUIDRE = regexp.MustCompile(`^[[:xdigit:]]{32}$`)
+
+// DesiredState is the declarative configuration of the FPGA boards of a host:
+// the bitstreams their ports should be programmed with. It's serialized as YAML
+// by ExportState and ImportState, e.g.
+//
+//	boards:
+//	- pciAddress: "0000:5e:00.0"
+//	  ports:
+//	  - id: 0
+//	    afu: d8424dc4a4a3c413f89e433683f9040b
+//	  - id: 1
+//	    bitstream: /srv/bitstreams/nlb3.gbs
+type DesiredState struct {
+	Boards []DesiredBoard `json:"boards"`
+}
+
+// DesiredBoard is the desired state of the ports of a board.
+type DesiredBoard struct {
+	// PCIAddress is the address of the board's physical function.
+	PCIAddress string        `json:"pciAddress"`
+	Ports      []DesiredPort `json:"ports"`
+}
+
+// DesiredPort is the bitstream a port should be programmed with. At least
+// one of Bitstream and AFU must be set. If both are, the AFU of the bitstream
+// file must be AFU.
+type DesiredPort struct {
+	// Bitstream is path to the bitstream file.
+	Bitstream string `json:"bitstream,omitempty"`
+	// AFU is the AFU UUID, used to find the bitstream in the bitstream
+	// directory if Bitstream isn't set.
+	AFU string `json:"afu,omitempty"`
+	// ID is the port ID within the FME.
+	ID uint32 `json:"id"`
+}
+
+// ExportState writes the current state of the host's FPGA boards to w as
+// YAML DesiredState: all the ports with an AFU programmed, by AFU UUID.
+// Imported back, it makes Reconcile restore that state.
+func ExportState(w io.Writer) error {
+	inv, err := GetInventory()
+	if err != nil {
+		return err
+	}
+
+	return writeState(w, stateOf(inv))
+}
+
+// stateOf returns state of the ports of the inventory with AFUs programmed.
+func stateOf(inv *Inventory) DesiredState {
+	state := DesiredState{Boards: []DesiredBoard{}}
+
+	for _, board := range inv.Boards {
+		desired := DesiredBoard{PCIAddress: board.PCIAddress, Ports: []DesiredPort{}}
+
+		for _, fme := range board.FMEs {
+			for _, port := range fme.Ports {
+				if port.ID == nil || IsEmptyAFU(port.AFU) {
+					continue
+				}
+
+				desired.Ports = append(desired.Ports, DesiredPort{ID: *port.ID, AFU: CanonizeID(port.AFU)})
+			}
+		}
+
+		sort.Slice(desired.Ports, func(i, j int) bool { return desired.Ports[i].ID < desired.Ports[j].ID })
+
+		state.Boards = append(state.Boards, desired)
+	}
+
+	return state
+}
+
+// writeState serializes the state to w as YAML.
+func writeState(w io.Writer, state DesiredState) error {
+	data, err := yaml.Marshal(state)
+	if err != nil {
+		return errors.Wrap(err, "unable to serialize state")
+	}
+
+	_, err = w.Write(data)
+
+	return errors.Wrap(err, "unable to write state")
+}
+
+// ImportState reads YAML DesiredState from r. Unknown fields, malformed
+// PCI addresses and AFU UUIDs, duplicate boards and ports, and ports without
+// bitstream are rejected.
+func ImportState(r io.Reader) (DesiredState, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return DesiredState{}, errors.Wrap(err, "unable to read state")
+	}
+
+	var state DesiredState
+	if err = yaml.UnmarshalStrict(data, &state); err != nil {
+		return DesiredState{}, errors.Wrap(err, "malformed state")
+	}
+
+	if err = state.validate(); err != nil {
+		return DesiredState{}, errors.Wrap(err, "invalid state")
+	}
+
+	return state, nil
+}
+
+func (s *DesiredState) validate() error {
+	boards := map[string]bool{}
+
+	for i := range s.Boards {
+		board := &s.Boards[i]
+
+		if !pciAddressRE.MatchString(board.PCIAddress) {
+			return errors.Errorf("board %d: malformed PCI address %q", i, board.PCIAddress)
+		}
+
+		if boards[board.PCIAddress] {
+			return errors.Errorf("board %s: duplicate entry", board.PCIAddress)
+		}
+
+		boards[board.PCIAddress] = true
+		ports := map[uint32]bool{}
+
+		for j := range board.Ports {
+			port := &board.Ports[j]
+
+			if ports[port.ID] {
+				return errors.Errorf("board %s: duplicate port %d", board.PCIAddress, port.ID)
+			}
+
+			ports[port.ID] = true
+
+			if port.Bitstream == "" && port.AFU == "" {
+				return errors.Errorf("board %s: port %d: neither bitstream nor afu set", board.PCIAddress, port.ID)
+			}
+
+			if port.AFU == "" {
+				continue
+			}
+
+			if port.AFU = CanonizeID(port.AFU); !afuUUIDRE.MatchString(port.AFU) {
+				return errors.Errorf("board %s: port %d: malformed afu %q", board.PCIAddress, port.ID, port.AFU)
+			}
+		}
+	}
+
+	return nil
+}
+
+// Bitstreams opens the bitstreams desired for the ports of fme, keyed by
+// port ID, as expected by Reconcile. Bitstreams given by AFU are looked up
+// in bitstreamDir by the FME interface UUID, see bitstream.GetFPGABitstream.
+// The map is empty if the state has no entry for the board of fme. The
+// caller is responsible for closing the bitstreams.
+func (s DesiredState) Bitstreams(fme FME, bitstreamDir string) (map[uint32]bitstream.File, error) {
+	pci, err := fme.GetPCIDevice()
+	if err != nil {
+		return nil, errors.Wrapf(err, "%s: unable to get PCI device", fme.GetName())
+	}
+
+	ret := map[uint32]bitstream.File{}
+
+	for _, board := range s.Boards {
+		if board.PCIAddress != pci.physicalFunction().BDF {
+			continue
+		}
+
+		for _, port := range board.Ports {
+			bs, openErr := openDesiredBitstream(fme, port, bitstreamDir)
+			if openErr != nil {
+				for _, opened := range ret {
+					opened.Close()
+				}
+
+				return nil, errors.Wrapf(openErr, "%s: port %d", fme.GetName(), port.ID)
+			}
+
+			ret[port.ID] = bs
+		}
+	}
+
+	return ret, nil
+}
+
+// openDesiredBitstream opens the bitstream desired for the port.
+func openDesiredBitstream(fme FME, port DesiredPort, bitstreamDir string) (bitstream.File, error) {
+	if port.Bitstream == "" {
+		return bitstream.GetFPGABitstream(bitstreamDir, fme.GetInterfaceUUID(), port.AFU)
+	}
+
+	bs, err := bitstream.Open(port.Bitstream)
+	if err != nil {
+		return nil, err
+	}
+
+	if afu := CanonizeID(bs.AcceleratorTypeUUID()); port.AFU != "" && afu != port.AFU {
+		bs.Close()
+
+		return nil, errors.Errorf("%s has AFU %s, %s expected", port.Bitstream, afu, port.AFU)
+	}
+
+	return bs, nil
+}
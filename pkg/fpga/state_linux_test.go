@@ -0,0 +1,167 @@
+// Copyright 2023 Intel Corporation. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fpga
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const testStateBitstreamDir = "bitstream/testdata/intel.com/fpga"
+
+func TestStateRoundTrip(t *testing.T) {
+	id0, id1, id2 := uint32(0), uint32(1), uint32(2)
+	inv := &Inventory{Boards: []*InventoryBoard{
+		{
+			PCIAddress: testBDF,
+			FMEs: []*InventoryFME{{Ports: []*InventoryPort{
+				{ID: &id1, AFU: "D8424DC4-A4A3-C413-F89E-433683F9040B"},
+				{ID: &id0, AFU: testAFUOld},
+				{ID: &id2, AFU: testAFUEmpty},
+				{AFU: testAFUNew},
+			}}},
+		},
+		{PCIAddress: "0000:af:00.0"},
+	}}
+
+	expected := DesiredState{Boards: []DesiredBoard{
+		{PCIAddress: testBDF, Ports: []DesiredPort{{ID: 0, AFU: testAFUOld}, {ID: 1, AFU: testAFUNew}}},
+		{PCIAddress: "0000:af:00.0", Ports: []DesiredPort{}},
+	}}
+
+	var buf bytes.Buffer
+	if err := writeState(&buf, stateOf(inv)); err != nil {
+		t.Fatalf("unable to write state: %+v", err)
+	}
+
+	state, err := ImportState(&buf)
+	if err != nil {
+		t.Fatalf("unable to import state: %+v", err)
+	}
+
+	if !reflect.DeepEqual(state, expected) {
+		t.Errorf("expected %+v, but got %+v", expected, state)
+	}
+
+	// hand edited document with paths
+	state.Boards[1].Ports = append(state.Boards[1].Ports, DesiredPort{ID: 3, Bitstream: "/srv/bitstreams/nlb3.gbs"})
+
+	buf.Reset()
+
+	if err = writeState(&buf, state); err != nil {
+		t.Fatalf("unable to write state: %+v", err)
+	}
+
+	reimported, err := ImportState(&buf)
+	if err != nil {
+		t.Fatalf("unable to import state: %+v", err)
+	}
+
+	if !reflect.DeepEqual(reimported, state) {
+		t.Errorf("expected %+v, but got %+v", state, reimported)
+	}
+}
+
+func TestImportStateMalformed(t *testing.T) {
+	tcases := map[string]string{
+		"not YAML":              "boards: [",
+		"unknown field":         "boards:\n- pciAddress: \"0000:5e:00.0\"\n  slots: []\n",
+		"wrong type":            "boards:\n- pciAddress: \"0000:5e:00.0\"\n  ports:\n  - id: first\n    afu: " + testAFUNew + "\n",
+		"malformed PCI address": "boards:\n- pciAddress: 5e:00.0\n  ports: []\n",
+		"duplicate board":       "boards:\n- pciAddress: \"0000:5e:00.0\"\n- pciAddress: \"0000:5e:00.0\"\n",
+		"duplicate port":        "boards:\n- pciAddress: \"0000:5e:00.0\"\n  ports:\n  - id: 0\n    afu: " + testAFUNew + "\n  - id: 0\n    afu: " + testAFUNew + "\n",
+		"no bitstream":          "boards:\n- pciAddress: \"0000:5e:00.0\"\n  ports:\n  - id: 0\n",
+		"malformed AFU":         "boards:\n- pciAddress: \"0000:5e:00.0\"\n  ports:\n  - id: 0\n    afu: nlb0\n",
+	}
+	for name, doc := range tcases {
+		t.Run(name, func(t *testing.T) {
+			if _, err := ImportState(strings.NewReader(doc)); err == nil {
+				t.Error("no error returned")
+			}
+		})
+	}
+}
+
+func TestDesiredStateBitstreams(t *testing.T) {
+	fme := newTestIntelFpgaFME(t, map[string]string{"pr/interface_id": testInterface})
+	gbs := testStateBitstreamDir + "/" + testInterface + "/" + testAFUNew + ".gbs"
+
+	tcases := []struct {
+		name        string
+		ports       []DesiredPort
+		expected    []uint32
+		expectedErr bool
+	}{
+		{
+			name:     "by AFU and path",
+			ports:    []DesiredPort{{ID: 0, AFU: testAFUNew}, {ID: 1, Bitstream: gbs, AFU: testAFUNew}},
+			expected: []uint32{0, 1},
+		},
+		{
+			name:        "unknown AFU",
+			ports:       []DesiredPort{{ID: 0, AFU: testAFUNew}, {ID: 1, AFU: testAFUOld}},
+			expectedErr: true,
+		},
+		{
+			name:        "AFU mismatch",
+			ports:       []DesiredPort{{ID: 0, Bitstream: gbs, AFU: testAFUOld}},
+			expectedErr: true,
+		},
+	}
+	for _, tc := range tcases {
+		t.Run(tc.name, func(t *testing.T) {
+			state := DesiredState{Boards: []DesiredBoard{
+				{PCIAddress: "0000:af:00.0", Ports: []DesiredPort{{ID: 5, AFU: testAFUOld}}},
+				{PCIAddress: testBDF, Ports: tc.ports},
+			}}
+
+			bitstreams, err := state.Bitstreams(fme, testStateBitstreamDir)
+			if tc.expectedErr {
+				if err == nil {
+					t.Error("no error returned")
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("unexpected error: %+v", err)
+			}
+
+			ids := []uint32{}
+
+			for _, id := range tc.expected {
+				bs, ok := bitstreams[id]
+				if !ok {
+					continue
+				}
+
+				ids = append(ids, id)
+
+				if afu := bs.AcceleratorTypeUUID(); afu != testAFUNew {
+					t.Errorf("port %d: expected AFU %s, but got %s", id, testAFUNew, afu)
+				}
+
+				bs.Close()
+			}
+
+			if !reflect.DeepEqual(ids, tc.expected) || len(bitstreams) != len(tc.expected) {
+				t.Errorf("expected bitstreams for ports %v, but got %v", tc.expected, bitstreams)
+			}
+		})
+	}
+}
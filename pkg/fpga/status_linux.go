@@ -0,0 +1,77 @@
+// Copyright 2023 Intel Corporation. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fpga
+
+import "github.com/pkg/errors"
+
+// portStatusFields is the number of the PortStatus fields collected.
+const portStatusFields = 4
+
+// PortStatus is a snapshot of the port state. Like the inventory items,
+// fields which couldn't be collected are left zero and the errors are
+// recorded in Errors keyed by the field name.
+type PortStatus struct {
+	Errors map[string]string `json:"errors,omitempty"`
+	// LatchedErrors are the error registers of the port.
+	LatchedErrors DeviceErrors `json:"latchedErrors"`
+	// AFU is the UUID of the AFU programmed to the port.
+	AFU string `json:"afu"`
+	// ResetCount is the number of port resets, see GetResetCount.
+	ResetCount uint64 `json:"resetCount"`
+	// Online is false if the port is released from the physical function.
+	Online bool `json:"online"`
+}
+
+// onlinePort is a port which knows its release state.
+type onlinePort interface {
+	Port
+	IsOnline() (bool, error)
+}
+
+// getPortStatus collects the status of the port. Failure to collect
+// a field doesn't prevent collecting the others. An error is only returned
+// if none of the fields could be collected, e.g. because the port is gone.
+func getPortStatus(port onlinePort) (PortStatus, error) {
+	var (
+		status   PortStatus
+		failures []error
+		err      error
+	)
+
+	record := func(field string) {
+		if err != nil {
+			recordError(&status.Errors, field, err)
+			failures = append(failures, errors.Wrap(err, field))
+		}
+	}
+
+	status.Online, err = port.IsOnline()
+	record("online")
+
+	status.AFU, err = port.ReadAcceleratorTypeUUIDFresh()
+	record("afu")
+
+	status.LatchedErrors, err = port.GetErrors()
+	record("latchedErrors")
+
+	status.ResetCount, err = port.GetResetCount()
+	record("resetCount")
+
+	if len(failures) == portStatusFields {
+		return status, errors.Wrapf(joinErrors(failures...), "%s: unable to get status", port.GetName())
+	}
+
+	return status, nil
+}
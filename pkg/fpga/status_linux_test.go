@@ -0,0 +1,111 @@
+// Copyright 2023 Intel Corporation. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fpga
+
+import (
+	"reflect"
+	"sort"
+	"syscall"
+	"testing"
+
+	"github.com/pkg/errors"
+)
+
+func TestPortStatus(t *testing.T) {
+	tcases := []struct {
+		files          map[string]string
+		expected       PortStatus
+		name           string
+		expectedErrors []string
+		gone           bool
+	}{
+		{
+			name: "all fields",
+			files: map[string]string{
+				"online":             "0\n",
+				"reset_count":        "3\n",
+				"errors/errors":      "0x10\n",
+				"errors/first_error": "0x10\n",
+			},
+			expected: PortStatus{
+				AFU:           testAFUOld,
+				ResetCount:    3,
+				LatchedErrors: DeviceErrors{Registers: map[string]uint64{"errors": 0x10, "first_error": 0x10}},
+			},
+		},
+		{
+			name:           "no error reporting",
+			files:          map[string]string{"online": "1\n"},
+			expected:       PortStatus{AFU: testAFUOld, Online: true},
+			expectedErrors: []string{"latchedErrors"},
+		},
+		{
+			name:           "malformed attributes",
+			files:          map[string]string{"online": "maybe\n", "reset_count": "many\n", "errors/errors": "0x0\n"},
+			expected:       PortStatus{AFU: testAFUOld, LatchedErrors: DeviceErrors{Registers: map[string]uint64{"errors": 0}}},
+			expectedErrors: []string{"online", "resetCount"},
+		},
+		{
+			name:           "port gone",
+			files:          map[string]string{"online": "1\n", "reset_count": "3\n", "errors/errors": "0x0\n"},
+			gone:           true,
+			expectedErrors: []string{"afu", "latchedErrors", "online", "resetCount"},
+		},
+	}
+	for _, tc := range tcases {
+		t.Run(tc.name, func(t *testing.T) {
+			port := newTestIntelFpgaPort(t, &testFME{}, testAFUOld)
+			if err := createTestFiles(port.SysFsPath, nil, tc.files); err != nil {
+				t.Fatal(err)
+			}
+
+			if tc.gone {
+				origReadFile := readFile
+				t.Cleanup(func() { readFile = origReadFile })
+
+				readFile = func(string) ([]byte, error) {
+					return nil, syscall.ENODEV
+				}
+			}
+
+			status, err := port.PortStatus()
+			if tc.gone != (err != nil) {
+				t.Errorf("unexpected error: %v", err)
+			}
+
+			if tc.gone && !errors.Is(err, ErrDeviceGone) {
+				t.Errorf("expected ErrDeviceGone, but got %v", err)
+			}
+
+			failed := []string{}
+			for field := range status.Errors {
+				failed = append(failed, field)
+			}
+
+			sort.Strings(failed)
+
+			if len(failed) != 0 || len(tc.expectedErrors) != 0 {
+				if !reflect.DeepEqual(failed, tc.expectedErrors) {
+					t.Errorf("expected failed fields %v, but got %v", tc.expectedErrors, failed)
+				}
+			}
+
+			status.Errors = nil
+			if !reflect.DeepEqual(status, tc.expected) {
+				t.Errorf("expected %+v, but got %+v", tc.expected, status)
+			}
+		})
+	}
+}
@@ -0,0 +1,94 @@
+// Copyright 2023 Intel Corporation. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fpga
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// User clock registers exposed by the intel-fpga port driver.
+const (
+	userClkDir         = "userclk"
+	userClkFreqSts     = "userclk_freqsts"
+	userClkFreqCntrCmd = "userclk_freqcntrcmd"
+	userClkFreqCntrSts = "userclk_freqcntrsts"
+
+	// userClkLocked is the PLL lock bit of the frequency status register.
+	userClkLocked = uint64(1) << 60
+	// userClkSelectLow selects the low (divided by 2) clock for the frequency counter.
+	userClkSelectLow = uint64(1) << 32
+	// userClkFreqMask masks measured frequency in the counter status register.
+	userClkFreqMask = uint64(0x1ffff)
+	// userClkFreqUnit is the unit of measured frequency in Hz.
+	userClkFreqUnit = 10000
+)
+
+// readUserClkRegister reads hex value of the user clock register.
+func readUserClkRegister(dir, name string) (uint64, error) {
+	data, err := readSysfsFile(filepath.Join(dir, name))
+	if err != nil {
+		return 0, errors.Wrapf(err, "%s: unable to read %s", dir, name)
+	}
+
+	val, err := strconv.ParseUint(strings.TrimPrefix(strings.TrimSpace(string(data)), "0x"), 16, 64)
+
+	return val, errors.Wrapf(err, "%s: unable to parse %s", dir, name)
+}
+
+// measureUserClk selects the clock for the frequency counter and returns measured frequency in Hz.
+func measureUserClk(dir string, sel uint64) (uint64, error) {
+	cmd := "0x" + strconv.FormatUint(sel, 16)
+	if err := os.WriteFile(filepath.Join(dir, userClkFreqCntrCmd), []byte(cmd), 0600); err != nil {
+		return 0, errors.Wrapf(err, "%s: unable to select clock", dir)
+	}
+
+	sts, err := readUserClkRegister(dir, userClkFreqCntrSts)
+
+	return (sts & userClkFreqMask) * userClkFreqUnit, err
+}
+
+// getUserClockStatus reads PLL lock state and measures the high and the low
+// user clock frequencies (in Hz) of the port. Measuring writes the frequency
+// counter command register, so it isn't allowed in read-only mode.
+func getUserClockStatus(port Port) (locked bool, actualHigh, actualLow uint64, err error) {
+	dir := filepath.Join(port.GetSysFsPath(), userClkDir)
+	if _, err = os.Stat(filepath.Join(dir, userClkFreqSts)); err != nil {
+		return false, 0, 0, errors.Wrapf(ErrNotSupported, "%s: no user clock", port.GetName())
+	}
+
+	if err = requireWritable(port.GetName(), "user clock measurement"); err != nil {
+		return false, 0, 0, err
+	}
+
+	sts, err := readUserClkRegister(dir, userClkFreqSts)
+	if err != nil {
+		return false, 0, 0, err
+	}
+
+	if actualHigh, err = measureUserClk(dir, 0); err != nil {
+		return false, 0, 0, err
+	}
+
+	if actualLow, err = measureUserClk(dir, userClkSelectLow); err != nil {
+		return false, 0, 0, err
+	}
+
+	return sts&userClkLocked != 0, actualHigh, actualLow, nil
+}
@@ -0,0 +1,126 @@
+// Copyright 2023 Intel Corporation. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fpga
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// portBusyCyclesFile and portClockCyclesFile are optional port attributes
+	// with free running counters of the AFU clock cycles the AFU was busy
+	// in and of all the AFU clock cycles.
+	portBusyCyclesFile  = "activity/busy_cycles"
+	portClockCyclesFile = "activity/clock_cycles"
+)
+
+var (
+	// utilizationWindow is the time.Duration set by SetUtilizationWindow.
+	utilizationWindow = int64(100 * time.Millisecond)
+
+	// utilizationSleep waits for the sampling window to pass.
+	utilizationSleep = time.Sleep
+)
+
+// SetUtilizationWindow sets the interval GetUtilization samples the activity
+// counters over for the whole process. The default is 100ms.
+func SetUtilizationWindow(window time.Duration) {
+	atomic.StoreInt64(&utilizationWindow, int64(window))
+}
+
+// getUtilizationWindow returns the interval set by SetUtilizationWindow.
+func getUtilizationWindow() time.Duration {
+	return time.Duration(atomic.LoadInt64(&utilizationWindow))
+}
+
+// activitySample is a snapshot of the port activity counters.
+type activitySample struct {
+	busy  uint64
+	clock uint64
+}
+
+// readActivity takes a snapshot of the activity counters of the port.
+func readActivity(port Port) (activitySample, error) {
+	var sample activitySample
+
+	counters := map[string]*uint64{
+		portBusyCyclesFile:  &sample.busy,
+		portClockCyclesFile: &sample.clock,
+	}
+
+	for attr, counter := range counters {
+		data, err := readSysfsFile(filepath.Join(port.GetSysFsPath(), attr))
+		if os.IsNotExist(err) {
+			return activitySample{}, errors.Wrapf(ErrNotSupported, "%s: no activity counters", port.GetName())
+		}
+
+		if err != nil {
+			return activitySample{}, errors.Wrapf(err, "%s: unable to read %s", port.GetName(), attr)
+		}
+
+		if *counter, err = strconv.ParseUint(strings.TrimSpace(string(data)), 10, 64); err != nil {
+			return activitySample{}, errors.Wrapf(err, "%s: unable to parse %s", port.GetName(), attr)
+		}
+	}
+
+	return sample, nil
+}
+
+// getUtilization takes two snapshots of the activity counters of the port
+// the utilization window apart and returns the fraction of the AFU clock
+// cycles the AFU was busy in between.
+func getUtilization(port Port) (float64, error) {
+	if port.GetSysFsPath() == "" {
+		return 0, errors.Errorf("%s: unknown sysfs entry", port.GetName())
+	}
+
+	window := getUtilizationWindow()
+
+	before, err := readActivity(port)
+	if err != nil {
+		return 0, err
+	}
+
+	utilizationSleep(window)
+
+	after, err := readActivity(port)
+	if err != nil {
+		return 0, err
+	}
+
+	if after.busy < before.busy || after.clock < before.clock {
+		return 0, errors.Errorf("%s: activity counters went back, the port was reset", port.GetName())
+	}
+
+	clock := after.clock - before.clock
+	if clock == 0 {
+		return 0, errors.Errorf("%s: AFU clock didn't tick in %v", port.GetName(), window)
+	}
+
+	busy := float64(after.busy-before.busy) / float64(clock)
+	if busy > 1 {
+		// The counters aren't read atomically.
+		busy = 1
+	}
+
+	return busy, nil
+}
@@ -0,0 +1,124 @@
+// Copyright 2023 Intel Corporation. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fpga
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+func TestGetUtilization(t *testing.T) {
+	origWindow := getUtilizationWindow()
+	SetUtilizationWindow(50 * time.Millisecond)
+
+	t.Cleanup(func() { SetUtilizationWindow(origWindow) })
+
+	tcases := []struct {
+		name          string
+		before, after [2]uint64
+		expected      float64
+		noCounters    bool
+		expectedErr   bool
+	}{
+		{
+			name:       "no counters",
+			noCounters: true,
+		},
+		{
+			name:     "idle",
+			before:   [2]uint64{1000, 5000},
+			after:    [2]uint64{1000, 25000},
+			expected: 0,
+		},
+		{
+			name:     "quarter busy",
+			before:   [2]uint64{1000, 5000},
+			after:    [2]uint64{6000, 25000},
+			expected: 0.25,
+		},
+		{
+			name:     "fully busy",
+			before:   [2]uint64{0, 0},
+			after:    [2]uint64{20010, 20000},
+			expected: 1,
+		},
+		{
+			name:        "reset in between",
+			before:      [2]uint64{6000, 25000},
+			after:       [2]uint64{10, 20},
+			expectedErr: true,
+		},
+		{
+			name:        "stopped clock",
+			before:      [2]uint64{1000, 5000},
+			after:       [2]uint64{1000, 5000},
+			expectedErr: true,
+		},
+	}
+	for _, tc := range tcases {
+		t.Run(tc.name, func(t *testing.T) {
+			port := newTestIntelFpgaPort(t, nil, testAFUOld)
+
+			writeCounters := func(counters [2]uint64) {
+				if tc.noCounters {
+					return
+				}
+
+				files := map[string]string{
+					portBusyCyclesFile:  fmt.Sprintln(counters[0]),
+					portClockCyclesFile: fmt.Sprintln(counters[1]),
+				}
+				if err := createTestFiles(port.SysFsPath, []string{"activity"}, files); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			origSleep := utilizationSleep
+			defer func() { utilizationSleep = origSleep }()
+
+			slept := time.Duration(0)
+			utilizationSleep = func(d time.Duration) {
+				slept += d
+				writeCounters(tc.after)
+			}
+
+			writeCounters(tc.before)
+
+			busy, err := port.GetUtilization()
+
+			switch {
+			case tc.noCounters:
+				if !errors.Is(err, ErrNotSupported) {
+					t.Errorf("expected ErrNotSupported, but got %v", err)
+				}
+			case tc.expectedErr:
+				if err == nil {
+					t.Error("no error returned")
+				}
+			case err != nil:
+				t.Errorf("unexpected error: %+v", err)
+			case busy != tc.expected:
+				t.Errorf("expected utilization %v, but got %v", tc.expected, busy)
+			}
+
+			if !tc.noCounters && slept != 50*time.Millisecond {
+				t.Errorf("expected sampling over 50ms, but slept %v", slept)
+			}
+		})
+	}
+}
@@ -15,18 +15,74 @@
 package fpga
 
 import (
+	"fmt"
+	"io/fs"
 	"os"
 	"path/filepath"
 	"strings"
+	"syscall"
 
 	"github.com/pkg/errors"
+	"golang.org/x/sys/unix"
 )
 
+// readFile reads sysfs attributes. Tests replace it to simulate failures.
+var readFile = os.ReadFile
+
+// deviceGoneError marks errors indicating that the device is gone.
+type deviceGoneError struct {
+	err error
+}
+
+func (e *deviceGoneError) Error() string {
+	return fmt.Sprintf("%v: %v", ErrDeviceGone, e.err)
+}
+
+func (e *deviceGoneError) Unwrap() error {
+	return e.err
+}
+
+// Is makes errors.Is(err, ErrDeviceGone) succeed.
+func (e *deviceGoneError) Is(target error) bool {
+	return target == ErrDeviceGone
+}
+
+// classifyDeviceError marks err with ErrDeviceGone if it's ENODEV or ENXIO,
+// which the kernel returns for operations on removed devices.
+func classifyDeviceError(err error) error {
+	if errors.Is(err, syscall.ENODEV) || errors.Is(err, syscall.ENXIO) {
+		return &deviceGoneError{err: err}
+	}
+
+	return err
+}
+
+// readSysfsFile reads sysfs attribute and classifies the failures caused by
+// removal of the device.
+func readSysfsFile(name string) ([]byte, error) {
+	data, err := readFile(name)
+
+	return data, classifyDeviceError(err)
+}
+
 // small helper function that reads several files into provided set of variables.
+// If dir contains wildcards, it's resolved with a single Glob and all the files
+// are read relative to the found directory. Nothing is read if the pattern
+// doesn't match exactly one directory. Missing files are skipped.
 func readFilesInDirectory(fileMap map[string]*string, dir string) error {
+	if hasWildcards(dir) {
+		dirs, err := filepath.Glob(dir)
+		if err != nil || len(dirs) != 1 {
+			// doesn't match unique directory, nothing to read
+			return nil
+		}
+
+		dir = dirs[0]
+	}
+
 	for k, v := range fileMap {
 		fname := filepath.Join(dir, k)
-		if strings.ContainsAny(fname, "?*[") {
+		if hasWildcards(k) {
 			// path contains wildcards, let's find by Glob needed file.
 			files, err := filepath.Glob(fname)
 
@@ -41,7 +97,7 @@ func readFilesInDirectory(fileMap map[string]*string, dir string) error {
 			fname = files[0]
 		}
 
-		b, err := os.ReadFile(fname)
+		b, err := readSysfsFile(fname)
 		if err != nil {
 			if os.IsNotExist(err) {
 				continue
@@ -56,6 +112,102 @@ func readFilesInDirectory(fileMap map[string]*string, dir string) error {
 	return nil
 }
 
+// hasWildcards returns true if the path is a Glob pattern.
+func hasWildcards(path string) bool {
+	return strings.ContainsAny(path, "?*[")
+}
+
+// resolveCharDev returns path to the character device node with the given
+// "major:minor" number. The /dev/char symlinks are used if they exist,
+// otherwise the device node is looked up among the top level entries of
+// /dev, as some environments (e.g. containers) don't populate /dev/char.
+func resolveCharDev(dev string) (string, error) {
+	realDev, err := filepath.EvalSymlinks(filepath.Join(devfsRoot, "char", dev))
+	if err == nil {
+		return realDev, nil
+	}
+
+	var major, minor uint32
+	if _, serr := fmt.Sscanf(dev, "%d:%d", &major, &minor); serr != nil {
+		return "", errors.Wrapf(serr, "malformed device number %q", dev)
+	}
+
+	entries, rerr := os.ReadDir(devfsRoot)
+	if rerr != nil {
+		return "", errors.Wrapf(err, "unable to list %s: %v", devfsRoot, rerr)
+	}
+
+	rdev := unix.Mkdev(major, minor)
+
+	for _, entry := range entries {
+		if entry.Type()&os.ModeCharDevice == 0 {
+			continue
+		}
+
+		fname := filepath.Join(devfsRoot, entry.Name())
+
+		fi, statErr := os.Stat(fname)
+		if statErr != nil {
+			continue
+		}
+
+		if st, ok := fi.Sys().(*syscall.Stat_t); ok && st.Rdev == rdev {
+			return fname, nil
+		}
+	}
+
+	return "", errors.Wrapf(err, "no device node found for %s", dev)
+}
+
+// errStopWalk terminates directory walk early.
+var errStopWalk = errors.New("stop walk")
+
+// findInSubtree returns path to the first file with the given name found
+// in dir or its subdirectories up to depth levels below. Symlinks are not
+// followed. Empty string is returned if nothing is found.
+func findInSubtree(dir, name string, depth int) (string, error) {
+	var found string
+
+	base := strings.Count(filepath.Clean(dir), string(filepath.Separator))
+
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if path == dir {
+				return err
+			}
+			// unreadable entries are skipped
+			return nil
+		}
+
+		if d.IsDir() && strings.Count(path, string(filepath.Separator))-base >= depth {
+			return filepath.SkipDir
+		}
+
+		if !d.IsDir() && d.Name() == name {
+			found = path
+
+			return errStopWalk
+		}
+
+		return nil
+	})
+	if errors.Is(err, errStopWalk) {
+		err = nil
+	}
+
+	return found, errors.Wrapf(err, "unable to search for %s in %s", name, dir)
+}
+
+// devNodeName derives device name (e.g. intel-fpga-port.0) from its device node
+// path. It's used when sysfs entry of the device is unavailable.
+func devNodeName(devPath string) string {
+	if devPath == "" {
+		return ""
+	}
+
+	return cleanBasename(devPath)
+}
+
 // returns filename of the argument after resolving symlinks.
 func cleanBasename(name string) string {
 	realPath, err := filepath.EvalSymlinks(name)
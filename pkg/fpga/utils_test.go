@@ -0,0 +1,238 @@
+// Copyright 2023 Intel Corporation. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fpga
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+
+	"github.com/pkg/errors"
+	"golang.org/x/sys/unix"
+)
+
+// setTestDevfsRoot points devfsRoot to a temporary directory for the duration of the test.
+func setTestDevfsRoot(t *testing.T) string {
+	t.Helper()
+
+	root := t.TempDir()
+	orig := devfsRoot
+	devfsRoot = root
+
+	t.Cleanup(func() { devfsRoot = orig })
+
+	return root
+}
+
+func TestResolveCharDev(t *testing.T) {
+	tcases := []struct {
+		name        string
+		dev         string
+		charLinks   bool
+		expected    string
+		expectedErr bool
+	}{
+		{
+			name:      "resolved with /dev/char",
+			dev:       "240:1",
+			charLinks: true,
+			expected:  "intel-fpga-fme.1",
+		},
+		{
+			name:     "resolved by scanning /dev",
+			dev:      "240:1",
+			expected: "intel-fpga-fme.1",
+		},
+		{
+			name:        "no such device",
+			dev:         "240:7",
+			expectedErr: true,
+		},
+		{
+			name:        "malformed device number",
+			dev:         "fme",
+			expectedErr: true,
+		},
+	}
+	for _, tc := range tcases {
+		t.Run(tc.name, func(t *testing.T) {
+			root := setTestDevfsRoot(t)
+
+			for minor, name := range []string{"intel-fpga-fme.0", "intel-fpga-fme.1", "intel-fpga-port.0"} {
+				if err := unix.Mknod(filepath.Join(root, name), unix.S_IFCHR|0600, int(unix.Mkdev(240, uint32(minor)))); err != nil {
+					t.Skipf("unable to create device node: %v", err)
+				}
+			}
+
+			if tc.charLinks {
+				if err := os.Mkdir(filepath.Join(root, "char"), 0750); err != nil {
+					t.Fatal(err)
+				}
+
+				if err := os.Symlink("../intel-fpga-fme.1", filepath.Join(root, "char", "240:1")); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			dev, err := resolveCharDev(tc.dev)
+			if tc.expectedErr {
+				if err == nil {
+					t.Errorf("no error returned, got %q", dev)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("unexpected error: %+v", err)
+			}
+
+			if dev != filepath.Join(root, tc.expected) {
+				t.Errorf("expected %q, but got %q", filepath.Join(root, tc.expected), dev)
+			}
+		})
+	}
+}
+
+func TestReadFilesInDirectory(t *testing.T) {
+	pciPath := filepath.Join(t.TempDir(), testBDF)
+	files := map[string]string{
+		filepath.Join(testIntelFME, "bitstream_id"):    testBitstreamA + "\n",
+		filepath.Join(testIntelFME, "ports_num"):       "1\n",
+		filepath.Join(testIntelFME, "pr/interface_id"): testInterface + "\n",
+	}
+
+	if err := createTestFiles(pciPath, nil, files); err != nil {
+		t.Fatal(err)
+	}
+
+	var bitstreamID, portsNum, interfaceID, missing string
+
+	fileMap := map[string]*string{
+		"bitstream_id":  &bitstreamID,
+		"ports_num":     &portsNum,
+		"p?/interface*": &interfaceID,
+		"socket_id":     &missing,
+	}
+
+	if err := readFilesInDirectory(fileMap, filepath.Join(pciPath, intelFpgaFmeGlobPCI)); err != nil {
+		t.Fatalf("unexpected error: %+v", err)
+	}
+
+	if bitstreamID != testBitstreamA || portsNum != "1" || interfaceID != testInterface || missing != "" {
+		t.Errorf("unexpected values: %q %q %q %q", bitstreamID, portsNum, interfaceID, missing)
+	}
+
+	// Second FME makes the directory pattern ambiguous.
+	if err := createTestFiles(pciPath, nil, map[string]string{"fpga/intel-fpga-dev.1/intel-fpga-fme.1/ports_num": "2"}); err != nil {
+		t.Fatal(err)
+	}
+
+	portsNum = ""
+	if err := readFilesInDirectory(fileMap, filepath.Join(pciPath, intelFpgaFmeGlobPCI)); err != nil {
+		t.Fatalf("unexpected error: %+v", err)
+	}
+
+	if portsNum != "" {
+		t.Errorf("ambiguous directory must not be read, but got %q", portsNum)
+	}
+}
+
+func TestReadSysfsFileDeviceGone(t *testing.T) {
+	origReadFile := readFile
+
+	t.Cleanup(func() { readFile = origReadFile })
+
+	readFile = func(name string) ([]byte, error) {
+		return nil, &os.PathError{Op: "read", Path: name, Err: syscall.ENODEV}
+	}
+
+	port := &IntelFpgaPort{SysFsPath: t.TempDir()}
+	if _, err := port.ReadAcceleratorTypeUUIDFresh(); !errors.Is(err, ErrDeviceGone) || !errors.Is(err, syscall.ENODEV) {
+		t.Errorf("expected ErrDeviceGone, but got %v", err)
+	}
+
+	readFile = func(name string) ([]byte, error) {
+		return nil, &os.PathError{Op: "open", Path: name, Err: syscall.ENOENT}
+	}
+
+	// Missing attributes are skipped as before.
+	if err := port.RefreshProperties(); err != nil {
+		t.Errorf("unexpected error: %+v", err)
+	}
+}
+
+// readFilesGlobPerFile is the previous implementation of readFilesInDirectory
+// that globs the full path of every file. It's kept for comparison only.
+func readFilesGlobPerFile(fileMap map[string]*string, dir string) error {
+	for k, v := range fileMap {
+		fname := filepath.Join(dir, k)
+		if hasWildcards(fname) {
+			files, err := filepath.Glob(fname)
+			if err != nil || len(files) != 1 {
+				continue
+			}
+
+			fname = files[0]
+		}
+
+		b, err := os.ReadFile(fname)
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+
+			return err
+		}
+
+		*v = strings.TrimSpace(string(b))
+	}
+
+	return nil
+}
+
+func BenchmarkReadFilesInDirectory(b *testing.B) {
+	pciPath := filepath.Join(b.TempDir(), testBDF)
+	props := []string{"bitstream_id", "bitstream_metadata", "dev", "ports_num", "socket_id", "pr/interface_id"}
+	files := make(map[string]string, len(props))
+	values := make([]string, len(props))
+	fileMap := make(map[string]*string, len(props))
+
+	for i, prop := range props {
+		files[filepath.Join(testIntelFME, prop)] = "value"
+		fileMap[prop] = &values[i]
+	}
+
+	if err := createTestFiles(pciPath, nil, files); err != nil {
+		b.Fatal(err)
+	}
+
+	dir := filepath.Join(pciPath, intelFpgaFmeGlobPCI)
+
+	for name, read := range map[string]func(map[string]*string, string) error{
+		"single glob":   readFilesInDirectory,
+		"glob per file": readFilesGlobPerFile,
+	} {
+		b.Run(name, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				if err := read(fileMap, dir); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}